@@ -0,0 +1,40 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/evermos/boilerplate-go/configs"
+	"github.com/evermos/boilerplate-go/event/consumer"
+	"github.com/evermos/boilerplate-go/shared/logger"
+	"github.com/rs/zerolog/log"
+)
+
+// redrive replays messages from a topic's dead-letter queue back to the topic's
+// queue, e.g. `go run ./cmd/redrive -topic foobarbaz -max 100`.
+func main() {
+	topic := flag.String("topic", "", "Name of the consumer topic whose dead-letter queue is redriven.")
+	max := flag.Int("max", 0, "Maximum number of messages to redrive, 0 redrives everything.")
+	flag.Parse()
+
+	logger.InitLogger()
+	config := configs.Get()
+	logger.SetLogLevel(config)
+
+	topicConfig, ok := consumer.TopicConfig(config, *topic)
+	if !ok {
+		log.Error().Str("topic", *topic).Msg("Unknown topic.")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if topicConfig.DeadLetterURL == "" || topicConfig.URL == "" {
+		log.Fatal().Str("topic", *topic).Msg("Topic has no queue or dead-letter queue configured.")
+	}
+
+	_, err := consumer.NewSQSConsumer(config).Redrive(topicConfig.DeadLetterURL, topicConfig.URL, *max)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		os.Exit(1)
+	}
+}
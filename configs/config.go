@@ -10,6 +10,19 @@ import (
 // Config is a struct that will receive configuration options via environment
 // variables.
 type Config struct {
+	Account struct {
+		PasswordResetExpirationSeconds int64  `mapstructure:"PASSWORD_RESET_EXPIRATION_SECONDS"`
+		PasswordResetURL               string `mapstructure:"PASSWORD_RESET_URL"`
+		// TokenSecret signs password reset and email verification tokens.
+		TokenSecret                   string `mapstructure:"TOKEN_SECRET"`
+		VerificationExpirationSeconds int64  `mapstructure:"VERIFICATION_EXPIRATION_SECONDS"`
+		VerificationURL               string `mapstructure:"VERIFICATION_URL"`
+	}
+
+	APIKey struct {
+		DefaultQuotaPerMinute int `mapstructure:"DEFAULT_QUOTA_PER_MINUTE"`
+	}
+
 	App struct {
 		CORS struct {
 			AllowCredentials bool     `mapstructure:"ALLOW_CREDENTIALS"`
@@ -25,6 +38,50 @@ type Config struct {
 		AuthUrl  string `mapstructure:"AUTH_URL"`
 	}
 
+	Auth struct {
+		// Mode selects how access tokens are verified: "local" verifies JWTs
+		// against the JWKS, "remote" calls App.AuthUrl.
+		Mode string `mapstructure:"MODE"`
+
+		JWT struct {
+			Audience string `mapstructure:"AUDIENCE"`
+			Issuer   string `mapstructure:"ISSUER"`
+		}
+
+		JWKS struct {
+			File           string `mapstructure:"FILE"`
+			RefreshSeconds int64  `mapstructure:"REFRESH_SECONDS"`
+			URL            string `mapstructure:"URL"`
+		}
+
+		Remote struct {
+			BreakerCooldownSeconds int64 `mapstructure:"BREAKER_COOLDOWN_SECONDS"`
+			BreakerMaxFailures     int   `mapstructure:"BREAKER_MAX_FAILURES"`
+			CacheSeconds           int64 `mapstructure:"CACHE_SECONDS"`
+			TimeoutSeconds         int64 `mapstructure:"TIMEOUT_SECONDS"`
+		}
+	}
+
+	Blob struct {
+		// BaseURL is the public URL blobs are served under. It defaults to
+		// App.URL + "/media" for "local", and to the bucket's URL for "s3".
+		BaseURL string `mapstructure:"BASE_URL"`
+		Dir     string `mapstructure:"DIR"`
+		// Driver selects where blobs are stored: "local", the default, stores
+		// them in Dir, "s3" in an S3-compatible bucket.
+		Driver string `mapstructure:"DRIVER"`
+
+		S3 struct {
+			AccessKeyID string `mapstructure:"ACCESS_KEY_ID"`
+			Bucket      string `mapstructure:"BUCKET"`
+			// Endpoint points to an S3-compatible store such as MinIO.
+			Endpoint        string `mapstructure:"ENDPOINT"`
+			ForcePathStyle  bool   `mapstructure:"FORCE_PATH_STYLE"`
+			Region          string `mapstructure:"REGION"`
+			SecretAccessKey string `mapstructure:"SECRET_ACCESS_KEY"`
+		}
+	}
+
 	Cache struct {
 		Redis struct {
 			Primary struct {
@@ -58,6 +115,17 @@ type Config struct {
 
 	Event struct {
 		Consumer struct {
+			Idempotency struct {
+				Store      string `mapstructure:"STORE"`
+				TTLSeconds int64  `mapstructure:"TTL_SECONDS"`
+			}
+
+			Signature struct {
+				AllowedCertHosts []string `mapstructure:"ALLOWED_CERT_HOSTS"`
+				CertCacheSeconds int64    `mapstructure:"CERT_CACHE_SECONDS"`
+				Enabled          bool     `mapstructure:"ENABLED"`
+			}
+
 			SQS struct {
 				AccessKeyID       string `mapstructure:"ACCESS_KEY_ID"`
 				BackoffSeconds    int    `mapstructure:"BACKOFF_SECONDS"`
@@ -68,12 +136,20 @@ type Config struct {
 				SecretAccessKey   string `mapstructure:"SECRET_ACCESS_KEY"`
 				WaitTimeSeconds   int64  `mapstructure:"WAIT_TIME_SECONDS"`
 
-				Topics struct {
-					FooBarBaz struct {
-						Enabled bool   `mapstructure:"ENABLED"`
-						URL     string `mapstructure:"URL"`
-					} `mapstructure:"FOOBARBAZ"`
+				Retry struct {
+					BackoffBaseSeconds int64 `mapstructure:"BACKOFF_BASE_SECONDS"`
+					BackoffMaxSeconds  int64 `mapstructure:"BACKOFF_MAX_SECONDS"`
+					MaxReceiveCount    int   `mapstructure:"MAX_RECEIVE_COUNT"`
 				}
+
+				Visibility struct {
+					HeartbeatSeconds int64 `mapstructure:"HEARTBEAT_SECONDS"`
+					TimeoutSeconds   int64 `mapstructure:"TIMEOUT_SECONDS"`
+				}
+
+				// Topics maps registered topic names to their queues, e.g.
+				// EVENT.CONSUMER.SQS.TOPICS.FOOBARBAZ.URL.
+				Topics map[string]SQSTopic
 			}
 		}
 
@@ -93,6 +169,62 @@ type Config struct {
 		}
 	}
 
+	Image struct {
+		MaxPixels     int   `mapstructure:"MAX_PIXELS"`
+		MaxSizeBytes  int64 `mapstructure:"MAX_SIZE_BYTES"`
+		ThumbnailSize int   `mapstructure:"THUMBNAIL_SIZE"`
+	}
+
+	Mail struct {
+		// Driver selects the mailer: "smtp", "file" writes messages to Dir,
+		// and "log", the default, logs them.
+		Driver string `mapstructure:"DRIVER"`
+		Dir    string `mapstructure:"DIR"`
+		From   string `mapstructure:"FROM"`
+
+		SMTP struct {
+			Host     string `mapstructure:"HOST"`
+			Password string `mapstructure:"PASSWORD"`
+			Port     string `mapstructure:"PORT"`
+			Username string `mapstructure:"USERNAME"`
+		}
+	}
+
+	OAuth struct {
+		AuthorizationCodeExpirationSeconds int64 `mapstructure:"AUTHORIZATION_CODE_EXPIRATION_SECONDS"`
+		// ClientScope limits which clients may request tokens, "*" or empty allows all.
+		ClientScope               []string `mapstructure:"CLIENT_SCOPE"`
+		ExpirationSeconds         int64    `mapstructure:"EXPIRATION_SECONDS"`
+		IntrospectionCacheSeconds int64    `mapstructure:"INTROSPECTION_CACHE_SECONDS"`
+		RefreshExpirationSeconds  int64    `mapstructure:"REFRESH_EXPIRATION_SECONDS"`
+		TokenPurgeIntervalSeconds int64    `mapstructure:"TOKEN_PURGE_INTERVAL_SECONDS"`
+		// TokenStore selects where access tokens are stored, "mysql" (default) or "redis".
+		TokenStore string `mapstructure:"TOKEN_STORE"`
+
+		// Login configures the brute-force protection of the password grant.
+		Login struct {
+			BaseDelayMilliseconds int64 `mapstructure:"BASE_DELAY_MILLISECONDS"`
+			FreeAttempts          int64 `mapstructure:"FREE_ATTEMPTS"`
+			IPLockout             int64 `mapstructure:"IP_LOCKOUT"`
+			LockoutSeconds        int64 `mapstructure:"LOCKOUT_SECONDS"`
+			MaxDelaySeconds       int64 `mapstructure:"MAX_DELAY_SECONDS"`
+			// TrustForwardedFor takes client IPs from X-Forwarded-For, set by a trusted proxy.
+			TrustForwardedFor bool  `mapstructure:"TRUST_FORWARDED_FOR"`
+			UsernameLockout   int64 `mapstructure:"USERNAME_LOCKOUT"`
+			WindowSeconds     int64 `mapstructure:"WINDOW_SECONDS"`
+		}
+	}
+
+	Search struct {
+		// Driver selects the product searcher: "mysql", the default, uses a
+		// FULLTEXT index, "bleve" an index embedded in each instance.
+		Driver string `mapstructure:"DRIVER"`
+
+		Bleve struct {
+			Path string `mapstructure:"PATH"`
+		}
+	}
+
 	Server struct {
 		Env      string `mapstructure:"ENV"`
 		LogLevel string `mapstructure:"LOG_LEVEL"`
@@ -102,6 +234,22 @@ type Config struct {
 			GracePeriodSeconds   int64 `mapstructure:"GRACE_PERIOD_SECONDS"`
 		}
 	}
+
+	Warehouse struct {
+		// Allocation selects how checkout picks the warehouses items ship
+		// from: "nearest", the default, to the shipping address, or
+		// "most_stock", the warehouse holding the most.
+		Allocation string `mapstructure:"ALLOCATION"`
+	}
+}
+
+// SQSTopic is the queue configuration of a consumed topic. Topic names are
+// case-insensitive and resolved in lower case.
+type SQSTopic struct {
+	DeadLetterURL string `mapstructure:"DEAD_LETTER_URL"`
+	Enabled       bool   `mapstructure:"ENABLED"`
+	URL           string `mapstructure:"URL"`
+	Workers       int    `mapstructure:"WORKERS"`
 }
 
 var (
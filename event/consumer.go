@@ -1,22 +1,107 @@
 package event
 
 import (
+	"sync"
+
+	"github.com/evermos/boilerplate-go/configs"
+	"github.com/evermos/boilerplate-go/event/consumer"
 	"github.com/evermos/boilerplate-go/event/domain/foobarbaz"
+	"github.com/evermos/boilerplate-go/infras"
+	"github.com/rs/zerolog/log"
 )
 
+// ProvideRegistry is the provider function for the topic Registry. Every
+// domain consuming events registers its topics here.
+func ProvideRegistry(fooBarBaz foobarbaz.ConsumerImpl) *consumer.Registry {
+	registry := consumer.NewRegistry()
+	fooBarBaz.Register(registry)
+	return registry
+}
+
 // Consumers is the wrapper to contain all event consumers.
 type Consumers struct {
-	FooBarBaz foobarbaz.ConsumerImpl
+	Config   *configs.Config
+	DB       *infras.MySQLConn
+	Ledger   consumer.Ledger
+	Registry *consumer.Registry
+	Verifier *consumer.Verifier
+
+	consumers []consumer.Consumer
 }
 
 // ProvideConsumers is the provider function for Consumers.
-func ProvideConsumers(fooBarBaz foobarbaz.ConsumerImpl) Consumers {
-	return Consumers{
-		FooBarBaz: fooBarBaz,
-	}
+func ProvideConsumers(config *configs.Config, db *infras.MySQLConn, registry *consumer.Registry, ledger consumer.Ledger, verifier *consumer.Verifier) *Consumers {
+	c := new(Consumers)
+	c.Config = config
+	c.DB = db
+	c.Ledger = ledger
+	c.Registry = registry
+	c.Verifier = verifier
+	return c
 }
 
-// Start starts all domains event consumer
+// Start starts a listener for every registered topic that is enabled in the
+// configuration. Topics with a TxHandler record their messages in the MySQL
+// ledger in the transaction of their writes, and others in the configured
+// ledger. Processed messages are purged from MySQL once past the ledger TTL.
 func (c *Consumers) Start() {
-	c.FooBarBaz.Start()
+	registered := make(map[string]bool)
+	purged := false
+
+	for _, topic := range c.Registry.Topics() {
+		registered[topic.Name] = true
+
+		topicConfig, ok := consumer.TopicConfig(c.Config, topic.Name)
+		if !ok || !topicConfig.Enabled {
+			log.Info().Str("topic", topic.Name).Msg("Consumer topic is not enabled.")
+			continue
+		}
+
+		if topicConfig.URL == "" {
+			log.Warn().Str("topic", topic.Name).Msg("Consumer topic is enabled without a queue URL.")
+			continue
+		}
+
+		_, mysqlLedger := c.Ledger.(*consumer.MySQLLedger)
+		if !purged && (mysqlLedger || topic.IsTransactional()) {
+			consumer.NewMySQLLedger(c.DB).StartPurge(consumer.LedgerTTL(c.Config))
+			purged = true
+		}
+
+		sqsConsumer := consumer.NewSQSConsumer(c.Config)
+		if topic.IsTransactional() {
+			sqsConsumer.Process = consumer.IdempotentTx(c.DB, topic.Name, topic.TxProcess)
+		} else {
+			sqsConsumer.Process = consumer.Idempotent(c.Ledger, topic.Name, topic.Process)
+		}
+		if c.Config.Event.Consumer.Signature.Enabled {
+			sqsConsumer.Process = consumer.Verified(c.Verifier, sqsConsumer.Process)
+		}
+		sqsConsumer.RetryPolicy = consumer.NewRetryPolicy(c.Config, topicConfig.DeadLetterURL)
+		if topicConfig.Workers > 0 {
+			sqsConsumer.Workers = topicConfig.Workers
+		}
+
+		c.consumers = append(c.consumers, sqsConsumer)
+		go sqsConsumer.Listen(topicConfig.URL)
+	}
+
+	for name := range c.Config.Event.Consumer.SQS.Topics {
+		if !registered[name] {
+			log.Warn().Str("topic", name).Msg("Configured consumer topic is not registered by any domain.")
+		}
+	}
+}
+
+// Stop stops all event consumers and waits for their in-flight messages.
+func (c *Consumers) Stop() {
+	var wg sync.WaitGroup
+	for _, sqsConsumer := range c.consumers {
+		wg.Add(1)
+		go func(sqsConsumer consumer.Consumer) {
+			defer wg.Done()
+			sqsConsumer.Stop()
+		}(sqsConsumer)
+	}
+	wg.Wait()
 }
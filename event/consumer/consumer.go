@@ -2,4 +2,5 @@ package consumer
 
 type Consumer interface {
 	Listen(url string)
+	Stop()
 }
@@ -0,0 +1,260 @@
+package consumer
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/evermos/boilerplate-go/configs"
+	"github.com/evermos/boilerplate-go/event/model"
+	"github.com/evermos/boilerplate-go/infras"
+	"github.com/go-redis/redis"
+	"github.com/gofrs/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// LedgerStoreMySQL records processed messages in the processed_messages table.
+	LedgerStoreMySQL = "mysql"
+	// LedgerStoreRedis records processed messages as Redis keys with a TTL.
+	LedgerStoreRedis = "redis"
+
+	// defaultLedgerTTL is how long a processed message is remembered when no TTL is configured.
+	defaultLedgerTTL = 7 * 24 * time.Hour
+	// ledgerPurgeInterval is how often processed messages past the TTL are purged from MySQL.
+	ledgerPurgeInterval = time.Hour
+	// ledgerPurgeBatchSize is the number of processed messages purged at once.
+	ledgerPurgeBatchSize = 1000
+)
+
+var ledgerQueries = struct {
+	selectProcessed string
+	insertProcessed string
+	purgeProcessed  string
+}{
+	selectProcessed: `SELECT COUNT(message_id) FROM processed_messages WHERE consumer = ? AND message_id = ?`,
+	insertProcessed: `INSERT IGNORE INTO processed_messages (consumer, message_id) VALUES (?, ?)`,
+	purgeProcessed:  `DELETE FROM processed_messages WHERE processed_at < ? LIMIT ?`,
+}
+
+// Ledger records which messages have already been processed by a consumer.
+type Ledger interface {
+	Processed(consumer string, messageID string) (processed bool, err error)
+	MarkProcessed(consumer string, messageID string) (err error)
+}
+
+// TxProcess represents a processing function that performs its writes in the
+// given transaction.
+type TxProcess func(tx *sqlx.Tx, e []byte) error
+
+// ProvideLedger is the provider for the Ledger selected in the configuration.
+func ProvideLedger(config *configs.Config, db *infras.MySQLConn, client *redis.Client) Ledger {
+	switch config.Event.Consumer.Idempotency.Store {
+	case LedgerStoreRedis:
+		return NewRedisLedger(client, LedgerTTL(config))
+	default:
+		return NewMySQLLedger(db)
+	}
+}
+
+// LedgerTTL returns how long processed messages are remembered: the Redis TTL
+// of their keys, and the retention of the processed_messages table.
+func LedgerTTL(config *configs.Config) time.Duration {
+	ttl := time.Duration(config.Event.Consumer.Idempotency.TTLSeconds) * time.Second
+	if ttl <= 0 {
+		ttl = defaultLedgerTTL
+	}
+
+	return ttl
+}
+
+// Idempotent wraps a processing function so that a message that was already
+// processed successfully by the consumer is skipped. A message is only recorded
+// once next succeeds, so concurrent deliveries of the same message may still
+// both be processed; use IdempotentTx where the handler's writes allow it.
+func Idempotent(ledger Ledger, consumer string, next Process) Process {
+	return func(e []byte) error {
+		messageID := messageIDOf(e)
+
+		processed, err := ledger.Processed(consumer, messageID)
+		if err != nil {
+			return err
+		}
+
+		if processed {
+			log.Info().Str("consumer", consumer).Str("messageId", messageID).Msg("Skipping already processed message.")
+			return nil
+		}
+
+		err = next(e)
+		if err != nil {
+			return err
+		}
+
+		return ledger.MarkProcessed(consumer, messageID)
+	}
+}
+
+// IdempotentTx wraps a transactional processing function so that the message
+// is recorded in the same transaction as the handler's writes. Duplicates are
+// skipped, and a failing handler rolls back its record with everything else.
+func IdempotentTx(db *infras.MySQLConn, consumer string, next TxProcess) Process {
+	ledger := NewMySQLLedger(db)
+
+	return func(e []byte) error {
+		messageID := messageIDOf(e)
+
+		return db.WithTransaction(func(tx *sqlx.Tx, c chan error) {
+			inserted, err := ledger.MarkProcessedTx(tx, consumer, messageID)
+			if err != nil {
+				c <- err
+				return
+			}
+
+			if !inserted {
+				log.Info().Str("consumer", consumer).Str("messageId", messageID).Msg("Skipping already processed message.")
+				c <- nil
+				return
+			}
+
+			c <- next(tx, e)
+		})
+	}
+}
+
+// messageIDOf returns the SNS message ID of a message, or a hash of its body
+// for messages that were not delivered through SNS.
+func messageIDOf(e []byte) string {
+	snsMessage := model.SNSMessage{}
+	err := json.Unmarshal(e, &snsMessage)
+	if err == nil && snsMessage.MessageID != uuid.Nil {
+		return snsMessage.MessageID.String()
+	}
+
+	return fmt.Sprintf("%x", sha256.Sum256(e))
+}
+
+// MySQLLedger is the MySQL-backed implementation of Ledger.
+type MySQLLedger struct {
+	DB *infras.MySQLConn
+}
+
+// NewMySQLLedger creates a new MySQLLedger.
+func NewMySQLLedger(db *infras.MySQLConn) *MySQLLedger {
+	return &MySQLLedger{DB: db}
+}
+
+// Processed checks whether a message was processed by the consumer.
+func (l *MySQLLedger) Processed(consumer string, messageID string) (processed bool, err error) {
+	err = l.DB.Write.Get(&processed, ledgerQueries.selectProcessed, consumer, messageID)
+	if err != nil {
+		log.Error().Err(err).Str("consumer", consumer).Str("messageId", messageID).Msg("failed resolving processed message")
+	}
+
+	return
+}
+
+// MarkProcessed records a message as processed by the consumer.
+func (l *MySQLLedger) MarkProcessed(consumer string, messageID string) (err error) {
+	_, err = l.DB.Write.Exec(ledgerQueries.insertProcessed, consumer, messageID)
+	if err != nil {
+		log.Error().Err(err).Str("consumer", consumer).Str("messageId", messageID).Msg("failed recording processed message")
+	}
+
+	return
+}
+
+// MarkProcessedTx records a message as processed by the consumer within a
+// transaction. It returns false if the message was already recorded.
+func (l *MySQLLedger) MarkProcessedTx(tx *sqlx.Tx, consumer string, messageID string) (inserted bool, err error) {
+	result, err := tx.Exec(ledgerQueries.insertProcessed, consumer, messageID)
+	if err != nil {
+		log.Error().Err(err).Str("consumer", consumer).Str("messageId", messageID).Msg("failed recording processed message")
+		return
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return
+	}
+
+	return affected > 0, nil
+}
+
+// Purge deletes the messages processed before a time in batches, so that a
+// large backlog does not hold locks on the table for long. It returns the
+// number of messages deleted.
+func (l *MySQLLedger) Purge(before time.Time) (purged int64, err error) {
+	for {
+		result, err := l.DB.Write.Exec(ledgerQueries.purgeProcessed, before, ledgerPurgeBatchSize)
+		if err != nil {
+			return purged, err
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return purged, err
+		}
+
+		purged += affected
+		if affected < ledgerPurgeBatchSize {
+			return purged, nil
+		}
+	}
+}
+
+// StartPurge purges the messages processed longer than ttl ago every hour in
+// the background, as Redis expires them.
+func (l *MySQLLedger) StartPurge(ttl time.Duration) {
+	go func() {
+		for range time.Tick(ledgerPurgeInterval) {
+			purged, err := l.Purge(time.Now().Add(-ttl))
+			if err != nil {
+				log.Error().Err(err).Msg("Failed purging processed messages")
+				continue
+			}
+			if purged > 0 {
+				log.Info().Int64("purged", purged).Msg("Purged processed messages")
+			}
+		}
+	}()
+}
+
+// RedisLedger is the Redis-backed implementation of Ledger. Processed messages
+// are forgotten after the TTL.
+type RedisLedger struct {
+	Client *redis.Client
+	TTL    time.Duration
+}
+
+// NewRedisLedger creates a new RedisLedger.
+func NewRedisLedger(client *redis.Client, ttl time.Duration) *RedisLedger {
+	return &RedisLedger{Client: client, TTL: ttl}
+}
+
+// Processed checks whether a message was processed by the consumer.
+func (l *RedisLedger) Processed(consumer string, messageID string) (processed bool, err error) {
+	count, err := l.Client.Exists(l.key(consumer, messageID)).Result()
+	if err != nil {
+		log.Error().Err(err).Str("consumer", consumer).Str("messageId", messageID).Msg("failed resolving processed message")
+		return
+	}
+
+	return count > 0, nil
+}
+
+// MarkProcessed records a message as processed by the consumer.
+func (l *RedisLedger) MarkProcessed(consumer string, messageID string) (err error) {
+	err = l.Client.Set(l.key(consumer, messageID), 1, l.TTL).Err()
+	if err != nil {
+		log.Error().Err(err).Str("consumer", consumer).Str("messageId", messageID).Msg("failed recording processed message")
+	}
+
+	return
+}
+
+func (l *RedisLedger) key(consumer string, messageID string) string {
+	return fmt.Sprintf("processed_messages:%s:%s", consumer, messageID)
+}
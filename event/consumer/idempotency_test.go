@@ -0,0 +1,154 @@
+package consumer_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/evermos/boilerplate-go/event/consumer"
+	"github.com/evermos/boilerplate-go/infras"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryLedger map[string]bool
+
+func (l memoryLedger) Processed(consumer string, messageID string) (bool, error) {
+	return l[consumer+":"+messageID], nil
+}
+
+func (l memoryLedger) MarkProcessed(consumer string, messageID string) error {
+	l[consumer+":"+messageID] = true
+	return nil
+}
+
+func TestIdempotent(t *testing.T) {
+	message := []byte(`{"Type":"Notification","MessageId":"4e80c5bf-b79b-4c90-8f91-82647f439e55","Message":"{}"}`)
+
+	t.Run("Skips Duplicates", func(t *testing.T) {
+		calls := 0
+		process := consumer.Idempotent(memoryLedger{}, "test", func(e []byte) error {
+			calls++
+			return nil
+		})
+
+		assert.NoError(t, process(message))
+		assert.NoError(t, process(message))
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("Retries Failures", func(t *testing.T) {
+		calls := 0
+		process := consumer.Idempotent(memoryLedger{}, "test", func(e []byte) error {
+			calls++
+			if calls == 1 {
+				return errors.New("failed")
+			}
+			return nil
+		})
+
+		assert.Error(t, process(message))
+		assert.NoError(t, process(message))
+		assert.NoError(t, process(message))
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("Separates Consumers", func(t *testing.T) {
+		ledger := memoryLedger{}
+		calls := 0
+		next := func(e []byte) error {
+			calls++
+			return nil
+		}
+
+		assert.NoError(t, consumer.Idempotent(ledger, "first", next)(message))
+		assert.NoError(t, consumer.Idempotent(ledger, "second", next)(message))
+		assert.Equal(t, 2, calls)
+	})
+}
+
+func TestIdempotentTx(t *testing.T) {
+	message := []byte(`{"Type":"Notification","MessageId":"4e80c5bf-b79b-4c90-8f91-82647f439e55","Message":"{}"}`)
+	messageID := "4e80c5bf-b79b-4c90-8f91-82647f439e55"
+
+	newDB := func(t *testing.T) (*infras.MySQLConn, sqlmock.Sqlmock) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+
+		sqlxDB := sqlx.NewDb(db, "mysql")
+		return &infras.MySQLConn{Read: sqlxDB, Write: sqlxDB}, mock
+	}
+
+	t.Run("Records In The Transaction", func(t *testing.T) {
+		db, mock := newDB(t)
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT IGNORE INTO processed_messages").WithArgs("test", messageID).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO foo").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		process := consumer.IdempotentTx(db, "test", func(tx *sqlx.Tx, e []byte) error {
+			_, err := tx.Exec("INSERT INTO foo VALUES (1)")
+			return err
+		})
+
+		assert.NoError(t, process(message))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Skips Duplicates", func(t *testing.T) {
+		db, mock := newDB(t)
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT IGNORE INTO processed_messages").WithArgs("test", messageID).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		calls := 0
+		process := consumer.IdempotentTx(db, "test", func(tx *sqlx.Tx, e []byte) error {
+			calls++
+			return nil
+		})
+
+		assert.NoError(t, process(message))
+		assert.Zero(t, calls)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Rolls Back Failures", func(t *testing.T) {
+		db, mock := newDB(t)
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT IGNORE INTO processed_messages").WithArgs("test", messageID).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectRollback()
+
+		process := consumer.IdempotentTx(db, "test", func(tx *sqlx.Tx, e []byte) error {
+			return errors.New("failed")
+		})
+
+		assert.EqualError(t, process(message), "failed")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestMySQLLedgerPurge(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "mysql")
+	ledger := consumer.NewMySQLLedger(&infras.MySQLConn{Read: sqlxDB, Write: sqlxDB})
+	before := time.Now().Add(-7 * 24 * time.Hour)
+
+	mock.ExpectExec("DELETE FROM processed_messages WHERE processed_at < \\? LIMIT \\?").
+		WithArgs(before, 1000).
+		WillReturnResult(sqlmock.NewResult(0, 1000))
+	mock.ExpectExec("DELETE FROM processed_messages WHERE processed_at < \\? LIMIT \\?").
+		WithArgs(before, 1000).
+		WillReturnResult(sqlmock.NewResult(0, 10))
+
+	purged, err := ledger.Purge(before)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(1010), purged)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
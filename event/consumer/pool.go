@@ -0,0 +1,80 @@
+package consumer
+
+import (
+	"hash/fnv"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// workerPool is a bounded set of workers handling SQS messages. Messages that
+// belong to a FIFO message group are always handed to the same worker, which
+// keeps them in order; all other messages go to whichever worker is free.
+type workerPool struct {
+	grouped []chan *sqs.Message
+	shared  chan *sqs.Message
+	wg      sync.WaitGroup
+}
+
+func newWorkerPool(size int, handle func(*sqs.Message)) *workerPool {
+	if size < 1 {
+		size = 1
+	}
+
+	pool := &workerPool{
+		grouped: make([]chan *sqs.Message, size),
+		shared:  make(chan *sqs.Message),
+	}
+
+	for i := range pool.grouped {
+		pool.grouped[i] = make(chan *sqs.Message)
+		pool.wg.Add(1)
+		go pool.work(pool.grouped[i], handle)
+	}
+
+	return pool
+}
+
+// dispatch hands a message to a worker, blocking until one accepts it.
+func (w *workerPool) dispatch(msg *sqs.Message) {
+	groupID, ok := msg.Attributes[sqs.MessageSystemAttributeNameMessageGroupId]
+	if !ok || groupID == nil {
+		w.shared <- msg
+		return
+	}
+
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(*groupID))
+	w.grouped[hash.Sum32()%uint32(len(w.grouped))] <- msg
+}
+
+// drain stops accepting messages and waits until in-flight ones are handled.
+func (w *workerPool) drain() {
+	close(w.shared)
+	for _, grouped := range w.grouped {
+		close(grouped)
+	}
+	w.wg.Wait()
+}
+
+func (w *workerPool) work(grouped chan *sqs.Message, handle func(*sqs.Message)) {
+	defer w.wg.Done()
+
+	shared := w.shared
+	for grouped != nil || shared != nil {
+		select {
+		case msg, ok := <-grouped:
+			if !ok {
+				grouped = nil
+				continue
+			}
+			handle(msg)
+		case msg, ok := <-shared:
+			if !ok {
+				shared = nil
+				continue
+			}
+			handle(msg)
+		}
+	}
+}
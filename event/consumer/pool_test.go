@@ -0,0 +1,78 @@
+package consumer
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/stretchr/testify/assert"
+)
+
+func groupedMessage(groupID string, seq int) *sqs.Message {
+	return &sqs.Message{
+		Body: aws.String(fmt.Sprint(seq)),
+		Attributes: map[string]*string{
+			sqs.MessageSystemAttributeNameMessageGroupId: aws.String(groupID),
+		},
+	}
+}
+
+func TestWorkerPool(t *testing.T) {
+	t.Run("Preserves Order Per Group", func(t *testing.T) {
+		var mu sync.Mutex
+		handled := make(map[string][]string)
+
+		pool := newWorkerPool(4, func(msg *sqs.Message) {
+			groupID := *msg.Attributes[sqs.MessageSystemAttributeNameMessageGroupId]
+			time.Sleep(time.Millisecond)
+
+			mu.Lock()
+			handled[groupID] = append(handled[groupID], *msg.Body)
+			mu.Unlock()
+		})
+
+		for seq := 0; seq < 20; seq++ {
+			for _, groupID := range []string{"a", "b", "c"} {
+				pool.dispatch(groupedMessage(groupID, seq))
+			}
+		}
+		pool.drain()
+
+		for _, groupID := range []string{"a", "b", "c"} {
+			assert.Len(t, handled[groupID], 20)
+			for seq, body := range handled[groupID] {
+				assert.Equal(t, fmt.Sprint(seq), body)
+			}
+		}
+	})
+
+	t.Run("Bounded Concurrency", func(t *testing.T) {
+		var inFlight, maxInFlight, total int32
+
+		pool := newWorkerPool(3, func(msg *sqs.Message) {
+			current := atomic.AddInt32(&inFlight, 1)
+			for {
+				max := atomic.LoadInt32(&maxInFlight)
+				if current <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, current) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&inFlight, -1)
+			atomic.AddInt32(&total, 1)
+		})
+
+		for i := 0; i < 30; i++ {
+			pool.dispatch(&sqs.Message{Body: aws.String(fmt.Sprint(i))})
+		}
+		pool.drain()
+
+		assert.Equal(t, int32(30), total)
+		assert.LessOrEqual(t, maxInFlight, int32(3))
+		assert.Greater(t, maxInFlight, int32(1))
+	})
+}
@@ -0,0 +1,131 @@
+package consumer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/evermos/boilerplate-go/configs"
+	"github.com/evermos/boilerplate-go/event/model"
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
+)
+
+// Decoder decodes the payload of an SNS message into the type its Handler
+// expects.
+type Decoder func(message string) (payload interface{}, err error)
+
+// Handler handles the decoded payload of an SNS message.
+type Handler func(msg model.SNSMessage, payload interface{}) error
+
+// TxHandler handles the decoded payload of an SNS message, performing its
+// writes in the given transaction.
+type TxHandler func(tx *sqlx.Tx, msg model.SNSMessage, payload interface{}) error
+
+// Topic represents a consumed topic. Its name resolves the topic's queue in
+// the configuration and identifies it in the processed message ledger. A
+// topic has either a Handler or a TxHandler; messages of a TxHandler are
+// recorded in the ledger in the transaction of its writes.
+type Topic struct {
+	Name      string
+	Decoder   Decoder
+	Handler   Handler
+	TxHandler TxHandler
+}
+
+// JSONDecoder creates a Decoder that unmarshals JSON payloads into the value
+// returned by newPayload, which must be a pointer, e.g.
+// JSONDecoder(func() interface{} { return &foobarbaz.FooRequestFormat{} }).
+func JSONDecoder(newPayload func() interface{}) Decoder {
+	return func(message string) (interface{}, error) {
+		payload := newPayload()
+		err := json.Unmarshal([]byte(message), payload)
+		if err != nil {
+			return nil, err
+		}
+
+		return payload, nil
+	}
+}
+
+// Process unwraps an SNS message, decodes its payload and handles it.
+func (t Topic) Process(e []byte) error {
+	snsMessage, payload, err := t.decode(e)
+	if err != nil {
+		return err
+	}
+
+	return t.Handler(snsMessage, payload)
+}
+
+// TxProcess unwraps an SNS message, decodes its payload and handles it in the
+// given transaction.
+func (t Topic) TxProcess(tx *sqlx.Tx, e []byte) error {
+	snsMessage, payload, err := t.decode(e)
+	if err != nil {
+		return err
+	}
+
+	return t.TxHandler(tx, snsMessage, payload)
+}
+
+// IsTransactional reports whether the topic is handled in a transaction.
+func (t Topic) IsTransactional() bool {
+	return t.TxHandler != nil
+}
+
+func (t Topic) decode(e []byte) (snsMessage model.SNSMessage, payload interface{}, err error) {
+	err = json.Unmarshal(e, &snsMessage)
+	if err != nil {
+		return
+	}
+
+	log.
+		Info().
+		Str("topic", t.Name).
+		Str("topicARN", snsMessage.TopicARN).
+		Interface("value", snsMessage).
+		Msg("Received SNS message")
+
+	payload, err = t.Decoder(snsMessage.Message)
+	return
+}
+
+// Registry contains the topics registered by all domains.
+type Registry struct {
+	topics []Topic
+	names  map[string]bool
+}
+
+// NewRegistry creates a new, empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]bool)}
+}
+
+// Register adds a topic to the registry. Topic names are case-insensitive and
+// must be unique, and topics have exactly one of a Handler and a TxHandler.
+func (r *Registry) Register(topic Topic) {
+	topic.Name = strings.ToLower(topic.Name)
+
+	if topic.Name == "" || topic.Decoder == nil || (topic.Handler == nil) == (topic.TxHandler == nil) {
+		panic(fmt.Sprintf("consumer topic %q is missing its name, decoder or handler, or has two handlers", topic.Name))
+	}
+
+	if r.names[topic.Name] {
+		panic(fmt.Sprintf("consumer topic %q is already registered", topic.Name))
+	}
+
+	r.names[topic.Name] = true
+	r.topics = append(r.topics, topic)
+}
+
+// Topics returns the registered topics in registration order.
+func (r *Registry) Topics() []Topic {
+	return r.topics
+}
+
+// TopicConfig resolves the queue configuration of a topic.
+func TopicConfig(config *configs.Config, name string) (topic configs.SQSTopic, ok bool) {
+	topic, ok = config.Event.Consumer.SQS.Topics[strings.ToLower(name)]
+	return
+}
@@ -0,0 +1,109 @@
+package consumer_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/evermos/boilerplate-go/event/consumer"
+	"github.com/evermos/boilerplate-go/event/model"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+type testPayload struct {
+	Name string `json:"name"`
+}
+
+func testTopic(name string, handled *[]testPayload) consumer.Topic {
+	return consumer.Topic{
+		Name: name,
+		Decoder: consumer.JSONDecoder(func() interface{} {
+			return &testPayload{}
+		}),
+		Handler: func(msg model.SNSMessage, payload interface{}) error {
+			*handled = append(*handled, *payload.(*testPayload))
+			return nil
+		},
+	}
+}
+
+func TestTopic(t *testing.T) {
+	t.Run("Decoded Payload", func(t *testing.T) {
+		var handled []testPayload
+		e, _ := json.Marshal(model.SNSMessage{Message: `{"name":"foo"}`})
+
+		err := testTopic("foo", &handled).Process(e)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []testPayload{{Name: "foo"}}, handled)
+	})
+
+	t.Run("Transactional", func(t *testing.T) {
+		var handled []testPayload
+		e, _ := json.Marshal(model.SNSMessage{Message: `{"name":"foo"}`})
+		topic := testTopic("foo", &handled)
+		assert.False(t, topic.IsTransactional())
+
+		handler := topic.Handler
+		topic.TxHandler = func(tx *sqlx.Tx, msg model.SNSMessage, payload interface{}) error {
+			return handler(msg, payload)
+		}
+		topic.Handler = nil
+
+		err := topic.TxProcess(nil, e)
+
+		assert.NoError(t, err)
+		assert.True(t, topic.IsTransactional())
+		assert.Equal(t, []testPayload{{Name: "foo"}}, handled)
+	})
+
+	t.Run("Malformed Payload", func(t *testing.T) {
+		var handled []testPayload
+		e, _ := json.Marshal(model.SNSMessage{Message: `{"name":`})
+
+		err := testTopic("foo", &handled).Process(e)
+
+		assert.Error(t, err)
+		assert.Empty(t, handled)
+	})
+}
+
+func TestRegistry(t *testing.T) {
+	var handled []testPayload
+
+	t.Run("Registration Order", func(t *testing.T) {
+		registry := consumer.NewRegistry()
+		registry.Register(testTopic("FooBarBaz", &handled))
+		registry.Register(testTopic("qux", &handled))
+
+		topics := registry.Topics()
+		assert.Len(t, topics, 2)
+		assert.Equal(t, "foobarbaz", topics[0].Name)
+		assert.Equal(t, "qux", topics[1].Name)
+	})
+
+	t.Run("Duplicate Topic", func(t *testing.T) {
+		registry := consumer.NewRegistry()
+		registry.Register(testTopic("foobarbaz", &handled))
+
+		assert.Panics(t, func() { registry.Register(testTopic("FOOBARBAZ", &handled)) })
+	})
+
+	t.Run("Handlers", func(t *testing.T) {
+		registry := consumer.NewRegistry()
+		txHandler := func(tx *sqlx.Tx, msg model.SNSMessage, payload interface{}) error { return nil }
+
+		both := testTopic("both", &handled)
+		both.TxHandler = txHandler
+		assert.Panics(t, func() { registry.Register(both) })
+
+		neither := testTopic("neither", &handled)
+		neither.Handler = nil
+		assert.Panics(t, func() { registry.Register(neither) })
+
+		transactional := testTopic("transactional", &handled)
+		transactional.Handler = nil
+		transactional.TxHandler = txHandler
+		assert.NotPanics(t, func() { registry.Register(transactional) })
+	})
+}
@@ -0,0 +1,108 @@
+package consumer
+
+import (
+	"errors"
+	"time"
+
+	"github.com/evermos/boilerplate-go/configs"
+)
+
+const (
+	// defaultMaxReceiveCount is used when no maximum receive count is configured.
+	defaultMaxReceiveCount = 5
+	// defaultBackoffBase is used when no backoff base is configured, matching
+	// the default SQS visibility timeout.
+	defaultBackoffBase = 30 * time.Second
+	// maxVisibilityTimeout is the largest visibility timeout SQS accepts (12 hours).
+	maxVisibilityTimeout = 12 * time.Hour
+)
+
+// PermanentError is a processing error that retrying cannot fix, such as a
+// forged message. Messages failing with one are forwarded to the dead-letter
+// queue right away.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// Permanent marks a processing error as permanent.
+func Permanent(err error) error {
+	return &PermanentError{Err: err}
+}
+
+// IsPermanent checks whether a processing error is permanent.
+func IsPermanent(err error) bool {
+	var permanent *PermanentError
+	return errors.As(err, &permanent)
+}
+
+// RetryPolicy represents how failed messages are retried before they are
+// forwarded to the dead-letter queue.
+type RetryPolicy struct {
+	BackoffBase     time.Duration
+	BackoffMax      time.Duration
+	DeadLetterURL   string
+	MaxReceiveCount int
+}
+
+// NewRetryPolicy creates a RetryPolicy from the SQS consumer configuration.
+func NewRetryPolicy(config *configs.Config, deadLetterURL string) RetryPolicy {
+	retry := config.Event.Consumer.SQS.Retry
+
+	policy := RetryPolicy{
+		BackoffBase:     time.Duration(retry.BackoffBaseSeconds) * time.Second,
+		BackoffMax:      time.Duration(retry.BackoffMaxSeconds) * time.Second,
+		DeadLetterURL:   deadLetterURL,
+		MaxReceiveCount: retry.MaxReceiveCount,
+	}
+
+	if policy.MaxReceiveCount <= 0 {
+		policy.MaxReceiveCount = defaultMaxReceiveCount
+	}
+
+	if policy.BackoffBase <= 0 {
+		policy.BackoffBase = defaultBackoffBase
+	}
+
+	if policy.BackoffMax <= 0 || policy.BackoffMax > maxVisibilityTimeout {
+		policy.BackoffMax = maxVisibilityTimeout
+	}
+
+	return policy
+}
+
+// Exhausted checks whether a message received receiveCount times should no
+// longer be retried.
+func (r RetryPolicy) Exhausted(receiveCount int) bool {
+	return receiveCount >= r.MaxReceiveCount
+}
+
+// Backoff returns how long a message received receiveCount times should stay
+// invisible before it is retried. The delay doubles on every receive and is
+// capped at BackoffMax.
+func (r RetryPolicy) Backoff(receiveCount int) time.Duration {
+	if receiveCount < 1 {
+		receiveCount = 1
+	}
+
+	backoff := r.BackoffBase
+	for i := 1; i < receiveCount; i++ {
+		backoff *= 2
+		if backoff >= r.BackoffMax {
+			return r.BackoffMax
+		}
+	}
+
+	if backoff > r.BackoffMax {
+		return r.BackoffMax
+	}
+
+	return backoff
+}
@@ -0,0 +1,82 @@
+package consumer_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/evermos/boilerplate-go/configs"
+	"github.com/evermos/boilerplate-go/event/consumer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	t.Run("Backoff", func(t *testing.T) {
+		policy := consumer.RetryPolicy{
+			BackoffBase:     10 * time.Second,
+			BackoffMax:      time.Minute,
+			MaxReceiveCount: 5,
+		}
+
+		tests := []struct {
+			receiveCount int
+			expected     time.Duration
+		}{
+			{receiveCount: 0, expected: 10 * time.Second},
+			{receiveCount: 1, expected: 10 * time.Second},
+			{receiveCount: 2, expected: 20 * time.Second},
+			{receiveCount: 3, expected: 40 * time.Second},
+			{receiveCount: 4, expected: time.Minute},
+			{receiveCount: 100, expected: time.Minute},
+		}
+
+		for _, tc := range tests {
+			assert.Equal(t, tc.expected, policy.Backoff(tc.receiveCount), "receiveCount %d", tc.receiveCount)
+		}
+	})
+
+	t.Run("Exhausted", func(t *testing.T) {
+		policy := consumer.RetryPolicy{MaxReceiveCount: 3}
+
+		assert.False(t, policy.Exhausted(2))
+		assert.True(t, policy.Exhausted(3))
+		assert.True(t, policy.Exhausted(4))
+	})
+
+	t.Run("Permanent Errors", func(t *testing.T) {
+		err := consumer.Permanent(errors.New("forged"))
+
+		assert.EqualError(t, err, "forged")
+		assert.True(t, consumer.IsPermanent(err))
+		assert.True(t, consumer.IsPermanent(fmt.Errorf("verifying: %w", err)))
+		assert.False(t, consumer.IsPermanent(errors.New("timeout")))
+		assert.False(t, consumer.IsPermanent(nil))
+	})
+
+	t.Run("Defaults", func(t *testing.T) {
+		policy := consumer.NewRetryPolicy(&configs.Config{}, "https://sqs/dlq")
+
+		assert.Equal(t, 5, policy.MaxReceiveCount)
+		assert.Equal(t, 30*time.Second, policy.BackoffBase)
+		assert.Equal(t, 12*time.Hour, policy.BackoffMax)
+		assert.Equal(t, "https://sqs/dlq", policy.DeadLetterURL)
+
+		// Messages are not redelivered right away without a configured base.
+		assert.Equal(t, 30*time.Second, policy.Backoff(1))
+		assert.Equal(t, time.Minute, policy.Backoff(2))
+	})
+
+	t.Run("Configured", func(t *testing.T) {
+		config := &configs.Config{}
+		config.Event.Consumer.SQS.Retry.BackoffBaseSeconds = 5
+		config.Event.Consumer.SQS.Retry.BackoffMaxSeconds = 60
+		config.Event.Consumer.SQS.Retry.MaxReceiveCount = 3
+
+		policy := consumer.NewRetryPolicy(config, "")
+
+		assert.Equal(t, 5*time.Second, policy.BackoffBase)
+		assert.Equal(t, time.Minute, policy.BackoffMax)
+		assert.Equal(t, 3, policy.MaxReceiveCount)
+	})
+}
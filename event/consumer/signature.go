@@ -0,0 +1,344 @@
+package consumer
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/evermos/boilerplate-go/configs"
+	"github.com/evermos/boilerplate-go/event/model"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// SignatureVersion1 signs SNS messages with SHA1withRSA.
+	SignatureVersion1 = "1"
+	// SignatureVersion2 signs SNS messages with SHA256withRSA.
+	SignatureVersion2 = "2"
+
+	// defaultCertCacheTTL is how long a signing certificate is cached when no TTL is configured.
+	defaultCertCacheTTL = 24 * time.Hour
+	// certFetchTimeout bounds how long fetching a signing certificate may take.
+	certFetchTimeout = 10 * time.Second
+	// maxCertSize bounds the size of a fetched signing certificate.
+	maxCertSize = 64 * 1024
+)
+
+// defaultAllowedCertHosts are the hosts SNS serves its signing certificates from.
+var defaultAllowedCertHosts = []string{"sns.*.amazonaws.com", "sns.*.amazonaws.com.cn"}
+
+// Errors returned when verifying SNS messages.
+var (
+	ErrUnsupportedSignatureVersion = errors.New("unsupported SNS signature version")
+	ErrInvalidSignature            = errors.New("invalid SNS signature")
+	ErrCertURLNotAllowed           = errors.New("SNS signing certificate URL is not allowed")
+)
+
+// CertSource resolves the certificate an SNS message was signed with.
+type CertSource interface {
+	Certificate(certURL string) (*x509.Certificate, error)
+}
+
+// Verifier verifies the signature of SNS messages.
+type Verifier struct {
+	Source CertSource
+}
+
+// ProvideVerifier is the provider for the SNS signature Verifier. Certificates
+// are fetched over HTTPS from the configured hosts and cached.
+func ProvideVerifier(config *configs.Config) *Verifier {
+	signature := config.Event.Consumer.Signature
+
+	allowedHosts := signature.AllowedCertHosts
+	if len(allowedHosts) == 0 {
+		allowedHosts = defaultAllowedCertHosts
+	}
+
+	ttl := time.Duration(signature.CertCacheSeconds) * time.Second
+	if ttl <= 0 {
+		ttl = defaultCertCacheTTL
+	}
+
+	return &Verifier{
+		Source: NewCachedCertSource(NewHTTPCertSource(allowedHosts), ttl),
+	}
+}
+
+// Verify checks the signature of an SNS message against its signing certificate.
+func (v *Verifier) Verify(msg model.SNSMessage) error {
+	var hash crypto.Hash
+	switch msg.SignatureVersion {
+	case SignatureVersion1:
+		hash = crypto.SHA1
+	case SignatureVersion2:
+		hash = crypto.SHA256
+	default:
+		return ErrUnsupportedSignatureVersion
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(msg.Signature)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	cert, err := v.Source.Certificate(msg.SigningCertURL)
+	if err != nil {
+		return err
+	}
+
+	if time.Now().After(cert.NotAfter) {
+		return ErrInvalidSignature
+	}
+
+	publicKey, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return ErrInvalidSignature
+	}
+
+	err = rsa.VerifyPKCS1v15(publicKey, hash, digest(hash, StringToSign(msg)), signature)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// Verified wraps a processing function so that only SNS messages with a valid
+// signature are processed. Malformed messages and messages failing
+// verification fail permanently, as retrying them would only waste workers;
+// failing to fetch the signing certificate may be retried.
+func Verified(verifier *Verifier, next Process) Process {
+	return func(e []byte) error {
+		snsMessage := model.SNSMessage{}
+		err := json.Unmarshal(e, &snsMessage)
+		if err != nil {
+			return Permanent(err)
+		}
+
+		err = verifier.Verify(snsMessage)
+		if err != nil {
+			log.
+				Warn().
+				Err(err).
+				Str("messageId", snsMessage.MessageID.String()).
+				Str("topicARN", snsMessage.TopicARN).
+				Msg("Rejected SNS message with an invalid signature.")
+
+			switch err {
+			case ErrInvalidSignature, ErrUnsupportedSignatureVersion, ErrCertURLNotAllowed:
+				return Permanent(err)
+			}
+			return err
+		}
+
+		return next(e)
+	}
+}
+
+// StringToSign builds the canonical string SNS signs for a message.
+func StringToSign(msg model.SNSMessage) string {
+	var fields [][2]string
+
+	switch msg.Type {
+	case "SubscriptionConfirmation", "UnsubscribeConfirmation":
+		fields = [][2]string{
+			{"Message", msg.Message},
+			{"MessageId", msg.MessageID.String()},
+			{"SubscribeURL", msg.SubscribeURL},
+			{"Timestamp", msg.Timestamp},
+			{"Token", msg.Token},
+			{"TopicArn", msg.TopicARN},
+			{"Type", msg.Type},
+		}
+	default:
+		fields = [][2]string{
+			{"Message", msg.Message},
+			{"MessageId", msg.MessageID.String()},
+		}
+		if msg.Subject != "" {
+			fields = append(fields, [2]string{"Subject", msg.Subject})
+		}
+		fields = append(fields,
+			[2]string{"Timestamp", msg.Timestamp},
+			[2]string{"TopicArn", msg.TopicARN},
+			[2]string{"Type", msg.Type},
+		)
+	}
+
+	var builder strings.Builder
+	for _, field := range fields {
+		builder.WriteString(field[0])
+		builder.WriteString("\n")
+		builder.WriteString(field[1])
+		builder.WriteString("\n")
+	}
+
+	return builder.String()
+}
+
+func digest(hash crypto.Hash, value string) []byte {
+	if hash == crypto.SHA1 {
+		sum := sha1.Sum([]byte(value))
+		return sum[:]
+	}
+
+	sum := sha256.Sum256([]byte(value))
+	return sum[:]
+}
+
+func parseCertificate(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM encoded certificate found")
+	}
+
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// HTTPCertSource fetches signing certificates over HTTPS, but only from
+// allowed hosts. Hosts may contain "*" to match a single label, e.g.
+// "sns.*.amazonaws.com".
+type HTTPCertSource struct {
+	AllowedHosts []string
+	Client       *http.Client
+}
+
+// NewHTTPCertSource creates a new HTTPCertSource.
+func NewHTTPCertSource(allowedHosts []string) *HTTPCertSource {
+	return &HTTPCertSource{
+		AllowedHosts: allowedHosts,
+		Client:       &http.Client{Timeout: certFetchTimeout},
+	}
+}
+
+// Certificate fetches the certificate at certURL.
+func (s *HTTPCertSource) Certificate(certURL string) (*x509.Certificate, error) {
+	parsed, err := url.Parse(certURL)
+	if err != nil || parsed.Scheme != "https" || !strings.HasSuffix(parsed.Path, ".pem") || !s.allowed(parsed.Hostname()) {
+		return nil, ErrCertURLNotAllowed
+	}
+
+	resp, err := s.Client.Get(parsed.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed fetching SNS signing certificate: %s", resp.Status)
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxCertSize))
+	if err != nil {
+		return nil, err
+	}
+
+	return parseCertificate(data)
+}
+
+func (s *HTTPCertSource) allowed(host string) bool {
+	labels := strings.Split(strings.ToLower(host), ".")
+
+	for _, allowedHost := range s.AllowedHosts {
+		patterns := strings.Split(strings.ToLower(allowedHost), ".")
+		if len(patterns) != len(labels) {
+			continue
+		}
+
+		matched := true
+		for i, pattern := range patterns {
+			if ok, _ := path.Match(pattern, labels[i]); !ok {
+				matched = false
+				break
+			}
+		}
+
+		if matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FileCertSource reads signing certificates from a local directory, using the
+// file name of the certificate URL. It is meant for tests and local runs.
+type FileCertSource struct {
+	Dir string
+}
+
+// Certificate reads the certificate named after certURL.
+func (s *FileCertSource) Certificate(certURL string) (*x509.Certificate, error) {
+	parsed, err := url.Parse(certURL)
+	if err != nil {
+		return nil, ErrCertURLNotAllowed
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(s.Dir, path.Base(parsed.Path)))
+	if err != nil {
+		return nil, err
+	}
+
+	return parseCertificate(data)
+}
+
+type cachedCert struct {
+	cert    *x509.Certificate
+	expires time.Time
+}
+
+// CachedCertSource caches the certificates resolved by another CertSource.
+type CachedCertSource struct {
+	Source CertSource
+	TTL    time.Duration
+
+	mu    sync.RWMutex
+	certs map[string]cachedCert
+}
+
+// NewCachedCertSource creates a new CachedCertSource.
+func NewCachedCertSource(source CertSource, ttl time.Duration) *CachedCertSource {
+	return &CachedCertSource{
+		Source: source,
+		TTL:    ttl,
+		certs:  make(map[string]cachedCert),
+	}
+}
+
+// Certificate resolves the certificate at certURL, from cache when possible.
+func (s *CachedCertSource) Certificate(certURL string) (*x509.Certificate, error) {
+	s.mu.RLock()
+	cached, ok := s.certs[certURL]
+	s.mu.RUnlock()
+
+	if ok && time.Now().Before(cached.expires) {
+		return cached.cert, nil
+	}
+
+	cert, err := s.Source.Certificate(certURL)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.certs[certURL] = cachedCert{cert: cert, expires: time.Now().Add(s.TTL)}
+	s.mu.Unlock()
+
+	return cert, nil
+}
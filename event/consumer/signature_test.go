@@ -0,0 +1,161 @@
+package consumer_test
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/evermos/boilerplate-go/event/consumer"
+	"github.com/evermos/boilerplate-go/event/model"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testCertURL = "https://sns.us-east-1.amazonaws.com/SimpleNotificationService-test.pem"
+
+func loadSigningKey(t *testing.T) *rsa.PrivateKey {
+	data, err := ioutil.ReadFile("testdata/sns-signing-key.pem")
+	require.NoError(t, err)
+
+	block, _ := pem.Decode(data)
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	require.NoError(t, err)
+
+	return key.(*rsa.PrivateKey)
+}
+
+func signedMessage(t *testing.T, version string) model.SNSMessage {
+	msg := model.SNSMessage{
+		Type:             "Notification",
+		MessageID:        uuid.Must(uuid.FromString("4e80c5bf-b79b-4c90-8f91-82647f439e55")),
+		TopicARN:         "arn:aws:sns:us-east-1:123456789012:foo-bar-baz",
+		Message:          `{"name":"foo"}`,
+		Timestamp:        "2021-08-24T20:57:59.000Z",
+		SignatureVersion: version,
+		SigningCertURL:   testCertURL,
+	}
+
+	var hash crypto.Hash
+	var digest []byte
+	if version == consumer.SignatureVersion1 {
+		sum := sha1.Sum([]byte(consumer.StringToSign(msg)))
+		hash, digest = crypto.SHA1, sum[:]
+	} else {
+		sum := sha256.Sum256([]byte(consumer.StringToSign(msg)))
+		hash, digest = crypto.SHA256, sum[:]
+	}
+
+	signature, err := rsa.SignPKCS1v15(rand.Reader, loadSigningKey(t), hash, digest)
+	require.NoError(t, err)
+	msg.Signature = base64.StdEncoding.EncodeToString(signature)
+
+	return msg
+}
+
+func TestVerifier(t *testing.T) {
+	verifier := &consumer.Verifier{Source: &consumer.FileCertSource{Dir: "testdata"}}
+
+	t.Run("Valid Signatures", func(t *testing.T) {
+		assert.NoError(t, verifier.Verify(signedMessage(t, consumer.SignatureVersion1)))
+		assert.NoError(t, verifier.Verify(signedMessage(t, consumer.SignatureVersion2)))
+	})
+
+	t.Run("Tampered Message", func(t *testing.T) {
+		msg := signedMessage(t, consumer.SignatureVersion2)
+		msg.Message = `{"name":"forged"}`
+
+		assert.Equal(t, consumer.ErrInvalidSignature, verifier.Verify(msg))
+	})
+
+	t.Run("Unsupported Version", func(t *testing.T) {
+		msg := signedMessage(t, consumer.SignatureVersion2)
+		msg.SignatureVersion = "3"
+
+		assert.Equal(t, consumer.ErrUnsupportedSignatureVersion, verifier.Verify(msg))
+	})
+
+	t.Run("Verified Middleware", func(t *testing.T) {
+		processed := 0
+		process := consumer.Verified(verifier, func(e []byte) error {
+			processed++
+			return nil
+		})
+
+		valid, _ := json.Marshal(signedMessage(t, consumer.SignatureVersion2))
+		assert.NoError(t, process(valid))
+
+		forged := signedMessage(t, consumer.SignatureVersion2)
+		forged.TopicARN = "arn:aws:sns:us-east-1:123456789012:other"
+		invalid, _ := json.Marshal(forged)
+		err := process(invalid)
+		assert.Equal(t, consumer.ErrInvalidSignature, errors.Unwrap(err))
+		assert.True(t, consumer.IsPermanent(err), "forged messages are not retried")
+
+		assert.True(t, consumer.IsPermanent(process([]byte("{"))), "malformed messages are not retried")
+
+		// Failing to fetch the certificate may succeed on retry.
+		unavailable := signedMessage(t, consumer.SignatureVersion2)
+		unavailable.SigningCertURL = "https://sns.us-east-1.amazonaws.com/missing.pem"
+		body, _ := json.Marshal(unavailable)
+		err = process(body)
+		assert.Error(t, err)
+		assert.False(t, consumer.IsPermanent(err))
+
+		assert.Equal(t, 1, processed)
+	})
+}
+
+func TestHTTPCertSource(t *testing.T) {
+	cert, err := ioutil.ReadFile("testdata/SimpleNotificationService-test.pem")
+	require.NoError(t, err)
+
+	fetches := 0
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		_, _ = w.Write(cert)
+	}))
+	defer server.Close()
+
+	t.Run("Disallowed Hosts", func(t *testing.T) {
+		source := consumer.NewHTTPCertSource([]string{"sns.*.amazonaws.com"})
+		source.Client = server.Client()
+
+		for _, certURL := range []string{
+			server.URL + "/cert.pem",
+			"http://sns.us-east-1.amazonaws.com/cert.pem",
+			"https://sns.us-east-1.amazonaws.com.evil.com/cert.pem",
+			"https://sns.evil.com/cert.pem",
+			"https://sns.us-east-1.amazonaws.com/cert.txt",
+		} {
+			_, err := source.Certificate(certURL)
+			assert.Equal(t, consumer.ErrCertURLNotAllowed, err, certURL)
+		}
+		assert.Equal(t, 0, fetches)
+	})
+
+	t.Run("Cached Fetch", func(t *testing.T) {
+		source := consumer.NewHTTPCertSource([]string{"127.0.0.1"})
+		source.Client = server.Client()
+		cached := consumer.NewCachedCertSource(source, time.Minute)
+
+		for i := 0; i < 3; i++ {
+			fetched, err := cached.Certificate(server.URL + "/SimpleNotificationService-test.pem")
+			require.NoError(t, err)
+			assert.Equal(t, "sns.us-east-1.amazonaws.com", fetched.Subject.CommonName)
+		}
+		assert.Equal(t, 1, fetches)
+	})
+}
@@ -1,6 +1,11 @@
 package consumer
 
 import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -41,11 +46,36 @@ func createSQSConfig(config *configs.Config) (*session.Session, error) {
 	})
 }
 
+const (
+	// AttributeErrorReason holds the processing error of a dead-lettered message.
+	AttributeErrorReason = "ErrorReason"
+	// AttributeSourceQueueURL holds the queue a dead-lettered message was consumed from.
+	AttributeSourceQueueURL = "SourceQueueUrl"
+	// AttributeReceiveCount holds how many times a dead-lettered message was received.
+	AttributeReceiveCount = "ReceiveCount"
+
+	// maxAttributeValueLength keeps error reasons well below the SQS message size limit.
+	maxAttributeValueLength = 1024
+	// redriveWaitTimeSeconds is the long polling duration used while draining a dead-letter queue.
+	redriveWaitTimeSeconds = 1
+)
+
 // SQSConsumer represents an SQS consumer.
 type SQSConsumer struct {
-	Process Process
-	config  *configs.Config
-	sqs     *sqs.SQS
+	Process     Process
+	RetryPolicy RetryPolicy
+	// Workers is the number of messages processed concurrently.
+	Workers int
+
+	config            *configs.Config
+	sqs               *sqs.SQS
+	heartbeat         time.Duration
+	visibilityTimeout time.Duration
+
+	mu       sync.Mutex
+	running  sync.WaitGroup
+	stop     chan struct{}
+	stopOnce sync.Once
 }
 
 // NewSQSConsumer create object Consumer
@@ -54,21 +84,87 @@ func NewSQSConsumer(config *configs.Config) *SQSConsumer {
 	if err != nil {
 		log.Fatal().Err(err).Msg("failed creating sqs config")
 	}
-	return &SQSConsumer{config: config, sqs: sqs.New(sess)}
+
+	visibility := config.Event.Consumer.SQS.Visibility
+	visibilityTimeout := time.Duration(visibility.TimeoutSeconds) * time.Second
+	if visibilityTimeout <= 0 {
+		visibilityTimeout = 2 * time.Duration(visibility.HeartbeatSeconds) * time.Second
+	}
+
+	return &SQSConsumer{
+		RetryPolicy:       NewRetryPolicy(config, ""),
+		Workers:           1,
+		config:            config,
+		sqs:               sqs.New(sess),
+		heartbeat:         time.Duration(visibility.HeartbeatSeconds) * time.Second,
+		visibilityTimeout: visibilityTimeout,
+		stop:              make(chan struct{}),
+	}
 }
 
-// Listen is a function to listen new message from sqs queue
+// Listen is a function to listen new message from sqs queue. Received messages
+// are handed to a pool of Workers, and Listen returns once Stop is called and
+// every in-flight message has been handled.
 func (p *SQSConsumer) Listen(url string) {
-	log.Info().Str("url", url).Msg("SQS Consumer will start polling.")
+	p.mu.Lock()
+	select {
+	case <-p.stop:
+		p.mu.Unlock()
+		return
+	default:
+	}
+	p.running.Add(1)
+	p.mu.Unlock()
+	defer p.running.Done()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-p.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	pool := newWorkerPool(p.Workers, func(message *sqs.Message) {
+		p.handle(message, url)
+	})
 
+	log.Info().Str("url", url).Int("workers", p.Workers).Msg("SQS Consumer will start polling.")
+	p.poll(ctx, url, pool)
+
+	log.Info().Str("url", url).Msg("SQS Consumer stopped polling, draining in-flight messages.")
+	pool.drain()
+	log.Info().Str("url", url).Msg("SQS Consumer drained.")
+}
+
+// Stop stops polling and waits until every in-flight message is handled.
+func (p *SQSConsumer) Stop() {
+	p.mu.Lock()
+	p.stopOnce.Do(func() {
+		close(p.stop)
+	})
+	p.mu.Unlock()
+
+	p.running.Wait()
+}
+
+func (p *SQSConsumer) poll(ctx context.Context, url string, pool *workerPool) {
 	retries := 0
-	for {
-		receiveResp, err := p.sqs.ReceiveMessage(&sqs.ReceiveMessageInput{
-			QueueUrl:            aws.String(url),
-			MaxNumberOfMessages: aws.Int64(p.config.Event.Consumer.SQS.MaxMessage),
-			WaitTimeSeconds:     aws.Int64(p.config.Event.Consumer.SQS.WaitTimeSeconds),
+	for ctx.Err() == nil {
+		receiveResp, err := p.sqs.ReceiveMessageWithContext(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:              aws.String(url),
+			MaxNumberOfMessages:   aws.Int64(p.config.Event.Consumer.SQS.MaxMessage),
+			WaitTimeSeconds:       aws.Int64(p.config.Event.Consumer.SQS.WaitTimeSeconds),
+			AttributeNames:        aws.StringSlice([]string{sqs.QueueAttributeNameAll}),
+			MessageAttributeNames: aws.StringSlice([]string{sqs.QueueAttributeNameAll}),
 		})
 		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+
 			if retries == p.config.Event.Consumer.SQS.MaxRetriesConsume {
 				log.Error().Err(err).Int("retries", retries).Msg("failed receiving message after maximum retries, failing permanently")
 				return
@@ -82,24 +178,195 @@ func (p *SQSConsumer) Listen(url string) {
 				Int("backoffSeconds", p.config.Event.Consumer.SQS.BackoffSeconds).
 				Msg("failed receiving message, will retry")
 			retries++
-			time.Sleep(time.Duration(p.config.Event.Consumer.SQS.BackoffSeconds) * time.Second)
+			select {
+			case <-time.After(time.Duration(p.config.Event.Consumer.SQS.BackoffSeconds) * time.Second):
+			case <-ctx.Done():
+			}
 			continue
 		} else {
 			retries = 0
 		}
 
 		for _, message := range receiveResp.Messages {
-			err := p.Process([]byte(*message.Body))
+			pool.dispatch(message)
+		}
+	}
+}
+
+// handle processes a single message. Successfully processed messages are
+// deleted, failed ones are made visible again after a backoff until the retry
+// policy is exhausted, after which they are forwarded to the dead-letter queue.
+// Messages failing permanently are forwarded without retries.
+func (p *SQSConsumer) handle(message *sqs.Message, url string) {
+	processErr := p.process(message, url)
+	if processErr == nil {
+		_ = p.deleteMessage(message, url)
+		return
+	}
+
+	receiveCount := receiveCountOf(message)
+	log.
+		Error().
+		Err(processErr).
+		Str("messageId", aws.StringValue(message.MessageId)).
+		Int("receiveCount", receiveCount).
+		Msg("failed processing message")
+
+	permanent := IsPermanent(processErr)
+	if !permanent && !p.RetryPolicy.Exhausted(receiveCount) {
+		backoff := p.RetryPolicy.Backoff(receiveCount)
+		err := p.changeMessageVisibility(message, url, backoff)
+		if err == nil {
+			log.
+				Info().
+				Str("messageId", aws.StringValue(message.MessageId)).
+				Dur("backoff", backoff).
+				Msg("message will be retried")
+		}
+		return
+	}
+
+	if p.RetryPolicy.DeadLetterURL == "" {
+		log.
+			Error().
+			Str("messageId", aws.StringValue(message.MessageId)).
+			Bool("permanent", permanent).
+			Msg("retries exhausted and no dead-letter queue configured, dropping message")
+		_ = p.deleteMessage(message, url)
+		return
+	}
+
+	err := p.sendMessage(p.RetryPolicy.DeadLetterURL, message, deadLetterAttributes(message, url, receiveCount, processErr))
+	if err != nil {
+		// Keep the message in the source queue so it will be picked up again.
+		return
+	}
+
+	log.
+		Warn().
+		Str("messageId", aws.StringValue(message.MessageId)).
+		Str("deadLetterUrl", p.RetryPolicy.DeadLetterURL).
+		Bool("permanent", permanent).
+		Msg("retries exhausted, message forwarded to dead-letter queue")
+	_ = p.deleteMessage(message, url)
+}
+
+// process runs the Process function while periodically extending the
+// message's visibility timeout, so long-running handlers keep ownership of the
+// message. The heartbeat is fully stopped before process returns.
+func (p *SQSConsumer) process(message *sqs.Message, url string) error {
+	if p.heartbeat <= 0 {
+		return p.Process([]byte(*message.Body))
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(p.heartbeat)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				_ = p.changeMessageVisibility(message, url, p.visibilityTimeout)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	defer func() {
+		close(done)
+		<-stopped
+	}()
+
+	return p.Process([]byte(*message.Body))
+}
+
+// Redrive moves up to max messages from a dead-letter queue back to the
+// target queue, stripping the attributes added when they were dead-lettered.
+// A max of zero or less redrives every message in the dead-letter queue.
+func (p *SQSConsumer) Redrive(deadLetterURL string, targetURL string, max int) (redriven int, err error) {
+	log.Info().Str("deadLetterUrl", deadLetterURL).Str("targetUrl", targetURL).Msg("Redriving dead-letter queue.")
+
+	for max <= 0 || redriven < max {
+		receiveResp, err := p.sqs.ReceiveMessage(&sqs.ReceiveMessageInput{
+			QueueUrl:              aws.String(deadLetterURL),
+			MaxNumberOfMessages:   aws.Int64(p.config.Event.Consumer.SQS.MaxMessage),
+			WaitTimeSeconds:       aws.Int64(redriveWaitTimeSeconds),
+			AttributeNames:        aws.StringSlice([]string{sqs.QueueAttributeNameAll}),
+			MessageAttributeNames: aws.StringSlice([]string{sqs.QueueAttributeNameAll}),
+		})
+		if err != nil {
+			log.Error().Err(err).Str("deadLetterUrl", deadLetterURL).Msg("failed receiving dead-lettered message")
+			return redriven, err
+		}
+
+		if len(receiveResp.Messages) == 0 {
+			break
+		}
+
+		for _, message := range receiveResp.Messages {
+			if max > 0 && redriven >= max {
+				break
+			}
+
+			err = p.sendMessage(targetURL, message, redriveAttributes(message))
 			if err != nil {
-				log.Error().Err(err).Msg("failed processing message")
+				return redriven, err
 			}
 
-			err = p.deleteMessage(message, url)
+			err = p.deleteMessage(message, deadLetterURL)
 			if err != nil {
-				log.Error().Err(err).Msg("failed deleting message")
+				return redriven, err
 			}
+
+			redriven++
 		}
 	}
+
+	log.Info().Int("count", redriven).Str("targetUrl", targetURL).Msg("Redrive completed.")
+
+	return redriven, nil
+}
+
+func (p *SQSConsumer) changeMessageVisibility(msg *sqs.Message, url string, timeout time.Duration) error {
+	output, err := p.sqs.ChangeMessageVisibility(&sqs.ChangeMessageVisibilityInput{
+		QueueUrl:          &url,
+		ReceiptHandle:     msg.ReceiptHandle,
+		VisibilityTimeout: aws.Int64(int64(timeout / time.Second)),
+	})
+	if err != nil {
+		log.Err(err).Interface("output", output).Msg("failed changing message visibility")
+		return err
+	}
+	return nil
+}
+
+func (p *SQSConsumer) sendMessage(url string, msg *sqs.Message, attributes map[string]*sqs.MessageAttributeValue) error {
+	input := &sqs.SendMessageInput{
+		QueueUrl:          &url,
+		MessageBody:       msg.Body,
+		MessageAttributes: attributes,
+	}
+
+	if strings.HasSuffix(url, ".fifo") {
+		groupID, ok := msg.Attributes[sqs.MessageSystemAttributeNameMessageGroupId]
+		if !ok {
+			groupID = msg.MessageId
+		}
+		input.MessageGroupId = groupID
+		input.MessageDeduplicationId = msg.MessageId
+	}
+
+	output, err := p.sqs.SendMessage(input)
+	if err != nil {
+		log.Err(err).Interface("output", output).Str("url", url).Msg("failed sending message")
+		return err
+	}
+	return nil
 }
 
 func (p *SQSConsumer) deleteMessage(msg *sqs.Message, url string) error {
@@ -113,3 +380,56 @@ func (p *SQSConsumer) deleteMessage(msg *sqs.Message, url string) error {
 	}
 	return nil
 }
+
+func receiveCountOf(msg *sqs.Message) int {
+	count, err := strconv.Atoi(aws.StringValue(msg.Attributes[sqs.MessageSystemAttributeNameApproximateReceiveCount]))
+	if err != nil {
+		return 1
+	}
+	return count
+}
+
+func deadLetterAttributes(msg *sqs.Message, sourceURL string, receiveCount int, processErr error) map[string]*sqs.MessageAttributeValue {
+	attributes := make(map[string]*sqs.MessageAttributeValue)
+	for name, value := range msg.MessageAttributes {
+		attributes[name] = value
+	}
+
+	reason := processErr.Error()
+	if len(reason) > maxAttributeValueLength {
+		reason = reason[:maxAttributeValueLength]
+	}
+
+	attributes[AttributeErrorReason] = stringAttribute(reason)
+	attributes[AttributeSourceQueueURL] = stringAttribute(sourceURL)
+	attributes[AttributeReceiveCount] = &sqs.MessageAttributeValue{
+		DataType:    aws.String("Number"),
+		StringValue: aws.String(fmt.Sprint(receiveCount)),
+	}
+
+	return attributes
+}
+
+func redriveAttributes(msg *sqs.Message) map[string]*sqs.MessageAttributeValue {
+	attributes := make(map[string]*sqs.MessageAttributeValue)
+	for name, value := range msg.MessageAttributes {
+		switch name {
+		case AttributeErrorReason, AttributeSourceQueueURL, AttributeReceiveCount:
+			continue
+		}
+		attributes[name] = value
+	}
+
+	if len(attributes) == 0 {
+		return nil
+	}
+
+	return attributes
+}
+
+func stringAttribute(value string) *sqs.MessageAttributeValue {
+	return &sqs.MessageAttributeValue{
+		DataType:    aws.String("String"),
+		StringValue: aws.String(value),
+	}
+}
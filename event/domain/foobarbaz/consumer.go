@@ -1,67 +1,48 @@
 package foobarbaz
 
 import (
-	"encoding/json"
 	"net/http"
 
-	"github.com/evermos/boilerplate-go/configs"
 	"github.com/evermos/boilerplate-go/event/consumer"
 	"github.com/evermos/boilerplate-go/event/model"
 	"github.com/evermos/boilerplate-go/internal/domain/foobarbaz"
 	"github.com/evermos/boilerplate-go/shared/failure"
 	"github.com/evermos/boilerplate-go/shared/logger"
-	"github.com/rs/zerolog/log"
+	"github.com/jmoiron/sqlx"
 )
 
-// ConsumerImpl is the SQS consumer implementation for this domain.
+// TopicName is the name this domain's topic is registered and configured under.
+const TopicName = "foobarbaz"
+
+// ConsumerImpl is the event consumer implementation for this domain.
 type ConsumerImpl struct {
-	Config   *configs.Config
-	Service  foobarbaz.FooService
-	Consumer consumer.Consumer
+	Service foobarbaz.FooService
 }
 
 // ProvideConsumerImpl is the provider for this consumer.
-func ProvideConsumerImpl(config *configs.Config, service foobarbaz.FooService) ConsumerImpl {
+func ProvideConsumerImpl(service foobarbaz.FooService) ConsumerImpl {
 	c := ConsumerImpl{}
-	c.Config = config
 	c.Service = service
-
-	sqsConsumer := consumer.NewSQSConsumer(config)
-	sqsConsumer.Process = c.processEvent
-	c.Consumer = sqsConsumer
-
 	return c
 }
 
-// Start starts up the SQS subscriber
-func (c *ConsumerImpl) Start() {
-	if c.Config.Event.Consumer.SQS.Topics.FooBarBaz.Enabled {
-		go c.Consumer.Listen(c.Config.Event.Consumer.SQS.Topics.FooBarBaz.URL)
-	}
+// Register registers this domain's topic. Foos are created in the transaction
+// recording the message in the processed message ledger, so that concurrent
+// deliveries of a message create a single Foo.
+func (c *ConsumerImpl) Register(registry *consumer.Registry) {
+	registry.Register(consumer.Topic{
+		Name: TopicName,
+		Decoder: consumer.JSONDecoder(func() interface{} {
+			return &foobarbaz.FooRequestFormat{}
+		}),
+		TxHandler: c.handleEvent,
+	})
 }
 
-func (c *ConsumerImpl) processEvent(value []byte) (err error) {
-	snsMessage := model.SNSMessage{}
-	err = json.Unmarshal(value, &snsMessage)
-	if err != nil {
-		logger.ErrorWithStack(err)
-		return
-	}
-
-	log.
-		Info().
-		Str("topicARN", snsMessage.TopicARN).
-		Interface("value", snsMessage).
-		Msg("Received SNS message")
-
-	requestFormat := foobarbaz.FooRequestFormat{}
-	err = json.Unmarshal([]byte(snsMessage.Message), &requestFormat)
-	if err != nil {
-		logger.ErrorWithStack(err)
-		return
-	}
+func (c *ConsumerImpl) handleEvent(tx *sqlx.Tx, msg model.SNSMessage, payload interface{}) (err error) {
+	requestFormat := payload.(*foobarbaz.FooRequestFormat)
 
-	_, err = c.Service.Create(requestFormat, snsMessage.MessageID)
+	_, err = c.Service.CreateTx(tx, *requestFormat, msg.MessageID)
 	if err != nil {
 		err = c.checkError(err)
 	}
@@ -12,8 +12,11 @@ import (
 type SNSMessage struct {
 	Type             string    `json:"Type"`
 	MessageID        uuid.UUID `json:"MessageId"`
+	Token            string    `json:"Token,omitempty"`
 	TopicARN         string    `json:"TopicArn"`
+	Subject          string    `json:"Subject,omitempty"`
 	Message          string    `json:"Message"`
+	SubscribeURL     string    `json:"SubscribeURL,omitempty"`
 	Timestamp        string    `json:"Timestamp"`
 	SignatureVersion string    `json:"SignatureVersion"`
 	Signature        string    `json:"Signature"`
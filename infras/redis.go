@@ -22,3 +22,8 @@ func RedisNewClient(config configs.Config) *redis.Client {
 
 	return client
 }
+
+// ProvideRedis is the provider for the primary Redis client.
+func ProvideRedis(config *configs.Config) *redis.Client {
+	return RedisNewClient(*config)
+}
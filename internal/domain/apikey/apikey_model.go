@@ -0,0 +1,192 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/evermos/boilerplate-go/shared"
+	"github.com/evermos/boilerplate-go/shared/nuuid"
+	"github.com/evermos/boilerplate-go/shared/oauth"
+	"github.com/gofrs/uuid"
+	"github.com/guregu/null"
+)
+
+const (
+	// KeyPrefix starts every API key, so that leaked keys are easy to spot.
+	KeyPrefix = "evk_"
+	// keySize is the number of random bytes of an API key.
+	keySize = 32
+	// displayPrefixLength is the number of leading characters of an API key
+	// stored in plain, to tell keys apart.
+	displayPrefixLength = len(KeyPrefix) + 8
+)
+
+// APIKey is a long-lived credential of a service. Only the hash of the key is
+// stored; the plain key is only known right after it is generated.
+type APIKey struct {
+	ID             uuid.UUID   `db:"id" validate:"required"`
+	Name           string      `db:"name" validate:"required"`
+	Prefix         string      `db:"prefix" validate:"required"`
+	KeyHash        string      `db:"key_hash" validate:"required"`
+	OwnerID        uuid.UUID   `db:"owner_id" validate:"required"`
+	Scope          null.String `db:"scope"`
+	QuotaPerMinute int         `db:"quota_per_minute" validate:"min=0"`
+	ExpiresAt      null.Time   `db:"expires_at"`
+	LastUsedAt     null.Time   `db:"last_used_at"`
+	CreatedAt      time.Time   `db:"created_at" validate:"required"`
+	CreatedBy      uuid.UUID   `db:"created_by" validate:"required"`
+	RevokedAt      null.Time   `db:"revoked_at"`
+	RevokedBy      nuuid.NUUID `db:"revoked_by"`
+
+	plainKey string
+}
+
+func (k APIKey) MarshalJSON() ([]byte, error) {
+	return json.Marshal(k.ToResponseFormat())
+}
+
+// NewFromRequestFormat creates a new API key with a generated key. The key is
+// owned by the user creating it unless another owner is given.
+func (k APIKey) NewFromRequestFormat(req APIKeyRequestFormat, userID uuid.UUID) (newKey APIKey, err error) {
+	if req.ExpiresAt.Valid && !req.ExpiresAt.Time.After(time.Now()) {
+		return newKey, errors.New("expiresAt must be in the future")
+	}
+
+	id, err := uuid.NewV4()
+	if err != nil {
+		return
+	}
+
+	newKey = APIKey{
+		ID:             id,
+		Name:           req.Name,
+		OwnerID:        userID,
+		QuotaPerMinute: req.QuotaPerMinute,
+		ExpiresAt:      req.ExpiresAt,
+		CreatedAt:      time.Now(),
+		CreatedBy:      userID,
+	}
+
+	if req.OwnerID.Valid {
+		newKey.OwnerID = req.OwnerID.UUID
+	}
+
+	if scope := strings.Join(strings.Fields(req.Scope), " "); scope != "" {
+		newKey.Scope = null.StringFrom(scope)
+	}
+
+	err = newKey.generateKey()
+	if err != nil {
+		return
+	}
+
+	err = newKey.Validate()
+
+	return
+}
+
+// Revoke revokes the API key, so that it can no longer authenticate.
+func (k *APIKey) Revoke(userID uuid.UUID) {
+	k.RevokedAt = null.TimeFrom(time.Now())
+	k.RevokedBy = nuuid.From(userID)
+}
+
+func (k *APIKey) IsRevoked() bool {
+	return k.RevokedAt.Valid
+}
+
+// IsActive checks whether the API key is neither revoked nor expired.
+func (k *APIKey) IsActive() bool {
+	return !k.IsRevoked() && (!k.ExpiresAt.Valid || time.Now().Before(k.ExpiresAt.Time))
+}
+
+// Scopes returns the scopes granted to the API key.
+func (k *APIKey) Scopes() oauth.Scope {
+	return oauth.ParseScope(k.Scope.String)
+}
+
+func (k *APIKey) generateKey() (err error) {
+	b := make([]byte, keySize)
+	_, err = rand.Read(b)
+	if err != nil {
+		return
+	}
+
+	key := KeyPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	k.plainKey = key
+	k.Prefix = key[:displayPrefixLength]
+	k.KeyHash = HashKey(key)
+
+	return
+}
+
+func (k *APIKey) Validate() (err error) {
+	validator := shared.GetValidator()
+	return validator.Struct(k)
+}
+
+// HashKey hashes a plain API key for storage and lookup. Keys are random and
+// long, so a fast hash suffices.
+func HashKey(plain string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(plain)))
+}
+
+// ToResponseFormat shows the plain key only right after it was generated.
+func (k APIKey) ToResponseFormat() APIKeyResponseFormat {
+	return APIKeyResponseFormat{
+		ID:             k.ID,
+		Name:           k.Name,
+		Key:            k.plainKey,
+		Prefix:         k.Prefix,
+		OwnerID:        k.OwnerID,
+		Scope:          k.Scope.String,
+		QuotaPerMinute: k.QuotaPerMinute,
+		ExpiresAt:      k.ExpiresAt,
+		LastUsedAt:     k.LastUsedAt,
+		CreatedAt:      k.CreatedAt,
+		RevokedAt:      k.RevokedAt,
+	}
+}
+
+// APIKeyRequestFormat is the payload to create an API key. A zero
+// quotaPerMinute uses the configured default.
+type APIKeyRequestFormat struct {
+	Name           string      `json:"name" validate:"required,max=100"`
+	OwnerID        nuuid.NUUID `json:"ownerId"`
+	Scope          string      `json:"scope" validate:"max=2000"`
+	QuotaPerMinute int         `json:"quotaPerMinute" validate:"min=0"`
+	ExpiresAt      null.Time   `json:"expiresAt"`
+}
+
+type APIKeyResponseFormat struct {
+	ID             uuid.UUID `json:"id"`
+	Name           string    `json:"name"`
+	Key            string    `json:"key,omitempty"`
+	Prefix         string    `json:"prefix"`
+	OwnerID        uuid.UUID `json:"ownerId"`
+	Scope          string    `json:"scope"`
+	QuotaPerMinute int       `json:"quotaPerMinute"`
+	ExpiresAt      null.Time `json:"expiresAt"`
+	LastUsedAt     null.Time `json:"lastUsedAt"`
+	CreatedAt      time.Time `json:"createdAt"`
+	RevokedAt      null.Time `json:"revokedAt"`
+}
+
+// Quota is the use of an API key's request quota in the current minute.
+type Quota struct {
+	Limit     int
+	Remaining int
+	Reset     time.Duration
+}
+
+// Exceeded checks whether the request that consumed the quota exceeded it.
+func (q Quota) Exceeded() bool {
+	return q.Remaining < 0
+}
@@ -0,0 +1,81 @@
+package apikey_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/evermos/boilerplate-go/internal/domain/apikey"
+	"github.com/evermos/boilerplate-go/shared/nuuid"
+	"github.com/gofrs/uuid"
+	"github.com/guregu/null"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKey(t *testing.T) {
+	adminID := uuid.Must(uuid.NewV4())
+	requestFormat := apikey.APIKeyRequestFormat{
+		Name:  "inventory-sync",
+		Scope: "foo:read  foo:write",
+	}
+
+	plainOf := func(key apikey.APIKey) string {
+		body, err := json.Marshal(key)
+		require.NoError(t, err)
+
+		var responseFormat apikey.APIKeyResponseFormat
+		require.NoError(t, json.Unmarshal(body, &responseFormat))
+		return responseFormat.Key
+	}
+
+	t.Run("Key Is Prefixed, Hashed And Shown Once", func(t *testing.T) {
+		key, err := apikey.APIKey{}.NewFromRequestFormat(requestFormat, adminID)
+		require.NoError(t, err)
+
+		plain := plainOf(key)
+		assert.True(t, strings.HasPrefix(plain, apikey.KeyPrefix))
+		assert.True(t, strings.HasPrefix(plain, key.Prefix))
+		assert.Equal(t, apikey.HashKey(plain), key.KeyHash)
+		assert.NotContains(t, key.KeyHash, plain)
+		assert.Equal(t, adminID, key.OwnerID)
+		assert.True(t, key.Scopes().Has("foo:write"))
+
+		assert.Empty(t, plainOf(apikey.APIKey{ID: key.ID, KeyHash: key.KeyHash}))
+	})
+
+	t.Run("Owner", func(t *testing.T) {
+		ownerID := uuid.Must(uuid.NewV4())
+		withOwner := requestFormat
+		withOwner.OwnerID = nuuid.From(ownerID)
+
+		key, err := apikey.APIKey{}.NewFromRequestFormat(withOwner, adminID)
+		require.NoError(t, err)
+
+		assert.Equal(t, ownerID, key.OwnerID)
+		assert.Equal(t, adminID, key.CreatedBy)
+	})
+
+	t.Run("Expired Or Revoked Keys Are Inactive", func(t *testing.T) {
+		key, err := apikey.APIKey{}.NewFromRequestFormat(requestFormat, adminID)
+		require.NoError(t, err)
+		assert.True(t, key.IsActive())
+
+		expired := key
+		expired.ExpiresAt = null.TimeFrom(time.Now().Add(-time.Second))
+		assert.False(t, expired.IsActive())
+
+		key.Revoke(adminID)
+		assert.False(t, key.IsActive())
+	})
+
+	t.Run("Expiry In The Past", func(t *testing.T) {
+		expired := requestFormat
+		expired.ExpiresAt = null.TimeFrom(time.Now().Add(-time.Hour))
+
+		_, err := apikey.APIKey{}.NewFromRequestFormat(expired, adminID)
+
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,181 @@
+package apikey
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/evermos/boilerplate-go/infras"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/logger"
+	"github.com/gofrs/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	apiKeyQueries = struct {
+		selectAPIKey   string
+		insertAPIKey   string
+		revokeAPIKey   string
+		updateLastUsed string
+	}{
+		selectAPIKey: `
+			SELECT
+				id,
+				name,
+				prefix,
+				key_hash,
+				owner_id,
+				scope,
+				quota_per_minute,
+				expires_at,
+				last_used_at,
+				created_at,
+				created_by,
+				revoked_at,
+				revoked_by
+			FROM api_key
+		`,
+
+		insertAPIKey: `
+			INSERT INTO api_key (
+				id,
+				name,
+				prefix,
+				key_hash,
+				owner_id,
+				scope,
+				quota_per_minute,
+				expires_at,
+				last_used_at,
+				created_at,
+				created_by,
+				revoked_at,
+				revoked_by
+			) VALUES (
+				:id,
+				:name,
+				:prefix,
+				:key_hash,
+				:owner_id,
+				:scope,
+				:quota_per_minute,
+				:expires_at,
+				:last_used_at,
+				:created_at,
+				:created_by,
+				:revoked_at,
+				:revoked_by
+			)
+		`,
+
+		revokeAPIKey: `
+			UPDATE api_key
+			SET
+				revoked_at = :revoked_at,
+				revoked_by = :revoked_by
+			WHERE id = :id
+		`,
+
+		updateLastUsed: `UPDATE api_key SET last_used_at = ? WHERE id = ?`,
+	}
+)
+
+type APIKeyRepository interface {
+	CreateAPIKey(key APIKey) (err error)
+	ResolveAPIKeys() (keys []APIKey, err error)
+	ResolveAPIKeyByID(id uuid.UUID) (key APIKey, err error)
+	ResolveAPIKeyByHash(keyHash string) (key APIKey, err error)
+	RevokeAPIKey(key APIKey) (err error)
+	UpdateLastUsed(id uuid.UUID, lastUsedAt time.Time) (err error)
+}
+
+type APIKeyRepositoryMySQL struct {
+	DB *infras.MySQLConn
+}
+
+func ProvideAPIKeyRepositoryMySQL(db *infras.MySQLConn) *APIKeyRepositoryMySQL {
+	s := new(APIKeyRepositoryMySQL)
+	s.DB = db
+
+	return s
+}
+
+func (r *APIKeyRepositoryMySQL) CreateAPIKey(key APIKey) (err error) {
+	return r.DB.WithTransaction(func(tx *sqlx.Tx, e chan error) {
+		if err := r.txExec(tx, apiKeyQueries.insertAPIKey, key); err != nil {
+			e <- err
+			return
+		}
+
+		e <- nil
+	})
+}
+
+// ResolveAPIKeys resolves every API key, newest first.
+func (r *APIKeyRepositoryMySQL) ResolveAPIKeys() (keys []APIKey, err error) {
+	err = r.DB.Read.Select(&keys, apiKeyQueries.selectAPIKey+" ORDER BY created_at DESC")
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+
+	return
+}
+
+func (r *APIKeyRepositoryMySQL) ResolveAPIKeyByID(id uuid.UUID) (key APIKey, err error) {
+	return r.resolveAPIKey(" WHERE id = ?", id.String())
+}
+
+func (r *APIKeyRepositoryMySQL) ResolveAPIKeyByHash(keyHash string) (key APIKey, err error) {
+	return r.resolveAPIKey(" WHERE key_hash = ?", keyHash)
+}
+
+func (r *APIKeyRepositoryMySQL) RevokeAPIKey(key APIKey) (err error) {
+	return r.DB.WithTransaction(func(tx *sqlx.Tx, e chan error) {
+		if err := r.txExec(tx, apiKeyQueries.revokeAPIKey, key); err != nil {
+			e <- err
+			return
+		}
+
+		e <- nil
+	})
+}
+
+func (r *APIKeyRepositoryMySQL) UpdateLastUsed(id uuid.UUID, lastUsedAt time.Time) (err error) {
+	_, err = r.DB.Write.Exec(apiKeyQueries.updateLastUsed, lastUsedAt, id.String())
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+
+	return
+}
+
+func (r *APIKeyRepositoryMySQL) resolveAPIKey(where string, arg interface{}) (key APIKey, err error) {
+	err = r.DB.Read.Get(&key, apiKeyQueries.selectAPIKey+where, arg)
+	if err != nil && err == sql.ErrNoRows {
+		err = failure.NotFound("api key")
+		return
+	}
+
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+
+	return
+}
+
+// Transactions
+func (r *APIKeyRepositoryMySQL) txExec(tx *sqlx.Tx, query string, key APIKey) (err error) {
+	stmt, err := tx.PrepareNamed(query)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(key)
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+
+	return
+}
@@ -0,0 +1,167 @@
+package apikey
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/evermos/boilerplate-go/configs"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/go-redis/redis"
+	"github.com/gofrs/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// defaultQuotaPerMinute is the quota of API keys created without one,
+	// when none is configured.
+	defaultQuotaPerMinute = 600
+	// lastUsedResolution is how stale the last-used time of an API key may
+	// get, so that busy keys are not written on every request.
+	lastUsedResolution = time.Minute
+	// quotaWindow is the window API key quotas are counted in.
+	quotaWindow = time.Minute
+)
+
+// ErrInvalidAPIKey is the message of the error authenticating an unknown,
+// revoked or expired API key.
+const ErrInvalidAPIKey = "Invalid API key"
+
+type APIKeyService interface {
+	CreateAPIKey(requestFormat APIKeyRequestFormat, userID uuid.UUID) (key APIKey, err error)
+	ResolveAPIKeys() (keys []APIKey, err error)
+	RevokeAPIKey(id uuid.UUID, userID uuid.UUID) (key APIKey, err error)
+	Authenticate(plain string) (key APIKey, err error)
+	ConsumeQuota(key APIKey) (quota Quota, err error)
+}
+
+type APIKeyServiceImpl struct {
+	APIKeyRepository APIKeyRepository
+	Redis            *redis.Client
+	Config           *configs.Config
+}
+
+func ProvideAPIKeyServiceImpl(apiKeyRepository APIKeyRepository, redis *redis.Client, config *configs.Config) *APIKeyServiceImpl {
+	s := new(APIKeyServiceImpl)
+	s.APIKeyRepository = apiKeyRepository
+	s.Redis = redis
+	s.Config = config
+
+	return s
+}
+
+// CreateAPIKey creates an API key. The returned key carries the plain key,
+// which is never shown again.
+func (s *APIKeyServiceImpl) CreateAPIKey(requestFormat APIKeyRequestFormat, userID uuid.UUID) (key APIKey, err error) {
+	key, err = key.NewFromRequestFormat(requestFormat, userID)
+	if err != nil {
+		return key, failure.BadRequest(err)
+	}
+
+	if key.QuotaPerMinute == 0 {
+		key.QuotaPerMinute = s.defaultQuota()
+	}
+
+	err = s.APIKeyRepository.CreateAPIKey(key)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (s *APIKeyServiceImpl) ResolveAPIKeys() (keys []APIKey, err error) {
+	return s.APIKeyRepository.ResolveAPIKeys()
+}
+
+// RevokeAPIKey revokes an API key that is not revoked yet.
+func (s *APIKeyServiceImpl) RevokeAPIKey(id uuid.UUID, userID uuid.UUID) (key APIKey, err error) {
+	key, err = s.APIKeyRepository.ResolveAPIKeyByID(id)
+	if err != nil {
+		return
+	}
+
+	if key.IsRevoked() {
+		return key, failure.NotFound("api key")
+	}
+
+	key.Revoke(userID)
+
+	err = s.APIKeyRepository.RevokeAPIKey(key)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// Authenticate resolves the active API key of a plain key, and records that it
+// was used.
+func (s *APIKeyServiceImpl) Authenticate(plain string) (key APIKey, err error) {
+	if !strings.HasPrefix(plain, KeyPrefix) {
+		return key, failure.Unauthorized(ErrInvalidAPIKey)
+	}
+
+	key, err = s.APIKeyRepository.ResolveAPIKeyByHash(HashKey(plain))
+	if failure.GetCode(err) == http.StatusNotFound {
+		return key, failure.Unauthorized(ErrInvalidAPIKey)
+	}
+	if err != nil {
+		return
+	}
+
+	if !key.IsActive() {
+		return key, failure.Unauthorized(ErrInvalidAPIKey)
+	}
+
+	now := time.Now()
+	if !key.LastUsedAt.Valid || now.Sub(key.LastUsedAt.Time) >= lastUsedResolution {
+		// A failure to record the use must not fail the request.
+		if err := s.APIKeyRepository.UpdateLastUsed(key.ID, now); err == nil {
+			key.LastUsedAt.SetValid(now)
+		}
+	}
+
+	return key, nil
+}
+
+// ConsumeQuota counts a request against the quota of an API key in the
+// current minute. When Redis is unavailable, requests are let through rather
+// than failing every service using API keys.
+func (s *APIKeyServiceImpl) ConsumeQuota(key APIKey) (quota Quota, err error) {
+	now := time.Now()
+	window := now.Truncate(quotaWindow)
+
+	quota = Quota{
+		Limit: key.QuotaPerMinute,
+		Reset: window.Add(quotaWindow).Sub(now),
+	}
+	if quota.Limit <= 0 {
+		quota.Limit = s.defaultQuota()
+	}
+
+	counter := fmt.Sprintf("api_key_quota:%s:%d", key.ID, window.Unix())
+
+	pipe := s.Redis.TxPipeline()
+	incr := pipe.Incr(counter)
+	pipe.Expire(counter, quotaWindow)
+	_, err = pipe.Exec()
+	if err != nil {
+		log.Warn().Err(err).Str("apiKey", key.ID.String()).Msg("Failed counting API key quota, letting the request through")
+		quota.Remaining = quota.Limit
+		return quota, nil
+	}
+
+	quota.Remaining = quota.Limit - int(incr.Val())
+
+	return
+}
+
+func (s *APIKeyServiceImpl) defaultQuota() int {
+	if s.Config.APIKey.DefaultQuotaPerMinute > 0 {
+		return s.Config.APIKey.DefaultQuotaPerMinute
+	}
+
+	return defaultQuotaPerMinute
+}
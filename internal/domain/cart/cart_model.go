@@ -93,25 +93,29 @@ type CartResponseFormat struct {
 
 // CartItem
 type CartItem struct {
-	ID        uuid.UUID   `db:"id"`
-	CartID    uuid.UUID   `db:"cart_id" validate:"required"`
-	ProductID uuid.UUID   `db:"product_id" validate:"required"`
-	UnitPrice float64     `db:"unit_price" validate:"required"`
-	Quantity  int         `db:"quantity" validate:"required,min=1"`
-	Cost      float64     `db:"cost" validate:"required,min=0"`
-	Stock     int         `db:"stock"`
-	CreatedAt time.Time   `db:"created_at" validate:"required"`
-	CreatedBy uuid.UUID   `db:"created_by" validate:"required"`
-	UpdatedAt null.Time   `db:"updated_at"`
-	UpdatedBy nuuid.NUUID `db:"updated_by"`
-	DeletedAt null.Time   `db:"deleted_at"`
-	DeletedBy nuuid.NUUID `db:"deleted_by"`
+	ID        uuid.UUID `db:"id"`
+	CartID    uuid.UUID `db:"cart_id" validate:"required"`
+	ProductID uuid.UUID `db:"product_id" validate:"required"`
+	VariantID uuid.UUID `db:"variant_id" validate:"required"`
+	UnitPrice float64   `db:"unit_price" validate:"required"`
+	Quantity  int       `db:"quantity" validate:"required,min=1"`
+	Cost      float64   `db:"cost" validate:"required,min=0"`
+	// ProductName, SKU and Stock are only resolved with the cart's details.
+	ProductName string      `db:"product_name"`
+	SKU         string      `db:"sku"`
+	Stock       int         `db:"stock"`
+	CreatedAt   time.Time   `db:"created_at" validate:"required"`
+	CreatedBy   uuid.UUID   `db:"created_by" validate:"required"`
+	UpdatedAt   null.Time   `db:"updated_at"`
+	UpdatedBy   nuuid.NUUID `db:"updated_by"`
+	DeletedAt   null.Time   `db:"deleted_at"`
+	DeletedBy   nuuid.NUUID `db:"deleted_by"`
 }
 
 func (ci CartItem) MarshalJSON() ([]byte, error) {
 	return json.Marshal(ci.ToResponseFormat())
 }
-func (ci CartItem) NewFromRequestFormat(req CartItemRequestFormat, userID uuid.UUID, cartID uuid.UUID, price float64) (newCartItem CartItem, err error) {
+func (ci CartItem) NewFromRequestFormat(req CartItemRequestFormat, userID uuid.UUID, cartID uuid.UUID, variantID uuid.UUID, price float64) (newCartItem CartItem, err error) {
 	cartItemID, err := uuid.NewV4()
 	if err != nil {
 		return
@@ -121,6 +125,7 @@ func (ci CartItem) NewFromRequestFormat(req CartItemRequestFormat, userID uuid.U
 		ID:        cartItemID,
 		CartID:    cartID,
 		ProductID: req.ProductID,
+		VariantID: variantID,
 		UnitPrice: price,
 		Quantity:  req.Quantity,
 		CreatedAt: time.Now(),
@@ -143,6 +148,7 @@ func (ci *CartItem) ToResponseFormat() CartItemResponseFormat {
 		ID:        ci.ID,
 		CartID:    ci.CartID,
 		ProductID: ci.ProductID,
+		VariantID: ci.VariantID,
 		UnitPrice: ci.UnitPrice,
 		Quantity:  ci.Quantity,
 		Cost:      ci.Cost,
@@ -155,14 +161,18 @@ func (ci *CartItem) ToResponseFormat() CartItemResponseFormat {
 	}
 }
 
+// CartItemRequestFormat is the payload to add a product to the cart. The
+// variantID may be left out for products sold without options.
 type CartItemRequestFormat struct {
 	ProductID uuid.UUID `json:"productID" validate:"required"`
+	VariantID uuid.UUID `json:"variantID"`
 	Quantity  int       `json:"quantity" validate:"required,min=1"`
 }
 type CartItemResponseFormat struct {
 	ID        uuid.UUID  `json:"ID"`
 	CartID    uuid.UUID  `json:"-"`
 	ProductID uuid.UUID  `json:"productID"`
+	VariantID uuid.UUID  `json:"variantID"`
 	UnitPrice float64    `json:"unitPrice"`
 	Quantity  int        `json:"quantity"`
 	Cost      float64    `json:"cost"`
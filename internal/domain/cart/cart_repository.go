@@ -18,7 +18,7 @@ type CartRepository interface {
 	ResolveItemsByCartID(ids []uuid.UUID) (cartItems []CartItem, err error)
 	ResolveOrCreateCartByUserID(userID uuid.UUID) (cart Cart, err error)
 	CreateCart(cart Cart) (err error)
-	ResolveCartItemByProductID(cartID, productID uuid.UUID) (cartItem CartItem, found bool, err error)
+	ResolveCartItemByVariantID(cartID, variantID uuid.UUID) (cartItem CartItem, found bool, err error)
 	UpdateItemQuantity(cartItem CartItem) (err error)
 	CreateCartItem(cartItem CartItem, userID uuid.UUID) (err error)
 	ResolveDetailedItemsByCartID(ids []uuid.UUID) (cartItems []CartItem, err error)
@@ -54,7 +54,7 @@ func (r *CartRepositoryMySQL) ResolveCartByUserID(id uuid.UUID) (cart Cart, err
 	return
 }
 func (r *CartRepositoryMySQL) ResolveItemsByCartID(ids []uuid.UUID) (cartItems []CartItem, err error) {
-	insertQuery := `SELECT id, cart_id, product_id, unit_price, quantity, cost, created_at, created_by, updated_at, updated_by, deleted_at, deleted_by FROM cart_item`
+	insertQuery := `SELECT id, cart_id, product_id, variant_id, unit_price, quantity, cost, created_at, created_by, updated_at, updated_by, deleted_at, deleted_by FROM cart_item`
 
 	if len(ids) == 0 {
 		return
@@ -108,10 +108,10 @@ func (r *CartRepositoryMySQL) CreateCart(cart Cart) (err error) {
 		e <- nil
 	})
 }
-func (r *CartRepositoryMySQL) ResolveCartItemByProductID(cartID, productID uuid.UUID) (cartItem CartItem, found bool, err error) {
-	selectQuery := `SELECT id, cart_id, product_id, unit_price, quantity, cost, created_at, created_by, updated_at, updated_by, deleted_at, deleted_by FROM cart_item WHERE cart_id = ? AND product_id = ?`
+func (r *CartRepositoryMySQL) ResolveCartItemByVariantID(cartID, variantID uuid.UUID) (cartItem CartItem, found bool, err error) {
+	selectQuery := `SELECT id, cart_id, product_id, variant_id, unit_price, quantity, cost, created_at, created_by, updated_at, updated_by, deleted_at, deleted_by FROM cart_item WHERE cart_id = ? AND variant_id = ?`
 
-	err = r.DB.Read.Get(&cartItem, selectQuery, cartID, productID)
+	err = r.DB.Read.Get(&cartItem, selectQuery, cartID, variantID)
 	if err != nil && err == sql.ErrNoRows {
 		return cartItem, false, nil
 	} else if err != nil {
@@ -156,8 +156,12 @@ func (r *CartRepositoryMySQL) CreateCartItem(cartItem CartItem, userID uuid.UUID
 		e <- nil
 	})
 }
+
+// ResolveDetailedItemsByCartID resolves the cart items along with the name of
+// their products and the SKU and stock of their variants. Items of deleted
+// products or variants are left out.
 func (r *CartRepositoryMySQL) ResolveDetailedItemsByCartID(ids []uuid.UUID) (cartItems []CartItem, err error) {
-	initialQuery := `SELECT cart_item.id, cart_item.cart_id, cart_item.product_id, cart_item.unit_price, cart_item.quantity, cart_item.cost, cart_item.created_at, cart_item.created_by, cart_item.updated_at, cart_item.updated_by, cart_item.deleted_at, cart_item.deleted_by, product.stock FROM cart_item JOIN product ON cart_item.product_id = product.id
+	initialQuery := `SELECT cart_item.id, cart_item.cart_id, cart_item.product_id, cart_item.variant_id, cart_item.unit_price, cart_item.quantity, cart_item.cost, cart_item.created_at, cart_item.created_by, cart_item.updated_at, cart_item.updated_by, cart_item.deleted_at, cart_item.deleted_by, product.name AS product_name, product_variant.sku, product_variant.stock FROM cart_item JOIN product ON cart_item.product_id = product.id AND product.deleted_at IS NULL JOIN product_variant ON cart_item.variant_id = product_variant.id AND product_variant.deleted_at IS NULL
 	`
 	if len(ids) == 0 {
 		return
@@ -197,8 +201,8 @@ func (r *CartRepositoryMySQL) txCreate(tx *sqlx.Tx, cart Cart) (err error) {
 	return
 }
 func (r *CartRepositoryMySQL) composeBulkInsertItemQuery(cartItems []CartItem) (query string, params []interface{}, err error) {
-	insertCartItemBulk := `INSERT INTO cart_item (id, cart_id, product_id, unit_price, quantity, cost, created_at, created_by, updated_at, updated_by, deleted_at, deleted_by) VALUES `
-	insertCartItemBulkPlaceholder := `(:id, :cart_id, :product_id, :unit_price, :quantity, :cost, :created_at, :created_by, :updated_at, :updated_by, :deleted_at, :deleted_by)`
+	insertCartItemBulk := `INSERT INTO cart_item (id, cart_id, product_id, variant_id, unit_price, quantity, cost, created_at, created_by, updated_at, updated_by, deleted_at, deleted_by) VALUES `
+	insertCartItemBulkPlaceholder := `(:id, :cart_id, :product_id, :variant_id, :unit_price, :quantity, :cost, :created_at, :created_by, :updated_at, :updated_by, :deleted_at, :deleted_by)`
 
 	values := []string{}
 	for _, ci := range cartItems {
@@ -206,6 +210,7 @@ func (r *CartRepositoryMySQL) composeBulkInsertItemQuery(cartItems []CartItem) (
 			"id":         ci.ID,
 			"cart_id":    ci.CartID,
 			"product_id": ci.ProductID,
+			"variant_id": ci.VariantID,
 			"unit_price": ci.UnitPrice,
 			"quantity":   ci.Quantity,
 			"cost":       ci.Cost,
@@ -250,7 +255,7 @@ func (r *CartRepositoryMySQL) txCreateItems(tx *sqlx.Tx, cartItems []CartItem) (
 	return
 }
 func (r *CartRepositoryMySQL) txUpdate(tx *sqlx.Tx, cartItem CartItem) (err error) {
-	updateQuery := `UPDATE cart_item SET id = :id, cart_id = :cart_id, product_id = :product_id, unit_price = :unit_price, quantity = :quantity, cost = :cost, created_at = :created_at, created_by = :created_by, updated_at = :updated_at, updated_by = :updated_by, deleted_at = :deleted_at, deleted_by = :deleted_by WHERE id = :id`
+	updateQuery := `UPDATE cart_item SET id = :id, cart_id = :cart_id, product_id = :product_id, variant_id = :variant_id, unit_price = :unit_price, quantity = :quantity, cost = :cost, created_at = :created_at, created_by = :created_by, updated_at = :updated_at, updated_by = :updated_by, deleted_at = :deleted_at, deleted_by = :deleted_by WHERE id = :id`
 	stmt, err := tx.PrepareNamed(updateQuery)
 	if err != nil {
 		logger.ErrorWithStack(err)
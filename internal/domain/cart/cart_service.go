@@ -32,12 +32,12 @@ func ProvideCartServiceImpl(cartRepository CartRepository, productService produc
 func (s *CartServiceImpl) AddToCart(requestFormat CartItemRequestFormat, userID uuid.UUID) (cartItem CartItem, err error) {
 	product, err := s.ProductService.ResolveByID(requestFormat.ProductID)
 	if err != nil {
-		return cartItem, failure.InternalError(err)
+		return
 	}
 
-	if product.Stock < requestFormat.Quantity {
-		logger.ErrorWithStack(err)
-		return cartItem, failure.BadRequestFromString("Not enough stock")
+	variant, err := product.ResolveVariant(requestFormat.VariantID)
+	if err != nil {
+		return
 	}
 
 	cart, err := s.CartRepository.ResolveOrCreateCartByUserID(userID)
@@ -46,20 +46,29 @@ func (s *CartServiceImpl) AddToCart(requestFormat CartItemRequestFormat, userID
 		return cartItem, failure.InternalError(err)
 	}
 
-	cartItem, err = cartItem.NewFromRequestFormat(requestFormat, userID, cart.ID, product.Price)
+	cartItem, err = cartItem.NewFromRequestFormat(requestFormat, userID, cart.ID, variant.ID, variant.EffectivePrice(product.Price))
 	if err != nil {
 		return cartItem, failure.InternalError(err)
 	}
 
-	existingCartItem, found, err := s.CartRepository.ResolveCartItemByProductID(cart.ID, product.ID)
+	existingCartItem, found, err := s.CartRepository.ResolveCartItemByVariantID(cart.ID, variant.ID)
 	if err != nil {
 		logger.ErrorWithStack(err)
 		return cartItem, failure.InternalError(err)
 	}
 
+	quantity := requestFormat.Quantity
+	if found {
+		quantity += existingCartItem.Quantity
+	}
+
+	if variant.Stock < quantity {
+		return cartItem, failure.BadRequestFromString("Not enough stock")
+	}
+
 	if found {
-		existingCartItem.Quantity += requestFormat.Quantity
-		existingCartItem.Cost = float64(existingCartItem.Quantity) * existingCartItem.UnitPrice
+		existingCartItem.Quantity = quantity
+		existingCartItem.Recalculate()
 		err = s.CartRepository.UpdateItemQuantity(existingCartItem)
 		if err != nil {
 			logger.ErrorWithStack(err)
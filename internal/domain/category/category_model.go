@@ -0,0 +1,255 @@
+package category
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/evermos/boilerplate-go/shared"
+	"github.com/evermos/boilerplate-go/shared/nuuid"
+	"github.com/gofrs/uuid"
+	"github.com/guregu/null"
+)
+
+// slugPattern matches slugs: lowercase letters and digits, separated by single
+// dashes.
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// Category is a node of the product taxonomy. Top-level categories have no
+// parent; siblings are ordered by their position, then by name.
+type Category struct {
+	ID        uuid.UUID   `db:"id" validate:"required"`
+	ParentID  nuuid.NUUID `db:"parent_id"`
+	Name      string      `db:"name" validate:"required,max=100"`
+	Slug      string      `db:"slug" validate:"required,max=100"`
+	Position  int         `db:"position" validate:"min=0"`
+	CreatedAt time.Time   `db:"created_at" validate:"required"`
+	CreatedBy uuid.UUID   `db:"created_by" validate:"required"`
+	UpdatedAt null.Time   `db:"updated_at"`
+	UpdatedBy nuuid.NUUID `db:"updated_by"`
+	DeletedAt null.Time   `db:"deleted_at"`
+	DeletedBy nuuid.NUUID `db:"deleted_by"`
+}
+
+func (c *Category) IsDeleted() (deleted bool) {
+	return c.DeletedAt.Valid && c.DeletedBy.Valid
+}
+
+func (c Category) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.ToResponseFormat())
+}
+
+// NewFromRequestFormat creates a new category. Without a slug, the slug is
+// derived from the name.
+func (c Category) NewFromRequestFormat(req CategoryRequestFormat, userID uuid.UUID) (newCategory Category, err error) {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return
+	}
+
+	newCategory = Category{
+		ID:        id,
+		ParentID:  req.ParentID,
+		Name:      strings.TrimSpace(req.Name),
+		Slug:      req.Slug,
+		Position:  req.Position,
+		CreatedAt: time.Now(),
+		CreatedBy: userID,
+	}
+
+	if newCategory.Slug == "" {
+		newCategory.Slug = Slugify(newCategory.Name)
+	}
+
+	err = newCategory.Validate()
+
+	return
+}
+
+// Update replaces the category's name, slug, parent and position. Without a
+// slug, the category keeps its own.
+func (c *Category) Update(req CategoryRequestFormat, userID uuid.UUID) (err error) {
+	c.ParentID = req.ParentID
+	c.Name = strings.TrimSpace(req.Name)
+	c.Position = req.Position
+	c.UpdatedAt = null.TimeFrom(time.Now())
+	c.UpdatedBy = nuuid.From(userID)
+
+	if req.Slug != "" {
+		c.Slug = req.Slug
+	}
+
+	if c.ParentID.Valid && c.ParentID.UUID == c.ID {
+		return errors.New("a category cannot be its own parent")
+	}
+
+	err = c.Validate()
+
+	return
+}
+
+func (c *Category) Validate() (err error) {
+	validator := shared.GetValidator()
+	err = validator.Struct(c)
+	if err != nil {
+		return
+	}
+
+	if !slugPattern.MatchString(c.Slug) {
+		return errors.New("slug must be lowercase letters and digits separated by single dashes")
+	}
+
+	return
+}
+
+func (c Category) ToResponseFormat() CategoryResponseFormat {
+	return CategoryResponseFormat{
+		ID:       c.ID,
+		ParentID: c.ParentID.Ptr(),
+		Name:     c.Name,
+		Slug:     c.Slug,
+		Position: c.Position,
+		Children: make([]CategoryResponseFormat, 0),
+	}
+}
+
+// Slugify derives a slug from a name: lowercase letters and digits, with every
+// other run of characters replaced by a single dash.
+func Slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		dash = true
+	}
+
+	return b.String()
+}
+
+// Categories is the taxonomy, or part of it.
+type Categories []Category
+
+// ByID resolves the category with the given ID.
+func (cs Categories) ByID(id uuid.UUID) (category Category, found bool) {
+	for _, category := range cs {
+		if category.ID == id {
+			return category, true
+		}
+	}
+
+	return category, false
+}
+
+// BySlug resolves the category with the given slug.
+func (cs Categories) BySlug(slug string) (category Category, found bool) {
+	for _, category := range cs {
+		if category.Slug == slug {
+			return category, true
+		}
+	}
+
+	return category, false
+}
+
+// Descendants returns the ID of the category followed by the IDs of every
+// category below it.
+func (cs Categories) Descendants(id uuid.UUID) (ids []uuid.UUID) {
+	ids = []uuid.UUID{id}
+	seen := map[uuid.UUID]bool{id: true}
+	for i := 0; i < len(ids); i++ {
+		for _, category := range cs {
+			if category.ParentID.Valid && category.ParentID.UUID == ids[i] && !seen[category.ID] {
+				seen[category.ID] = true
+				ids = append(ids, category.ID)
+			}
+		}
+	}
+
+	return
+}
+
+// CheckParent checks that the category may be placed under the parent: the
+// parent must exist, and must not be below the category itself, which would
+// make a cycle.
+func (cs Categories) CheckParent(category Category) error {
+	if !category.ParentID.Valid {
+		return nil
+	}
+
+	if _, found := cs.ByID(category.ParentID.UUID); !found {
+		return errors.New("parentID is not a category")
+	}
+
+	for _, id := range cs.Descendants(category.ID) {
+		if id == category.ParentID.UUID {
+			return errors.New("a category cannot be placed below itself")
+		}
+	}
+
+	return nil
+}
+
+// Tree arranges the categories into a tree. Categories of which the parent is
+// missing are placed at the top level.
+func (cs Categories) Tree() []CategoryResponseFormat {
+	sorted := make(Categories, len(cs))
+	copy(sorted, cs)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Position != sorted[j].Position {
+			return sorted[i].Position < sorted[j].Position
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	children := make(map[uuid.UUID][]Category)
+	var roots []Category
+	for _, category := range sorted {
+		if _, found := cs.ByID(category.ParentID.UUID); category.ParentID.Valid && found {
+			children[category.ParentID.UUID] = append(children[category.ParentID.UUID], category)
+			continue
+		}
+		roots = append(roots, category)
+	}
+
+	var build func(categories []Category) []CategoryResponseFormat
+	build = func(categories []Category) []CategoryResponseFormat {
+		nodes := make([]CategoryResponseFormat, 0, len(categories))
+		for _, category := range categories {
+			node := category.ToResponseFormat()
+			node.Children = build(children[category.ID])
+			nodes = append(nodes, node)
+		}
+		return nodes
+	}
+
+	return build(roots)
+}
+
+// CategoryRequestFormat is the payload to create or replace a category.
+// Categories without a parent are placed at the top level.
+type CategoryRequestFormat struct {
+	Name     string      `json:"name" validate:"required,max=100"`
+	Slug     string      `json:"slug" validate:"max=100"`
+	ParentID nuuid.NUUID `json:"parentID"`
+	Position int         `json:"position" validate:"min=0"`
+}
+
+type CategoryResponseFormat struct {
+	ID       uuid.UUID                `json:"id"`
+	ParentID *uuid.UUID               `json:"parentID"`
+	Name     string                   `json:"name"`
+	Slug     string                   `json:"slug"`
+	Position int                      `json:"position"`
+	Children []CategoryResponseFormat `json:"children"`
+}
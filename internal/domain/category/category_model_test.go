@@ -0,0 +1,69 @@
+package category_test
+
+import (
+	"testing"
+
+	"github.com/evermos/boilerplate-go/internal/domain/category"
+	"github.com/evermos/boilerplate-go/shared/nuuid"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCategories(t *testing.T) {
+	userID := uuid.Must(uuid.NewV4())
+	newCategory := func(name string, parent *category.Category, position int) category.Category {
+		req := category.CategoryRequestFormat{Name: name, Position: position}
+		if parent != nil {
+			req.ParentID = nuuid.From(parent.ID)
+		}
+
+		c, err := category.Category{}.NewFromRequestFormat(req, userID)
+		require.NoError(t, err)
+
+		return c
+	}
+
+	electronics := newCategory("Electronics", nil, 1)
+	apparel := newCategory("Apparel", nil, 1)
+	phones := newCategory("Phones & Tablets", &electronics, 0)
+	android := newCategory("Android", &phones, 0)
+	categories := category.Categories{android, phones, electronics, apparel}
+
+	t.Run("Derives Slugs", func(t *testing.T) {
+		assert.Equal(t, "phones-tablets", phones.Slug)
+		assert.Equal(t, "t-shirts-2024", category.Slugify("  T-Shirts (2024) "))
+
+		_, err := category.Category{}.NewFromRequestFormat(category.CategoryRequestFormat{Name: "Bags", Slug: "Bags!"}, userID)
+		assert.Error(t, err)
+	})
+
+	t.Run("Builds The Tree", func(t *testing.T) {
+		tree := categories.Tree()
+
+		require.Len(t, tree, 2)
+		assert.Equal(t, "apparel", tree[0].Slug)
+		assert.Equal(t, "electronics", tree[1].Slug)
+		require.Len(t, tree[1].Children, 1)
+		require.Len(t, tree[1].Children[0].Children, 1)
+		assert.Equal(t, "android", tree[1].Children[0].Children[0].Slug)
+		assert.Empty(t, tree[0].Children)
+	})
+
+	t.Run("Resolves Descendants", func(t *testing.T) {
+		assert.Equal(t, []uuid.UUID{electronics.ID, phones.ID, android.ID}, categories.Descendants(electronics.ID))
+		assert.Equal(t, []uuid.UUID{apparel.ID}, categories.Descendants(apparel.ID))
+	})
+
+	t.Run("Rejects Cycles", func(t *testing.T) {
+		moved := electronics
+		moved.ParentID = nuuid.From(android.ID)
+		assert.Error(t, categories.CheckParent(moved))
+
+		moved.ParentID = nuuid.From(apparel.ID)
+		assert.NoError(t, categories.CheckParent(moved))
+
+		moved.ParentID = nuuid.From(uuid.Must(uuid.NewV4()))
+		assert.Error(t, categories.CheckParent(moved))
+	})
+}
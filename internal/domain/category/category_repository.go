@@ -0,0 +1,176 @@
+package category
+
+import (
+	"database/sql"
+
+	"github.com/evermos/boilerplate-go/infras"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/logger"
+	"github.com/go-sql-driver/mysql"
+	"github.com/gofrs/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// mysqlErrDuplicateEntry is the MySQL error number of a unique key violation.
+const mysqlErrDuplicateEntry = 1062
+
+var (
+	categoryQueries = struct {
+		selectCategory        string
+		insertCategory        string
+		updateCategory        string
+		updateProductCategory string
+	}{
+		selectCategory: `
+			SELECT
+				id,
+				parent_id,
+				name,
+				slug,
+				position,
+				created_at,
+				created_by,
+				updated_at,
+				updated_by,
+				deleted_at,
+				deleted_by
+			FROM category
+		`,
+
+		insertCategory: `
+			INSERT INTO category (
+				id,
+				parent_id,
+				name,
+				slug,
+				position,
+				created_at,
+				created_by,
+				updated_at,
+				updated_by,
+				deleted_at,
+				deleted_by
+			) VALUES (
+				:id,
+				:parent_id,
+				:name,
+				:slug,
+				:position,
+				:created_at,
+				:created_by,
+				:updated_at,
+				:updated_by,
+				:deleted_at,
+				:deleted_by
+			)
+		`,
+
+		updateCategory: `
+			UPDATE category
+			SET
+				parent_id = :parent_id,
+				name = :name,
+				slug = :slug,
+				position = :position,
+				updated_at = :updated_at,
+				updated_by = :updated_by,
+				deleted_at = :deleted_at,
+				deleted_by = :deleted_by
+			WHERE id = :id
+		`,
+
+		// Products keep the name of their category for searching.
+		updateProductCategory: `UPDATE product SET category = :name WHERE category_id = :id`,
+	}
+)
+
+type CategoryRepository interface {
+	CreateCategory(category Category) (err error)
+	ResolveCategories() (categories Categories, err error)
+	ResolveCategoryByID(id uuid.UUID) (category Category, err error)
+	UpdateCategory(category Category) (err error)
+}
+
+type CategoryRepositoryMySQL struct {
+	DB *infras.MySQLConn
+}
+
+func ProvideCategoryRepositoryMySQL(db *infras.MySQLConn) *CategoryRepositoryMySQL {
+	s := new(CategoryRepositoryMySQL)
+	s.DB = db
+
+	return s
+}
+
+func (r *CategoryRepositoryMySQL) CreateCategory(category Category) (err error) {
+	return r.DB.WithTransaction(func(tx *sqlx.Tx, e chan error) {
+		if err := r.txExec(tx, categoryQueries.insertCategory, category); err != nil {
+			e <- err
+			return
+		}
+
+		e <- nil
+	})
+}
+
+// ResolveCategories resolves every category that is not deleted.
+func (r *CategoryRepositoryMySQL) ResolveCategories() (categories Categories, err error) {
+	err = r.DB.Read.Select(&categories, categoryQueries.selectCategory+" WHERE deleted_at IS NULL ORDER BY position, name")
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+
+	return
+}
+
+func (r *CategoryRepositoryMySQL) ResolveCategoryByID(id uuid.UUID) (category Category, err error) {
+	err = r.DB.Read.Get(&category, categoryQueries.selectCategory+" WHERE id = ? AND deleted_at IS NULL", id.String())
+	if err != nil && err == sql.ErrNoRows {
+		err = failure.NotFound("category")
+		return
+	}
+
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+
+	return
+}
+
+// UpdateCategory updates the category, along with the category name of its
+// products.
+func (r *CategoryRepositoryMySQL) UpdateCategory(category Category) (err error) {
+	return r.DB.WithTransaction(func(tx *sqlx.Tx, e chan error) {
+		if err := r.txExec(tx, categoryQueries.updateCategory, category); err != nil {
+			e <- err
+			return
+		}
+
+		if err := r.txExec(tx, categoryQueries.updateProductCategory, category); err != nil {
+			e <- err
+			return
+		}
+
+		e <- nil
+	})
+}
+
+// Transactions
+func (r *CategoryRepositoryMySQL) txExec(tx *sqlx.Tx, query string, category Category) (err error) {
+	stmt, err := tx.PrepareNamed(query)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(category)
+	if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == mysqlErrDuplicateEntry {
+		return failure.Conflict("save", "slug", "already used by another category")
+	}
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+
+	return
+}
@@ -0,0 +1,110 @@
+package category
+
+import (
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/gofrs/uuid"
+)
+
+type CategoryService interface {
+	CreateCategory(requestFormat CategoryRequestFormat, userID uuid.UUID) (category Category, err error)
+	UpdateCategory(id uuid.UUID, requestFormat CategoryRequestFormat, userID uuid.UUID) (category Category, err error)
+	ResolveCategories() (categories Categories, err error)
+	ResolveByID(id uuid.UUID) (category Category, err error)
+	OnRenamed(listener func(category Category))
+}
+
+type CategoryServiceImpl struct {
+	CategoryRepository CategoryRepository
+	renamed            []func(category Category)
+}
+
+func ProvideCategoryServiceImpl(categoryRepository CategoryRepository) *CategoryServiceImpl {
+	s := new(CategoryServiceImpl)
+	s.CategoryRepository = categoryRepository
+
+	return s
+}
+
+func (s *CategoryServiceImpl) CreateCategory(requestFormat CategoryRequestFormat, userID uuid.UUID) (category Category, err error) {
+	category, err = category.NewFromRequestFormat(requestFormat, userID)
+	if err != nil {
+		return category, failure.BadRequest(err)
+	}
+
+	err = s.checkParent(category)
+	if err != nil {
+		return
+	}
+
+	err = s.CategoryRepository.CreateCategory(category)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// UpdateCategory updates a category. Renaming a category renames it on its
+// products too, and notifies the rename listeners once saved.
+func (s *CategoryServiceImpl) UpdateCategory(id uuid.UUID, requestFormat CategoryRequestFormat, userID uuid.UUID) (category Category, err error) {
+	category, err = s.CategoryRepository.ResolveCategoryByID(id)
+	if err != nil {
+		return
+	}
+
+	name := category.Name
+	err = category.Update(requestFormat, userID)
+	if err != nil {
+		return category, failure.BadRequest(err)
+	}
+
+	err = s.checkParent(category)
+	if err != nil {
+		return
+	}
+
+	err = s.CategoryRepository.UpdateCategory(category)
+	if err != nil {
+		return
+	}
+
+	if category.Name != name {
+		for _, listener := range s.renamed {
+			listener(category)
+		}
+	}
+
+	return
+}
+
+// OnRenamed registers a listener called with each category renamed, letting
+// domains that depend on categories keep their copies of the name in sync.
+func (s *CategoryServiceImpl) OnRenamed(listener func(category Category)) {
+	s.renamed = append(s.renamed, listener)
+}
+
+func (s *CategoryServiceImpl) ResolveCategories() (categories Categories, err error) {
+	return s.CategoryRepository.ResolveCategories()
+}
+
+func (s *CategoryServiceImpl) ResolveByID(id uuid.UUID) (category Category, err error) {
+	return s.CategoryRepository.ResolveCategoryByID(id)
+}
+
+func (s *CategoryServiceImpl) checkParent(category Category) (err error) {
+	if !category.ParentID.Valid {
+		return
+	}
+
+	categories, err := s.CategoryRepository.ResolveCategories()
+	if err != nil {
+		return
+	}
+
+	err = categories.CheckParent(category)
+	if err != nil {
+		return failure.BadRequest(err)
+	}
+
+	return
+}
@@ -0,0 +1,83 @@
+package category_test
+
+import (
+	"testing"
+
+	"github.com/evermos/boilerplate-go/internal/domain/category"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// categoryRepository is an in-memory CategoryRepository.
+type categoryRepository struct {
+	categories map[uuid.UUID]category.Category
+}
+
+func (r *categoryRepository) CreateCategory(category category.Category) (err error) {
+	r.categories[category.ID] = category
+	return
+}
+
+func (r *categoryRepository) ResolveCategories() (categories category.Categories, err error) {
+	for _, category := range r.categories {
+		categories = append(categories, category)
+	}
+	return
+}
+
+func (r *categoryRepository) ResolveCategoryByID(id uuid.UUID) (category category.Category, err error) {
+	category, ok := r.categories[id]
+	if !ok {
+		return category, failure.NotFound("category")
+	}
+	return
+}
+
+func (r *categoryRepository) UpdateCategory(category category.Category) (err error) {
+	r.categories[category.ID] = category
+	return
+}
+
+func TestCategoryServiceRename(t *testing.T) {
+	userID := uuid.Must(uuid.NewV4())
+	service := category.ProvideCategoryServiceImpl(&categoryRepository{categories: make(map[uuid.UUID]category.Category)})
+
+	var renamed []category.Category
+	service.OnRenamed(func(category category.Category) {
+		renamed = append(renamed, category)
+	})
+
+	created, err := service.CreateCategory(category.CategoryRequestFormat{Name: "Shoes"}, userID)
+	require.NoError(t, err)
+
+	t.Run("Unchanged Name", func(t *testing.T) {
+		renamed = nil
+
+		_, err := service.UpdateCategory(created.ID, category.CategoryRequestFormat{Name: "Shoes", Position: 2}, userID)
+
+		require.NoError(t, err)
+		assert.Empty(t, renamed)
+	})
+
+	t.Run("Renamed", func(t *testing.T) {
+		renamed = nil
+
+		updated, err := service.UpdateCategory(created.ID, category.CategoryRequestFormat{Name: "Sneakers"}, userID)
+
+		require.NoError(t, err)
+		require.Len(t, renamed, 1)
+		assert.Equal(t, updated, renamed[0])
+		assert.Equal(t, "Sneakers", renamed[0].Name)
+	})
+
+	t.Run("Invalid Update", func(t *testing.T) {
+		renamed = nil
+
+		_, err := service.UpdateCategory(created.ID, category.CategoryRequestFormat{Name: "Boots", Slug: "Not A Slug"}, userID)
+
+		assert.Error(t, err)
+		assert.Empty(t, renamed)
+	})
+}
@@ -133,6 +133,7 @@ var (
 // FooRepository is the repository for Foo data.
 type FooRepository interface {
 	Create(foo Foo) (err error)
+	CreateTx(tx *sqlx.Tx, foo Foo) (err error)
 	ExistsByID(id uuid.UUID) (exists bool, err error)
 	ResolveByID(id uuid.UUID) (foo Foo, err error)
 	ResolveItemsByFooIDs(ids []uuid.UUID) (fooItems []FooItem, err error)
@@ -180,6 +181,15 @@ func (r *FooRepositoryMySQL) Create(foo Foo) (err error) {
 	})
 }
 
+// CreateTx creates a new Foo in the given transaction.
+func (r *FooRepositoryMySQL) CreateTx(tx *sqlx.Tx, foo Foo) (err error) {
+	if err = r.txCreate(tx, foo); err != nil {
+		return
+	}
+
+	return r.txCreateItems(tx, foo.Items)
+}
+
 // ExistsByID checks the existence of a Foo by its ID.
 func (r *FooRepositoryMySQL) ExistsByID(id uuid.UUID) (exists bool, err error) {
 	err = r.DB.Read.Get(
@@ -8,11 +8,13 @@ import (
 	"github.com/evermos/boilerplate-go/event/producer"
 	"github.com/evermos/boilerplate-go/shared/failure"
 	"github.com/gofrs/uuid"
+	"github.com/jmoiron/sqlx"
 )
 
 // FooService is the service interface for Foo entities.
 type FooService interface {
 	Create(requestFormat FooRequestFormat, userID uuid.UUID) (foo Foo, err error)
+	CreateTx(tx *sqlx.Tx, requestFormat FooRequestFormat, userID uuid.UUID) (foo Foo, err error)
 	ResolveByID(id uuid.UUID, withItems bool) (foo Foo, err error)
 	SoftDelete(id uuid.UUID, userID uuid.UUID) (foo Foo, err error)
 	Update(id uuid.UUID, requestFormat FooRequestFormat, userID uuid.UUID) (foo Foo, err error)
@@ -52,6 +54,31 @@ func (s *FooServiceImpl) Create(requestFormat FooRequestFormat, userID uuid.UUID
 		return
 	}
 
+	s.publishCreated(requestFormat)
+
+	return
+}
+
+// CreateTx creates a new Foo in the given transaction. The creation is
+// published before the transaction commits, so a failing commit may publish
+// a Foo that was not created.
+func (s *FooServiceImpl) CreateTx(tx *sqlx.Tx, requestFormat FooRequestFormat, userID uuid.UUID) (foo Foo, err error) {
+	foo, err = foo.NewFromRequestFormat(requestFormat, userID)
+	if err != nil {
+		return foo, failure.BadRequest(err)
+	}
+
+	err = s.FooRepository.CreateTx(tx, foo)
+	if err != nil {
+		return
+	}
+
+	s.publishCreated(requestFormat)
+
+	return
+}
+
+func (s *FooServiceImpl) publishCreated(requestFormat FooRequestFormat) {
 	if s.Config.Event.Producer.SNS.Topics.FooCreated.Enabled {
 		e := model.NewEvent(FooBarBazEventType, requestFormat)
 		s.Producer.Publish(model.PublishRequest{
@@ -59,8 +86,6 @@ func (s *FooServiceImpl) Create(requestFormat FooRequestFormat, userID uuid.UUID
 			Topic: s.Config.Event.Producer.SNS.Topics.FooCreated.ARN,
 		})
 	}
-
-	return
 }
 
 // ResolveByID resolves a Foo by its ID.
@@ -0,0 +1,163 @@
+package oauthclient
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/evermos/boilerplate-go/shared"
+	"github.com/evermos/boilerplate-go/shared/nuuid"
+	"github.com/gofrs/uuid"
+	"github.com/guregu/null"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// secretSize is the number of random bytes of a client secret.
+const secretSize = 32
+
+// clientIDPattern matches the client IDs that can be created.
+var clientIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Client is an OAuth2 client. Only the bcrypt hash of its secret is stored;
+// the plain secret is only known right after it is generated.
+type Client struct {
+	ClientID     string      `db:"client_id" validate:"required"`
+	ClientSecret string      `db:"client_secret" validate:"required"`
+	RedirectURI  null.String `db:"redirect_uri"`
+	GrantTypes   string      `db:"grant_types" validate:"required"`
+	Scope        null.String `db:"scope"`
+	CreatedAt    time.Time   `db:"created_at" validate:"required"`
+	CreatedBy    nuuid.NUUID `db:"created_by"`
+	UpdatedAt    null.Time   `db:"updated_at"`
+	UpdatedBy    nuuid.NUUID `db:"updated_by"`
+	DisabledAt   null.Time   `db:"disabled_at"`
+	DisabledBy   nuuid.NUUID `db:"disabled_by"`
+
+	plainSecret string
+}
+
+func (c *Client) IsDisabled() (disabled bool) {
+	return c.DisabledAt.Valid
+}
+
+func (c Client) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.ToResponseFormat())
+}
+
+// NewFromRequestFormat creates a new client with a generated secret.
+func (c Client) NewFromRequestFormat(req ClientRequestFormat, userID uuid.UUID) (newClient Client, err error) {
+	if !clientIDPattern.MatchString(req.ClientID) {
+		return newClient, errors.New("clientId may only contain letters, digits, underscores and dashes")
+	}
+
+	for _, grantType := range req.GrantTypes {
+		if grantType == "authorization_code" && req.RedirectURI == "" {
+			return newClient, errors.New("redirectUri is required for the authorization_code grant")
+		}
+	}
+
+	newClient = Client{
+		ClientID:   req.ClientID,
+		GrantTypes: strings.Join(req.GrantTypes, " "),
+		CreatedAt:  time.Now(),
+		CreatedBy:  nuuid.From(userID),
+	}
+
+	if req.RedirectURI != "" {
+		newClient.RedirectURI = null.StringFrom(req.RedirectURI)
+	}
+
+	if scope := strings.Join(strings.Fields(req.Scope), " "); scope != "" {
+		newClient.Scope = null.StringFrom(scope)
+	}
+
+	err = newClient.generateSecret()
+	if err != nil {
+		return
+	}
+
+	err = newClient.Validate()
+
+	return
+}
+
+// RotateSecret replaces the secret of the client with a new generated one.
+func (c *Client) RotateSecret(userID uuid.UUID) (err error) {
+	err = c.generateSecret()
+	if err != nil {
+		return
+	}
+
+	c.UpdatedAt = null.TimeFrom(time.Now())
+	c.UpdatedBy = nuuid.From(userID)
+
+	return c.Validate()
+}
+
+// Disable disables the client, so that it can no longer authenticate.
+func (c *Client) Disable(userID uuid.UUID) {
+	c.DisabledAt = null.TimeFrom(time.Now())
+	c.DisabledBy = nuuid.From(userID)
+}
+
+func (c *Client) generateSecret() (err error) {
+	b := make([]byte, secretSize)
+	_, err = rand.Read(b)
+	if err != nil {
+		return
+	}
+
+	secret := base64.RawURLEncoding.EncodeToString(b)
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+	if err != nil {
+		return
+	}
+
+	c.plainSecret = secret
+	c.ClientSecret = string(hash)
+
+	return
+}
+
+func (c *Client) Validate() (err error) {
+	validator := shared.GetValidator()
+	return validator.Struct(c)
+}
+
+// ToResponseFormat shows the plain secret only right after it was generated.
+func (c Client) ToResponseFormat() ClientResponseFormat {
+	return ClientResponseFormat{
+		ClientID:     c.ClientID,
+		ClientSecret: c.plainSecret,
+		RedirectURI:  c.RedirectURI,
+		GrantTypes:   strings.Fields(c.GrantTypes),
+		Scope:        c.Scope.String,
+		CreatedAt:    c.CreatedAt,
+		UpdatedAt:    c.UpdatedAt,
+		DisabledAt:   c.DisabledAt,
+	}
+}
+
+// ClientRequestFormat is the payload to create a client.
+type ClientRequestFormat struct {
+	ClientID    string   `json:"clientId" validate:"required,min=3,max=32"`
+	RedirectURI string   `json:"redirectUri" validate:"omitempty,url,max=1000"`
+	GrantTypes  []string `json:"grantTypes" validate:"required,min=1,dive,oneof=client_credentials password refresh_token authorization_code"`
+	Scope       string   `json:"scope" validate:"max=2000"`
+}
+
+type ClientResponseFormat struct {
+	ClientID     string      `json:"clientId"`
+	ClientSecret string      `json:"clientSecret,omitempty"`
+	RedirectURI  null.String `json:"redirectUri"`
+	GrantTypes   []string    `json:"grantTypes"`
+	Scope        string      `json:"scope"`
+	CreatedAt    time.Time   `json:"createdAt"`
+	UpdatedAt    null.Time   `json:"updatedAt"`
+	DisabledAt   null.Time   `json:"disabledAt"`
+}
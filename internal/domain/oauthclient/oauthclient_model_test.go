@@ -0,0 +1,90 @@
+package oauthclient_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/evermos/boilerplate-go/internal/domain/oauthclient"
+	"github.com/evermos/boilerplate-go/shared/oauth"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient(t *testing.T) {
+	adminID := uuid.Must(uuid.NewV4())
+	requestFormat := oauthclient.ClientRequestFormat{
+		ClientID:   "client_app",
+		GrantTypes: []string{"client_credentials"},
+		Scope:      "foo:read  foo:write",
+	}
+
+	secretOf := func(client oauthclient.Client) string {
+		body, err := json.Marshal(client)
+		require.NoError(t, err)
+
+		var responseFormat oauthclient.ClientResponseFormat
+		require.NoError(t, json.Unmarshal(body, &responseFormat))
+		return responseFormat.ClientSecret
+	}
+
+	verifies := func(client oauthclient.Client, secret string) bool {
+		stored := oauth.OauthClient{ClientID: client.ClientID, ClientSecret: client.ClientSecret, DisabledAt: client.DisabledAt}
+		return stored.VerifyClient(oauth.Credential{ClientID: client.ClientID, ClientSecret: secret})
+	}
+
+	t.Run("Secret Is Hashed And Shown Once", func(t *testing.T) {
+		client, err := oauthclient.Client{}.NewFromRequestFormat(requestFormat, adminID)
+		require.NoError(t, err)
+
+		secret := secretOf(client)
+		assert.NotEmpty(t, secret)
+		assert.NotEqual(t, secret, client.ClientSecret)
+		assert.Equal(t, "foo:read foo:write", client.Scope.String)
+		assert.True(t, verifies(client, secret))
+		assert.False(t, verifies(client, client.ClientSecret))
+
+		var stored oauthclient.Client
+		stored.ClientID = client.ClientID
+		stored.ClientSecret = client.ClientSecret
+		assert.Empty(t, secretOf(stored))
+	})
+
+	t.Run("Rotate Secret", func(t *testing.T) {
+		client, err := oauthclient.Client{}.NewFromRequestFormat(requestFormat, adminID)
+		require.NoError(t, err)
+		oldSecret := secretOf(client)
+
+		require.NoError(t, client.RotateSecret(adminID))
+
+		assert.False(t, verifies(client, oldSecret))
+		assert.True(t, verifies(client, secretOf(client)))
+	})
+
+	t.Run("Disabled Client Never Verifies", func(t *testing.T) {
+		client, err := oauthclient.Client{}.NewFromRequestFormat(requestFormat, adminID)
+		require.NoError(t, err)
+
+		client.Disable(adminID)
+
+		assert.False(t, verifies(client, secretOf(client)))
+	})
+
+	t.Run("Authorization Code Requires Redirect URI", func(t *testing.T) {
+		_, err := oauthclient.Client{}.NewFromRequestFormat(oauthclient.ClientRequestFormat{
+			ClientID:   "client_partner",
+			GrantTypes: []string{"authorization_code"},
+		}, adminID)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("Invalid Client ID", func(t *testing.T) {
+		_, err := oauthclient.Client{}.NewFromRequestFormat(oauthclient.ClientRequestFormat{
+			ClientID:   "client app",
+			GrantTypes: []string{"client_credentials"},
+		}, adminID)
+
+		assert.Error(t, err)
+	})
+}
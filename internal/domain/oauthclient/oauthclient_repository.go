@@ -0,0 +1,227 @@
+package oauthclient
+
+import (
+	"database/sql"
+
+	"github.com/evermos/boilerplate-go/infras"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/logger"
+	"github.com/evermos/boilerplate-go/shared/oauth"
+	"github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+)
+
+// mysqlErrDuplicateEntry is the MySQL error number of a unique key violation.
+const mysqlErrDuplicateEntry = 1062
+
+var (
+	clientQueries = struct {
+		selectClient             string
+		insertClient             string
+		updateSecret             string
+		disableClient            string
+		revokeRefreshTokens      string
+		deleteAuthorizationCodes string
+	}{
+		selectClient: `
+			SELECT
+				client_id,
+				client_secret,
+				redirect_uri,
+				grant_types,
+				scope,
+				created_at,
+				created_by,
+				updated_at,
+				updated_by,
+				disabled_at,
+				disabled_by
+			FROM oauth_clients
+		`,
+
+		insertClient: `
+			INSERT INTO oauth_clients (
+				client_id,
+				client_secret,
+				redirect_uri,
+				grant_types,
+				scope,
+				created_at,
+				created_by,
+				updated_at,
+				updated_by,
+				disabled_at,
+				disabled_by
+			) VALUES (
+				:client_id,
+				:client_secret,
+				:redirect_uri,
+				:grant_types,
+				:scope,
+				:created_at,
+				:created_by,
+				:updated_at,
+				:updated_by,
+				:disabled_at,
+				:disabled_by
+			)
+		`,
+
+		updateSecret: `
+			UPDATE oauth_clients
+			SET
+				client_secret = :client_secret,
+				updated_at = :updated_at,
+				updated_by = :updated_by
+			WHERE client_id = :client_id
+		`,
+
+		disableClient: `
+			UPDATE oauth_clients
+			SET
+				disabled_at = :disabled_at,
+				disabled_by = :disabled_by
+			WHERE client_id = :client_id
+		`,
+
+		revokeRefreshTokens: `UPDATE oauth_refresh_tokens SET revoked_at = NOW() WHERE client_id = ? AND revoked_at IS NULL`,
+
+		deleteAuthorizationCodes: `DELETE FROM oauth_authorization_codes WHERE client_id = ?`,
+	}
+)
+
+type ClientRepository interface {
+	CreateClient(client Client) (err error)
+	ResolveClientByID(clientID string) (client Client, err error)
+	UpdateSecret(client Client) (err error)
+	DisableClient(client Client) (err error)
+}
+
+type ClientRepositoryMySQL struct {
+	DB     *infras.MySQLConn
+	Tokens oauth.TokenStore
+}
+
+func ProvideClientRepositoryMySQL(db *infras.MySQLConn, tokens oauth.TokenStore) *ClientRepositoryMySQL {
+	s := new(ClientRepositoryMySQL)
+	s.DB = db
+	s.Tokens = tokens
+
+	return s
+}
+
+// CreateClient stores a client, failing with a conflict when its ID is taken.
+func (r *ClientRepositoryMySQL) CreateClient(client Client) (err error) {
+	err = r.DB.WithTransaction(func(tx *sqlx.Tx, e chan error) {
+		if err := r.txExec(tx, clientQueries.insertClient, client); err != nil {
+			e <- err
+			return
+		}
+
+		e <- nil
+	})
+
+	if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == mysqlErrDuplicateEntry {
+		err = failure.Conflict("create", "client", "clientId already exists")
+	}
+
+	return
+}
+
+func (r *ClientRepositoryMySQL) ResolveClientByID(clientID string) (client Client, err error) {
+	err = r.DB.Read.Get(&client, clientQueries.selectClient+" WHERE client_id = ?", clientID)
+	if err != nil && err == sql.ErrNoRows {
+		err = failure.NotFound("client")
+		logger.ErrorWithStack(err)
+		return
+	}
+
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+
+	return
+}
+
+func (r *ClientRepositoryMySQL) UpdateSecret(client Client) (err error) {
+	return r.DB.WithTransaction(func(tx *sqlx.Tx, e chan error) {
+		if err := r.txExec(tx, clientQueries.updateSecret, client); err != nil {
+			e <- err
+			return
+		}
+
+		e <- nil
+	})
+}
+
+// DisableClient disables a client and revokes every token and authorization
+// code issued to it.
+func (r *ClientRepositoryMySQL) DisableClient(client Client) (err error) {
+	err = r.DB.WithTransaction(func(tx *sqlx.Tx, e chan error) {
+		if err := r.txExec(tx, clientQueries.disableClient, client); err != nil {
+			e <- err
+			return
+		}
+
+		if err := r.txRevokeTokens(tx, client.ClientID); err != nil {
+			e <- err
+			return
+		}
+
+		e <- nil
+	})
+	if err != nil {
+		return
+	}
+
+	// Token stores in MySQL revoked the tokens in the transaction.
+	if _, ok := r.Tokens.(oauth.TxTokenStore); ok {
+		return
+	}
+
+	err = r.Tokens.RevokeClient(client.ClientID)
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+
+	return
+}
+
+// Transactions
+func (r *ClientRepositoryMySQL) txExec(tx *sqlx.Tx, query string, client Client) (err error) {
+	stmt, err := tx.PrepareNamed(query)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(client)
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+
+	return
+}
+
+func (r *ClientRepositoryMySQL) txRevokeTokens(tx *sqlx.Tx, clientID string) (err error) {
+	for _, query := range []string{
+		clientQueries.revokeRefreshTokens,
+		clientQueries.deleteAuthorizationCodes,
+	} {
+		_, err = tx.Exec(query, clientID)
+		if err != nil {
+			logger.ErrorWithStack(err)
+			return
+		}
+	}
+
+	if tokens, ok := r.Tokens.(oauth.TxTokenStore); ok {
+		err = tokens.RevokeClientTx(tx, clientID)
+		if err != nil {
+			logger.ErrorWithStack(err)
+		}
+	}
+
+	return
+}
@@ -0,0 +1,93 @@
+package oauthclient
+
+import (
+	"github.com/evermos/boilerplate-go/configs"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/gofrs/uuid"
+)
+
+type ClientService interface {
+	CreateClient(requestFormat ClientRequestFormat, userID uuid.UUID) (client Client, err error)
+	RotateSecret(clientID string, userID uuid.UUID) (client Client, err error)
+	DisableClient(clientID string, userID uuid.UUID) (client Client, err error)
+}
+
+type ClientServiceImpl struct {
+	ClientRepository ClientRepository
+	Config           *configs.Config
+}
+
+func ProvideClientServiceImpl(clientRepository ClientRepository, config *configs.Config) *ClientServiceImpl {
+	s := new(ClientServiceImpl)
+	s.ClientRepository = clientRepository
+	s.Config = config
+
+	return s
+}
+
+// CreateClient creates a client. The returned client carries the plain
+// secret, which is never shown again.
+func (s *ClientServiceImpl) CreateClient(requestFormat ClientRequestFormat, userID uuid.UUID) (client Client, err error) {
+	client, err = client.NewFromRequestFormat(requestFormat, userID)
+	if err != nil {
+		return client, failure.BadRequest(err)
+	}
+
+	err = s.ClientRepository.CreateClient(client)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// RotateSecret replaces the secret of an enabled client. The returned client
+// carries the new plain secret, which is never shown again.
+func (s *ClientServiceImpl) RotateSecret(clientID string, userID uuid.UUID) (client Client, err error) {
+	client, err = s.resolveEnabledClient(clientID)
+	if err != nil {
+		return
+	}
+
+	err = client.RotateSecret(userID)
+	if err != nil {
+		return client, failure.InternalError(err)
+	}
+
+	err = s.ClientRepository.UpdateSecret(client)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// DisableClient disables an enabled client and revokes its tokens.
+func (s *ClientServiceImpl) DisableClient(clientID string, userID uuid.UUID) (client Client, err error) {
+	client, err = s.resolveEnabledClient(clientID)
+	if err != nil {
+		return
+	}
+
+	client.Disable(userID)
+
+	err = s.ClientRepository.DisableClient(client)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (s *ClientServiceImpl) resolveEnabledClient(clientID string) (client Client, err error) {
+	client, err = s.ClientRepository.ResolveClientByID(clientID)
+	if err != nil {
+		return
+	}
+
+	if client.IsDisabled() {
+		return client, failure.NotFound("client")
+	}
+
+	return
+}
@@ -2,9 +2,13 @@ package order
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/evermos/boilerplate-go/internal/domain/cart"
+	"github.com/evermos/boilerplate-go/internal/domain/product"
+	"github.com/evermos/boilerplate-go/internal/domain/warehouse"
 	"github.com/evermos/boilerplate-go/shared"
 	"github.com/evermos/boilerplate-go/shared/failure"
 	"github.com/evermos/boilerplate-go/shared/nuuid"
@@ -22,18 +26,23 @@ const (
 	OrderStatusCanceled   OrderStatus = "canceled"
 )
 
+// Order is a checked out cart, shipped to its shipping address. Its items
+// record the warehouse they ship from.
 type Order struct {
-	ID        uuid.UUID   `db:"order_id" validate:"required"`
-	UserID    uuid.UUID   `db:"user_id" validate:"required"`
-	TotalCost float64     `db:"total_cost" validate:"required"`
-	Status    OrderStatus `db:"status" validate:"required,oneof=pending processing shipped delivered canceled"`
-	CreatedAt time.Time   `db:"created_at" validate:"required"`
-	CreatedBy uuid.UUID   `db:"created_by" validate:"required"`
-	UpdatedAt null.Time   `db:"updated_at"`
-	UpdatedBy nuuid.NUUID `db:"updated_by"`
-	DeletedAt null.Time   `db:"deleted_at"`
-	DeletedBy nuuid.NUUID `db:"deleted_by"`
-	Items     []OrderItem `db:"-" validate:"required,dive,required"`
+	ID                uuid.UUID   `db:"id" validate:"required"`
+	UserID            uuid.UUID   `db:"user_id" validate:"required"`
+	TotalCost         float64     `db:"total_cost" validate:"required"`
+	Status            OrderStatus `db:"status" validate:"required,oneof=pending processing shipped delivered canceled"`
+	ShippingAddress   null.String `db:"shipping_address"`
+	ShippingLatitude  null.Float  `db:"shipping_latitude"`
+	ShippingLongitude null.Float  `db:"shipping_longitude"`
+	CreatedAt         time.Time   `db:"created_at" validate:"required"`
+	CreatedBy         uuid.UUID   `db:"created_by" validate:"required"`
+	UpdatedAt         null.Time   `db:"updated_at"`
+	UpdatedBy         nuuid.NUUID `db:"updated_by"`
+	DeletedAt         null.Time   `db:"deleted_at"`
+	DeletedBy         nuuid.NUUID `db:"deleted_by"`
+	Items             []OrderItem `db:"-" validate:"required,dive,required"`
 }
 
 func (o *Order) AttachItems(items []OrderItem) Order {
@@ -50,34 +59,75 @@ func (o *Order) IsDeleted() (deleted bool) {
 func (o Order) MarshalJSON() ([]byte, error) {
 	return json.Marshal(o.ToResponseFormat())
 }
-func (o Order) NewFromRequestFormat(req OrderRequestFormat, userID uuid.UUID) (newOrder Order, err error) {
+
+// NewFromRequestFormat creates a new order of the requested cart items. Each
+// item keeps the product's current name and the variant's SKU and price, so
+// that later changes to the product do not alter the order. Items ship from
+// the warehouses allocated to their cart item, by cart item ID; an item
+// shipping from several warehouses is split into an item per warehouse.
+func (o Order) NewFromRequestFormat(req OrderRequestFormat, userID uuid.UUID, cartItems []cart.CartItem, allocations map[uuid.UUID][]warehouse.Allocation) (newOrder Order, err error) {
 	orderID, err := uuid.NewV4()
 	if err != nil {
 		return
 	}
 
 	newOrder = Order{
-		ID:        orderID,
-		UserID:    userID,
-		Status:    OrderStatusPending,
-		CreatedAt: time.Now(),
-		CreatedBy: userID,
+		ID:                orderID,
+		UserID:            userID,
+		Status:            OrderStatusPending,
+		ShippingAddress:   null.NewString(req.ShippingAddress.Address, req.ShippingAddress.Address != ""),
+		ShippingLatitude:  req.ShippingAddress.Latitude,
+		ShippingLongitude: req.ShippingAddress.Longitude,
+		CreatedAt:         time.Now(),
+		CreatedBy:         userID,
+	}
+
+	_, err = req.ShippingAddress.Location()
+	if err != nil {
+		return
 	}
 
-	fmt.Println(req.Items)
 	items := make([]OrderItem, 0)
 	for _, requestItem := range req.Items {
 		item := OrderItem{}
-		item, err = item.NewFromRequestFormat(requestItem, orderID)
+		item, err = item.NewFromRequestFormat(requestItem, orderID, userID, cartItems)
 		if err != nil {
 			return
 		}
-		items = append(items, item)
+
+		if len(allocations[item.CartItemID]) == 0 {
+			return newOrder, errors.New("requested item has no warehouse to ship from")
+		}
+
+		for _, allocation := range allocations[item.CartItemID] {
+			allocated := item
+			allocated.WarehouseID = allocation.WarehouseID
+			allocated.Quantity = allocation.Quantity
+			allocated.Recalculate()
+			items = append(items, allocated)
+		}
 	}
 	newOrder.Items = items
 
 	newOrder.Recalculate()
 
+	err = newOrder.Validate()
+
+	return
+}
+
+// StockMovements takes the stock of the ordered variants from the warehouses
+// they ship from, referencing the order.
+func (o Order) StockMovements() (movements []product.StockMovement, err error) {
+	for _, item := range o.Items {
+		var movement product.StockMovement
+		movement, err = movement.NewStockMovement(item.ProductID, item.VariantID, item.WarehouseID, -item.Quantity, product.StockReasonCheckout, o.ID.String(), o.UserID)
+		if err != nil {
+			return
+		}
+		movements = append(movements, movement)
+	}
+
 	return
 }
 func (o *Order) Recalculate() {
@@ -93,17 +143,20 @@ func (o *Order) Recalculate() {
 }
 func (o Order) ToResponseFormat() OrderResponseFormat {
 	resp := OrderResponseFormat{
-		ID:        o.ID,
-		UserID:    o.UserID,
-		TotalCost: o.TotalCost,
-		Status:    o.Status,
-		CreatedAt: o.CreatedAt,
-		CreatedBy: o.CreatedBy,
-		UpdatedAt: o.UpdatedAt,
-		UpdatedBy: o.UpdatedBy.Ptr(),
-		DeletedAt: o.DeletedAt,
-		DeletedBy: o.DeletedBy.Ptr(),
-		Items:     make([]OrderItemResponseFormat, 0),
+		ID:                o.ID,
+		UserID:            o.UserID,
+		TotalCost:         o.TotalCost,
+		Status:            o.Status,
+		ShippingAddress:   o.ShippingAddress,
+		ShippingLatitude:  o.ShippingLatitude,
+		ShippingLongitude: o.ShippingLongitude,
+		CreatedAt:         o.CreatedAt,
+		CreatedBy:         o.CreatedBy,
+		UpdatedAt:         o.UpdatedAt,
+		UpdatedBy:         o.UpdatedBy.Ptr(),
+		DeletedAt:         o.DeletedAt,
+		DeletedBy:         o.DeletedBy.Ptr(),
+		Items:             make([]OrderItemResponseFormat, 0),
 	}
 
 	for _, item := range o.Items {
@@ -145,65 +198,121 @@ func (o *Order) Validate() (err error) {
 }
 
 type OrderRequestFormat struct {
-	Items []OrderItemRequestFormat `json:"items" validate:"required,dive,required"`
+	Items           []OrderItemRequestFormat     `json:"items" validate:"required,dive,required"`
+	ShippingAddress ShippingAddressRequestFormat `json:"shippingAddress"`
 }
 type OrderResponseFormat struct {
-	ID        uuid.UUID                 `json:"ID"`
-	UserID    uuid.UUID                 `json:"userID"`
-	TotalCost float64                   `json:"totalCost"`
-	Status    OrderStatus               `json:"status"`
-	CreatedAt time.Time                 `json:"createdAt"`
-	CreatedBy uuid.UUID                 `json:"createdBy"`
-	UpdatedAt null.Time                 `json:"updatedAt"`
-	UpdatedBy *uuid.UUID                `json:"updatedBy"`
-	DeletedAt null.Time                 `json:"deletedAt,omitempty"`
-	DeletedBy *uuid.UUID                `json:"deletedBy,omitempty"`
-	Items     []OrderItemResponseFormat `json:"items"`
+	ID                uuid.UUID                 `json:"ID"`
+	UserID            uuid.UUID                 `json:"userID"`
+	TotalCost         float64                   `json:"totalCost"`
+	Status            OrderStatus               `json:"status"`
+	ShippingAddress   null.String               `json:"shippingAddress" swaggertype:"string"`
+	ShippingLatitude  null.Float                `json:"shippingLatitude" swaggertype:"number"`
+	ShippingLongitude null.Float                `json:"shippingLongitude" swaggertype:"number"`
+	CreatedAt         time.Time                 `json:"createdAt"`
+	CreatedBy         uuid.UUID                 `json:"createdBy"`
+	UpdatedAt         null.Time                 `json:"updatedAt"`
+	UpdatedBy         *uuid.UUID                `json:"updatedBy"`
+	DeletedAt         null.Time                 `json:"deletedAt,omitempty"`
+	DeletedBy         *uuid.UUID                `json:"deletedBy,omitempty"`
+	Items             []OrderItemResponseFormat `json:"items"`
+}
+
+// ShippingAddressRequestFormat is the address an order ships to. Its
+// coordinates, when given, let items ship from the nearest warehouses.
+type ShippingAddressRequestFormat struct {
+	Address   string     `json:"address" validate:"max=500"`
+	Latitude  null.Float `json:"latitude" swaggertype:"number"`
+	Longitude null.Float `json:"longitude" swaggertype:"number"`
+}
+
+// Location returns the coordinates of the address, or nil without them.
+func (a ShippingAddressRequestFormat) Location() (location *warehouse.Location, err error) {
+	if a.Latitude.Valid != a.Longitude.Valid {
+		return nil, errors.New("latitude and longitude must be set together")
+	}
+
+	if !a.Latitude.Valid {
+		return nil, nil
+	}
+
+	location = &warehouse.Location{Latitude: a.Latitude.Float64, Longitude: a.Longitude.Float64}
+	err = location.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return
 }
 
 // Order Item
 type OrderItem struct {
-	CartItemID uuid.UUID   `db:"-" validate:"required"`
-	OrderID    uuid.UUID   `db:"order_id" validate:"required"`
-	ProductID  uuid.UUID   `db:"product_id" validate:"required"`
-	Quantity   int         `db:"quantity" validate:"required,min=1"`
-	UnitPrice  float64     `db:"unit_price" validate:"required"`
-	Cost       float64     `db:"cost" validate:"required,min=0"`
-	CreatedAt  time.Time   `db:"created_at"`
-	CreatedBy  uuid.UUID   `db:"created_by"`
-	UpdatedAt  null.Time   `db:"deleted_at"`
-	UpdatedBy  nuuid.NUUID `db:"updated_by"`
-	DeletedAt  null.Time   `db:"deleted_at"`
-	DeletedBy  nuuid.NUUID `db:"deleted_by"`
+	CartItemID  uuid.UUID   `db:"-" validate:"required"`
+	OrderID     uuid.UUID   `db:"order_id" validate:"required"`
+	ProductID   uuid.UUID   `db:"product_id" validate:"required"`
+	VariantID   uuid.UUID   `db:"variant_id" validate:"required"`
+	WarehouseID uuid.UUID   `db:"warehouse_id" validate:"required"`
+	ProductName string      `db:"product_name" validate:"required"`
+	SKU         string      `db:"sku" validate:"required"`
+	Quantity    int         `db:"quantity" validate:"required,min=1"`
+	UnitPrice   float64     `db:"unit_price" validate:"required"`
+	Cost        float64     `db:"cost" validate:"required,min=0"`
+	CreatedAt   time.Time   `db:"created_at"`
+	CreatedBy   uuid.UUID   `db:"created_by"`
+	UpdatedAt   null.Time   `db:"updated_at"`
+	UpdatedBy   nuuid.NUUID `db:"updated_by"`
+	DeletedAt   null.Time   `db:"deleted_at"`
+	DeletedBy   nuuid.NUUID `db:"deleted_by"`
 }
 
 func (oi OrderItem) MarshalJSON() ([]byte, error) {
 	return json.Marshal(oi.ToResponseFormat())
 }
-func (oi OrderItem) NewFromRequestFormat(format OrderItemRequestFormat, orderID uuid.UUID) (newOrderItem OrderItem, err error) {
-	newOrderItem = OrderItem{
-		CartItemID: format.CartItemID,
-		OrderID:    orderID,
+func (oi OrderItem) NewFromRequestFormat(format OrderItemRequestFormat, orderID uuid.UUID, userID uuid.UUID, cartItems []cart.CartItem) (newOrderItem OrderItem, err error) {
+	for _, cartItem := range cartItems {
+		if cartItem.ID != format.CartItemID {
+			continue
+		}
+
+		newOrderItem = OrderItem{
+			CartItemID:  cartItem.ID,
+			OrderID:     orderID,
+			ProductID:   cartItem.ProductID,
+			VariantID:   cartItem.VariantID,
+			ProductName: cartItem.ProductName,
+			SKU:         cartItem.SKU,
+			Quantity:    cartItem.Quantity,
+			UnitPrice:   cartItem.UnitPrice,
+			CreatedAt:   time.Now(),
+			CreatedBy:   userID,
+		}
+		newOrderItem.Recalculate()
+
+		return
 	}
 
-	return
+	return newOrderItem, errors.New("requested item not found in cart")
 }
 func (oi *OrderItem) Recalculate() {
 	oi.Cost = float64(oi.Quantity) * oi.UnitPrice
 }
 func (oi *OrderItem) ToResponseFormat() OrderItemResponseFormat {
 	return OrderItemResponseFormat{
-		OrderID:   oi.OrderID,
-		ProductID: oi.ProductID,
-		Quantity:  oi.Quantity,
-		UnitPrice: oi.UnitPrice,
-		Cost:      oi.Cost,
-		CreatedAt: oi.CreatedAt,
-		CreatedBy: oi.CreatedBy,
-		UpdatedAt: oi.UpdatedAt,
-		UpdatedBy: oi.UpdatedBy.Ptr(),
-		DeletedAt: oi.DeletedAt,
-		DeletedBy: oi.DeletedBy.Ptr(),
+		OrderID:     oi.OrderID,
+		ProductID:   oi.ProductID,
+		VariantID:   oi.VariantID,
+		WarehouseID: oi.WarehouseID,
+		ProductName: oi.ProductName,
+		SKU:         oi.SKU,
+		Quantity:    oi.Quantity,
+		UnitPrice:   oi.UnitPrice,
+		Cost:        oi.Cost,
+		CreatedAt:   oi.CreatedAt,
+		CreatedBy:   oi.CreatedBy,
+		UpdatedAt:   oi.UpdatedAt,
+		UpdatedBy:   oi.UpdatedBy.Ptr(),
+		DeletedAt:   oi.DeletedAt,
+		DeletedBy:   oi.DeletedBy.Ptr(),
 	}
 }
 
@@ -211,15 +320,19 @@ type OrderItemRequestFormat struct {
 	CartItemID uuid.UUID `json:"cartItemID" validate:"required"`
 }
 type OrderItemResponseFormat struct {
-	OrderID   uuid.UUID  `json:"-"`
-	ProductID uuid.UUID  `json:"productID"`
-	Quantity  int        `json:"quantity"`
-	UnitPrice float64    `json:"unitPrice"`
-	Cost      float64    `json:"cost"`
-	CreatedAt time.Time  `json:"createdAt"`
-	CreatedBy uuid.UUID  `json:"createdBy"`
-	UpdatedAt null.Time  `json:"updatedAt"`
-	UpdatedBy *uuid.UUID `json:"updatedBy"`
-	DeletedAt null.Time  `json:"deletedAt,omitempty"`
-	DeletedBy *uuid.UUID `json:"deletedBy,omitempty"`
+	OrderID     uuid.UUID  `json:"-"`
+	ProductID   uuid.UUID  `json:"productID"`
+	VariantID   uuid.UUID  `json:"variantID"`
+	WarehouseID uuid.UUID  `json:"warehouseID"`
+	ProductName string     `json:"productName"`
+	SKU         string     `json:"sku"`
+	Quantity    int        `json:"quantity"`
+	UnitPrice   float64    `json:"unitPrice"`
+	Cost        float64    `json:"cost"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	CreatedBy   uuid.UUID  `json:"createdBy"`
+	UpdatedAt   null.Time  `json:"updatedAt"`
+	UpdatedBy   *uuid.UUID `json:"updatedBy"`
+	DeletedAt   null.Time  `json:"deletedAt,omitempty"`
+	DeletedBy   *uuid.UUID `json:"deletedBy,omitempty"`
 }
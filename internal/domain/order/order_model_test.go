@@ -0,0 +1,96 @@
+package order_test
+
+import (
+	"testing"
+
+	"github.com/evermos/boilerplate-go/internal/domain/cart"
+	"github.com/evermos/boilerplate-go/internal/domain/order"
+	"github.com/evermos/boilerplate-go/internal/domain/product"
+	"github.com/evermos/boilerplate-go/internal/domain/warehouse"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrder(t *testing.T) {
+	userID := uuid.Must(uuid.NewV4())
+	cartItems := []cart.CartItem{
+		{ID: uuid.Must(uuid.NewV4()), ProductID: uuid.Must(uuid.NewV4()), VariantID: uuid.Must(uuid.NewV4()), ProductName: "iPhone 13", SKU: "IP13-128", UnitPrice: 799, Quantity: 2},
+		{ID: uuid.Must(uuid.NewV4()), ProductID: uuid.Must(uuid.NewV4()), VariantID: uuid.Must(uuid.NewV4()), ProductName: "Galaxy S21", SKU: "GS21-128", UnitPrice: 699, Quantity: 1},
+	}
+	main := uuid.Must(uuid.NewV4())
+	north := uuid.Must(uuid.NewV4())
+	allocations := map[uuid.UUID][]warehouse.Allocation{
+		cartItems[0].ID: {{WarehouseID: main, Quantity: 2}},
+		cartItems[1].ID: {{WarehouseID: north, Quantity: 1}},
+	}
+
+	t.Run("Items Snapshot The Cart", func(t *testing.T) {
+		newOrder, err := order.Order{}.NewFromRequestFormat(order.OrderRequestFormat{
+			Items: []order.OrderItemRequestFormat{{CartItemID: cartItems[0].ID}},
+		}, userID, cartItems, allocations)
+		require.NoError(t, err)
+
+		require.Len(t, newOrder.Items, 1)
+		item := newOrder.Items[0]
+		assert.Equal(t, newOrder.ID, item.OrderID)
+		assert.Equal(t, cartItems[0].ProductID, item.ProductID)
+		assert.Equal(t, cartItems[0].VariantID, item.VariantID)
+		assert.Equal(t, "iPhone 13", item.ProductName)
+		assert.Equal(t, "IP13-128", item.SKU)
+		assert.Equal(t, 2, item.Quantity)
+		assert.Equal(t, float64(799), item.UnitPrice)
+		assert.Equal(t, float64(1598), item.Cost)
+		assert.Equal(t, float64(1598), newOrder.TotalCost)
+		assert.Equal(t, userID, item.CreatedBy)
+		assert.Equal(t, main, item.WarehouseID)
+	})
+
+	t.Run("Splits Items Across Warehouses", func(t *testing.T) {
+		split := map[uuid.UUID][]warehouse.Allocation{
+			cartItems[0].ID: {{WarehouseID: main, Quantity: 1}, {WarehouseID: north, Quantity: 1}},
+		}
+		newOrder, err := order.Order{}.NewFromRequestFormat(order.OrderRequestFormat{
+			Items: []order.OrderItemRequestFormat{{CartItemID: cartItems[0].ID}},
+		}, userID, cartItems, split)
+		require.NoError(t, err)
+
+		require.Len(t, newOrder.Items, 2)
+		assert.Equal(t, north, newOrder.Items[1].WarehouseID)
+		assert.Equal(t, 1, newOrder.Items[1].Quantity)
+		assert.Equal(t, float64(799), newOrder.Items[1].Cost)
+		assert.Equal(t, float64(1598), newOrder.TotalCost)
+
+		_, err = order.Order{}.NewFromRequestFormat(order.OrderRequestFormat{
+			Items: []order.OrderItemRequestFormat{{CartItemID: cartItems[1].ID}},
+		}, userID, cartItems, split)
+		assert.Error(t, err)
+	})
+
+	t.Run("Takes The Stock Of Its Items", func(t *testing.T) {
+		newOrder, err := order.Order{}.NewFromRequestFormat(order.OrderRequestFormat{
+			Items: []order.OrderItemRequestFormat{{CartItemID: cartItems[0].ID}, {CartItemID: cartItems[1].ID}},
+		}, userID, cartItems, allocations)
+		require.NoError(t, err)
+
+		movements, err := newOrder.StockMovements()
+		require.NoError(t, err)
+
+		require.Len(t, movements, 2)
+		assert.Equal(t, cartItems[0].VariantID, movements[0].VariantID)
+		assert.Equal(t, main, movements[0].WarehouseID)
+		assert.Equal(t, -2, movements[0].Quantity)
+		assert.Equal(t, product.StockReasonCheckout, movements[0].Reason)
+		assert.Equal(t, newOrder.ID.String(), movements[0].ReferenceID.String)
+		assert.Equal(t, userID, movements[0].ActorID)
+		assert.Equal(t, -1, movements[1].Quantity)
+	})
+
+	t.Run("Item Not In Cart", func(t *testing.T) {
+		_, err := order.Order{}.NewFromRequestFormat(order.OrderRequestFormat{
+			Items: []order.OrderItemRequestFormat{{CartItemID: uuid.Must(uuid.NewV4())}},
+		}, userID, cartItems, allocations)
+
+		assert.Error(t, err)
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/evermos/boilerplate-go/infras"
+	"github.com/evermos/boilerplate-go/internal/domain/product"
 	"github.com/evermos/boilerplate-go/shared/failure"
 	"github.com/evermos/boilerplate-go/shared/logger"
 	"github.com/gofrs/uuid"
@@ -40,9 +41,14 @@ func (r *OrderRepositoryMySQL) Checkout(order Order, cartID uuid.UUID) (err erro
 		return
 	}
 
-	var productIDs []uuid.UUID
+	var cartItemIDs []uuid.UUID
 	for _, item := range order.Items {
-		productIDs = append(productIDs, item.ProductID)
+		cartItemIDs = append(cartItemIDs, item.CartItemID)
+	}
+
+	movements, err := order.StockMovements()
+	if err != nil {
+		return
 	}
 
 	return r.DB.WithTransaction(func(tx *sqlx.Tx, e chan error) {
@@ -66,8 +72,16 @@ func (r *OrderRepositoryMySQL) Checkout(order Order, cartID uuid.UUID) (err erro
 			return
 		}
 
+		// Take the stock of the ordered items
+		for _, movement := range movements {
+			if err := product.ApplyStockMovementTx(tx, movement); err != nil {
+				txErr(err)
+				return
+			}
+		}
+
 		// Remove checked out cart items
-		if err := r.txRemoveCheckedOutCartItems(tx, cartID, productIDs); err != nil {
+		if err := r.txRemoveCheckedOutCartItems(tx, cartID, cartItemIDs); err != nil {
 			txErr(err)
 			return
 		}
@@ -90,15 +104,12 @@ func (r *OrderRepositoryMySQL) ExistsByID(id uuid.UUID) (exists bool, err error)
 
 // Transactions
 func (r *OrderRepositoryMySQL) composeBulkInsertItemQuery(orderItems []OrderItem) (query string, params []interface{}, err error) {
-	bulkQuery := `INSERT INTO order_item (cart_id, product_id, unit_price, quantity, cost, created_at, created_by, updated_at, updated_by, deleted_at, deleted_by) VALUES `
-	bulkPlaceholderQuery := `(:cart_id, :product_id, :unit_price, :quantity, :cost, :created_at, :created_by, :updated_at, :updated_by, :deleted_at, :deleted_by)`
+	bulkQuery := `INSERT INTO order_item (order_id, product_id, variant_id, warehouse_id, product_name, sku, unit_price, quantity, cost, created_at, created_by, updated_at, updated_by, deleted_at, deleted_by) VALUES `
+	bulkPlaceholderQuery := `(:order_id, :product_id, :variant_id, :warehouse_id, :product_name, :sku, :unit_price, :quantity, :cost, :created_at, :created_by, :updated_at, :updated_by, :deleted_at, :deleted_by)`
 
 	values := []string{}
 	for _, oi := range orderItems {
-		param := map[string]interface{}{
-			"order_id": oi.OrderID,
-		}
-		q, args, err := sqlx.Named(bulkPlaceholderQuery, param)
+		q, args, err := sqlx.Named(bulkPlaceholderQuery, oi)
 		if err != nil {
 			return query, params, err
 		}
@@ -109,7 +120,7 @@ func (r *OrderRepositoryMySQL) composeBulkInsertItemQuery(orderItems []OrderItem
 	return
 }
 func (r *OrderRepositoryMySQL) txCreate(tx *sqlx.Tx, order Order) (err error) {
-	query := `INSERT INTO orders (id, user_id, total_cost, status, created_at, created_by, updated_at, updated_by, deleted_at, deleted_by) VALUES (:id, :user_id, :total_cost, :status, :created_at, :created_by, :updated_at, :updated_by, :deleted_at, :deleted_by)`
+	query := `INSERT INTO orders (id, user_id, total_cost, status, shipping_address, shipping_latitude, shipping_longitude, created_at, created_by, updated_at, updated_by, deleted_at, deleted_by) VALUES (:id, :user_id, :total_cost, :status, :shipping_address, :shipping_latitude, :shipping_longitude, :created_at, :created_by, :updated_at, :updated_by, :deleted_at, :deleted_by)`
 
 	stmt, err := tx.PrepareNamed(query)
 	if err != nil {
@@ -151,13 +162,16 @@ func (r *OrderRepositoryMySQL) txCreateItems(tx *sqlx.Tx, orderItems []OrderItem
 func (r *OrderRepositoryMySQL) txTransferItemsToOrder(tx *sqlx.Tx, orderItems []OrderItem) error {
 	return r.txCreateItems(tx, orderItems)
 }
-func (r *OrderRepositoryMySQL) txRemoveCheckedOutCartItems(tx *sqlx.Tx, cartID uuid.UUID, productIDs []uuid.UUID) error {
-	if len(productIDs) == 0 {
+
+// txRemoveCheckedOutCartItems removes the checked out cart items, leaving other
+// variants of the same products in the cart.
+func (r *OrderRepositoryMySQL) txRemoveCheckedOutCartItems(tx *sqlx.Tx, cartID uuid.UUID, cartItemIDs []uuid.UUID) error {
+	if len(cartItemIDs) == 0 {
 		return nil
 	}
 
-	query := `DELETE FROM cart_item WHERE cart_id = ? AND product_id IN (?)`
-	query, args, err := sqlx.In(query, cartID, productIDs)
+	query := `DELETE FROM cart_item WHERE cart_id = ? AND id IN (?)`
+	query, args, err := sqlx.In(query, cartID, cartItemIDs)
 	if err != nil {
 		logger.ErrorWithStack(err)
 		return err
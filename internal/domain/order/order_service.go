@@ -8,6 +8,7 @@ import (
 	"github.com/evermos/boilerplate-go/configs"
 	"github.com/evermos/boilerplate-go/internal/domain/cart"
 	"github.com/evermos/boilerplate-go/internal/domain/product"
+	"github.com/evermos/boilerplate-go/internal/domain/warehouse"
 	"github.com/evermos/boilerplate-go/shared/failure"
 	"github.com/gofrs/uuid"
 )
@@ -17,21 +18,26 @@ type OrderService interface {
 }
 
 type OrderServiceImpl struct {
-	OrderRepository   OrderRepository
-	CartService       cart.CartService
-	ProductRepository product.ProductRepository
-	Config            *configs.Config
+	OrderRepository  OrderRepository
+	CartService      cart.CartService
+	ProductService   product.ProductService
+	WarehouseService warehouse.WarehouseService
+	Config           *configs.Config
 }
 
-func ProvideOrderServiceImpl(orderRepository OrderRepository, cartService cart.CartService, config *configs.Config) *OrderServiceImpl {
+func ProvideOrderServiceImpl(orderRepository OrderRepository, cartService cart.CartService, productService product.ProductService, warehouseService warehouse.WarehouseService, config *configs.Config) *OrderServiceImpl {
 	s := new(OrderServiceImpl)
 	s.OrderRepository = orderRepository
 	s.CartService = cartService
+	s.ProductService = productService
+	s.WarehouseService = warehouseService
 	s.Config = config
 
 	return s
 }
 
+// Checkout orders the requested cart items, taking their stock from the
+// warehouses the configured allocation picks.
 func (s *OrderServiceImpl) Checkout(requestFormat OrderRequestFormat, userID uuid.UUID) (order Order, err error) {
 	cart, err := s.CartService.ResolveDetailsByUserID(userID)
 	if err != nil {
@@ -43,14 +49,14 @@ func (s *OrderServiceImpl) Checkout(requestFormat OrderRequestFormat, userID uui
 		return order, err
 	}
 
-	var insufficientStockProducts []string
+	var insufficientStockSKUs []string
 	for _, reqItem := range requestFormat.Items {
 		var foundInCart bool
 		for _, cartItem := range cart.Items {
 			if reqItem.CartItemID == cartItem.ID {
 				foundInCart = true
 				if cartItem.Stock < cartItem.Quantity {
-					insufficientStockProducts = append(insufficientStockProducts, cartItem.ProductID.String())
+					insufficientStockSKUs = append(insufficientStockSKUs, cartItem.SKU)
 				}
 				break
 			}
@@ -60,11 +66,21 @@ func (s *OrderServiceImpl) Checkout(requestFormat OrderRequestFormat, userID uui
 		}
 	}
 
-	if len(insufficientStockProducts) > 0 {
-		return order, errors.New(fmt.Sprintf("Insufficient stock for products with IDs: %s", strings.Join(insufficientStockProducts, ", ")))
+	if len(insufficientStockSKUs) > 0 {
+		return order, errors.New(fmt.Sprintf("Insufficient stock for SKUs: %s", strings.Join(insufficientStockSKUs, ", ")))
 	}
 
-	order, err = order.NewFromRequestFormat(requestFormat, userID)
+	destination, err := requestFormat.ShippingAddress.Location()
+	if err != nil {
+		return order, failure.BadRequest(err)
+	}
+
+	allocations, err := s.allocate(requestFormat, cart.Items, destination)
+	if err != nil {
+		return
+	}
+
+	order, err = order.NewFromRequestFormat(requestFormat, userID, cart.Items, allocations)
 	if err != nil {
 		return order, failure.BadRequest(err)
 	}
@@ -74,5 +90,49 @@ func (s *OrderServiceImpl) Checkout(requestFormat OrderRequestFormat, userID uui
 		return
 	}
 
+	productIDs := make([]uuid.UUID, 0, len(order.Items))
+	for _, item := range order.Items {
+		productIDs = append(productIDs, item.ProductID)
+	}
+	s.ProductService.IndexProducts(productIDs)
+
 	return
 }
+
+// allocate picks the warehouses the requested cart items ship from, by cart
+// item ID.
+func (s *OrderServiceImpl) allocate(requestFormat OrderRequestFormat, cartItems []cart.CartItem, destination *warehouse.Location) (allocations map[uuid.UUID][]warehouse.Allocation, err error) {
+	requested := make(map[uuid.UUID]bool, len(requestFormat.Items))
+	for _, item := range requestFormat.Items {
+		requested[item.CartItemID] = true
+	}
+
+	variantIDs := make([]uuid.UUID, 0, len(requestFormat.Items))
+	for _, item := range cartItems {
+		if requested[item.ID] {
+			variantIDs = append(variantIDs, item.VariantID)
+		}
+	}
+
+	stocks, err := s.ProductService.ResolveWarehouseStock(variantIDs)
+	if err != nil {
+		return
+	}
+
+	byVariant := make(map[uuid.UUID]map[uuid.UUID]int)
+	for _, stock := range stocks {
+		if byVariant[stock.VariantID] == nil {
+			byVariant[stock.VariantID] = make(map[uuid.UUID]int)
+		}
+		byVariant[stock.VariantID][stock.WarehouseID] = stock.Stock
+	}
+
+	demands := make([]warehouse.Demand, 0, len(variantIDs))
+	for _, item := range cartItems {
+		if requested[item.ID] {
+			demands = append(demands, warehouse.Demand{ID: item.ID, Quantity: item.Quantity, Stock: byVariant[item.VariantID]})
+		}
+	}
+
+	return s.WarehouseService.Allocate(demands, destination)
+}
@@ -1,115 +1,1001 @@
 package product
 
 import (
+	"database/sql/driver"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/evermos/boilerplate-go/internal/domain/category"
 	"github.com/evermos/boilerplate-go/shared"
+	"github.com/evermos/boilerplate-go/shared/failure"
 	"github.com/evermos/boilerplate-go/shared/nuuid"
 	"github.com/gofrs/uuid"
 	"github.com/guregu/null"
 )
 
+// Product is a product sold by a shop. It links to its category by ID, and
+// keeps the category's name for searching.
 type Product struct {
-	ID        uuid.UUID   `db:"id" validate:"required"`
-	UserID    uuid.UUID   `db:"user_id" validate:"required"`
-	Name      string      `db:"name" validate:"required"`
-	Price     float64     `db:"price" validate:"required,min=0"`
-	Brand     string      `db:"brand" validate:"required"`
-	Category  string      `db:"category" validate:"required"`
-	Stock     int         `db:"stock" validate:"required,min=0"`
-	CreatedAt time.Time   `db:"created_at" validate:"required"`
-	CreatedBy uuid.UUID   `db:"created_by" validate:"required"`
-	UpdatedAt null.Time   `db:"updated_at"`
-	UpdatedBy nuuid.NUUID `db:"updated_by"`
-	DeletedAt null.Time   `db:"deleted_at"`
-	DeletedBy nuuid.NUUID `db:"deleted_by"`
-}
-
-type ProductPagination struct {
-	Data        []Product `json:"data"`
-	Total       int       `json:"total"`
-	PerPage     int       `json:"perPage"`
-	CurrentPage int       `json:"currentPage"`
-	TotalPages  int       `json:"totalPages"`
+	ID         uuid.UUID   `db:"id" validate:"required"`
+	UserID     uuid.UUID   `db:"user_id" validate:"required"`
+	Name       string      `db:"name" validate:"required"`
+	Price      float64     `db:"price" validate:"required,min=0"`
+	Brand      string      `db:"brand" validate:"required"`
+	CategoryID uuid.UUID   `db:"category_id" validate:"required"`
+	Category   string      `db:"category" validate:"required"`
+	Stock      int         `db:"stock" validate:"min=0"`
+	CreatedAt  time.Time   `db:"created_at" validate:"required"`
+	CreatedBy  uuid.UUID   `db:"created_by" validate:"required"`
+	UpdatedAt  null.Time   `db:"updated_at"`
+	UpdatedBy  nuuid.NUUID `db:"updated_by"`
+	DeletedAt  null.Time   `db:"deleted_at"`
+	DeletedBy  nuuid.NUUID `db:"deleted_by"`
+	Variants   []Variant   `db:"-" validate:"dive"`
+	Images     []Image     `db:"-"`
 }
 
+const (
+	// DefaultProductSort and DefaultProductOrder list the newest products
+	// first.
+	DefaultProductSort  = "created_at"
+	DefaultProductOrder = "desc"
+)
+
+// productSorts are the columns products may be sorted by.
+var productSorts = map[string]bool{
+	"name":       true,
+	"price":      true,
+	"brand":      true,
+	"stock":      true,
+	"created_at": true,
+}
+
+// ProductQueryParams are the filters, sort and pagination of product lists.
+// Lists are paginated by cursor, while keyword searches, ordered by
+// relevance, are paginated by page. Categories are category slugs, which
+// match the categories below them too; the service resolves them to
+// CategoryIDs.
 type ProductQueryParams struct {
-	Page     int
-	Limit    int
-	Sort     string
-	Order    string
-	Brand    string
-	Category string
+	Page        int
+	Limit       int
+	Sort        string
+	Order       string
+	Cursor      string
+	Brands      []string
+	Categories  []string
+	CategoryIDs []uuid.UUID
+	MinPrice    null.Float
+	MaxPrice    null.Float
+	InStock     bool
+	// Query searches the products by keywords, ordering them by relevance.
+	Query string
+
+	// after is the position of the cursor, resolved by the service.
+	after *productKeyset
+}
+
+// ProductFacets are the numbers of products matching a query per brand and per
+// category.
+type ProductFacets struct {
+	Brands     map[string]int
+	Categories map[uuid.UUID]int
+}
+
+// resolveCategories resolves the category slugs of the filter to the IDs of
+// the categories and of every category below them.
+func (p *ProductQueryParams) resolveCategories(categories category.Categories) error {
+	p.CategoryIDs = nil
+	for _, slug := range p.Categories {
+		found, ok := categories.BySlug(slug)
+		if !ok {
+			return fmt.Errorf("category %s does not exist", slug)
+		}
+		p.CategoryIDs = append(p.CategoryIDs, categories.Descendants(found.ID)...)
+	}
+
+	return nil
 }
 
+// ToFacets lists the brands and categories of the matching products, the
+// most common first. Categories count the products of the categories below
+// them too, like filtering by them does, and are identified by slug.
+func (f ProductFacets) ToFacets(categories category.Categories) map[string][]shared.Facet {
+	brands := make([]shared.Facet, 0, len(f.Brands))
+	for brand, count := range f.Brands {
+		brands = append(brands, shared.Facet{Value: brand, Label: brand, Count: count})
+	}
+
+	categoryFacets := make([]shared.Facet, 0)
+	for _, c := range categories {
+		count := 0
+		for _, id := range categories.Descendants(c.ID) {
+			count += f.Categories[id]
+		}
+		if count > 0 {
+			categoryFacets = append(categoryFacets, shared.Facet{Value: c.Slug, Label: c.Name, Count: count})
+		}
+	}
+
+	return map[string][]shared.Facet{
+		"brand":    sortFacets(brands),
+		"category": sortFacets(categoryFacets),
+	}
+}
+func sortFacets(facets []shared.Facet) []shared.Facet {
+	sort.SliceStable(facets, func(i, j int) bool {
+		if facets[i].Count != facets[j].Count {
+			return facets[i].Count > facets[j].Count
+		}
+		return facets[i].Label < facets[j].Label
+	})
+
+	return facets
+}
+
+// productKeyset is the sort value and ID of the last product of a page.
+type productKeyset struct {
+	value interface{}
+	id    string
+}
+
+func (p ProductQueryParams) offset() int {
+	if p.Page > 1 {
+		return (p.Page - 1) * p.Limit
+	}
+
+	return 0
+}
+
+// normalize applies the sort of the cursor, if any, and otherwise falls back
+// to the default sort for unknown sorts and orders.
+func (p *ProductQueryParams) normalize() (err error) {
+	if p.Cursor != "" {
+		cursor, err := shared.DecodeCursor(p.Cursor)
+		if err != nil {
+			return err
+		}
+
+		value, err := parseSortValue(cursor.Sort, cursor.Value)
+		if err != nil || !productSorts[cursor.Sort] {
+			return shared.ErrInvalidCursor
+		}
+
+		p.Sort, p.Order = cursor.Sort, cursor.Order
+		p.after = &productKeyset{value: value, id: cursor.ID}
+	}
+
+	if !productSorts[p.Sort] {
+		p.Sort, p.Order = DefaultProductSort, DefaultProductOrder
+	}
+
+	if p.Order != "asc" && p.Order != "desc" {
+		p.Order = "asc"
+	}
+
+	return nil
+}
+
+// nextCursor is the cursor of the page following the given product.
+func (p ProductQueryParams) nextCursor(last Product) string {
+	return shared.Cursor{
+		Sort:  p.Sort,
+		Order: p.Order,
+		Value: last.sortValue(p.Sort),
+		ID:    last.ID.String(),
+	}.Encode()
+}
+
+// sortValue returns the value of the product's sort column, as kept in
+// cursors.
+func (p Product) sortValue(sort string) string {
+	switch sort {
+	case "name":
+		return p.Name
+	case "price":
+		return strconv.FormatFloat(p.Price, 'f', -1, 64)
+	case "brand":
+		return p.Brand
+	case "stock":
+		return strconv.Itoa(p.Stock)
+	default:
+		return p.CreatedAt.Format(time.RFC3339Nano)
+	}
+}
+
+// parseSortValue parses a sort value kept in a cursor into the type of its
+// column.
+func parseSortValue(sort string, value string) (interface{}, error) {
+	switch sort {
+	case "price":
+		return strconv.ParseFloat(value, 64)
+	case "stock":
+		return strconv.Atoi(value)
+	case "created_at":
+		return time.Parse(time.RFC3339Nano, value)
+	default:
+		return value, nil
+	}
+}
+func (p *Product) AttachVariants(variants []Variant) Product {
+	for _, variant := range variants {
+		if variant.ProductID == p.ID {
+			p.Variants = append(p.Variants, variant)
+		}
+	}
+
+	return *p
+}
+
+// AttachWarehouseStock attaches the stock of the product's variants in each
+// warehouse.
+func (p *Product) AttachWarehouseStock(stocks []WarehouseStock) Product {
+	for i := range p.Variants {
+		for _, stock := range stocks {
+			if stock.VariantID == p.Variants[i].ID {
+				p.Variants[i].Warehouses = append(p.Variants[i].Warehouses, stock)
+			}
+		}
+	}
+
+	return *p
+}
+func (p *Product) AttachImages(images []Image) Product {
+	for _, image := range images {
+		if image.ProductID == p.ID {
+			p.Images = append(p.Images, image)
+		}
+	}
+
+	return *p
+}
 func (p *Product) IsDeleted() (deleted bool) {
 	return p.DeletedAt.Valid && p.DeletedBy.Valid
 }
 func (p Product) MarshalJSON() ([]byte, error) {
 	return json.Marshal(p.ToResponseFormat())
 }
-func (p Product) NewProductFromRequestFormat(req ProductRequestFormat, userID uuid.UUID) (newProduct Product, err error) {
+func (p Product) NewProductFromRequestFormat(req ProductRequestFormat, category category.Category, userID uuid.UUID) (newProduct Product, err error) {
 	productID, err := uuid.NewV4()
+	if err != nil {
+		return
+	}
 
 	newProduct = Product{
-		ID:        productID,
-		UserID:    userID,
-		Name:      req.Name,
-		Price:     req.Price,
-		Brand:     req.Brand,
-		Category:  req.Category,
-		Stock:     req.Stock,
-		CreatedAt: time.Now(),
-		CreatedBy: userID,
+		ID:         productID,
+		UserID:     userID,
+		Name:       req.Name,
+		Price:      req.Price,
+		Brand:      req.Brand,
+		CategoryID: category.ID,
+		Category:   category.Name,
+		CreatedAt:  time.Now(),
+		CreatedBy:  userID,
+	}
+
+	err = newProduct.replaceVariants(req, userID)
+	if err != nil {
+		return
 	}
 
 	err = newProduct.Validate()
 
 	return
 }
+
+// Update replaces the product's details and variants. Placed orders keep the
+// details the product had at checkout.
+func (p *Product) Update(req ProductRequestFormat, category category.Category, userID uuid.UUID) (err error) {
+	p.Name = req.Name
+	p.Price = req.Price
+	p.Brand = req.Brand
+	p.CategoryID = category.ID
+	p.Category = category.Name
+	p.UpdatedAt = null.TimeFrom(time.Now())
+	p.UpdatedBy = nuuid.From(userID)
+
+	err = p.replaceVariants(req, userID)
+	if err != nil {
+		return
+	}
+
+	err = p.Validate()
+
+	return
+}
+
+// replaceVariants makes the product's variants those requested, or a single
+// default variant holding the requested stock when none are. Variants are
+// matched by SKU, so that carts keep pointing at the same variant; variants
+// no longer requested are soft deleted, and deleted ones requested again are
+// restored. The product's stock becomes the sum of its variants' stock.
+func (p *Product) replaceVariants(req ProductRequestFormat, userID uuid.UUID) (err error) {
+	requested := req.Variants
+	if len(requested) == 0 {
+		requested = []VariantRequestFormat{{
+			SKU:     p.defaultSKU(),
+			Options: VariantOptions{},
+			Stock:   req.Stock,
+		}}
+		// Keep the SKU of the existing default variant, if any.
+		for _, variant := range p.Variants {
+			if !variant.IsDeleted() && len(variant.Options) == 0 {
+				requested[0].SKU = variant.SKU
+			}
+		}
+	}
+
+	err = validateVariantRequests(requested)
+	if err != nil {
+		return
+	}
+
+	now := time.Now()
+	bySKU := make(map[string]int)
+	for i, variant := range p.Variants {
+		bySKU[variant.SKU] = i
+	}
+
+	kept := make(map[string]bool)
+	for _, variantReq := range requested {
+		kept[variantReq.SKU] = true
+
+		i, found := bySKU[variantReq.SKU]
+		if !found {
+			var variant Variant
+			variant, err = variant.NewFromRequestFormat(variantReq, p.ID, userID)
+			if err != nil {
+				return
+			}
+			p.Variants = append(p.Variants, variant)
+			continue
+		}
+
+		p.Variants[i].Update(variantReq, userID)
+	}
+
+	p.Stock = 0
+	for i := range p.Variants {
+		variant := &p.Variants[i]
+		if !kept[variant.SKU] && !variant.IsDeleted() {
+			variant.DeletedAt = null.TimeFrom(now)
+			variant.DeletedBy = nuuid.From(userID)
+		}
+		if !variant.IsDeleted() {
+			p.Stock += variant.Stock
+		}
+	}
+
+	return
+}
+
+// defaultSKU is the SKU of the variant of products sold without options.
+func (p *Product) defaultSKU() string {
+	return "SKU-" + strings.ToUpper(p.ID.String()[:8])
+}
+
+// ActiveVariants returns the variants that are not deleted.
+func (p Product) ActiveVariants() (variants []Variant) {
+	for _, variant := range p.Variants {
+		if !variant.IsDeleted() {
+			variants = append(variants, variant)
+		}
+	}
+
+	return
+}
+
+// ResolveVariant resolves the active variant of the product with the given ID.
+// Products with a single variant resolve it without an ID.
+func (p Product) ResolveVariant(id uuid.UUID) (variant Variant, err error) {
+	variants := p.ActiveVariants()
+	if id == uuid.Nil {
+		if len(variants) != 1 {
+			return variant, failure.BadRequestFromString("variantID is required for products with options")
+		}
+
+		return variants[0], nil
+	}
+
+	for _, variant := range variants {
+		if variant.ID == id {
+			return variant, nil
+		}
+	}
+
+	return variant, failure.NotFound("variant")
+}
+
+// Options returns the variant matrix: every option with its values, in the
+// order the variants list them.
+func (p Product) Options() map[string][]string {
+	options := make(map[string][]string)
+	for _, variant := range p.ActiveVariants() {
+		for _, name := range variant.Options.names() {
+			value := variant.Options[name]
+			if !containsString(options[name], value) {
+				options[name] = append(options[name], value)
+			}
+		}
+	}
+
+	return options
+}
+
+// SoftDelete marks the product as deleted, so that it is no longer listed or
+// sold.
+func (p *Product) SoftDelete(userID uuid.UUID) (err error) {
+	if p.IsDeleted() {
+		return failure.Conflict("softDelete", "product", "already marked as deleted")
+	}
+
+	p.DeletedAt = null.TimeFrom(time.Now())
+	p.DeletedBy = nuuid.From(userID)
+
+	return
+}
 func (p *Product) Validate() (err error) {
 	validator := shared.GetValidator()
 	return validator.Struct(p)
 }
 func (p Product) ToResponseFormat() ProductResponseFormat {
-	return ProductResponseFormat{
-		ID:        p.ID,
-		UserID:    p.UserID,
-		Name:      p.Name,
-		Price:     p.Price,
-		Brand:     p.Brand,
-		Category:  p.Category,
-		Stock:     p.Stock,
-		CreatedBy: p.CreatedBy,
-		CreatedAt: p.CreatedAt,
-		UpdatedAt: p.UpdatedAt,
-		UpdatedBy: p.UpdatedBy.Ptr(),
-		DeletedAt: p.DeletedAt,
-		DeletedBy: p.DeletedBy.Ptr(),
+	resp := ProductResponseFormat{
+		ID:         p.ID,
+		UserID:     p.UserID,
+		Name:       p.Name,
+		Price:      p.Price,
+		Brand:      p.Brand,
+		CategoryID: p.CategoryID,
+		Category:   p.Category,
+		Stock:      p.Stock,
+		Options:    p.Options(),
+		Variants:   make([]VariantResponseFormat, 0),
+		Images:     make([]ImageResponseFormat, 0),
+		CreatedBy:  p.CreatedBy,
+		CreatedAt:  p.CreatedAt,
+		UpdatedAt:  p.UpdatedAt,
+		UpdatedBy:  p.UpdatedBy.Ptr(),
+		DeletedAt:  p.DeletedAt,
+		DeletedBy:  p.DeletedBy.Ptr(),
 	}
+
+	for _, variant := range p.ActiveVariants() {
+		resp.Variants = append(resp.Variants, variant.ToResponseFormat(p.Price))
+	}
+
+	for _, image := range p.Images {
+		if !image.IsDeleted() {
+			resp.Images = append(resp.Images, image.ToResponseFormat())
+		}
+	}
+
+	return resp
 }
 
+// ProductRequestFormat is the payload to create or replace a product. Products
+// sold in several options list their variants, whose stock replaces stock;
+// products without variants are sold as a single default variant. Stock is the
+// stock across warehouses; changing it moves the stock of the default
+// warehouse, while the stock of other warehouses is adjusted through stock
+// movements.
 type ProductRequestFormat struct {
-	Name     string  `json:"name" validate:"required"`
-	Price    float64 `json:"price" validate:"required"`
-	Brand    string  `json:"brand" validate:"required"`
-	Category string  `json:"category" validate:"required"`
-	Stock    int     `json:"stock" validate:"required"`
+	Name       string                 `json:"name" validate:"required"`
+	Price      float64                `json:"price" validate:"required"`
+	Brand      string                 `json:"brand" validate:"required"`
+	CategoryID uuid.UUID              `json:"categoryID" validate:"required"`
+	Stock      int                    `json:"stock" validate:"min=0"`
+	Variants   []VariantRequestFormat `json:"variants" validate:"dive"`
 }
 
 type ProductResponseFormat struct {
-	ID        uuid.UUID  `json:"id"`
-	UserID    uuid.UUID  `json:"userID"`
-	Name      string     `json:"name"`
-	Price     float64    `json:"price"`
-	Brand     string     `json:"brand"`
-	Category  string     `json:"category"`
-	Stock     int        `json:"stock"`
-	CreatedAt time.Time  `json:"createdAt"`
-	CreatedBy uuid.UUID  `json:"createdBy"`
-	UpdatedAt null.Time  `json:"updatedAt"`
-	UpdatedBy *uuid.UUID `json:"updatedBy"`
-	DeletedAt null.Time  `json:"deletedAt,omitempty"`
-	DeletedBy *uuid.UUID `json:"deletedBy,omitempty"`
+	ID         uuid.UUID               `json:"id"`
+	UserID     uuid.UUID               `json:"userID"`
+	Name       string                  `json:"name"`
+	Price      float64                 `json:"price"`
+	Brand      string                  `json:"brand"`
+	CategoryID uuid.UUID               `json:"categoryID"`
+	Category   string                  `json:"category"`
+	Stock      int                     `json:"stock"`
+	Options    map[string][]string     `json:"options"`
+	Variants   []VariantResponseFormat `json:"variants"`
+	Images     []ImageResponseFormat   `json:"images"`
+	CreatedAt  time.Time               `json:"createdAt"`
+	CreatedBy  uuid.UUID               `json:"createdBy"`
+	UpdatedAt  null.Time               `json:"updatedAt"`
+	UpdatedBy  *uuid.UUID              `json:"updatedBy"`
+	DeletedAt  null.Time               `json:"deletedAt,omitempty"`
+	DeletedBy  *uuid.UUID              `json:"deletedBy,omitempty"`
+}
+
+// VariantOptions are the option values that set a variant apart, such as its
+// size and color. They are stored as a JSON object.
+type VariantOptions map[string]string
+
+func (o VariantOptions) Value() (driver.Value, error) {
+	if o == nil {
+		return "{}", nil
+	}
+
+	b, err := json.Marshal(o)
+	return string(b), err
+}
+func (o *VariantOptions) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	case nil:
+		*o = VariantOptions{}
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into VariantOptions", src)
+	}
+
+	options := VariantOptions{}
+	err := json.Unmarshal(data, &options)
+	if err != nil {
+		return err
+	}
+
+	*o = options
+	return nil
+}
+
+// names returns the option names, sorted.
+func (o VariantOptions) names() []string {
+	names := make([]string, 0, len(o))
+	for name := range o {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// combination identifies the option values regardless of their order.
+func (o VariantOptions) combination() string {
+	var pairs []string
+	for _, name := range o.names() {
+		pairs = append(pairs, name+"="+o[name])
+	}
+
+	return strings.Join(pairs, ";")
+}
+
+// Variant is a purchasable version of a product, such as a size and color,
+// with its own SKU and stock. Its price overrides the product's when set. Its
+// stock is the sum of its stock in every warehouse.
+type Variant struct {
+	ID         uuid.UUID        `db:"id" validate:"required"`
+	ProductID  uuid.UUID        `db:"product_id" validate:"required"`
+	SKU        string           `db:"sku" validate:"required,max=64"`
+	Options    VariantOptions   `db:"options"`
+	Price      null.Float       `db:"price"`
+	Stock      int              `db:"stock" validate:"min=0"`
+	CreatedAt  time.Time        `db:"created_at" validate:"required"`
+	CreatedBy  uuid.UUID        `db:"created_by" validate:"required"`
+	UpdatedAt  null.Time        `db:"updated_at"`
+	UpdatedBy  nuuid.NUUID      `db:"updated_by"`
+	DeletedAt  null.Time        `db:"deleted_at"`
+	DeletedBy  nuuid.NUUID      `db:"deleted_by"`
+	Warehouses []WarehouseStock `db:"-"`
+}
+
+func (v *Variant) IsDeleted() (deleted bool) {
+	return v.DeletedAt.Valid && v.DeletedBy.Valid
+}
+func (v Variant) NewFromRequestFormat(req VariantRequestFormat, productID uuid.UUID, userID uuid.UUID) (newVariant Variant, err error) {
+	variantID, err := uuid.NewV4()
+	if err != nil {
+		return
+	}
+
+	newVariant = Variant{
+		ID:        variantID,
+		ProductID: productID,
+		SKU:       req.SKU,
+		Options:   req.Options,
+		Price:     req.Price,
+		Stock:     req.Stock,
+		CreatedAt: time.Now(),
+		CreatedBy: userID,
+	}
+	if newVariant.Options == nil {
+		newVariant.Options = VariantOptions{}
+	}
+
+	return
+}
+
+// Update replaces the variant's options, price and stock, restoring it when
+// it was deleted.
+func (v *Variant) Update(req VariantRequestFormat, userID uuid.UUID) {
+	v.Options = req.Options
+	if v.Options == nil {
+		v.Options = VariantOptions{}
+	}
+	v.Price = req.Price
+	v.Stock = req.Stock
+	v.UpdatedAt = null.TimeFrom(time.Now())
+	v.UpdatedBy = nuuid.From(userID)
+	v.DeletedAt = null.Time{}
+	v.DeletedBy = nuuid.NUUID{}
+}
+
+// WarehouseStock returns the sum of the variant's stock in every warehouse.
+func (v Variant) WarehouseStock() (stock int) {
+	for _, warehouseStock := range v.Warehouses {
+		stock += warehouseStock.Stock
+	}
+
+	return
+}
+
+// EffectivePrice is the price the variant sells at.
+func (v Variant) EffectivePrice(productPrice float64) float64 {
+	if v.Price.Valid {
+		return v.Price.Float64
+	}
+
+	return productPrice
+}
+func (v Variant) ToResponseFormat(productPrice float64) VariantResponseFormat {
+	resp := VariantResponseFormat{
+		ID:         v.ID,
+		SKU:        v.SKU,
+		Options:    v.Options,
+		Price:      v.EffectivePrice(productPrice),
+		Stock:      v.Stock,
+		Warehouses: make([]WarehouseStockResponseFormat, 0),
+	}
+
+	for _, stock := range v.Warehouses {
+		resp.Warehouses = append(resp.Warehouses, stock.ToResponseFormat())
+	}
+
+	return resp
+}
+
+// validateVariantRequests checks that the requested variants share the same
+// options, and that neither their SKUs nor their option values repeat.
+func validateVariantRequests(requests []VariantRequestFormat) error {
+	skus := make(map[string]bool)
+	combinations := make(map[string]bool)
+	var names string
+	for i, req := range requests {
+		if skus[req.SKU] {
+			return fmt.Errorf("sku %s is repeated", req.SKU)
+		}
+		skus[req.SKU] = true
+
+		if i == 0 {
+			names = strings.Join(req.Options.names(), ",")
+		} else if strings.Join(req.Options.names(), ",") != names {
+			return errors.New("every variant must have the same options")
+		}
+
+		combination := req.Options.combination()
+		if combinations[combination] {
+			return fmt.Errorf("options of sku %s are repeated", req.SKU)
+		}
+		combinations[combination] = true
+	}
+
+	return nil
+}
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+type VariantRequestFormat struct {
+	SKU     string         `json:"sku" validate:"required,max=64"`
+	Options VariantOptions `json:"options" validate:"dive,keys,required,max=50,endkeys,required,max=100"`
+	Price   null.Float     `json:"price" swaggertype:"number"`
+	Stock   int            `json:"stock" validate:"min=0"`
+}
+type VariantResponseFormat struct {
+	ID         uuid.UUID                      `json:"id"`
+	SKU        string                         `json:"sku"`
+	Options    VariantOptions                 `json:"options"`
+	Price      float64                        `json:"price"`
+	Stock      int                            `json:"stock"`
+	Warehouses []WarehouseStockResponseFormat `json:"warehouses"`
+}
+
+// WarehouseStock is the stock of a variant in a warehouse.
+type WarehouseStock struct {
+	WarehouseID uuid.UUID `db:"warehouse_id"`
+	VariantID   uuid.UUID `db:"variant_id"`
+	ProductID   uuid.UUID `db:"product_id"`
+	Stock       int       `db:"stock"`
+}
+
+func (s WarehouseStock) ToResponseFormat() WarehouseStockResponseFormat {
+	return WarehouseStockResponseFormat{
+		WarehouseID: s.WarehouseID,
+		Stock:       s.Stock,
+	}
+}
+
+type WarehouseStockResponseFormat struct {
+	WarehouseID uuid.UUID `json:"warehouseID"`
+	Stock       int       `json:"stock"`
+}
+
+// Image is a picture of a product, stored in the blob store along with its
+// thumbnail. Images are shown in the order of their position.
+type Image struct {
+	ID           uuid.UUID   `db:"id" validate:"required"`
+	ProductID    uuid.UUID   `db:"product_id" validate:"required"`
+	Position     int         `db:"position"`
+	ContentType  string      `db:"content_type" validate:"required"`
+	Width        int         `db:"width" validate:"min=1"`
+	Height       int         `db:"height" validate:"min=1"`
+	Size         int         `db:"size" validate:"min=1"`
+	BlobKey      string      `db:"blob_key" validate:"required"`
+	URL          string      `db:"url" validate:"required"`
+	ThumbnailKey string      `db:"thumbnail_key" validate:"required"`
+	ThumbnailURL string      `db:"thumbnail_url" validate:"required"`
+	CreatedAt    time.Time   `db:"created_at" validate:"required"`
+	CreatedBy    uuid.UUID   `db:"created_by" validate:"required"`
+	DeletedAt    null.Time   `db:"deleted_at"`
+	DeletedBy    nuuid.NUUID `db:"deleted_by"`
+}
+
+func (i *Image) IsDeleted() (deleted bool) {
+	return i.DeletedAt.Valid && i.DeletedBy.Valid
+}
+
+// NewImage creates a new image of a product. The image and its thumbnail are
+// stored under the product's key prefix, with the extension of their type.
+func (i Image) NewImage(productID uuid.UUID, contentType string, width int, height int, size int, userID uuid.UUID) (newImage Image, err error) {
+	imageID, err := uuid.NewV4()
+	if err != nil {
+		return
+	}
+
+	newImage = Image{
+		ID:          imageID,
+		ProductID:   productID,
+		ContentType: contentType,
+		Width:       width,
+		Height:      height,
+		Size:        size,
+		CreatedAt:   time.Now(),
+		CreatedBy:   userID,
+	}
+
+	return
+}
+
+// Keys returns the blob keys of the image and of its thumbnail.
+func (i Image) Keys(extension string, thumbnailExtension string) (key string, thumbnailKey string) {
+	prefix := fmt.Sprintf("products/%s/images/%s", i.ProductID, i.ID)
+	return prefix + extension, prefix + "-thumbnail" + thumbnailExtension
+}
+func (i *Image) SoftDelete(userID uuid.UUID) {
+	i.DeletedAt = null.TimeFrom(time.Now())
+	i.DeletedBy = nuuid.From(userID)
+}
+func (i *Image) Validate() (err error) {
+	validator := shared.GetValidator()
+	return validator.Struct(i)
+}
+func (i Image) ToResponseFormat() ImageResponseFormat {
+	return ImageResponseFormat{
+		ID:           i.ID,
+		Position:     i.Position,
+		URL:          i.URL,
+		ThumbnailURL: i.ThumbnailURL,
+		ContentType:  i.ContentType,
+		Width:        i.Width,
+		Height:       i.Height,
+	}
+}
+
+type ImageResponseFormat struct {
+	ID           uuid.UUID `json:"id"`
+	Position     int       `json:"position"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnailUrl"`
+	ContentType  string    `json:"contentType"`
+	Width        int       `json:"width"`
+	Height       int       `json:"height"`
+}
+
+// StockReason is why the stock of a variant changed.
+type StockReason string
+
+const (
+	// StockReasonInitial is the stock a variant is created with.
+	StockReasonInitial StockReason = "initial"
+	// StockReasonCheckout is the stock taken by a checked out order.
+	StockReasonCheckout StockReason = "checkout"
+	// StockReasonCancel is the stock put back by a canceled order.
+	StockReasonCancel StockReason = "cancel"
+	// StockReasonReturn is the stock put back by returned items.
+	StockReasonReturn StockReason = "return"
+	// StockReasonManual is a manual correction of the stock.
+	StockReasonManual StockReason = "manual"
+	// StockReasonImport is the stock imported from another system.
+	StockReasonImport StockReason = "import"
+)
+
+// StockMovement is an entry of the inventory ledger: a change of the stock of
+// a variant in a warehouse, by whom, why and for what. Balance is the
+// variant's stock in the warehouse right after the change, so that the stock
+// of a variant is the sum of its movements.
+type StockMovement struct {
+	ID          uuid.UUID   `db:"id" validate:"required"`
+	ProductID   uuid.UUID   `db:"product_id" validate:"required"`
+	VariantID   uuid.UUID   `db:"variant_id" validate:"required"`
+	WarehouseID uuid.UUID   `db:"warehouse_id" validate:"required"`
+	SKU         string      `db:"sku"`
+	Quantity    int         `db:"quantity" validate:"required"`
+	Balance     int         `db:"balance"`
+	Reason      StockReason `db:"reason" validate:"required,oneof=initial checkout cancel return manual import"`
+	ReferenceID null.String `db:"reference_id"`
+	ActorID     uuid.UUID   `db:"actor_id" validate:"required"`
+	Note        null.String `db:"note"`
+	CreatedAt   time.Time   `db:"created_at" validate:"required"`
+}
+
+func (m StockMovement) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.ToResponseFormat())
+}
+
+// NewStockMovement creates a movement of the stock of a variant in a warehouse
+// by quantity, negative when stock is taken.
+func (m StockMovement) NewStockMovement(productID uuid.UUID, variantID uuid.UUID, warehouseID uuid.UUID, quantity int, reason StockReason, referenceID string, actorID uuid.UUID) (newMovement StockMovement, err error) {
+	movementID, err := uuid.NewV4()
+	if err != nil {
+		return
+	}
+
+	newMovement = StockMovement{
+		ID:          movementID,
+		ProductID:   productID,
+		VariantID:   variantID,
+		WarehouseID: warehouseID,
+		Quantity:    quantity,
+		Reason:      reason,
+		ReferenceID: null.NewString(referenceID, referenceID != ""),
+		ActorID:     actorID,
+		CreatedAt:   time.Now(),
+	}
+
+	err = newMovement.Validate()
+
+	return
+}
+
+// NewFromRequestFormat creates a movement adjusting the stock of a variant of
+// the product in a warehouse.
+func (m StockMovement) NewFromRequestFormat(req StockMovementRequestFormat, variant Variant, warehouseID uuid.UUID, actorID uuid.UUID) (newMovement StockMovement, err error) {
+	newMovement, err = m.NewStockMovement(variant.ProductID, variant.ID, warehouseID, req.Quantity, req.Reason, req.ReferenceID, actorID)
+	if err != nil {
+		return
+	}
+
+	newMovement.SKU = variant.SKU
+	newMovement.Note = null.NewString(req.Note, req.Note != "")
+
+	return
+}
+func (m *StockMovement) Validate() (err error) {
+	validator := shared.GetValidator()
+	return validator.Struct(m)
+}
+func (m StockMovement) ToResponseFormat() StockMovementResponseFormat {
+	return StockMovementResponseFormat{
+		ID:          m.ID,
+		VariantID:   m.VariantID,
+		WarehouseID: m.WarehouseID,
+		SKU:         m.SKU,
+		Quantity:    m.Quantity,
+		Balance:     m.Balance,
+		Reason:      m.Reason,
+		ReferenceID: m.ReferenceID,
+		ActorID:     m.ActorID,
+		Note:        m.Note,
+		CreatedAt:   m.CreatedAt,
+	}
+}
+
+// stockMovements records the changes of the stock of the product's variants
+// from the current stock, by variant ID, as movements of the stock in a
+// warehouse. Variants missing from current are new, with no stock yet.
+func (p Product) stockMovements(current map[uuid.UUID]int, warehouseID uuid.UUID, reason StockReason, actorID uuid.UUID) (movements []StockMovement, err error) {
+	for _, variant := range p.Variants {
+		quantity := variant.Stock - current[variant.ID]
+		if quantity == 0 {
+			continue
+		}
+
+		var movement StockMovement
+		movement, err = movement.NewStockMovement(p.ID, variant.ID, warehouseID, quantity, reason, "", actorID)
+		if err != nil {
+			return
+		}
+		movements = append(movements, movement)
+	}
+
+	return
+}
+
+// StockLevels compares the stock of every active variant with its stock
+// according to the ledger, the sum of its movements by variant ID, and with
+// the sum of its stock in every warehouse.
+func (p Product) StockLevels(ledger map[uuid.UUID]int) []StockLevel {
+	levels := make([]StockLevel, 0)
+	for _, variant := range p.ActiveVariants() {
+		warehouseStock := variant.WarehouseStock()
+		levels = append(levels, StockLevel{
+			VariantID:      variant.ID,
+			SKU:            variant.SKU,
+			Stock:          variant.Stock,
+			LedgerStock:    ledger[variant.ID],
+			WarehouseStock: warehouseStock,
+			Reconciled:     variant.Stock == ledger[variant.ID] && variant.Stock == warehouseStock,
+		})
+	}
+
+	return levels
+}
+
+// StockMovementRequestFormat is the payload to adjust the stock of a variant
+// in a warehouse, by a negative quantity to take stock. Products with a single
+// variant may leave variantID out, and leaving warehouseID out adjusts the
+// stock of the default warehouse.
+type StockMovementRequestFormat struct {
+	VariantID   uuid.UUID   `json:"variantID"`
+	WarehouseID uuid.UUID   `json:"warehouseID"`
+	Quantity    int         `json:"quantity" validate:"required"`
+	Reason      StockReason `json:"reason" validate:"required,oneof=cancel return manual import"`
+	ReferenceID string      `json:"referenceID" validate:"max=100"`
+	Note        string      `json:"note" validate:"max=255"`
+}
+type StockMovementResponseFormat struct {
+	ID          uuid.UUID   `json:"id"`
+	VariantID   uuid.UUID   `json:"variantID"`
+	WarehouseID uuid.UUID   `json:"warehouseID"`
+	SKU         string      `json:"sku"`
+	Quantity    int         `json:"quantity"`
+	Balance     int         `json:"balance"`
+	Reason      StockReason `json:"reason"`
+	ReferenceID null.String `json:"referenceID" swaggertype:"string"`
+	ActorID     uuid.UUID   `json:"actorID"`
+	Note        null.String `json:"note" swaggertype:"string"`
+	CreatedAt   time.Time   `json:"createdAt"`
+}
+
+// StockLevel is the stock of a variant along with its stock according to the
+// ledger and its stock across warehouses, which are reconciled when they are
+// all equal.
+type StockLevel struct {
+	VariantID      uuid.UUID `json:"variantID"`
+	SKU            string    `json:"sku"`
+	Stock          int       `json:"stock"`
+	LedgerStock    int       `json:"ledgerStock"`
+	WarehouseStock int       `json:"warehouseStock"`
+	Reconciled     bool      `json:"reconciled"`
+}
+
+// StockHistory is a page of the stock movements of a product, newest first,
+// along with the reconciliation of its variants' stock against the ledger.
+type StockHistory struct {
+	Levels    []StockLevel `json:"levels"`
+	Movements shared.Page  `json:"movements"`
 }
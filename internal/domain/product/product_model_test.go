@@ -0,0 +1,129 @@
+package product_test
+
+import (
+	"testing"
+
+	"github.com/evermos/boilerplate-go/internal/domain/category"
+	"github.com/evermos/boilerplate-go/internal/domain/product"
+	"github.com/gofrs/uuid"
+	"github.com/guregu/null"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProductVariants(t *testing.T) {
+	userID := uuid.Must(uuid.NewV4())
+	apparel := category.Category{ID: uuid.Must(uuid.NewV4()), Name: "Apparel", Slug: "apparel"}
+	req := product.ProductRequestFormat{
+		Name:       "T-Shirt",
+		Price:      100,
+		Brand:      "Evermos",
+		CategoryID: apparel.ID,
+		Variants: []product.VariantRequestFormat{
+			{SKU: "TS-S-RED", Options: product.VariantOptions{"size": "S", "color": "red"}, Stock: 3},
+			{SKU: "TS-M-RED", Options: product.VariantOptions{"size": "M", "color": "red"}, Stock: 2},
+			{SKU: "TS-M-BLUE", Options: product.VariantOptions{"size": "M", "color": "blue"}, Price: null.FloatFrom(120), Stock: 1},
+		},
+	}
+
+	t.Run("Default Variant", func(t *testing.T) {
+		p, err := product.Product{}.NewProductFromRequestFormat(product.ProductRequestFormat{
+			Name: "Mug", Price: 50, Brand: "Evermos", CategoryID: apparel.ID, Stock: 7,
+		}, apparel, userID)
+		require.NoError(t, err)
+
+		require.Len(t, p.Variants, 1)
+		assert.Equal(t, 7, p.Stock)
+		assert.Empty(t, p.Variants[0].Options)
+
+		variant, err := p.ResolveVariant(uuid.Nil)
+		require.NoError(t, err)
+		assert.Equal(t, float64(50), variant.EffectivePrice(p.Price))
+	})
+
+	t.Run("Variant Matrix", func(t *testing.T) {
+		p, err := product.Product{}.NewProductFromRequestFormat(req, apparel, userID)
+		require.NoError(t, err)
+
+		assert.Equal(t, 6, p.Stock)
+		assert.Equal(t, map[string][]string{"color": {"red", "blue"}, "size": {"S", "M"}}, p.Options())
+		assert.Equal(t, float64(120), p.ToResponseFormat().Variants[2].Price)
+
+		_, err = p.ResolveVariant(uuid.Nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("Update Matches Variants By SKU", func(t *testing.T) {
+		p, err := product.Product{}.NewProductFromRequestFormat(req, apparel, userID)
+		require.NoError(t, err)
+		redS := p.Variants[0]
+
+		update := req
+		update.Variants = []product.VariantRequestFormat{
+			{SKU: "TS-S-RED", Options: product.VariantOptions{"size": "S", "color": "red"}, Stock: 10},
+			{SKU: "TS-L-RED", Options: product.VariantOptions{"size": "L", "color": "red"}, Stock: 4},
+		}
+		require.NoError(t, p.Update(update, apparel, userID))
+
+		active := p.ActiveVariants()
+		require.Len(t, active, 2)
+		assert.Equal(t, redS.ID, active[0].ID)
+		assert.Equal(t, 10, active[0].Stock)
+		assert.Equal(t, "TS-L-RED", active[1].SKU)
+		assert.Equal(t, 14, p.Stock)
+		assert.Len(t, p.Variants, 4)
+	})
+
+	t.Run("Reconciles Stock Against The Ledger", func(t *testing.T) {
+		p, err := product.Product{}.NewProductFromRequestFormat(req, apparel, userID)
+		require.NoError(t, err)
+
+		main, north := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+		p.AttachWarehouseStock([]product.WarehouseStock{
+			{WarehouseID: main, VariantID: p.Variants[0].ID, ProductID: p.ID, Stock: 2},
+			{WarehouseID: north, VariantID: p.Variants[0].ID, ProductID: p.ID, Stock: 1},
+			{WarehouseID: main, VariantID: p.Variants[1].ID, ProductID: p.ID, Stock: 2},
+		})
+
+		ledger := map[uuid.UUID]int{p.Variants[0].ID: 3, p.Variants[1].ID: 5, p.Variants[2].ID: 1}
+		levels := p.StockLevels(ledger)
+
+		require.Len(t, levels, 3)
+		assert.True(t, levels[0].Reconciled)
+		assert.Equal(t, 3, levels[0].WarehouseStock)
+		assert.False(t, levels[1].Reconciled)
+		assert.Equal(t, 2, levels[1].Stock)
+		assert.Equal(t, 5, levels[1].LedgerStock)
+		assert.False(t, levels[2].Reconciled)
+		assert.Equal(t, 1, levels[2].LedgerStock)
+		assert.Equal(t, 0, levels[2].WarehouseStock)
+		assert.Len(t, p.ToResponseFormat().Variants[0].Warehouses, 2)
+	})
+
+	t.Run("Invalid Variants", func(t *testing.T) {
+		invalid := map[string][]product.VariantRequestFormat{
+			"Repeated SKU": {
+				{SKU: "A", Options: product.VariantOptions{"size": "S"}},
+				{SKU: "A", Options: product.VariantOptions{"size": "M"}},
+			},
+			"Repeated Options": {
+				{SKU: "A", Options: product.VariantOptions{"size": "S"}},
+				{SKU: "B", Options: product.VariantOptions{"size": "S"}},
+			},
+			"Different Options": {
+				{SKU: "A", Options: product.VariantOptions{"size": "S"}},
+				{SKU: "B", Options: product.VariantOptions{"color": "red"}},
+			},
+		}
+
+		for name, variants := range invalid {
+			t.Run(name, func(t *testing.T) {
+				invalidReq := req
+				invalidReq.Variants = variants
+
+				_, err := product.Product{}.NewProductFromRequestFormat(invalidReq, apparel, userID)
+				assert.Error(t, err)
+			})
+		}
+	})
+}
@@ -2,18 +2,41 @@ package product
 
 import (
 	"database/sql"
+	"fmt"
+	"strings"
 
 	"github.com/evermos/boilerplate-go/infras"
 	"github.com/evermos/boilerplate-go/shared/failure"
 	"github.com/evermos/boilerplate-go/shared/logger"
+	"github.com/go-sql-driver/mysql"
 	"github.com/gofrs/uuid"
 	"github.com/jmoiron/sqlx"
 )
 
+// mysqlErrDuplicateEntry is the MySQL error number of a unique key violation.
+const mysqlErrDuplicateEntry = 1062
+
 var (
 	productQueries = struct {
 		selectProducts string
 		insertProduct  string
+		updateProduct  string
+		selectVariants string
+		upsertVariants string
+		selectImages   string
+		insertImage    string
+		deleteImage    string
+
+		selectStockMovements string
+		insertStockMovement  string
+		lockVariantStock     string
+		moveVariantStock     string
+		moveProductStock     string
+		sumStockMovements    string
+
+		selectWarehouseStock string
+		ensureWarehouseStock string
+		moveWarehouseStock   string
 	}{
 		selectProducts: `
 			SELECT
@@ -22,6 +45,7 @@ var (
 				name,
 				price,
 				brand,
+				category_id,
 				category,
 				stock,
 				created_at,
@@ -41,6 +65,7 @@ var (
 				name,
 				price,
 				brand,
+				category_id,
 				category,
 				stock,
 				created_at,
@@ -55,6 +80,7 @@ var (
 				:name,
 				:price,
 				:brand,
+				:category_id,
 				:category,
 				:stock,
 				:created_at,
@@ -65,14 +91,245 @@ var (
 				:deleted_by
 			)
 		`,
+
+		updateProduct: `
+			UPDATE product
+			SET
+				name = :name,
+				price = :price,
+				brand = :brand,
+				category_id = :category_id,
+				category = :category,
+				stock = :stock,
+				updated_at = :updated_at,
+				updated_by = :updated_by,
+				deleted_at = :deleted_at,
+				deleted_by = :deleted_by
+			WHERE id = :id
+		`,
+
+		selectVariants: `
+			SELECT
+				id,
+				product_id,
+				sku,
+				options,
+				price,
+				stock,
+				created_at,
+				created_by,
+				updated_at,
+				updated_by,
+				deleted_at,
+				deleted_by
+			FROM product_variant
+		`,
+
+		upsertVariants: `
+			INSERT INTO product_variant (
+				id,
+				product_id,
+				sku,
+				options,
+				price,
+				stock,
+				created_at,
+				created_by,
+				updated_at,
+				updated_by,
+				deleted_at,
+				deleted_by
+			) VALUES %s
+			ON DUPLICATE KEY UPDATE
+				options = VALUES(options),
+				price = VALUES(price),
+				stock = VALUES(stock),
+				updated_at = VALUES(updated_at),
+				updated_by = VALUES(updated_by),
+				deleted_at = VALUES(deleted_at),
+				deleted_by = VALUES(deleted_by)
+		`,
+
+		selectImages: `
+			SELECT
+				id,
+				product_id,
+				position,
+				content_type,
+				width,
+				height,
+				size,
+				blob_key,
+				url,
+				thumbnail_key,
+				thumbnail_url,
+				created_at,
+				created_by,
+				deleted_at,
+				deleted_by
+			FROM product_image
+		`,
+
+		insertImage: `
+			INSERT INTO product_image (
+				id,
+				product_id,
+				position,
+				content_type,
+				width,
+				height,
+				size,
+				blob_key,
+				url,
+				thumbnail_key,
+				thumbnail_url,
+				created_at,
+				created_by,
+				deleted_at,
+				deleted_by
+			) VALUES (
+				:id,
+				:product_id,
+				:position,
+				:content_type,
+				:width,
+				:height,
+				:size,
+				:blob_key,
+				:url,
+				:thumbnail_key,
+				:thumbnail_url,
+				:created_at,
+				:created_by,
+				:deleted_at,
+				:deleted_by
+			)
+		`,
+
+		deleteImage: `
+			UPDATE product_image
+			SET
+				deleted_at = :deleted_at,
+				deleted_by = :deleted_by
+			WHERE id = :id
+		`,
+
+		selectStockMovements: `
+			SELECT
+				stock_movement.id,
+				stock_movement.product_id,
+				stock_movement.variant_id,
+				stock_movement.warehouse_id,
+				product_variant.sku,
+				stock_movement.quantity,
+				stock_movement.balance,
+				stock_movement.reason,
+				stock_movement.reference_id,
+				stock_movement.actor_id,
+				stock_movement.note,
+				stock_movement.created_at
+			FROM stock_movement
+			JOIN product_variant ON product_variant.id = stock_movement.variant_id
+		`,
+
+		// The balance is the variant's stock in the warehouse once the movement
+		// is applied.
+		insertStockMovement: `
+			INSERT INTO stock_movement (
+				id,
+				product_id,
+				variant_id,
+				warehouse_id,
+				quantity,
+				balance,
+				reason,
+				reference_id,
+				actor_id,
+				note,
+				created_at
+			)
+			SELECT
+				:id,
+				:product_id,
+				:variant_id,
+				:warehouse_id,
+				:quantity,
+				stock,
+				:reason,
+				:reference_id,
+				:actor_id,
+				:note,
+				:created_at
+			FROM warehouse_stock
+			WHERE warehouse_id = :warehouse_id AND variant_id = :variant_id
+		`,
+
+		lockVariantStock: `SELECT id, stock FROM product_variant WHERE product_id = ? FOR UPDATE`,
+
+		moveVariantStock: `UPDATE product_variant SET stock = stock + :quantity WHERE id = :variant_id AND product_id = :product_id`,
+
+		moveProductStock: `UPDATE product SET stock = stock + :quantity WHERE id = :product_id`,
+
+		sumStockMovements: `
+			SELECT
+				variant_id,
+				SUM(quantity) AS stock
+			FROM stock_movement
+			WHERE product_id = ?
+			GROUP BY variant_id
+		`,
+
+		selectWarehouseStock: `
+			SELECT
+				warehouse_stock.warehouse_id,
+				warehouse_stock.variant_id,
+				warehouse_stock.product_id,
+				warehouse_stock.stock
+			FROM warehouse_stock
+			JOIN warehouse ON warehouse.id = warehouse_stock.warehouse_id
+			WHERE warehouse.deleted_at IS NULL
+		`,
+
+		// Variants have no stock in a warehouse until stock is moved there.
+		ensureWarehouseStock: `
+			INSERT INTO warehouse_stock (warehouse_id, variant_id, product_id, stock)
+			VALUES (:warehouse_id, :variant_id, :product_id, 0)
+			ON DUPLICATE KEY UPDATE stock = stock
+		`,
+
+		// Stock is only taken while enough is left in the warehouse.
+		moveWarehouseStock: `
+			UPDATE warehouse_stock
+			SET stock = stock + :quantity
+			WHERE warehouse_id = :warehouse_id AND variant_id = :variant_id AND stock + :quantity >= 0
+		`,
 	}
 )
 
+// facetQuery counts products per value of a column, formatted with the column
+// and the condition.
+const facetQuery = `SELECT %[1]s AS value, COUNT(id) AS count FROM product WHERE %[2]s GROUP BY %[1]s`
+
+const upsertVariantPlaceholder = `(:id, :product_id, :sku, :options, :price, :stock, :created_at, :created_by, :updated_at, :updated_by, :deleted_at, :deleted_by)`
+
 type ProductRepository interface {
-	CreateProduct(product Product) (err error)
+	CreateProduct(product Product, warehouseID uuid.UUID) (err error)
 	ResolveProductsByQuery(params ProductQueryParams) (products []Product, err error)
-	CountAllProducts() (total int, err error)
+	CountProducts(params ProductQueryParams) (total int, err error)
+	ResolveProductFacets(params ProductQueryParams) (facets ProductFacets, err error)
 	ResolveProductByID(id uuid.UUID) (product Product, err error)
+	ResolveProductsByIDs(ids []uuid.UUID) (products []Product, err error)
+	ResolveProductIDsByCategoryID(categoryID uuid.UUID) (ids []uuid.UUID, err error)
+	ResolveVariantsByProductIDs(ids []uuid.UUID) (variants []Variant, err error)
+	UpdateProduct(product Product, warehouseID uuid.UUID) (err error)
+	CreateImage(image Image) (created Image, err error)
+	ResolveImagesByProductIDs(ids []uuid.UUID) (images []Image, err error)
+	DeleteImage(image Image) (err error)
+	ApplyStockMovement(movement StockMovement) (err error)
+	ResolveStockMovements(productID uuid.UUID, limit int, offset int) (movements []StockMovement, err error)
+	CountStockMovements(productID uuid.UUID) (total int, err error)
+	ResolveLedgerStock(productID uuid.UUID) (ledger map[uuid.UUID]int, err error)
+	ResolveWarehouseStockByVariantIDs(ids []uuid.UUID) (stocks []WarehouseStock, err error)
 }
 
 type ProductRepositoryMySQL struct {
@@ -86,7 +343,9 @@ func ProvideProductRepositoryMySQL(db *infras.MySQLConn) *ProductRepositoryMySQL
 	return s
 }
 
-func (r *ProductRepositoryMySQL) CreateProduct(product Product) (err error) {
+// CreateProduct creates the product, with the stock of its variants in the
+// warehouse.
+func (r *ProductRepositoryMySQL) CreateProduct(product Product, warehouseID uuid.UUID) (err error) {
 	exists, err := r.ExistsByID(product.ID)
 	if err != nil {
 		logger.ErrorWithStack(err)
@@ -105,75 +364,165 @@ func (r *ProductRepositoryMySQL) CreateProduct(product Product) (err error) {
 			return
 		}
 
+		if err := r.txUpsertVariants(tx, product.Variants); err != nil {
+			e <- err
+			return
+		}
+
+		movements, err := product.stockMovements(nil, warehouseID, StockReasonInitial, product.CreatedBy)
+		if err != nil {
+			e <- err
+			return
+		}
+
+		if err := r.txMoveWarehouseStock(tx, movements); err != nil {
+			e <- err
+			return
+		}
+
+		if err := r.txInsertStockMovements(tx, movements); err != nil {
+			e <- err
+			return
+		}
+
 		e <- nil
 	})
 }
-func (r *ProductRepositoryMySQL) ResolveProductsByQuery(params ProductQueryParams) (products []Product, err error) {
-	query := productQueries.selectProducts
 
-	var args []interface{}
-
-	if params.Category != "" {
-		query += " AND category = ?"
-		args = append(args, params.Category)
-	}
-
-	if params.Brand != "" {
-		query += " AND query = ?"
-		args = append(args, params.Brand)
-	}
+// ResolveProductsByQuery resolves the products that are not deleted and match
+// the filters, after the cursor if any. One product more than the limit is
+// resolved, telling whether another page follows.
+func (r *ProductRepositoryMySQL) ResolveProductsByQuery(params ProductQueryParams) (products []Product, err error) {
+	where, args := composeProductFilter(params)
+	query := productQueries.selectProducts + where
 
-	if params.Sort != "" && params.Order != "" {
-		allowedSorts := map[string]bool{
-			"name":  true,
-			"price": true,
-			"brand": true,
-			"stock": true,
-		}
-		allowedOrders := map[string]bool{
-			"asc":  true,
-			"desc": true,
+	// Sorts and orders are checked against productSorts by the service, so
+	// they are safe to format into the query.
+	if params.Sort != "" {
+		comparison := ">"
+		if params.Order == "desc" {
+			comparison = "<"
 		}
 
-		if allowedSorts[params.Sort] && allowedOrders[params.Order] {
-			query += " ORDER BY " + params.Sort + " " + params.Order
+		if params.after != nil {
+			query += fmt.Sprintf(" AND (%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?))", params.Sort, comparison)
+			args = append(args, params.after.value, params.after.value, params.after.id)
 		}
+
+		query += fmt.Sprintf(" ORDER BY %[1]s %[2]s, id %[2]s", params.Sort, params.Order)
 	}
 
 	if params.Limit > 0 {
 		query += " LIMIT ?"
-		args = append(args, params.Limit)
-
-		if params.Page > 1 {
-			offset := (params.Page - 1) * params.Limit
-			query += " OFFSET ?"
-			args = append(args, offset)
-		}
+		args = append(args, params.Limit+1)
 	}
 
 	err = r.DB.Read.Select(&products, query, args...)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			err = failure.NotFound("courses")
-			logger.ErrorWithStack(err)
-			return nil, err
-		}
-
 		logger.ErrorWithStack(err)
 		return nil, err
 	}
 
+	err = r.attachDetails(products)
+	if err != nil {
+		return nil, err
+	}
+
 	return products, nil
 }
-func (r *ProductRepositoryMySQL) CountAllProducts() (total int, err error) {
-	query := `SELECT COUNT(*) FROM product`
 
-	err = r.DB.Read.QueryRow(query).Scan(&total)
+// CountProducts counts the products that ResolveProductsByQuery lists across
+// all pages.
+func (r *ProductRepositoryMySQL) CountProducts(params ProductQueryParams) (total int, err error) {
+	where, args := composeProductFilter(params)
+
+	err = r.DB.Read.Get(&total, "SELECT COUNT(id) FROM product WHERE 1=1"+where, args...)
 	if err != nil {
 		logger.ErrorWithStack(err)
 	}
 
-	return total, nil
+	return
+}
+
+// ResolveProductFacets counts the products that ResolveProductsByQuery lists
+// across all pages, per brand and per category.
+func (r *ProductRepositoryMySQL) ResolveProductFacets(params ProductQueryParams) (facets ProductFacets, err error) {
+	where, args := composeProductFilter(params)
+	return resolveFacets(r.DB, "1=1"+where, args)
+}
+
+// resolveFacets counts the products matching the condition per brand and per
+// category.
+func resolveFacets(db *infras.MySQLConn, condition string, args []interface{}) (facets ProductFacets, err error) {
+	var counts []struct {
+		Value string `db:"value"`
+		Count int    `db:"count"`
+	}
+
+	err = db.Read.Select(&counts, fmt.Sprintf(facetQuery, "brand", condition), args...)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+
+	facets.Brands = make(map[string]int, len(counts))
+	for _, count := range counts {
+		facets.Brands[count.Value] = count.Count
+	}
+
+	counts = nil
+	err = db.Read.Select(&counts, fmt.Sprintf(facetQuery, "category_id", condition), args...)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+
+	facets.Categories = make(map[uuid.UUID]int, len(counts))
+	for _, count := range counts {
+		id, err := uuid.FromString(count.Value)
+		if err != nil {
+			continue
+		}
+		facets.Categories[id] = count.Count
+	}
+
+	return facets, nil
+}
+
+// composeProductFilter composes the conditions of the product filters, shared
+// by listing, counting and searching products.
+func composeProductFilter(params ProductQueryParams) (where string, args []interface{}) {
+	where = " AND deleted_at IS NULL"
+
+	if len(params.CategoryIDs) > 0 {
+		where += " AND category_id IN (?" + strings.Repeat(", ?", len(params.CategoryIDs)-1) + ")"
+		for _, id := range params.CategoryIDs {
+			args = append(args, id.String())
+		}
+	}
+
+	if len(params.Brands) > 0 {
+		where += " AND brand IN (?" + strings.Repeat(", ?", len(params.Brands)-1) + ")"
+		for _, brand := range params.Brands {
+			args = append(args, brand)
+		}
+	}
+
+	if params.MinPrice.Valid {
+		where += " AND price >= ?"
+		args = append(args, params.MinPrice.Float64)
+	}
+
+	if params.MaxPrice.Valid {
+		where += " AND price <= ?"
+		args = append(args, params.MaxPrice.Float64)
+	}
+
+	if params.InStock {
+		where += " AND stock > 0"
+	}
+
+	return
 }
 func (r *ProductRepositoryMySQL) ExistsByID(id uuid.UUID) (exists bool, err error) {
 	err = r.DB.Read.Get(
@@ -188,22 +537,327 @@ func (r *ProductRepositoryMySQL) ExistsByID(id uuid.UUID) (exists bool, err erro
 	return
 }
 func (r *ProductRepositoryMySQL) ResolveProductByID(id uuid.UUID) (product Product, err error) {
-	query := `SELECT * FROM product`
-
 	err = r.DB.Read.Get(
 		&product,
-		query+" WHERE id = ?",
+		productQueries.selectProducts+" AND id = ?",
 		id.String())
 
 	if err != nil && err == sql.ErrNoRows {
 		err = failure.NotFound("product")
 		logger.ErrorWithStack(err)
 		return
+	} else if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+
+	products := []Product{product}
+	err = r.attachDetails(products)
+	if err != nil {
+		return
+	}
+
+	return products[0], nil
+}
+
+// ResolveProductsByIDs resolves the products that are not deleted, in the
+// order of the IDs.
+func (r *ProductRepositoryMySQL) ResolveProductsByIDs(ids []uuid.UUID) (products []Product, err error) {
+	if len(ids) == 0 {
+		return
+	}
+
+	query, args, err := sqlx.In(productQueries.selectProducts+" AND deleted_at IS NULL AND id IN (?)", ids)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+
+	var resolved []Product
+	err = r.DB.Read.Select(&resolved, query, args...)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+
+	byID := make(map[uuid.UUID]Product, len(resolved))
+	for _, product := range resolved {
+		byID[product.ID] = product
 	}
 
+	for _, id := range ids {
+		if product, ok := byID[id]; ok {
+			products = append(products, product)
+		}
+	}
+
+	err = r.attachDetails(products)
+
 	return
 }
 
+// ResolveProductIDsByCategoryID resolves the IDs of the products in the
+// category that are not deleted.
+func (r *ProductRepositoryMySQL) ResolveProductIDsByCategoryID(categoryID uuid.UUID) (ids []uuid.UUID, err error) {
+	err = r.DB.Read.Select(&ids, "SELECT id FROM product WHERE category_id = ? AND deleted_at IS NULL", categoryID.String())
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+
+	return
+}
+
+// ResolveVariantsByProductIDs resolves the variants of the products, deleted
+// ones included, in the order they were created.
+func (r *ProductRepositoryMySQL) ResolveVariantsByProductIDs(ids []uuid.UUID) (variants []Variant, err error) {
+	if len(ids) == 0 {
+		return
+	}
+
+	query, args, err := sqlx.In(productQueries.selectVariants+" WHERE product_id IN (?) ORDER BY created_at, sku", ids)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+
+	err = r.DB.Read.Select(&variants, query, args...)
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+
+	return
+}
+
+// attachDetails attaches the variants, their stock in each warehouse and the
+// images of the products.
+func (r *ProductRepositoryMySQL) attachDetails(products []Product) (err error) {
+	ids := make([]uuid.UUID, 0, len(products))
+	for _, product := range products {
+		ids = append(ids, product.ID)
+	}
+
+	variants, err := r.ResolveVariantsByProductIDs(ids)
+	if err != nil {
+		return
+	}
+
+	stocks, err := r.resolveWarehouseStock("product_id", ids)
+	if err != nil {
+		return
+	}
+
+	images, err := r.ResolveImagesByProductIDs(ids)
+	if err != nil {
+		return
+	}
+
+	for i := range products {
+		products[i].AttachVariants(variants)
+		products[i].AttachWarehouseStock(stocks)
+		products[i].AttachImages(images)
+	}
+
+	return
+}
+
+// ResolveWarehouseStockByVariantIDs resolves the stock of the variants in
+// each warehouse that is not deleted.
+func (r *ProductRepositoryMySQL) ResolveWarehouseStockByVariantIDs(ids []uuid.UUID) (stocks []WarehouseStock, err error) {
+	return r.resolveWarehouseStock("variant_id", ids)
+}
+
+// resolveWarehouseStock resolves the warehouse stock of which the column, a
+// constant, is one of the IDs, ordered by warehouse.
+func (r *ProductRepositoryMySQL) resolveWarehouseStock(column string, ids []uuid.UUID) (stocks []WarehouseStock, err error) {
+	if len(ids) == 0 {
+		return
+	}
+
+	query, args, err := sqlx.In(productQueries.selectWarehouseStock+" AND warehouse_stock."+column+" IN (?) ORDER BY warehouse.code", ids)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+
+	err = r.DB.Read.Select(&stocks, query, args...)
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+
+	return
+}
+
+// CreateImage creates an image positioned after the product's other images.
+func (r *ProductRepositoryMySQL) CreateImage(image Image) (created Image, err error) {
+	err = r.DB.WithTransaction(func(tx *sqlx.Tx, e chan error) {
+		// Locking the product serializes concurrent uploads, so that their
+		// positions do not collide.
+		var productID string
+		if err := tx.Get(&productID, "SELECT id FROM product WHERE id = ? FOR UPDATE", image.ProductID.String()); err != nil {
+			logger.ErrorWithStack(err)
+			e <- err
+			return
+		}
+
+		if err := tx.Get(&image.Position, "SELECT COALESCE(MAX(position), 0) + 1 FROM product_image WHERE product_id = ?", image.ProductID.String()); err != nil {
+			logger.ErrorWithStack(err)
+			e <- err
+			return
+		}
+
+		if err := r.txExecImage(tx, productQueries.insertImage, image); err != nil {
+			e <- err
+			return
+		}
+
+		e <- nil
+	})
+
+	return image, err
+}
+
+// ResolveImagesByProductIDs resolves the images of the products that are not
+// deleted, in the order of their position.
+func (r *ProductRepositoryMySQL) ResolveImagesByProductIDs(ids []uuid.UUID) (images []Image, err error) {
+	if len(ids) == 0 {
+		return
+	}
+
+	query, args, err := sqlx.In(productQueries.selectImages+" WHERE product_id IN (?) AND deleted_at IS NULL ORDER BY position", ids)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+
+	err = r.DB.Read.Select(&images, query, args...)
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+
+	return
+}
+func (r *ProductRepositoryMySQL) DeleteImage(image Image) (err error) {
+	return r.DB.WithTransaction(func(tx *sqlx.Tx, e chan error) {
+		if err := r.txExecImage(tx, productQueries.deleteImage, image); err != nil {
+			e <- err
+			return
+		}
+
+		e <- nil
+	})
+}
+
+// ApplyStockMovement moves the stock of the variant and of its product, and
+// records the movement.
+func (r *ProductRepositoryMySQL) ApplyStockMovement(movement StockMovement) (err error) {
+	return r.DB.WithTransaction(func(tx *sqlx.Tx, e chan error) {
+		if err := ApplyStockMovementTx(tx, movement); err != nil {
+			e <- err
+			return
+		}
+
+		e <- nil
+	})
+}
+
+// ResolveStockMovements resolves a page of the stock movements of the
+// product, newest first.
+func (r *ProductRepositoryMySQL) ResolveStockMovements(productID uuid.UUID, limit int, offset int) (movements []StockMovement, err error) {
+	err = r.DB.Read.Select(
+		&movements,
+		productQueries.selectStockMovements+" WHERE stock_movement.product_id = ? ORDER BY stock_movement.created_at DESC, stock_movement.id DESC LIMIT ? OFFSET ?",
+		productID.String(), limit, offset)
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+
+	return
+}
+func (r *ProductRepositoryMySQL) CountStockMovements(productID uuid.UUID) (total int, err error) {
+	err = r.DB.Read.Get(&total, "SELECT COUNT(id) FROM stock_movement WHERE product_id = ?", productID.String())
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+
+	return
+}
+
+// ResolveLedgerStock resolves the stock of the product's variants according
+// to the ledger, by variant ID.
+func (r *ProductRepositoryMySQL) ResolveLedgerStock(productID uuid.UUID) (ledger map[uuid.UUID]int, err error) {
+	var sums []struct {
+		VariantID uuid.UUID `db:"variant_id"`
+		Stock     int       `db:"stock"`
+	}
+
+	err = r.DB.Read.Select(&sums, productQueries.sumStockMovements, productID.String())
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+
+	ledger = make(map[uuid.UUID]int, len(sums))
+	for _, sum := range sums {
+		ledger[sum.VariantID] = sum.Stock
+	}
+
+	return
+}
+
+// UpdateProduct updates the product, moving the stock of its variants in the
+// warehouse by how much their stock changed.
+func (r *ProductRepositoryMySQL) UpdateProduct(product Product, warehouseID uuid.UUID) (err error) {
+	exists, err := r.ExistsByID(product.ID)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+
+	if !exists {
+		err = failure.NotFound("product")
+		logger.ErrorWithStack(err)
+		return
+	}
+
+	return r.DB.WithTransaction(func(tx *sqlx.Tx, e chan error) {
+		// Locking the variants keeps the stock from moving between reading it
+		// and replacing it, so that the movements add up.
+		current, err := r.txLockVariantStock(tx, product.ID)
+		if err != nil {
+			e <- err
+			return
+		}
+
+		movements, err := product.stockMovements(current, warehouseID, StockReasonManual, product.UpdatedBy.UUID)
+		if err != nil {
+			e <- err
+			return
+		}
+
+		if err := r.txUpdate(tx, product); err != nil {
+			e <- err
+			return
+		}
+
+		if err := r.txUpsertVariants(tx, product.Variants); err != nil {
+			e <- err
+			return
+		}
+
+		if err := r.txMoveWarehouseStock(tx, movements); err != nil {
+			e <- err
+			return
+		}
+
+		if err := r.txInsertStockMovements(tx, movements); err != nil {
+			e <- err
+			return
+		}
+
+		e <- nil
+	})
+}
+
 // Transactions
 func (r *ProductRepositoryMySQL) txCreate(tx *sqlx.Tx, product Product) (err error) {
 	stmt, err := tx.PrepareNamed(productQueries.insertProduct)
@@ -220,3 +874,169 @@ func (r *ProductRepositoryMySQL) txCreate(tx *sqlx.Tx, product Product) (err err
 
 	return
 }
+func (r *ProductRepositoryMySQL) txUpdate(tx *sqlx.Tx, product Product) (err error) {
+	stmt, err := tx.PrepareNamed(productQueries.updateProduct)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(product)
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+
+	return
+}
+
+func (r *ProductRepositoryMySQL) txUpsertVariants(tx *sqlx.Tx, variants []Variant) (err error) {
+	if len(variants) == 0 {
+		return
+	}
+
+	values := []string{}
+	var args []interface{}
+	for _, variant := range variants {
+		q, variantArgs, err := sqlx.Named(upsertVariantPlaceholder, variant)
+		if err != nil {
+			logger.ErrorWithStack(err)
+			return err
+		}
+		values = append(values, q)
+		args = append(args, variantArgs...)
+	}
+
+	_, err = tx.Exec(fmt.Sprintf(productQueries.upsertVariants, strings.Join(values, ",")), args...)
+	if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == mysqlErrDuplicateEntry {
+		return failure.Conflict("create", "sku", "already used by another product")
+	}
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+
+	return
+}
+func (r *ProductRepositoryMySQL) txExecImage(tx *sqlx.Tx, query string, image Image) (err error) {
+	stmt, err := tx.PrepareNamed(query)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(image)
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+
+	return
+}
+
+// txLockVariantStock locks the variants of the product, resolving their
+// stock by variant ID.
+func (r *ProductRepositoryMySQL) txLockVariantStock(tx *sqlx.Tx, productID uuid.UUID) (current map[uuid.UUID]int, err error) {
+	var stocks []struct {
+		ID    uuid.UUID `db:"id"`
+		Stock int       `db:"stock"`
+	}
+
+	err = tx.Select(&stocks, productQueries.lockVariantStock, productID.String())
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+
+	current = make(map[uuid.UUID]int, len(stocks))
+	for _, stock := range stocks {
+		current[stock.ID] = stock.Stock
+	}
+
+	return
+}
+
+// txMoveWarehouseStock moves the warehouse stock of movements of which the
+// stock of the variants and products was already moved.
+func (r *ProductRepositoryMySQL) txMoveWarehouseStock(tx *sqlx.Tx, movements []StockMovement) (err error) {
+	for _, movement := range movements {
+		err = moveWarehouseStockTx(tx, movement)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// txInsertStockMovements records movements of which the stock was already
+// moved.
+func (r *ProductRepositoryMySQL) txInsertStockMovements(tx *sqlx.Tx, movements []StockMovement) (err error) {
+	for _, movement := range movements {
+		_, err = tx.NamedExec(productQueries.insertStockMovement, movement)
+		if err != nil {
+			logger.ErrorWithStack(err)
+			return
+		}
+	}
+
+	return
+}
+
+// ApplyStockMovementTx moves the stock of the variant in the warehouse, of the
+// variant and of its product, and records the movement, within a transaction
+// of any domain. Taking more stock than is left in the warehouse fails with a
+// conflict. Rows are locked in the order product updates lock them: variant,
+// product, then warehouse stock.
+func ApplyStockMovementTx(tx *sqlx.Tx, movement StockMovement) (err error) {
+	_, err = tx.NamedExec(productQueries.moveVariantStock, movement)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+
+	_, err = tx.NamedExec(productQueries.moveProductStock, movement)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+
+	err = moveWarehouseStockTx(tx, movement)
+	if err != nil {
+		return
+	}
+
+	_, err = tx.NamedExec(productQueries.insertStockMovement, movement)
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+
+	return
+}
+
+// moveWarehouseStockTx moves the stock of the variant in the warehouse. Taking
+// more stock than is left in the warehouse fails with a conflict.
+func moveWarehouseStockTx(tx *sqlx.Tx, movement StockMovement) (err error) {
+	_, err = tx.NamedExec(productQueries.ensureWarehouseStock, movement)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+
+	result, err := tx.NamedExec(productQueries.moveWarehouseStock, movement)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+
+	moved, err := result.RowsAffected()
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+
+	if moved == 0 {
+		return failure.Conflict("move", "stock", "not enough stock left in the warehouse")
+	}
+
+	return
+}
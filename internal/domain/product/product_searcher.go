@@ -0,0 +1,442 @@
+package product
+
+import (
+	"os"
+	"strings"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/keyword"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/search/query"
+	"github.com/evermos/boilerplate-go/configs"
+	"github.com/evermos/boilerplate-go/infras"
+	"github.com/evermos/boilerplate-go/shared/logger"
+	"github.com/gofrs/uuid"
+	"github.com/guregu/null"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// SearcherMySQLDriver searches products with a FULLTEXT index.
+	SearcherMySQLDriver = "mysql"
+	// SearcherBleveDriver searches products with an index embedded in each
+	// instance, for single instances.
+	SearcherBleveDriver = "bleve"
+
+	// defaultBlevePath is the path of the Bleve index, when none is
+	// configured.
+	defaultBlevePath = "data/products.bleve"
+
+	// searchMappingVersion is the version of the Bleve index mapping. Indexes
+	// created with another version are created again.
+	searchMappingVersion = "2"
+	// maxFacets is the largest number of brands and of categories counted
+	// by the Bleve index.
+	maxFacets = 1000
+)
+
+// searchMappingVersionKey is the internal key of the Bleve index holding the
+// version of its mapping.
+var searchMappingVersionKey = []byte("mappingVersion")
+
+var (
+	searchQueries = struct {
+		searchProducts string
+		countProducts  string
+		matchProducts  string
+	}{
+		// The FULLTEXT index uses the ngram parser, so that misspelled words
+		// still share most of their bigrams with the right ones.
+		searchProducts: `
+			SELECT
+				id
+			FROM product
+			WHERE MATCH(name, brand, category) AGAINST (? IN NATURAL LANGUAGE MODE)
+		`,
+
+		countProducts: `
+			SELECT
+				COUNT(id)
+			FROM product
+			WHERE MATCH(name, brand, category) AGAINST (? IN NATURAL LANGUAGE MODE)
+		`,
+
+		matchProducts: `MATCH(name, brand, category) AGAINST (? IN NATURAL LANGUAGE MODE)`,
+	}
+)
+
+// Searcher searches products by keywords over their name, brand and category,
+// tolerating typos. Products are kept in sync by indexing them when written.
+type Searcher interface {
+	// Search resolves the IDs of the products matching params.Query and its
+	// brand and category filters, most relevant first, along with how many
+	// products match.
+	Search(params ProductQueryParams) (ids []uuid.UUID, total int, err error)
+	// Facets counts the products Search matches across all pages, per brand
+	// and per category.
+	Facets(params ProductQueryParams) (facets ProductFacets, err error)
+	Index(product Product) (err error)
+	Remove(id uuid.UUID) (err error)
+}
+
+// ProvideSearcher is the provider for the Searcher selected by Search.Driver.
+func ProvideSearcher(db *infras.MySQLConn, productRepository ProductRepository, config *configs.Config) Searcher {
+	switch config.Search.Driver {
+	case SearcherMySQLDriver, "":
+		return &SearcherMySQL{DB: db}
+	case SearcherBleveDriver:
+		path := config.Search.Bleve.Path
+		if path == "" {
+			path = defaultBlevePath
+		}
+
+		searcher, created, err := NewSearcherBleve(path)
+		if err != nil {
+			log.Fatal().Err(err).Str("path", path).Msg("Failed opening the product search index.")
+		}
+
+		if created {
+			err = searcher.Reindex(productRepository)
+			if err != nil {
+				log.Error().Err(err).Msg("Failed indexing the products.")
+			}
+		}
+
+		return searcher
+	default:
+		log.Fatal().Str("driver", config.Search.Driver).Msg("Unknown search driver.")
+		return nil
+	}
+}
+
+// SearcherMySQL searches the product table through its FULLTEXT index, which
+// MySQL keeps in sync with the table.
+type SearcherMySQL struct {
+	DB *infras.MySQLConn
+}
+
+func (s *SearcherMySQL) Search(params ProductQueryParams) (ids []uuid.UUID, total int, err error) {
+	where, filterArgs := composeProductFilter(params)
+	args := append([]interface{}{params.Query}, filterArgs...)
+
+	err = s.DB.Read.Get(&total, searchQueries.countProducts+where, args...)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+
+	// Natural language mode orders the matches by relevance.
+	query := searchQueries.searchProducts + where
+	if params.Limit > 0 {
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, params.Limit, params.offset())
+	}
+
+	err = s.DB.Read.Select(&ids, query, args...)
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+
+	return
+}
+
+func (s *SearcherMySQL) Facets(params ProductQueryParams) (facets ProductFacets, err error) {
+	where, filterArgs := composeProductFilter(params)
+	args := append([]interface{}{params.Query}, filterArgs...)
+
+	return resolveFacets(s.DB, searchQueries.matchProducts+where, args)
+}
+
+// Index does nothing, as MySQL updates the FULLTEXT index itself.
+func (s *SearcherMySQL) Index(product Product) (err error) {
+	return nil
+}
+
+// Remove does nothing, as deleted products are left out of searches.
+func (s *SearcherMySQL) Remove(id uuid.UUID) (err error) {
+	return nil
+}
+
+// searchDocument is what the Bleve index stores of a product. Brand is also
+// indexed whole, lowercased to filter on regardless of case like MySQL does,
+// and as is to count products per brand. Category ID is indexed to filter and
+// count on; price and stock are indexed to filter on ranges.
+type searchDocument struct {
+	Name         string  `json:"name"`
+	Brand        string  `json:"brand"`
+	Category     string  `json:"category"`
+	BrandKeyword string  `json:"brandKeyword"`
+	BrandFacet   string  `json:"brandFacet"`
+	CategoryID   string  `json:"categoryID"`
+	Price        float64 `json:"price"`
+	Stock        float64 `json:"stock"`
+}
+
+// searchFields are the fields keywords are searched in, with the boost of
+// their matches.
+var searchFields = []struct {
+	name  string
+	boost float64
+}{
+	{name: "name", boost: 3},
+	{name: "brand", boost: 2},
+	{name: "category", boost: 1},
+}
+
+// SearcherBleve searches an embedded Bleve index. Every instance keeps its own
+// index, so it only suits single instances.
+type SearcherBleve struct {
+	index bleve.Index
+}
+
+// NewSearcherBleve opens the Bleve index at path, creating it when it does not
+// exist yet or was created with another mapping.
+func NewSearcherBleve(path string) (searcher *SearcherBleve, created bool, err error) {
+	index, err := bleve.Open(path)
+	if err == nil && !hasSearchMapping(index) {
+		log.Info().Str("path", path).Msg("Recreating the product search index with a new mapping.")
+		index.Close()
+		err = os.RemoveAll(path)
+		if err != nil {
+			return nil, false, err
+		}
+		err = bleve.ErrorIndexPathDoesNotExist
+	}
+	if err == bleve.ErrorIndexPathDoesNotExist {
+		index, err = newBleveIndex(path)
+		created = true
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &SearcherBleve{index: index}, created, nil
+}
+
+// hasSearchMapping checks whether the index was created with the current
+// mapping.
+func hasSearchMapping(index bleve.Index) bool {
+	version, err := index.GetInternal(searchMappingVersionKey)
+	return err == nil && string(version) == searchMappingVersion
+}
+
+func newBleveIndex(path string) (index bleve.Index, err error) {
+	index, err = bleve.New(path, newSearchMapping())
+	if err != nil {
+		return
+	}
+
+	err = index.SetInternal(searchMappingVersionKey, []byte(searchMappingVersion))
+
+	return
+}
+
+// NewSearcherBleveInMemory creates an empty Bleve index held in memory.
+func NewSearcherBleveInMemory() (searcher *SearcherBleve, err error) {
+	index, err := bleve.NewMemOnly(newSearchMapping())
+	if err != nil {
+		return
+	}
+
+	return &SearcherBleve{index: index}, nil
+}
+
+func newSearchMapping() *mapping.IndexMappingImpl {
+	text := bleve.NewTextFieldMapping()
+
+	whole := bleve.NewTextFieldMapping()
+	whole.Analyzer = keyword.Name
+
+	numeric := bleve.NewNumericFieldMapping()
+
+	document := bleve.NewDocumentMapping()
+	document.AddFieldMappingsAt("name", text)
+	document.AddFieldMappingsAt("brand", text)
+	document.AddFieldMappingsAt("category", text)
+	document.AddFieldMappingsAt("brandKeyword", whole)
+	document.AddFieldMappingsAt("brandFacet", whole)
+	document.AddFieldMappingsAt("categoryID", whole)
+	document.AddFieldMappingsAt("price", numeric)
+	document.AddFieldMappingsAt("stock", numeric)
+
+	indexMapping := bleve.NewIndexMapping()
+	indexMapping.DefaultMapping = document
+
+	return indexMapping
+}
+
+// Search ranks exact matches and matches in the name higher.
+func (s *SearcherBleve) Search(params ProductQueryParams) (ids []uuid.UUID, total int, err error) {
+	size := params.Limit
+	if size <= 0 {
+		size = int(^uint(0) >> 1)
+	}
+
+	result, err := s.index.Search(bleve.NewSearchRequestOptions(composeSearchQuery(params), size, params.offset(), false))
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+
+	for _, hit := range result.Hits {
+		id, err := uuid.FromString(hit.ID)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, int(result.Total), nil
+}
+
+func (s *SearcherBleve) Facets(params ProductQueryParams) (facets ProductFacets, err error) {
+	request := bleve.NewSearchRequestOptions(composeSearchQuery(params), 0, 0, false)
+	request.AddFacet("brand", bleve.NewFacetRequest("brandFacet", maxFacets))
+	request.AddFacet("category", bleve.NewFacetRequest("categoryID", maxFacets))
+
+	result, err := s.index.Search(request)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+
+	facets = ProductFacets{
+		Brands:     make(map[string]int),
+		Categories: make(map[uuid.UUID]int),
+	}
+
+	for _, term := range result.Facets["brand"].Terms {
+		facets.Brands[term.Term] = term.Count
+	}
+
+	for _, term := range result.Facets["category"].Terms {
+		id, err := uuid.FromString(term.Term)
+		if err != nil {
+			continue
+		}
+		facets.Categories[id] = term.Count
+	}
+
+	return facets, nil
+}
+
+// composeSearchQuery matches every keyword exactly and, depending on its
+// length, with one or two typos, along with the filters.
+func composeSearchQuery(params ProductQueryParams) query.Query {
+	keywords := bleve.NewDisjunctionQuery()
+	for _, term := range strings.Fields(strings.ToLower(params.Query)) {
+		for _, field := range searchFields {
+			exact := bleve.NewMatchQuery(term)
+			exact.SetField(field.name)
+			exact.SetBoost(field.boost * 2)
+			keywords.AddQuery(exact)
+
+			if fuzziness := typoTolerance(term); fuzziness > 0 {
+				fuzzy := bleve.NewFuzzyQuery(term)
+				fuzzy.SetField(field.name)
+				fuzzy.SetFuzziness(fuzziness)
+				fuzzy.SetBoost(field.boost)
+				keywords.AddQuery(fuzzy)
+			}
+		}
+	}
+
+	conjuncts := []query.Query{keywords}
+	if len(params.CategoryIDs) > 0 {
+		ids := make([]string, 0, len(params.CategoryIDs))
+		for _, id := range params.CategoryIDs {
+			ids = append(ids, id.String())
+		}
+		conjuncts = append(conjuncts, anyTerm("categoryID", ids))
+	}
+
+	if len(params.Brands) > 0 {
+		conjuncts = append(conjuncts, anyTerm("brandKeyword", params.Brands))
+	}
+
+	if params.MinPrice.Valid || params.MaxPrice.Valid {
+		conjuncts = append(conjuncts, numericRange("price", params.MinPrice, params.MaxPrice))
+	}
+
+	if params.InStock {
+		conjuncts = append(conjuncts, numericRange("stock", null.FloatFrom(1), null.Float{}))
+	}
+
+	return bleve.NewConjunctionQuery(conjuncts...)
+}
+
+func (s *SearcherBleve) Index(product Product) (err error) {
+	return s.index.Index(product.ID.String(), newSearchDocument(product))
+}
+
+func (s *SearcherBleve) Remove(id uuid.UUID) (err error) {
+	return s.index.Delete(id.String())
+}
+
+// Reindex indexes every product that is not deleted.
+func (s *SearcherBleve) Reindex(productRepository ProductRepository) (err error) {
+	products, err := productRepository.ResolveProductsByQuery(ProductQueryParams{})
+	if err != nil {
+		return
+	}
+
+	batch := s.index.NewBatch()
+	for _, product := range products {
+		err = batch.Index(product.ID.String(), newSearchDocument(product))
+		if err != nil {
+			return
+		}
+	}
+
+	log.Info().Int("products", len(products)).Msg("Indexing products for search.")
+
+	return s.index.Batch(batch)
+}
+
+// anyTerm matches documents of which the field is any of the values.
+func anyTerm(field string, values []string) query.Query {
+	terms := bleve.NewDisjunctionQuery()
+	for _, value := range values {
+		term := bleve.NewTermQuery(strings.ToLower(value))
+		term.SetField(field)
+		terms.AddQuery(term)
+	}
+
+	return terms
+}
+
+// numericRange matches documents of which the field is within the inclusive
+// range, open where a bound is null.
+func numericRange(field string, min null.Float, max null.Float) query.Query {
+	inclusive := true
+	numeric := bleve.NewNumericRangeInclusiveQuery(min.Ptr(), max.Ptr(), &inclusive, &inclusive)
+	numeric.SetField(field)
+
+	return numeric
+}
+
+func newSearchDocument(product Product) searchDocument {
+	return searchDocument{
+		Name:         product.Name,
+		Brand:        product.Brand,
+		Category:     product.Category,
+		BrandKeyword: strings.ToLower(product.Brand),
+		BrandFacet:   product.Brand,
+		CategoryID:   product.CategoryID.String(),
+		Price:        product.Price,
+		Stock:        float64(product.Stock),
+	}
+}
+
+// typoTolerance is how many typos a keyword may have: none for short ones,
+// which would match too many words otherwise.
+func typoTolerance(term string) int {
+	switch length := len([]rune(term)); {
+	case length <= 2:
+		return 0
+	case length <= 5:
+		return 1
+	default:
+		return 2
+	}
+}
@@ -0,0 +1,92 @@
+package product_test
+
+import (
+	"testing"
+
+	"github.com/evermos/boilerplate-go/internal/domain/product"
+	"github.com/gofrs/uuid"
+	"github.com/guregu/null"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearcherBleve(t *testing.T) {
+	searcher, err := product.NewSearcherBleveInMemory()
+	require.NoError(t, err)
+
+	electronics, computers := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+	products := []product.Product{
+		{ID: uuid.Must(uuid.NewV4()), Name: "iPhone 13", Brand: "Apple", CategoryID: electronics, Category: "Electronics", Price: 799, Stock: 5},
+		{ID: uuid.Must(uuid.NewV4()), Name: "Galaxy S21", Brand: "Samsung", CategoryID: electronics, Category: "Electronics", Price: 699, Stock: 0},
+		{ID: uuid.Must(uuid.NewV4()), Name: "Macbook Pro", Brand: "Apple", CategoryID: computers, Category: "Computers", Price: 1299, Stock: 3},
+	}
+	for _, p := range products {
+		require.NoError(t, searcher.Index(p))
+	}
+
+	t.Run("Tolerates Typos", func(t *testing.T) {
+		ids, total, err := searcher.Search(product.ProductQueryParams{Query: "iphnoe"})
+		require.NoError(t, err)
+
+		assert.Equal(t, 1, total)
+		assert.Equal(t, []uuid.UUID{products[0].ID}, ids)
+	})
+
+	t.Run("Ranks Name Matches First", func(t *testing.T) {
+		ids, _, err := searcher.Search(product.ProductQueryParams{Query: "apple macbook"})
+		require.NoError(t, err)
+
+		require.Len(t, ids, 2)
+		assert.Equal(t, products[2].ID, ids[0])
+	})
+
+	t.Run("Filters By Brands", func(t *testing.T) {
+		ids, total, err := searcher.Search(product.ProductQueryParams{Query: "electronics", Brands: []string{"samsung", "Xiaomi"}})
+		require.NoError(t, err)
+
+		assert.Equal(t, 1, total)
+		assert.Equal(t, []uuid.UUID{products[1].ID}, ids)
+	})
+
+	t.Run("Filters By Categories", func(t *testing.T) {
+		ids, total, err := searcher.Search(product.ProductQueryParams{Query: "apple", CategoryIDs: []uuid.UUID{computers}})
+		require.NoError(t, err)
+
+		assert.Equal(t, 1, total)
+		assert.Equal(t, []uuid.UUID{products[2].ID}, ids)
+	})
+
+	t.Run("Counts Facets", func(t *testing.T) {
+		facets, err := searcher.Facets(product.ProductQueryParams{Query: "apple samsung"})
+		require.NoError(t, err)
+
+		assert.Equal(t, map[string]int{"Apple": 2, "Samsung": 1}, facets.Brands)
+		assert.Equal(t, map[uuid.UUID]int{electronics: 2, computers: 1}, facets.Categories)
+	})
+
+	t.Run("Filters By Price And Stock", func(t *testing.T) {
+		ids, _, err := searcher.Search(product.ProductQueryParams{Query: "apple", MaxPrice: null.FloatFrom(799)})
+		require.NoError(t, err)
+		assert.Equal(t, []uuid.UUID{products[0].ID}, ids)
+
+		ids, _, err = searcher.Search(product.ProductQueryParams{Query: "electronics", InStock: true})
+		require.NoError(t, err)
+		assert.Equal(t, []uuid.UUID{products[0].ID}, ids)
+	})
+
+	t.Run("Paginates", func(t *testing.T) {
+		ids, total, err := searcher.Search(product.ProductQueryParams{Query: "apple", Limit: 1, Page: 2})
+		require.NoError(t, err)
+
+		assert.Equal(t, 2, total)
+		assert.Len(t, ids, 1)
+	})
+
+	t.Run("Removes Products", func(t *testing.T) {
+		require.NoError(t, searcher.Remove(products[1].ID))
+
+		_, total, err := searcher.Search(product.ProductQueryParams{Query: "galaxy"})
+		require.NoError(t, err)
+		assert.Equal(t, 0, total)
+	})
+}
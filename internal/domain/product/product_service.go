@@ -1,75 +1,178 @@
 package product
 
 import (
-	"math"
+	"fmt"
+	"net/http"
 
 	"github.com/evermos/boilerplate-go/configs"
+	"github.com/evermos/boilerplate-go/internal/domain/category"
+	"github.com/evermos/boilerplate-go/internal/domain/warehouse"
+	"github.com/evermos/boilerplate-go/shared"
+	"github.com/evermos/boilerplate-go/shared/blobstore"
 	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/imaging"
+	"github.com/evermos/boilerplate-go/shared/logger"
+	"github.com/evermos/boilerplate-go/shared/rbac"
 	"github.com/gofrs/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// defaultImageMaxSizeBytes is the size limit of uploaded images, when
+	// none is configured.
+	defaultImageMaxSizeBytes = 10 << 20
+	// defaultImageMaxPixels is the limit of the pixels of uploaded images,
+	// when none is configured.
+	defaultImageMaxPixels = 40000000
+	// defaultThumbnailSize is the size thumbnails fit in, when none is
+	// configured.
+	defaultThumbnailSize = 320
 )
 
 type ProductService interface {
 	CreateProduct(requestFormat ProductRequestFormat, userID uuid.UUID) (product Product, err error)
-	GetProducts(params ProductQueryParams) (products []Product, total int, err error)
-	CreatePaginationResponse(products []Product, total int, limit int, page int) (productPagination ProductPagination, err error)
+	GetProducts(params ProductQueryParams) (page shared.Page, err error)
 	ResolveByID(id uuid.UUID) (product Product, err error)
+	UpdateProduct(id uuid.UUID, requestFormat ProductRequestFormat, userID uuid.UUID, role rbac.Role) (product Product, err error)
+	SoftDeleteProduct(id uuid.UUID, userID uuid.UUID, role rbac.Role) (product Product, err error)
+	AddImage(id uuid.UUID, content []byte, userID uuid.UUID, role rbac.Role) (product Product, err error)
+	DeleteImage(id uuid.UUID, imageID uuid.UUID, userID uuid.UUID, role rbac.Role) (product Product, err error)
+	MaxImageSize() int64
+	AdjustStock(id uuid.UUID, requestFormat StockMovementRequestFormat, userID uuid.UUID, role rbac.Role) (product Product, err error)
+	ResolveStockHistory(id uuid.UUID, page int, limit int, userID uuid.UUID, role rbac.Role) (history StockHistory, err error)
+	ResolveWarehouseStock(variantIDs []uuid.UUID) (stocks []WarehouseStock, err error)
+	IndexProducts(ids []uuid.UUID)
 }
 
 type ProductServiceImpl struct {
 	ProductRepository ProductRepository
+	Searcher          Searcher
+	CategoryService   category.CategoryService
+	WarehouseService  warehouse.WarehouseService
+	BlobStore         blobstore.BlobStore
 	Config            *configs.Config
 }
 
-func ProvideProductServiceImpl(productRepository ProductRepository, config *configs.Config) *ProductServiceImpl {
+func ProvideProductServiceImpl(productRepository ProductRepository, searcher Searcher, categoryService category.CategoryService, warehouseService warehouse.WarehouseService, blobStore blobstore.BlobStore, config *configs.Config) *ProductServiceImpl {
 	s := new(ProductServiceImpl)
 	s.ProductRepository = productRepository
+	s.Searcher = searcher
+	s.CategoryService = categoryService
+	s.WarehouseService = warehouseService
+	s.BlobStore = blobStore
 	s.Config = config
 
+	categoryService.OnRenamed(s.indexCategoryProducts)
+
 	return s
 }
 
+// CreateProduct creates a product, with its stock in the default warehouse.
 func (s *ProductServiceImpl) CreateProduct(requestFormat ProductRequestFormat, userID uuid.UUID) (product Product, err error) {
-	product, err = product.NewProductFromRequestFormat(requestFormat, userID)
+	category, err := s.resolveCategory(requestFormat.CategoryID)
+	if err != nil {
+		return
+	}
+
+	defaultWarehouse, err := s.WarehouseService.ResolveDefault()
 	if err != nil {
 		return
 	}
 
+	product, err = product.NewProductFromRequestFormat(requestFormat, category, userID)
 	if err != nil {
 		return product, failure.BadRequest(err)
 	}
 
-	err = s.ProductRepository.CreateProduct(product)
+	err = s.ProductRepository.CreateProduct(product, defaultWarehouse.ID)
+	if err != nil {
+		return
+	}
+
+	product, err = s.ResolveByID(product.ID)
 	if err != nil {
 		return
 	}
 
+	s.index(product)
+
 	return
 }
 
-func (s *ProductServiceImpl) GetProducts(params ProductQueryParams) (products []Product, total int, err error) {
-	products, err = s.ProductRepository.ResolveProductsByQuery(params)
+// GetProducts resolves a page of products, along with the number of matching
+// products per brand and category. Products are listed by cursor, while
+// products searched by keywords are ordered by relevance and paged by number.
+func (s *ProductServiceImpl) GetProducts(params ProductQueryParams) (page shared.Page, err error) {
+	categories, err := s.CategoryService.ResolveCategories()
 	if err != nil {
 		return
 	}
 
-	total, err = s.ProductRepository.CountAllProducts()
+	err = params.resolveCategories(categories)
+	if err != nil {
+		return page, failure.BadRequest(err)
+	}
+
+	if params.Query != "" {
+		return s.searchProducts(params, categories)
+	}
+
+	err = params.normalize()
+	if err != nil {
+		return page, failure.BadRequest(err)
+	}
+
+	products, err := s.ProductRepository.ResolveProductsByQuery(params)
+	if err != nil {
+		return
+	}
+
+	total, err := s.ProductRepository.CountProducts(params)
 	if err != nil {
 		return
 	}
 
+	facets, err := s.ProductRepository.ResolveProductFacets(params)
+	if err != nil {
+		return
+	}
+
+	var nextCursor string
+	if params.Limit > 0 && len(products) > params.Limit {
+		products = products[:params.Limit]
+		nextCursor = params.nextCursor(products[len(products)-1])
+	}
+
+	page = shared.NewPage(products, total, params.Limit)
+	page.NextCursor = nextCursor
+	page.Facets = facets.ToFacets(categories)
+
 	return
 }
-func (s *ProductServiceImpl) CreatePaginationResponse(products []Product, total int, limit int, page int) (productPagination ProductPagination, err error) {
-	productPagination = ProductPagination{
-		Data:        products,
-		Total:       total,
-		PerPage:     limit,
-		CurrentPage: page,
-		TotalPages:  int(math.Ceil(float64(total) / float64(limit))),
+
+func (s *ProductServiceImpl) searchProducts(params ProductQueryParams, categories category.Categories) (page shared.Page, err error) {
+	ids, total, err := s.Searcher.Search(params)
+	if err != nil {
+		return
+	}
+
+	facets, err := s.Searcher.Facets(params)
+	if err != nil {
+		return
+	}
+
+	products, err := s.ProductRepository.ResolveProductsByIDs(ids)
+	if err != nil {
+		return
 	}
 
+	page = shared.NewPage(products, total, params.Limit)
+	page.CurrentPage = params.Page
+	page.Facets = facets.ToFacets(categories)
+
 	return
 }
+
 func (s *ProductServiceImpl) ResolveByID(id uuid.UUID) (product Product, err error) {
 	product, err = s.ProductRepository.ResolveProductByID(id)
 	if product.IsDeleted() {
@@ -78,3 +181,356 @@ func (s *ProductServiceImpl) ResolveByID(id uuid.UUID) (product Product, err err
 
 	return
 }
+
+// UpdateProduct updates a product the user may write. Changes to the stock of
+// its variants move their stock in the default warehouse.
+func (s *ProductServiceImpl) UpdateProduct(id uuid.UUID, requestFormat ProductRequestFormat, userID uuid.UUID, role rbac.Role) (product Product, err error) {
+	product, err = s.resolveWritableProduct(id, userID, role)
+	if err != nil {
+		return
+	}
+
+	category, err := s.resolveCategory(requestFormat.CategoryID)
+	if err != nil {
+		return
+	}
+
+	defaultWarehouse, err := s.WarehouseService.ResolveDefault()
+	if err != nil {
+		return
+	}
+
+	err = product.Update(requestFormat, category, userID)
+	if err != nil {
+		return product, failure.BadRequest(err)
+	}
+
+	err = s.ProductRepository.UpdateProduct(product, defaultWarehouse.ID)
+	if err != nil {
+		return
+	}
+
+	product, err = s.ResolveByID(id)
+	if err != nil {
+		return
+	}
+
+	s.index(product)
+
+	return
+}
+
+// SoftDeleteProduct marks a product the user may write as deleted.
+func (s *ProductServiceImpl) SoftDeleteProduct(id uuid.UUID, userID uuid.UUID, role rbac.Role) (product Product, err error) {
+	product, err = s.resolveWritableProduct(id, userID, role)
+	if err != nil {
+		return
+	}
+
+	err = product.SoftDelete(userID)
+	if err != nil {
+		return
+	}
+
+	defaultWarehouse, err := s.WarehouseService.ResolveDefault()
+	if err != nil {
+		return
+	}
+
+	err = s.ProductRepository.UpdateProduct(product, defaultWarehouse.ID)
+	if err != nil {
+		return
+	}
+
+	s.index(product)
+
+	return
+}
+
+// AddImage adds an image to a product the user may write, after its other
+// images. The image type is sniffed from the content, and a thumbnail is
+// stored along with it.
+func (s *ProductServiceImpl) AddImage(id uuid.UUID, content []byte, userID uuid.UUID, role rbac.Role) (product Product, err error) {
+	product, err = s.resolveWritableProduct(id, userID, role)
+	if err != nil {
+		return
+	}
+
+	if int64(len(content)) > s.MaxImageSize() {
+		return product, failure.BadRequestFromString(fmt.Sprintf("image must be at most %d bytes", s.MaxImageSize()))
+	}
+
+	img, contentType, err := imaging.Decode(content, s.maxImagePixels())
+	if err != nil {
+		return product, failure.BadRequest(err)
+	}
+
+	thumbnail, thumbnailType, err := imaging.Encode(imaging.Thumbnail(img, s.thumbnailSize()), contentType)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return product, failure.InternalError(err)
+	}
+
+	image, err := Image{}.NewImage(product.ID, contentType, img.Bounds().Dx(), img.Bounds().Dy(), len(content), userID)
+	if err != nil {
+		return product, failure.InternalError(err)
+	}
+
+	image.BlobKey, image.ThumbnailKey = image.Keys(imaging.Extension(contentType), imaging.Extension(thumbnailType))
+	image.URL = s.BlobStore.URL(image.BlobKey)
+	image.ThumbnailURL = s.BlobStore.URL(image.ThumbnailKey)
+
+	err = image.Validate()
+	if err != nil {
+		return product, failure.InternalError(err)
+	}
+
+	err = s.BlobStore.Put(image.BlobKey, content, contentType)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return product, failure.InternalError(err)
+	}
+
+	err = s.BlobStore.Put(image.ThumbnailKey, thumbnail, thumbnailType)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		s.deleteImageBlobs(image)
+		return product, failure.InternalError(err)
+	}
+
+	_, err = s.ProductRepository.CreateImage(image)
+	if err != nil {
+		s.deleteImageBlobs(image)
+		return
+	}
+
+	return s.ResolveByID(id)
+}
+
+// DeleteImage removes an image of a product the user may write. The positions
+// of the other images are kept.
+func (s *ProductServiceImpl) DeleteImage(id uuid.UUID, imageID uuid.UUID, userID uuid.UUID, role rbac.Role) (product Product, err error) {
+	product, err = s.resolveWritableProduct(id, userID, role)
+	if err != nil {
+		return
+	}
+
+	for _, image := range product.Images {
+		if image.ID != imageID {
+			continue
+		}
+
+		image.SoftDelete(userID)
+		err = s.ProductRepository.DeleteImage(image)
+		if err != nil {
+			return
+		}
+
+		s.deleteImageBlobs(image)
+
+		return s.ResolveByID(id)
+	}
+
+	return product, failure.NotFound("image")
+}
+
+// AdjustStock moves the stock of a variant of a product the user may write in
+// a warehouse, the default one unless requested otherwise, recording why in
+// the ledger.
+func (s *ProductServiceImpl) AdjustStock(id uuid.UUID, requestFormat StockMovementRequestFormat, userID uuid.UUID, role rbac.Role) (product Product, err error) {
+	product, err = s.resolveWritableProduct(id, userID, role)
+	if err != nil {
+		return
+	}
+
+	variant, err := product.ResolveVariant(requestFormat.VariantID)
+	if err != nil {
+		return
+	}
+
+	warehouse, err := s.resolveWarehouse(requestFormat.WarehouseID)
+	if err != nil {
+		return
+	}
+
+	movement, err := StockMovement{}.NewFromRequestFormat(requestFormat, variant, warehouse.ID, userID)
+	if err != nil {
+		return product, failure.BadRequest(err)
+	}
+
+	err = s.ProductRepository.ApplyStockMovement(movement)
+	if err != nil {
+		return
+	}
+
+	product, err = s.ResolveByID(id)
+	if err != nil {
+		return
+	}
+
+	s.index(product)
+
+	return
+}
+
+// ResolveStockHistory resolves a page of the stock movements of a product the
+// user may write, along with the reconciliation of its stock against them.
+func (s *ProductServiceImpl) ResolveStockHistory(id uuid.UUID, page int, limit int, userID uuid.UUID, role rbac.Role) (history StockHistory, err error) {
+	product, err := s.resolveWritableProduct(id, userID, role)
+	if err != nil {
+		return
+	}
+
+	movements, err := s.ProductRepository.ResolveStockMovements(id, limit, (page-1)*limit)
+	if err != nil {
+		return
+	}
+
+	total, err := s.ProductRepository.CountStockMovements(id)
+	if err != nil {
+		return
+	}
+
+	ledger, err := s.ProductRepository.ResolveLedgerStock(id)
+	if err != nil {
+		return
+	}
+
+	if movements == nil {
+		movements = make([]StockMovement, 0)
+	}
+
+	history.Levels = product.StockLevels(ledger)
+	history.Movements = shared.NewPage(movements, total, limit)
+	history.Movements.CurrentPage = page
+
+	for _, level := range history.Levels {
+		if !level.Reconciled {
+			log.Warn().Str("product", id.String()).Str("sku", level.SKU).Int("stock", level.Stock).Int("ledgerStock", level.LedgerStock).Msg("Stock does not match the ledger")
+		}
+	}
+
+	return
+}
+
+// ResolveWarehouseStock resolves the stock of the variants in each warehouse.
+func (s *ProductServiceImpl) ResolveWarehouseStock(variantIDs []uuid.UUID) (stocks []WarehouseStock, err error) {
+	return s.ProductRepository.ResolveWarehouseStockByVariantIDs(variantIDs)
+}
+
+// IndexProducts keeps the searcher in sync with products of which the stock
+// was moved by other domains.
+func (s *ProductServiceImpl) IndexProducts(ids []uuid.UUID) {
+	products, err := s.ProductRepository.ResolveProductsByIDs(ids)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed resolving products to index for search")
+		return
+	}
+
+	for _, product := range products {
+		s.index(product)
+	}
+}
+
+// indexCategoryProducts re-indexes the products of a renamed category, so
+// search matches them by the new category name.
+func (s *ProductServiceImpl) indexCategoryProducts(category category.Category) {
+	ids, err := s.ProductRepository.ResolveProductIDsByCategoryID(category.ID)
+	if err != nil {
+		log.Warn().Err(err).Str("category", category.ID.String()).Msg("Failed resolving products of renamed category to index for search")
+		return
+	}
+
+	s.IndexProducts(ids)
+}
+
+// MaxImageSize is the size limit of uploaded images, in bytes.
+func (s *ProductServiceImpl) MaxImageSize() int64 {
+	if s.Config.Image.MaxSizeBytes > 0 {
+		return s.Config.Image.MaxSizeBytes
+	}
+
+	return defaultImageMaxSizeBytes
+}
+
+func (s *ProductServiceImpl) maxImagePixels() int {
+	if s.Config.Image.MaxPixels > 0 {
+		return s.Config.Image.MaxPixels
+	}
+
+	return defaultImageMaxPixels
+}
+
+func (s *ProductServiceImpl) thumbnailSize() int {
+	if s.Config.Image.ThumbnailSize > 0 {
+		return s.Config.Image.ThumbnailSize
+	}
+
+	return defaultThumbnailSize
+}
+
+// index keeps the searcher in sync with a written product. The product table
+// stays the source of truth, so failures are logged rather than failing the
+// write.
+func (s *ProductServiceImpl) index(product Product) {
+	var err error
+	if product.IsDeleted() {
+		err = s.Searcher.Remove(product.ID)
+	} else {
+		err = s.Searcher.Index(product)
+	}
+
+	if err != nil {
+		log.Warn().Err(err).Str("product", product.ID.String()).Msg("Failed indexing product for search")
+	}
+}
+
+// deleteImageBlobs deletes the blobs of an image. Failures only leave unused
+// blobs behind, so they are logged rather than returned.
+func (s *ProductServiceImpl) deleteImageBlobs(image Image) {
+	for _, key := range []string{image.BlobKey, image.ThumbnailKey} {
+		if err := s.BlobStore.Delete(key); err != nil {
+			log.Warn().Err(err).Str("key", key).Msg("Failed deleting image blob")
+		}
+	}
+}
+
+// resolveCategory resolves the category a product is placed in.
+func (s *ProductServiceImpl) resolveCategory(id uuid.UUID) (category category.Category, err error) {
+	category, err = s.CategoryService.ResolveByID(id)
+	if failure.GetCode(err) == http.StatusNotFound {
+		return category, failure.BadRequestFromString("categoryID is not a category")
+	}
+
+	return
+}
+
+// resolveWarehouse resolves the warehouse stock is moved in, the default one
+// when none is given.
+func (s *ProductServiceImpl) resolveWarehouse(id uuid.UUID) (warehouse warehouse.Warehouse, err error) {
+	if id == uuid.Nil {
+		return s.WarehouseService.ResolveDefault()
+	}
+
+	warehouse, err = s.WarehouseService.ResolveByID(id)
+	if failure.GetCode(err) == http.StatusNotFound {
+		return warehouse, failure.BadRequestFromString("warehouseID is not a warehouse")
+	}
+
+	return
+}
+
+// resolveWritableProduct resolves a product that is not deleted, failing when
+// the user neither owns it nor may write every product.
+func (s *ProductServiceImpl) resolveWritableProduct(id uuid.UUID, userID uuid.UUID, role rbac.Role) (product Product, err error) {
+	product, err = s.ResolveByID(id)
+	if err != nil {
+		return
+	}
+
+	if !rbac.CanAccess(role, rbac.PermissionProductWrite, userID, product.UserID) {
+		return product, failure.Forbidden("User does not own the product")
+	}
+
+	return
+}
@@ -0,0 +1,230 @@
+package user
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/evermos/boilerplate-go/shared"
+	"github.com/evermos/boilerplate-go/shared/nuuid"
+	"github.com/evermos/boilerplate-go/shared/rbac"
+	"github.com/gofrs/uuid"
+	"github.com/guregu/null"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type User struct {
+	ID       uuid.UUID `db:"id" validate:"required"`
+	Username string    `db:"username" validate:"required"`
+	Email    string    `db:"email" validate:"required,email"`
+	// EmailVerifiedAt is set once the user proved to own the email.
+	EmailVerifiedAt null.Time   `db:"email_verified_at"`
+	Telephone       string      `db:"telephone" validate:"required"`
+	Password        string      `db:"password" validate:"required"`
+	Role            rbac.Role   `db:"role" validate:"required"`
+	CreatedAt       time.Time   `db:"created_at" validate:"required"`
+	CreatedBy       uuid.UUID   `db:"created_by" validate:"required"`
+	UpdatedAt       null.Time   `db:"updated_at"`
+	UpdatedBy       nuuid.NUUID `db:"updated_by"`
+	DeletedAt       null.Time   `db:"deleted_at"`
+	DeletedBy       nuuid.NUUID `db:"deleted_by"`
+}
+
+func (u *User) IsDeleted() (deleted bool) {
+	return u.DeletedAt.Valid && u.DeletedBy.Valid
+}
+func (u User) MarshalJSON() ([]byte, error) {
+	return json.Marshal(u.ToResponseFormat())
+}
+
+// NewFromRequestFormat creates a new user with its password hashed with bcrypt.
+// Usernames and emails are stored in lower case.
+func (u User) NewFromRequestFormat(req RegisterRequestFormat) (newUser User, err error) {
+	userID, err := uuid.NewV4()
+	if err != nil {
+		return
+	}
+
+	password, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return
+	}
+
+	newUser = User{
+		ID:        userID,
+		Username:  strings.ToLower(strings.TrimSpace(req.Username)),
+		Email:     strings.ToLower(strings.TrimSpace(req.Email)),
+		Telephone: strings.TrimSpace(req.Telephone),
+		Password:  string(password),
+		Role:      rbac.RoleUser,
+		CreatedAt: time.Now(),
+		CreatedBy: userID,
+	}
+
+	err = newUser.Validate()
+
+	return
+}
+
+// UpdatePassword replaces the password of the user, hashed with bcrypt.
+func (u *User) UpdatePassword(password string) (err error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return
+	}
+
+	u.Password = string(hash)
+	u.UpdatedAt = null.TimeFrom(time.Now())
+	u.UpdatedBy = nuuid.From(u.ID)
+
+	return u.Validate()
+}
+
+// VerifyEmail marks the email of the user as verified.
+func (u *User) VerifyEmail() {
+	u.EmailVerifiedAt = null.TimeFrom(time.Now())
+	u.UpdatedAt = null.TimeFrom(time.Now())
+	u.UpdatedBy = nuuid.From(u.ID)
+}
+
+// UpdateRole changes the role of the user.
+func (u *User) UpdateRole(req RoleRequestFormat, userID uuid.UUID) (err error) {
+	u.Role = req.Role
+	u.UpdatedAt = null.TimeFrom(time.Now())
+	u.UpdatedBy = nuuid.From(userID)
+
+	return u.Validate()
+}
+func (u *User) Validate() (err error) {
+	validator := shared.GetValidator()
+	return validator.Struct(u)
+}
+func (u User) ToResponseFormat() UserResponseFormat {
+	return UserResponseFormat{
+		ID:              u.ID,
+		Username:        u.Username,
+		Email:           u.Email,
+		EmailVerifiedAt: u.EmailVerifiedAt,
+		Telephone:       u.Telephone,
+		Role:            u.Role,
+		CreatedAt:       u.CreatedAt,
+		UpdatedAt:       u.UpdatedAt,
+	}
+}
+
+// RegisterRequestFormat is the payload to register a user. Passwords are
+// limited to the 72 bytes bcrypt takes into account.
+type RegisterRequestFormat struct {
+	Username  string `json:"username" validate:"required,min=3,max=100"`
+	Email     string `json:"email" validate:"required,email,max=255"`
+	Telephone string `json:"telephone" validate:"required,min=8,max=20"`
+	Password  string `json:"password" validate:"required,min=8,max=72"`
+}
+
+type RoleRequestFormat struct {
+	Role rbac.Role `json:"role" validate:"required,oneof=admin shop_admin user"`
+}
+
+type UserResponseFormat struct {
+	ID              uuid.UUID `json:"id"`
+	Username        string    `json:"username"`
+	Email           string    `json:"email"`
+	EmailVerifiedAt null.Time `json:"emailVerifiedAt"`
+	Telephone       string    `json:"telephone"`
+	Role            rbac.Role `json:"role"`
+	CreatedAt       time.Time `json:"createdAt"`
+	UpdatedAt       null.Time `json:"updatedAt"`
+}
+
+// TokenPurpose is what a user token may be used for.
+type TokenPurpose string
+
+const (
+	TokenPurposePasswordReset     TokenPurpose = "password_reset"
+	TokenPurposeEmailVerification TokenPurpose = "email_verification"
+)
+
+// tokenSize is the number of random bytes of a user token.
+const tokenSize = 32
+
+// UserToken is a single use, expiring token sent to a user to reset their
+// password or verify their email. Tokens are signed for their purpose, and
+// only their hash is stored.
+type UserToken struct {
+	TokenHash string       `db:"token_hash"`
+	UserID    uuid.UUID    `db:"user_id"`
+	Purpose   TokenPurpose `db:"purpose"`
+	Expires   time.Time    `db:"expires"`
+	UsedAt    null.Time    `db:"used_at"`
+	CreatedAt time.Time    `db:"created_at"`
+}
+
+// NewUserToken creates a new token of the user for the purpose, signed with
+// the secret. The plain token is returned to be sent to the user.
+func NewUserToken(userID uuid.UUID, purpose TokenPurpose, ttl time.Duration, secret []byte) (token UserToken, plain string, err error) {
+	b := make([]byte, tokenSize)
+	_, err = rand.Read(b)
+	if err != nil {
+		return
+	}
+
+	random := base64.RawURLEncoding.EncodeToString(b)
+	plain = random + "." + signToken(random, purpose, secret)
+
+	token = UserToken{
+		TokenHash: HashToken(plain),
+		UserID:    userID,
+		Purpose:   purpose,
+		Expires:   time.Now().Add(ttl),
+		CreatedAt: time.Now(),
+	}
+
+	return
+}
+
+// VerifyTokenSignature checks that a plain token was signed with the secret
+// for the purpose, so that forged tokens are rejected without a lookup.
+func VerifyTokenSignature(plain string, purpose TokenPurpose, secret []byte) bool {
+	parts := strings.Split(plain, ".")
+	if len(parts) != 2 {
+		return false
+	}
+
+	return hmac.Equal([]byte(parts[1]), []byte(signToken(parts[0], purpose, secret)))
+}
+
+// HashToken hashes a plain token for storage and lookup.
+func HashToken(plain string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(plain)))
+}
+
+// IsUsable checks whether the token is unused and unexpired.
+func (t *UserToken) IsUsable() bool {
+	return !t.UsedAt.Valid && time.Now().Before(t.Expires)
+}
+
+func signToken(random string, purpose TokenPurpose, secret []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(string(purpose) + ":" + random))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+type PasswordResetRequestFormat struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+}
+
+// PasswordResetConfirmFormat is the payload to set a new password with a
+// password reset token.
+type PasswordResetConfirmFormat struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=8,max=72"`
+}
+
+type EmailVerificationFormat struct {
+	Token string `json:"token" validate:"required"`
+}
@@ -0,0 +1,42 @@
+package user_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/evermos/boilerplate-go/internal/domain/user"
+	"github.com/gofrs/uuid"
+	"github.com/guregu/null"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserToken(t *testing.T) {
+	secret := []byte("secret")
+	userID := uuid.Must(uuid.NewV4())
+
+	t.Run("Signed For Its Purpose", func(t *testing.T) {
+		token, plain, err := user.NewUserToken(userID, user.TokenPurposePasswordReset, time.Hour, secret)
+		require.NoError(t, err)
+
+		assert.Equal(t, user.HashToken(plain), token.TokenHash)
+		assert.NotContains(t, token.TokenHash, plain)
+		assert.True(t, user.VerifyTokenSignature(plain, user.TokenPurposePasswordReset, secret))
+		assert.False(t, user.VerifyTokenSignature(plain, user.TokenPurposeEmailVerification, secret))
+		assert.False(t, user.VerifyTokenSignature(plain, user.TokenPurposePasswordReset, []byte("other")))
+		assert.False(t, user.VerifyTokenSignature("forged.signature", user.TokenPurposePasswordReset, secret))
+	})
+
+	t.Run("Single Use And Expiring", func(t *testing.T) {
+		token, _, err := user.NewUserToken(userID, user.TokenPurposeEmailVerification, time.Hour, secret)
+		require.NoError(t, err)
+		assert.True(t, token.IsUsable())
+
+		token.UsedAt = null.TimeFrom(time.Now())
+		assert.False(t, token.IsUsable())
+
+		expired, _, err := user.NewUserToken(userID, user.TokenPurposeEmailVerification, -time.Second, secret)
+		require.NoError(t, err)
+		assert.False(t, expired.IsUsable())
+	})
+}
@@ -0,0 +1,447 @@
+package user
+
+import (
+	"database/sql"
+
+	"github.com/evermos/boilerplate-go/infras"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/logger"
+	"github.com/evermos/boilerplate-go/shared/oauth"
+	"github.com/go-sql-driver/mysql"
+	"github.com/gofrs/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// mysqlErrDuplicateEntry is the MySQL error number of a unique key violation.
+const mysqlErrDuplicateEntry = 1062
+
+var (
+	userQueries = struct {
+		selectUser               string
+		selectConflicts          string
+		insertUser               string
+		updateRole               string
+		updatePassword           string
+		updateEmailVerified      string
+		insertToken              string
+		selectToken              string
+		useToken                 string
+		invalidateTokens         string
+		revokeRefreshTokens      string
+		deleteAuthorizationCodes string
+	}{
+		selectUser: `
+			SELECT
+				id,
+				username,
+				email,
+				email_verified_at,
+				telephone,
+				password,
+				role,
+				created_at,
+				created_by,
+				updated_at,
+				updated_by,
+				deleted_at,
+				deleted_by
+			FROM user
+		`,
+
+		selectConflicts: `
+			SELECT
+				username,
+				email,
+				telephone
+			FROM user
+			WHERE username = ? OR email = ? OR telephone = ?
+		`,
+
+		insertUser: `
+			INSERT INTO user (
+				id,
+				username,
+				email,
+				email_verified_at,
+				telephone,
+				password,
+				role,
+				created_at,
+				created_by,
+				updated_at,
+				updated_by,
+				deleted_at,
+				deleted_by
+			) VALUES (
+				:id,
+				:username,
+				:email,
+				:email_verified_at,
+				:telephone,
+				:password,
+				:role,
+				:created_at,
+				:created_by,
+				:updated_at,
+				:updated_by,
+				:deleted_at,
+				:deleted_by
+			)
+		`,
+
+		updateRole: `
+			UPDATE user
+			SET
+				role = :role,
+				updated_at = :updated_at,
+				updated_by = :updated_by
+			WHERE id = :id
+		`,
+
+		updatePassword: `
+			UPDATE user
+			SET
+				password = :password,
+				updated_at = :updated_at,
+				updated_by = :updated_by
+			WHERE id = :id
+		`,
+
+		updateEmailVerified: `
+			UPDATE user
+			SET
+				email_verified_at = :email_verified_at,
+				updated_at = :updated_at,
+				updated_by = :updated_by
+			WHERE id = :id
+		`,
+
+		insertToken: `
+			INSERT INTO user_token (
+				token_hash,
+				user_id,
+				purpose,
+				expires,
+				used_at,
+				created_at
+			) VALUES (
+				:token_hash,
+				:user_id,
+				:purpose,
+				:expires,
+				:used_at,
+				:created_at
+			)
+		`,
+
+		selectToken: `
+			SELECT
+				token_hash,
+				user_id,
+				purpose,
+				expires,
+				used_at,
+				created_at
+			FROM user_token
+		`,
+
+		useToken: `UPDATE user_token SET used_at = NOW() WHERE token_hash = ? AND used_at IS NULL AND expires > NOW()`,
+
+		invalidateTokens: `UPDATE user_token SET used_at = NOW() WHERE user_id = ? AND purpose = ? AND used_at IS NULL`,
+
+		revokeRefreshTokens: `UPDATE oauth_refresh_tokens SET revoked_at = NOW() WHERE user_id = ? AND revoked_at IS NULL`,
+
+		deleteAuthorizationCodes: `DELETE FROM oauth_authorization_codes WHERE user_id = ?`,
+	}
+)
+
+// errTokenUnusable is returned when a user token was used or expired in the meantime.
+var errTokenUnusable = failure.BadRequestFromString("token is invalid or expired")
+
+type UserRepository interface {
+	CreateUser(user User) (err error)
+	ResolveUserByID(id uuid.UUID) (user User, err error)
+	ResolveUserByEmail(email string) (user User, err error)
+	UpdateRole(user User) (err error)
+	CreateToken(token UserToken) (err error)
+	ResolveTokenByHash(tokenHash string) (token UserToken, err error)
+	ResetPassword(user User, token UserToken) (err error)
+	VerifyEmail(user User, token UserToken) (err error)
+}
+
+type UserRepositoryMySQL struct {
+	DB     *infras.MySQLConn
+	Tokens oauth.TokenStore
+}
+
+func ProvideUserRepositoryMySQL(db *infras.MySQLConn, tokens oauth.TokenStore) *UserRepositoryMySQL {
+	s := new(UserRepositoryMySQL)
+	s.DB = db
+	s.Tokens = tokens
+
+	return s
+}
+
+// CreateUser stores a user, failing with a conflict when its username, email
+// or telephone is already taken.
+func (r *UserRepositoryMySQL) CreateUser(user User) (err error) {
+	err = r.checkConflicts(user)
+	if err != nil {
+		return
+	}
+
+	err = r.DB.WithTransaction(func(tx *sqlx.Tx, e chan error) {
+		if err := r.txCreate(tx, user); err != nil {
+			e <- err
+			return
+		}
+
+		e <- nil
+	})
+
+	// A concurrent registration may take the same value between the check and the insert.
+	if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == mysqlErrDuplicateEntry {
+		err = failure.Conflict("register", "user", "username, email or telephone already exists")
+	}
+
+	return
+}
+
+func (r *UserRepositoryMySQL) ResolveUserByID(id uuid.UUID) (user User, err error) {
+	err = r.DB.Read.Get(&user, userQueries.selectUser+" WHERE id = ?", id.String())
+	if err != nil && err == sql.ErrNoRows {
+		err = failure.NotFound("user")
+		logger.ErrorWithStack(err)
+		return
+	}
+
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+
+	return
+}
+
+func (r *UserRepositoryMySQL) ResolveUserByEmail(email string) (user User, err error) {
+	err = r.DB.Read.Get(&user, userQueries.selectUser+" WHERE email = ? AND deleted_at IS NULL", email)
+	if err != nil && err == sql.ErrNoRows {
+		err = failure.NotFound("user")
+		return
+	}
+
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+
+	return
+}
+
+func (r *UserRepositoryMySQL) CreateToken(token UserToken) (err error) {
+	_, err = r.DB.Write.NamedExec(userQueries.insertToken, token)
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+
+	return
+}
+
+func (r *UserRepositoryMySQL) ResolveTokenByHash(tokenHash string) (token UserToken, err error) {
+	err = r.DB.Read.Get(&token, userQueries.selectToken+" WHERE token_hash = ?", tokenHash)
+	if err != nil && err == sql.ErrNoRows {
+		err = errTokenUnusable
+		return
+	}
+
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+
+	return
+}
+
+// ResetPassword uses a password reset token to store the user's new password.
+// Every other password reset token of the user is invalidated, and every
+// token issued to the user is revoked, so that existing sessions end.
+func (r *UserRepositoryMySQL) ResetPassword(user User, token UserToken) (err error) {
+	err = r.DB.WithTransaction(func(tx *sqlx.Tx, e chan error) {
+		if err := r.txUseToken(tx, token); err != nil {
+			e <- err
+			return
+		}
+
+		if err := r.txUpdate(tx, userQueries.updatePassword, user); err != nil {
+			e <- err
+			return
+		}
+
+		if err := r.txRevokeSessions(tx, user.ID, token.Purpose); err != nil {
+			e <- err
+			return
+		}
+
+		e <- nil
+	})
+	if err != nil {
+		return
+	}
+
+	// Token stores in MySQL revoked the tokens in the transaction.
+	if _, ok := r.Tokens.(oauth.TxTokenStore); ok {
+		return
+	}
+
+	err = r.Tokens.RevokeUser(user.ID.String())
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+
+	return
+}
+
+// VerifyEmail uses an email verification token to mark the user's email as verified.
+func (r *UserRepositoryMySQL) VerifyEmail(user User, token UserToken) (err error) {
+	return r.DB.WithTransaction(func(tx *sqlx.Tx, e chan error) {
+		if err := r.txUseToken(tx, token); err != nil {
+			e <- err
+			return
+		}
+
+		if err := r.txUpdate(tx, userQueries.updateEmailVerified, user); err != nil {
+			e <- err
+			return
+		}
+
+		e <- nil
+	})
+}
+
+func (r *UserRepositoryMySQL) UpdateRole(user User) (err error) {
+	return r.DB.WithTransaction(func(tx *sqlx.Tx, e chan error) {
+		if err := r.txUpdateRole(tx, user); err != nil {
+			e <- err
+			return
+		}
+
+		e <- nil
+	})
+}
+
+func (r *UserRepositoryMySQL) checkConflicts(user User) (err error) {
+	var existing []User
+	err = r.DB.Read.Select(&existing, userQueries.selectConflicts, user.Username, user.Email, user.Telephone)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+
+	for _, e := range existing {
+		switch {
+		case e.Username == user.Username:
+			return failure.Conflict("register", "username", "already exists")
+		case e.Email == user.Email:
+			return failure.Conflict("register", "email", "already exists")
+		default:
+			return failure.Conflict("register", "telephone", "already exists")
+		}
+	}
+
+	return
+}
+
+// Transactions
+func (r *UserRepositoryMySQL) txCreate(tx *sqlx.Tx, user User) (err error) {
+	stmt, err := tx.PrepareNamed(userQueries.insertUser)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(user)
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+
+	return
+}
+
+func (r *UserRepositoryMySQL) txUpdateRole(tx *sqlx.Tx, user User) (err error) {
+	stmt, err := tx.PrepareNamed(userQueries.updateRole)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(user)
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+
+	return
+}
+
+func (r *UserRepositoryMySQL) txUpdate(tx *sqlx.Tx, query string, user User) (err error) {
+	stmt, err := tx.PrepareNamed(query)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(user)
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+
+	return
+}
+
+// txUseToken marks a token as used, failing when a concurrent request used it first.
+func (r *UserRepositoryMySQL) txUseToken(tx *sqlx.Tx, token UserToken) (err error) {
+	result, err := tx.Exec(userQueries.useToken, token.TokenHash)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+
+	if affected == 0 {
+		return errTokenUnusable
+	}
+
+	return
+}
+
+func (r *UserRepositoryMySQL) txRevokeSessions(tx *sqlx.Tx, userID uuid.UUID, purpose TokenPurpose) (err error) {
+	_, err = tx.Exec(userQueries.invalidateTokens, userID.String(), purpose)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+
+	for _, query := range []string{
+		userQueries.revokeRefreshTokens,
+		userQueries.deleteAuthorizationCodes,
+	} {
+		_, err = tx.Exec(query, userID.String())
+		if err != nil {
+			logger.ErrorWithStack(err)
+			return
+		}
+	}
+
+	if tokens, ok := r.Tokens.(oauth.TxTokenStore); ok {
+		err = tokens.RevokeUserTx(tx, userID.String())
+		if err != nil {
+			logger.ErrorWithStack(err)
+		}
+	}
+
+	return
+}
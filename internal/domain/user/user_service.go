@@ -0,0 +1,243 @@
+package user
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/evermos/boilerplate-go/configs"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/logger"
+	"github.com/evermos/boilerplate-go/shared/mailer"
+	"github.com/gofrs/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// defaultPasswordResetExpiration is how long password reset tokens are valid when none is configured.
+	defaultPasswordResetExpiration = time.Hour
+	// defaultVerificationExpiration is how long email verification tokens are valid when none is configured.
+	defaultVerificationExpiration = 48 * time.Hour
+)
+
+type UserService interface {
+	Register(requestFormat RegisterRequestFormat) (user User, err error)
+	UpdateRole(id uuid.UUID, requestFormat RoleRequestFormat, userID uuid.UUID) (user User, err error)
+	RequestPasswordReset(requestFormat PasswordResetRequestFormat) (err error)
+	ResetPassword(requestFormat PasswordResetConfirmFormat) (err error)
+	RequestEmailVerification(id uuid.UUID) (err error)
+	VerifyEmail(requestFormat EmailVerificationFormat) (user User, err error)
+}
+
+type UserServiceImpl struct {
+	UserRepository UserRepository
+	Mailer         mailer.Mailer
+	Config         *configs.Config
+
+	tokenSecret []byte
+}
+
+func ProvideUserServiceImpl(userRepository UserRepository, mailer mailer.Mailer, config *configs.Config) *UserServiceImpl {
+	s := new(UserServiceImpl)
+	s.UserRepository = userRepository
+	s.Mailer = mailer
+	s.Config = config
+	s.tokenSecret = []byte(config.Account.TokenSecret)
+
+	if len(s.tokenSecret) == 0 {
+		log.Warn().Msg("No account token secret configured, password reset and verification links will not survive a restart.")
+		s.tokenSecret = make([]byte, 32)
+		_, _ = rand.Read(s.tokenSecret)
+	}
+
+	return s
+}
+
+// Register registers a user, and sends them a link to verify their email.
+// Failing to send the link does not fail the registration, as the user can
+// request another one.
+func (s *UserServiceImpl) Register(requestFormat RegisterRequestFormat) (user User, err error) {
+	user, err = user.NewFromRequestFormat(requestFormat)
+	if err != nil {
+		return user, failure.BadRequest(err)
+	}
+
+	err = s.UserRepository.CreateUser(user)
+	if err != nil {
+		return
+	}
+
+	if err := s.sendEmailVerification(user); err != nil {
+		logger.ErrorWithStack(err)
+	}
+
+	return
+}
+
+func (s *UserServiceImpl) UpdateRole(id uuid.UUID, requestFormat RoleRequestFormat, userID uuid.UUID) (user User, err error) {
+	user, err = s.UserRepository.ResolveUserByID(id)
+	if err != nil {
+		return
+	}
+
+	if user.IsDeleted() {
+		return user, failure.NotFound("user")
+	}
+
+	err = user.UpdateRole(requestFormat, userID)
+	if err != nil {
+		return user, failure.BadRequest(err)
+	}
+
+	err = s.UserRepository.UpdateRole(user)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// RequestPasswordReset sends a password reset link to the user with the
+// email. Unknown emails succeed silently, so that the response does not
+// reveal which emails are registered.
+func (s *UserServiceImpl) RequestPasswordReset(requestFormat PasswordResetRequestFormat) (err error) {
+	user, err := s.UserRepository.ResolveUserByEmail(strings.ToLower(strings.TrimSpace(requestFormat.Email)))
+	if failure.GetCode(err) == http.StatusNotFound {
+		return nil
+	}
+
+	if err != nil {
+		return
+	}
+
+	link, err := s.issueToken(user, TokenPurposePasswordReset, s.Config.Account.PasswordResetURL, s.Config.Account.PasswordResetExpirationSeconds, defaultPasswordResetExpiration)
+	if err != nil {
+		return
+	}
+
+	return s.Mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body:    fmt.Sprintf("Hi %s,\n\nUse this link to reset your password:\n\n%s\n\nIf you did not ask to reset your password, you can ignore this message.\n", user.Username, link),
+	})
+}
+
+// ResetPassword sets a new password with a password reset token. Every
+// token issued to the user is revoked, so that existing sessions end.
+func (s *UserServiceImpl) ResetPassword(requestFormat PasswordResetConfirmFormat) (err error) {
+	user, token, err := s.resolveToken(requestFormat.Token, TokenPurposePasswordReset)
+	if err != nil {
+		return
+	}
+
+	err = user.UpdatePassword(requestFormat.Password)
+	if err != nil {
+		return failure.BadRequest(err)
+	}
+
+	return s.UserRepository.ResetPassword(user, token)
+}
+
+// RequestEmailVerification sends an email verification link to the user.
+func (s *UserServiceImpl) RequestEmailVerification(id uuid.UUID) (err error) {
+	user, err := s.UserRepository.ResolveUserByID(id)
+	if err != nil {
+		return
+	}
+
+	if user.IsDeleted() {
+		return failure.NotFound("user")
+	}
+
+	if user.EmailVerifiedAt.Valid {
+		return failure.Conflict("verify", "email", "already verified")
+	}
+
+	return s.sendEmailVerification(user)
+}
+
+// VerifyEmail verifies the email of a user with an email verification token.
+func (s *UserServiceImpl) VerifyEmail(requestFormat EmailVerificationFormat) (user User, err error) {
+	user, token, err := s.resolveToken(requestFormat.Token, TokenPurposeEmailVerification)
+	if err != nil {
+		return
+	}
+
+	user.VerifyEmail()
+
+	err = s.UserRepository.VerifyEmail(user, token)
+	return
+}
+
+func (s *UserServiceImpl) sendEmailVerification(user User) (err error) {
+	link, err := s.issueToken(user, TokenPurposeEmailVerification, s.Config.Account.VerificationURL, s.Config.Account.VerificationExpirationSeconds, defaultVerificationExpiration)
+	if err != nil {
+		return
+	}
+
+	return s.Mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email",
+		Body:    fmt.Sprintf("Hi %s,\n\nUse this link to verify your email:\n\n%s\n", user.Username, link),
+	})
+}
+
+// issueToken stores a new token of the user, and returns the link carrying
+// it. Without a link URL, the plain token is returned.
+func (s *UserServiceImpl) issueToken(user User, purpose TokenPurpose, linkURL string, expirationSeconds int64, defaultExpiration time.Duration) (link string, err error) {
+	ttl := time.Duration(expirationSeconds) * time.Second
+	if ttl <= 0 {
+		ttl = defaultExpiration
+	}
+
+	token, plain, err := NewUserToken(user.ID, purpose, ttl, s.tokenSecret)
+	if err != nil {
+		return
+	}
+
+	err = s.UserRepository.CreateToken(token)
+	if err != nil {
+		return
+	}
+
+	if linkURL == "" {
+		return plain, nil
+	}
+
+	link = linkURL + "?token=" + url.QueryEscape(plain)
+	if strings.Contains(linkURL, "?") {
+		link = linkURL + "&token=" + url.QueryEscape(plain)
+	}
+
+	return link, nil
+}
+
+// resolveToken resolves a usable token for the purpose and its user.
+func (s *UserServiceImpl) resolveToken(plain string, purpose TokenPurpose) (user User, token UserToken, err error) {
+	if !VerifyTokenSignature(plain, purpose, s.tokenSecret) {
+		return user, token, errTokenUnusable
+	}
+
+	token, err = s.UserRepository.ResolveTokenByHash(HashToken(plain))
+	if err != nil {
+		return
+	}
+
+	if token.Purpose != purpose || !token.IsUsable() {
+		return user, token, errTokenUnusable
+	}
+
+	user, err = s.UserRepository.ResolveUserByID(token.UserID)
+	if err != nil {
+		return
+	}
+
+	if user.IsDeleted() {
+		return user, token, errTokenUnusable
+	}
+
+	return
+}
@@ -0,0 +1,140 @@
+package warehouse
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/evermos/boilerplate-go/configs"
+	"github.com/gofrs/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// AllocationNearest ships items from the warehouses nearest to the
+	// shipping address.
+	AllocationNearest = "nearest"
+	// AllocationMostStock ships items from the warehouses holding the most of
+	// them.
+	AllocationMostStock = "most_stock"
+)
+
+// ErrNotEnoughStock is returned when the warehouses together hold less than
+// the quantity to ship.
+var ErrNotEnoughStock = errors.New("not enough stock left in the warehouses")
+
+// Demand is a quantity of a variant to ship, along with the stock of the
+// variant by warehouse ID.
+type Demand struct {
+	ID       uuid.UUID
+	Quantity int
+	Stock    map[uuid.UUID]int
+}
+
+// Allocation is the quantity of a demand shipped from a warehouse.
+type Allocation struct {
+	WarehouseID uuid.UUID
+	Quantity    int
+}
+
+// Allocator ranks the warehouses a demand may ship from, the preferred first.
+type Allocator interface {
+	Rank(warehouses Warehouses, demand Demand, destination *Location) Warehouses
+}
+
+// ProvideAllocator is the provider for the Allocator selected by
+// Warehouse.Allocation.
+func ProvideAllocator(config *configs.Config) Allocator {
+	switch config.Warehouse.Allocation {
+	case AllocationNearest, "":
+		return NearestAllocator{}
+	case AllocationMostStock:
+		return MostStockAllocator{}
+	default:
+		log.Fatal().Str("allocation", config.Warehouse.Allocation).Msg("Unknown warehouse allocation.")
+		return nil
+	}
+}
+
+// Allocate picks the warehouses a demand ships from: the first ranked
+// warehouse holding the whole quantity, or otherwise the ranked warehouses in
+// turn, each shipping what it holds.
+func Allocate(allocator Allocator, warehouses Warehouses, demand Demand, destination *Location) (allocations []Allocation, err error) {
+	ranked := allocator.Rank(warehouses, demand, destination)
+	for _, warehouse := range ranked {
+		if demand.Stock[warehouse.ID] >= demand.Quantity {
+			return []Allocation{{WarehouseID: warehouse.ID, Quantity: demand.Quantity}}, nil
+		}
+	}
+
+	remaining := demand.Quantity
+	for _, warehouse := range ranked {
+		quantity := demand.Stock[warehouse.ID]
+		if quantity <= 0 {
+			continue
+		}
+		if quantity > remaining {
+			quantity = remaining
+		}
+
+		allocations = append(allocations, Allocation{WarehouseID: warehouse.ID, Quantity: quantity})
+		remaining -= quantity
+		if remaining == 0 {
+			return allocations, nil
+		}
+	}
+
+	return nil, ErrNotEnoughStock
+}
+
+// NearestAllocator prefers the warehouses nearest to the shipping address.
+// Without a shipping address, or for warehouses without coordinates, it
+// prefers those holding the most.
+type NearestAllocator struct{}
+
+func (a NearestAllocator) Rank(warehouses Warehouses, demand Demand, destination *Location) Warehouses {
+	ranked := MostStockAllocator{}.Rank(warehouses, demand, destination)
+	if destination == nil {
+		return ranked
+	}
+
+	distance := func(warehouse Warehouse) (float64, bool) {
+		location, ok := warehouse.Location()
+		if !ok {
+			return 0, false
+		}
+		return location.DistanceTo(*destination), true
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		di, iLocated := distance(ranked[i])
+		dj, jLocated := distance(ranked[j])
+		if iLocated != jLocated {
+			return iLocated
+		}
+		return iLocated && di < dj
+	})
+
+	return ranked
+}
+
+// MostStockAllocator prefers the warehouses holding the most of the variant,
+// then the default warehouse.
+type MostStockAllocator struct{}
+
+func (a MostStockAllocator) Rank(warehouses Warehouses, demand Demand, destination *Location) Warehouses {
+	ranked := make(Warehouses, len(warehouses))
+	copy(ranked, warehouses)
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		si, sj := demand.Stock[ranked[i].ID], demand.Stock[ranked[j].ID]
+		if si != sj {
+			return si > sj
+		}
+		if ranked[i].IsDefault != ranked[j].IsDefault {
+			return ranked[i].IsDefault
+		}
+		return ranked[i].Code < ranked[j].Code
+	})
+
+	return ranked
+}
@@ -0,0 +1,92 @@
+package warehouse_test
+
+import (
+	"testing"
+
+	"github.com/evermos/boilerplate-go/internal/domain/warehouse"
+	"github.com/gofrs/uuid"
+	"github.com/guregu/null"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAllocate(t *testing.T) {
+	newWarehouse := func(code string, latitude float64, longitude float64) warehouse.Warehouse {
+		return warehouse.Warehouse{
+			ID:        uuid.Must(uuid.NewV4()),
+			Code:      code,
+			Latitude:  null.FloatFrom(latitude),
+			Longitude: null.FloatFrom(longitude),
+		}
+	}
+
+	jakarta := newWarehouse("jakarta", -6.2088, 106.8456)
+	jakarta.IsDefault = true
+	surabaya := newWarehouse("surabaya", -7.2575, 112.7521)
+	unlocated := warehouse.Warehouse{ID: uuid.Must(uuid.NewV4()), Code: "unlocated"}
+	warehouses := warehouse.Warehouses{jakarta, surabaya, unlocated}
+	malang := &warehouse.Location{Latitude: -7.9666, Longitude: 112.6326}
+
+	demand := warehouse.Demand{
+		ID:       uuid.Must(uuid.NewV4()),
+		Quantity: 3,
+		Stock:    map[uuid.UUID]int{jakarta.ID: 10, surabaya.ID: 4, unlocated.ID: 20},
+	}
+
+	t.Run("Measures Distances", func(t *testing.T) {
+		from, _ := jakarta.Location()
+		to, _ := surabaya.Location()
+
+		assert.InDelta(t, 663, from.DistanceTo(to), 5)
+		assert.Zero(t, from.DistanceTo(from))
+	})
+
+	t.Run("Nearest Warehouse", func(t *testing.T) {
+		allocations, err := warehouse.Allocate(warehouse.NearestAllocator{}, warehouses, demand, malang)
+		require.NoError(t, err)
+
+		assert.Equal(t, []warehouse.Allocation{{WarehouseID: surabaya.ID, Quantity: 3}}, allocations)
+	})
+
+	t.Run("Nearest Without Shipping Coordinates Holds The Most", func(t *testing.T) {
+		allocations, err := warehouse.Allocate(warehouse.NearestAllocator{}, warehouses, demand, nil)
+		require.NoError(t, err)
+
+		assert.Equal(t, []warehouse.Allocation{{WarehouseID: unlocated.ID, Quantity: 3}}, allocations)
+	})
+
+	t.Run("Warehouse Holding The Most", func(t *testing.T) {
+		allocations, err := warehouse.Allocate(warehouse.MostStockAllocator{}, warehouses, demand, malang)
+		require.NoError(t, err)
+
+		assert.Equal(t, []warehouse.Allocation{{WarehouseID: unlocated.ID, Quantity: 3}}, allocations)
+	})
+
+	t.Run("Skips Warehouses Holding Too Little", func(t *testing.T) {
+		large := demand
+		large.Quantity = 8
+
+		allocations, err := warehouse.Allocate(warehouse.NearestAllocator{}, warehouses, large, malang)
+		require.NoError(t, err)
+
+		assert.Equal(t, []warehouse.Allocation{{WarehouseID: jakarta.ID, Quantity: 8}}, allocations)
+	})
+
+	t.Run("Splits Across Warehouses", func(t *testing.T) {
+		split := demand
+		split.Quantity = 30
+
+		allocations, err := warehouse.Allocate(warehouse.NearestAllocator{}, warehouses, split, malang)
+		require.NoError(t, err)
+
+		assert.Equal(t, []warehouse.Allocation{
+			{WarehouseID: surabaya.ID, Quantity: 4},
+			{WarehouseID: jakarta.ID, Quantity: 10},
+			{WarehouseID: unlocated.ID, Quantity: 16},
+		}, allocations)
+
+		split.Quantity = 35
+		_, err = warehouse.Allocate(warehouse.NearestAllocator{}, warehouses, split, malang)
+		assert.Equal(t, warehouse.ErrNotEnoughStock, err)
+	})
+}
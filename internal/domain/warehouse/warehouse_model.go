@@ -0,0 +1,198 @@
+package warehouse
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/evermos/boilerplate-go/shared"
+	"github.com/evermos/boilerplate-go/shared/nuuid"
+	"github.com/gofrs/uuid"
+	"github.com/guregu/null"
+)
+
+// earthRadiusKilometers is the mean radius of the Earth.
+const earthRadiusKilometers = 6371.0
+
+// Warehouse is a place stock is kept in and orders ship from. Warehouses
+// with coordinates may be picked by their distance to the shipping address.
+// The default warehouse holds the stock set through products.
+type Warehouse struct {
+	ID        uuid.UUID   `db:"id" validate:"required"`
+	Code      string      `db:"code" validate:"required,max=50"`
+	Name      string      `db:"name" validate:"required,max=100"`
+	Address   null.String `db:"address"`
+	Latitude  null.Float  `db:"latitude"`
+	Longitude null.Float  `db:"longitude"`
+	IsDefault bool        `db:"is_default"`
+	CreatedAt time.Time   `db:"created_at" validate:"required"`
+	CreatedBy uuid.UUID   `db:"created_by" validate:"required"`
+	UpdatedAt null.Time   `db:"updated_at"`
+	UpdatedBy nuuid.NUUID `db:"updated_by"`
+	DeletedAt null.Time   `db:"deleted_at"`
+	DeletedBy nuuid.NUUID `db:"deleted_by"`
+}
+
+func (w *Warehouse) IsDeleted() (deleted bool) {
+	return w.DeletedAt.Valid && w.DeletedBy.Valid
+}
+func (w Warehouse) MarshalJSON() ([]byte, error) {
+	return json.Marshal(w.ToResponseFormat())
+}
+func (w Warehouse) NewFromRequestFormat(req WarehouseRequestFormat, userID uuid.UUID) (newWarehouse Warehouse, err error) {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return
+	}
+
+	newWarehouse = Warehouse{
+		ID:        id,
+		Code:      strings.TrimSpace(req.Code),
+		Name:      strings.TrimSpace(req.Name),
+		Address:   null.NewString(req.Address, req.Address != ""),
+		Latitude:  req.Latitude,
+		Longitude: req.Longitude,
+		IsDefault: req.IsDefault,
+		CreatedAt: time.Now(),
+		CreatedBy: userID,
+	}
+
+	err = newWarehouse.Validate()
+
+	return
+}
+
+// Update replaces the warehouse's details. The default warehouse stays the
+// default until another warehouse is made the default.
+func (w *Warehouse) Update(req WarehouseRequestFormat, userID uuid.UUID) (err error) {
+	if w.IsDefault && !req.IsDefault {
+		return errors.New("make another warehouse the default instead")
+	}
+
+	w.Code = strings.TrimSpace(req.Code)
+	w.Name = strings.TrimSpace(req.Name)
+	w.Address = null.NewString(req.Address, req.Address != "")
+	w.Latitude = req.Latitude
+	w.Longitude = req.Longitude
+	w.IsDefault = req.IsDefault
+	w.UpdatedAt = null.TimeFrom(time.Now())
+	w.UpdatedBy = nuuid.From(userID)
+
+	err = w.Validate()
+
+	return
+}
+
+// Location returns the coordinates of the warehouse, if it has them.
+func (w Warehouse) Location() (location Location, ok bool) {
+	if !w.Latitude.Valid || !w.Longitude.Valid {
+		return location, false
+	}
+
+	return Location{Latitude: w.Latitude.Float64, Longitude: w.Longitude.Float64}, true
+}
+func (w *Warehouse) Validate() (err error) {
+	validator := shared.GetValidator()
+	err = validator.Struct(w)
+	if err != nil {
+		return
+	}
+
+	if w.Latitude.Valid != w.Longitude.Valid {
+		return errors.New("latitude and longitude must be set together")
+	}
+
+	if location, ok := w.Location(); ok {
+		return location.Validate()
+	}
+
+	return
+}
+func (w Warehouse) ToResponseFormat() WarehouseResponseFormat {
+	return WarehouseResponseFormat{
+		ID:        w.ID,
+		Code:      w.Code,
+		Name:      w.Name,
+		Address:   w.Address,
+		Latitude:  w.Latitude,
+		Longitude: w.Longitude,
+		IsDefault: w.IsDefault,
+		CreatedAt: w.CreatedAt,
+		CreatedBy: w.CreatedBy,
+		UpdatedAt: w.UpdatedAt,
+		UpdatedBy: w.UpdatedBy.Ptr(),
+	}
+}
+
+// Warehouses are the warehouses stock is kept in.
+type Warehouses []Warehouse
+
+// ByID resolves the warehouse with the given ID.
+func (ws Warehouses) ByID(id uuid.UUID) (warehouse Warehouse, found bool) {
+	for _, warehouse := range ws {
+		if warehouse.ID == id {
+			return warehouse, true
+		}
+	}
+
+	return warehouse, false
+}
+
+// Location is a point on the Earth, in degrees.
+type Location struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// DistanceTo is the great-circle distance to another location, in
+// kilometers.
+func (l Location) DistanceTo(other Location) float64 {
+	lat1, lat2 := radians(l.Latitude), radians(other.Latitude)
+	dLat := lat2 - lat1
+	dLon := radians(other.Longitude - l.Longitude)
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+
+	return 2 * earthRadiusKilometers * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+func (l Location) Validate() error {
+	if l.Latitude < -90 || l.Latitude > 90 {
+		return errors.New("latitude must be between -90 and 90")
+	}
+
+	if l.Longitude < -180 || l.Longitude > 180 {
+		return errors.New("longitude must be between -180 and 180")
+	}
+
+	return nil
+}
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
+
+// WarehouseRequestFormat is the payload to create or replace a warehouse.
+// Making a warehouse the default makes the previous default an ordinary
+// warehouse.
+type WarehouseRequestFormat struct {
+	Code      string     `json:"code" validate:"required,max=50"`
+	Name      string     `json:"name" validate:"required,max=100"`
+	Address   string     `json:"address" validate:"max=500"`
+	Latitude  null.Float `json:"latitude" swaggertype:"number"`
+	Longitude null.Float `json:"longitude" swaggertype:"number"`
+	IsDefault bool       `json:"isDefault"`
+}
+type WarehouseResponseFormat struct {
+	ID        uuid.UUID   `json:"id"`
+	Code      string      `json:"code"`
+	Name      string      `json:"name"`
+	Address   null.String `json:"address" swaggertype:"string"`
+	Latitude  null.Float  `json:"latitude" swaggertype:"number"`
+	Longitude null.Float  `json:"longitude" swaggertype:"number"`
+	IsDefault bool        `json:"isDefault"`
+	CreatedAt time.Time   `json:"createdAt"`
+	CreatedBy uuid.UUID   `json:"createdBy"`
+	UpdatedAt null.Time   `json:"updatedAt"`
+	UpdatedBy *uuid.UUID  `json:"updatedBy"`
+}
@@ -0,0 +1,212 @@
+package warehouse
+
+import (
+	"database/sql"
+
+	"github.com/evermos/boilerplate-go/infras"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/logger"
+	"github.com/go-sql-driver/mysql"
+	"github.com/gofrs/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// mysqlErrDuplicateEntry is the MySQL error number of a unique key violation.
+const mysqlErrDuplicateEntry = 1062
+
+var (
+	warehouseQueries = struct {
+		selectWarehouse string
+		insertWarehouse string
+		updateWarehouse string
+		unsetDefault    string
+	}{
+		selectWarehouse: `
+			SELECT
+				id,
+				code,
+				name,
+				address,
+				latitude,
+				longitude,
+				is_default,
+				created_at,
+				created_by,
+				updated_at,
+				updated_by,
+				deleted_at,
+				deleted_by
+			FROM warehouse
+		`,
+
+		insertWarehouse: `
+			INSERT INTO warehouse (
+				id,
+				code,
+				name,
+				address,
+				latitude,
+				longitude,
+				is_default,
+				created_at,
+				created_by,
+				updated_at,
+				updated_by,
+				deleted_at,
+				deleted_by
+			) VALUES (
+				:id,
+				:code,
+				:name,
+				:address,
+				:latitude,
+				:longitude,
+				:is_default,
+				:created_at,
+				:created_by,
+				:updated_at,
+				:updated_by,
+				:deleted_at,
+				:deleted_by
+			)
+		`,
+
+		updateWarehouse: `
+			UPDATE warehouse
+			SET
+				code = :code,
+				name = :name,
+				address = :address,
+				latitude = :latitude,
+				longitude = :longitude,
+				is_default = :is_default,
+				updated_at = :updated_at,
+				updated_by = :updated_by,
+				deleted_at = :deleted_at,
+				deleted_by = :deleted_by
+			WHERE id = :id
+		`,
+
+		// There is a single default warehouse.
+		unsetDefault: `UPDATE warehouse SET is_default = 0 WHERE is_default = 1 AND id != :id`,
+	}
+)
+
+type WarehouseRepository interface {
+	CreateWarehouse(warehouse Warehouse) (err error)
+	ResolveWarehouses() (warehouses Warehouses, err error)
+	ResolveWarehouseByID(id uuid.UUID) (warehouse Warehouse, err error)
+	ResolveDefaultWarehouse() (warehouse Warehouse, err error)
+	UpdateWarehouse(warehouse Warehouse) (err error)
+}
+
+type WarehouseRepositoryMySQL struct {
+	DB *infras.MySQLConn
+}
+
+func ProvideWarehouseRepositoryMySQL(db *infras.MySQLConn) *WarehouseRepositoryMySQL {
+	s := new(WarehouseRepositoryMySQL)
+	s.DB = db
+
+	return s
+}
+
+// CreateWarehouse creates the warehouse. A default warehouse replaces the
+// previous default.
+func (r *WarehouseRepositoryMySQL) CreateWarehouse(warehouse Warehouse) (err error) {
+	return r.DB.WithTransaction(func(tx *sqlx.Tx, e chan error) {
+		if err := r.txExec(tx, warehouseQueries.insertWarehouse, warehouse); err != nil {
+			e <- err
+			return
+		}
+
+		if err := r.txUnsetDefault(tx, warehouse); err != nil {
+			e <- err
+			return
+		}
+
+		e <- nil
+	})
+}
+
+// ResolveWarehouses resolves every warehouse that is not deleted.
+func (r *WarehouseRepositoryMySQL) ResolveWarehouses() (warehouses Warehouses, err error) {
+	err = r.DB.Read.Select(&warehouses, warehouseQueries.selectWarehouse+" WHERE deleted_at IS NULL ORDER BY code")
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+
+	return
+}
+func (r *WarehouseRepositoryMySQL) ResolveWarehouseByID(id uuid.UUID) (warehouse Warehouse, err error) {
+	err = r.DB.Read.Get(&warehouse, warehouseQueries.selectWarehouse+" WHERE id = ? AND deleted_at IS NULL", id.String())
+	if err != nil && err == sql.ErrNoRows {
+		err = failure.NotFound("warehouse")
+		return
+	}
+
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+
+	return
+}
+func (r *WarehouseRepositoryMySQL) ResolveDefaultWarehouse() (warehouse Warehouse, err error) {
+	err = r.DB.Read.Get(&warehouse, warehouseQueries.selectWarehouse+" WHERE is_default = 1 AND deleted_at IS NULL")
+	if err != nil && err == sql.ErrNoRows {
+		err = failure.NotFound("default warehouse")
+		logger.ErrorWithStack(err)
+		return
+	}
+
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+
+	return
+}
+
+// UpdateWarehouse updates the warehouse. A default warehouse replaces the
+// previous default.
+func (r *WarehouseRepositoryMySQL) UpdateWarehouse(warehouse Warehouse) (err error) {
+	return r.DB.WithTransaction(func(tx *sqlx.Tx, e chan error) {
+		if err := r.txExec(tx, warehouseQueries.updateWarehouse, warehouse); err != nil {
+			e <- err
+			return
+		}
+
+		if err := r.txUnsetDefault(tx, warehouse); err != nil {
+			e <- err
+			return
+		}
+
+		e <- nil
+	})
+}
+
+// Transactions
+func (r *WarehouseRepositoryMySQL) txExec(tx *sqlx.Tx, query string, warehouse Warehouse) (err error) {
+	stmt, err := tx.PrepareNamed(query)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(warehouse)
+	if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == mysqlErrDuplicateEntry {
+		return failure.Conflict("save", "code", "already used by another warehouse")
+	}
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+
+	return
+}
+func (r *WarehouseRepositoryMySQL) txUnsetDefault(tx *sqlx.Tx, warehouse Warehouse) (err error) {
+	if !warehouse.IsDefault {
+		return
+	}
+
+	return r.txExec(tx, warehouseQueries.unsetDefault, warehouse)
+}
@@ -0,0 +1,95 @@
+package warehouse
+
+import (
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/gofrs/uuid"
+)
+
+type WarehouseService interface {
+	CreateWarehouse(requestFormat WarehouseRequestFormat, userID uuid.UUID) (warehouse Warehouse, err error)
+	UpdateWarehouse(id uuid.UUID, requestFormat WarehouseRequestFormat, userID uuid.UUID) (warehouse Warehouse, err error)
+	ResolveWarehouses() (warehouses Warehouses, err error)
+	ResolveByID(id uuid.UUID) (warehouse Warehouse, err error)
+	ResolveDefault() (warehouse Warehouse, err error)
+	Allocate(demands []Demand, destination *Location) (allocations map[uuid.UUID][]Allocation, err error)
+}
+
+type WarehouseServiceImpl struct {
+	WarehouseRepository WarehouseRepository
+	Allocator           Allocator
+}
+
+func ProvideWarehouseServiceImpl(warehouseRepository WarehouseRepository, allocator Allocator) *WarehouseServiceImpl {
+	s := new(WarehouseServiceImpl)
+	s.WarehouseRepository = warehouseRepository
+	s.Allocator = allocator
+
+	return s
+}
+
+func (s *WarehouseServiceImpl) CreateWarehouse(requestFormat WarehouseRequestFormat, userID uuid.UUID) (warehouse Warehouse, err error) {
+	warehouse, err = warehouse.NewFromRequestFormat(requestFormat, userID)
+	if err != nil {
+		return warehouse, failure.BadRequest(err)
+	}
+
+	err = s.WarehouseRepository.CreateWarehouse(warehouse)
+	if err != nil {
+		return
+	}
+
+	return
+}
+func (s *WarehouseServiceImpl) UpdateWarehouse(id uuid.UUID, requestFormat WarehouseRequestFormat, userID uuid.UUID) (warehouse Warehouse, err error) {
+	warehouse, err = s.WarehouseRepository.ResolveWarehouseByID(id)
+	if err != nil {
+		return
+	}
+
+	err = warehouse.Update(requestFormat, userID)
+	if err != nil {
+		return warehouse, failure.BadRequest(err)
+	}
+
+	err = s.WarehouseRepository.UpdateWarehouse(warehouse)
+	if err != nil {
+		return
+	}
+
+	return
+}
+func (s *WarehouseServiceImpl) ResolveWarehouses() (warehouses Warehouses, err error) {
+	return s.WarehouseRepository.ResolveWarehouses()
+}
+func (s *WarehouseServiceImpl) ResolveByID(id uuid.UUID) (warehouse Warehouse, err error) {
+	return s.WarehouseRepository.ResolveWarehouseByID(id)
+}
+
+// ResolveDefault resolves the warehouse holding the stock set through
+// products.
+func (s *WarehouseServiceImpl) ResolveDefault() (warehouse Warehouse, err error) {
+	return s.WarehouseRepository.ResolveDefaultWarehouse()
+}
+
+// Allocate picks the warehouses every demand ships from, by demand ID, with
+// the configured allocator. Demands the warehouses cannot fulfil together
+// fail with a conflict.
+func (s *WarehouseServiceImpl) Allocate(demands []Demand, destination *Location) (allocations map[uuid.UUID][]Allocation, err error) {
+	warehouses, err := s.WarehouseRepository.ResolveWarehouses()
+	if err != nil {
+		return
+	}
+
+	allocations = make(map[uuid.UUID][]Allocation, len(demands))
+	for _, demand := range demands {
+		allocations[demand.ID], err = Allocate(s.Allocator, warehouses, demand, destination)
+		if err == ErrNotEnoughStock {
+			return nil, failure.Conflict("allocate", "stock", err.Error())
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return
+}
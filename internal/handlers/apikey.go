@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/evermos/boilerplate-go/internal/domain/apikey"
+	"github.com/evermos/boilerplate-go/shared"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/rbac"
+	"github.com/evermos/boilerplate-go/transport/http/middleware"
+	"github.com/evermos/boilerplate-go/transport/http/response"
+	"github.com/go-chi/chi"
+	"github.com/gofrs/uuid"
+)
+
+type APIKeyHandler struct {
+	APIKeyService  apikey.APIKeyService
+	AuthMiddleware *middleware.Authentication
+}
+
+func ProvideAPIKeyHandler(apiKeyService apikey.APIKeyService, authMiddleware *middleware.Authentication) APIKeyHandler {
+	return APIKeyHandler{
+		APIKeyService:  apiKeyService,
+		AuthMiddleware: authMiddleware,
+	}
+}
+
+func (h *APIKeyHandler) Router(r chi.Router) {
+	r.Route("/api-keys", func(r chi.Router) {
+		r.Group(func(r chi.Router) {
+			r.Use(h.AuthMiddleware.ValidateAuth)
+			r.Use(h.AuthMiddleware.RequirePermission(rbac.PermissionAPIKeyManage))
+			r.Get("/", h.ResolveAPIKeys)
+			r.Post("/", h.CreateAPIKey)
+			r.Post("/{id}/revoke", h.RevokeAPIKey)
+		})
+	})
+}
+
+// CreateAPIKey creates a new API key.
+// @Summary Create a new API key.
+// @Description This endpoint creates a new API key for a service. The key is only stored hashed, and is shown in this response only.
+// @Description Services send the key in the X-API-Key header. Each key is limited to quotaPerMinute requests per minute; zero uses the configured default.
+// @Tags api-keys
+// @Security EVMOauthToken
+// @Param apiKey body apikey.APIKeyRequestFormat true "The API key to be created."
+// @Produce json
+// @Success 201 {object} response.Base{data=apikey.APIKeyResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/api-keys [post]
+func (h *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+	var requestFormat apikey.APIKeyRequestFormat
+	err := decoder.Decode(&requestFormat)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	err = shared.GetValidator().Struct(requestFormat)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	claims, ok := r.Context().Value("claims").(shared.Claims)
+	if !ok {
+		response.WithError(w, failure.Unauthorized("User not authorized"))
+		return
+	}
+
+	key, err := h.APIKeyService.CreateAPIKey(requestFormat, claims.UserID)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	response.WithJSON(w, http.StatusCreated, key)
+}
+
+// ResolveAPIKeys retrieves every API key.
+// @Summary Retrieve every API key.
+// @Description This endpoint retrieves every API key, newest first, without the keys themselves.
+// @Tags api-keys
+// @Security EVMOauthToken
+// @Produce json
+// @Success 200 {object} response.Base{data=[]apikey.APIKeyResponseFormat}
+// @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/api-keys [get]
+func (h *APIKeyHandler) ResolveAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.APIKeyService.ResolveAPIKeys()
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+
+	response.WithJSON(w, http.StatusOK, keys)
+}
+
+// RevokeAPIKey revokes an API key.
+// @Summary Revoke an API key.
+// @Description This endpoint revokes an API key, which stops working immediately.
+// @Tags api-keys
+// @Security EVMOauthToken
+// @Param id path string true "The API key's identifier."
+// @Produce json
+// @Success 200 {object} response.Base{data=apikey.APIKeyResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/api-keys/{id}/revoke [post]
+func (h *APIKeyHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.FromString(chi.URLParam(r, "id"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	claims, ok := r.Context().Value("claims").(shared.Claims)
+	if !ok {
+		response.WithError(w, failure.Unauthorized("User not authorized"))
+		return
+	}
+
+	key, err := h.APIKeyService.RevokeAPIKey(id, claims.UserID)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+
+	response.WithJSON(w, http.StatusOK, key)
+}
@@ -0,0 +1,519 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/evermos/boilerplate-go/internal/domain/user"
+	"github.com/evermos/boilerplate-go/shared"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/logger"
+	"github.com/evermos/boilerplate-go/shared/oauth"
+	"github.com/evermos/boilerplate-go/shared/rbac"
+	"github.com/evermos/boilerplate-go/transport/http/middleware"
+	"github.com/evermos/boilerplate-go/transport/http/response"
+	"github.com/go-chi/chi"
+	"github.com/gofrs/uuid"
+)
+
+type AuthHandler struct {
+	UserService    user.UserService
+	Token          *oauth.Token
+	LoginGuard     *oauth.LoginGuard
+	AuthMiddleware *middleware.Authentication
+}
+
+func ProvideAuthHandler(userService user.UserService, token *oauth.Token, loginGuard *oauth.LoginGuard, authMiddleware *middleware.Authentication) AuthHandler {
+	return AuthHandler{
+		UserService:    userService,
+		Token:          token,
+		LoginGuard:     loginGuard,
+		AuthMiddleware: authMiddleware,
+	}
+}
+
+func (h *AuthHandler) Router(r chi.Router) {
+	r.Route("/auth", func(r chi.Router) {
+		r.Post("/register", h.Register)
+		r.Post("/token", h.CreateToken)
+		r.Post("/revoke", h.RevokeToken)
+		r.Post("/password/forgot", h.RequestPasswordReset)
+		r.Post("/password/reset", h.ResetPassword)
+		r.Post("/email/verify", h.VerifyEmail)
+
+		r.Group(func(r chi.Router) {
+			r.Use(h.AuthMiddleware.Password)
+			r.Post("/email/verification", h.RequestEmailVerification)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(h.AuthMiddleware.ValidateAuth)
+			r.Use(h.AuthMiddleware.RequirePermission(rbac.PermissionUserManage))
+			r.Post("/unlock", h.UnlockLogin)
+		})
+	})
+}
+
+// LoginUnlockRequestFormat is the payload to lift a login lockout. At least
+// one of the username and the IP address is required.
+type LoginUnlockRequestFormat struct {
+	Username string `json:"username" validate:"required_without=IP"`
+	IP       string `json:"ip" validate:"omitempty,ip"`
+}
+
+// tokenRequestFormat is the JSON form of a token or revocation request.
+// Form-encoded requests, as RFC 6749 and RFC 7009 specify them, use the same
+// parameter names.
+type tokenRequestFormat struct {
+	GrantType    string `json:"grant_type"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	Username     string `json:"username"`
+	Password     string `json:"password"`
+	RefreshToken string `json:"refresh_token"`
+	Scope        string `json:"scope"`
+	Code         string `json:"code"`
+	RedirectURI  string `json:"redirect_uri"`
+	CodeVerifier string `json:"code_verifier"`
+	// Token and TokenTypeHint are the parameters of revocation requests.
+	Token         string `json:"token"`
+	TokenTypeHint string `json:"token_type_hint"`
+}
+
+// Register registers a new user.
+// @Summary Register a new user.
+// @Description This endpoint registers a new user. The username, email and telephone must be unique.
+// @Tags auth
+// @Param user body user.RegisterRequestFormat true "The user to be registered."
+// @Produce json
+// @Success 201 {object} response.Base{data=user.UserResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 409 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/auth/register [post]
+func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+	var requestFormat user.RegisterRequestFormat
+	err := decoder.Decode(&requestFormat)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	err = shared.GetValidator().Struct(requestFormat)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	user, err := h.UserService.Register(requestFormat)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+
+	response.WithJSON(w, http.StatusCreated, user)
+}
+
+// CreateToken issues an access token.
+// @Summary Issue an access token.
+// @Description This endpoint issues an access token for the client_credentials, password, refresh_token and authorization_code grants.
+// @Description Password and authorization_code grants also issue a refresh token when the client allows the refresh_token grant.
+// @Description Tokens are issued with the requested scope, which must be within the client's scope.
+// @Description Authorization codes are single use, and require the PKCE code_verifier and the redirect_uri they were issued for.
+// @Description Refresh tokens are rotated on every use, and reusing one revokes all tokens descending from the same login.
+// @Description Clients authenticate with HTTP Basic or with client_id and client_secret parameters.
+// @Description The username of the password grant may be the user's username, email or telephone.
+// @Description Failed logins are throttled per username and IP address with progressive delays, then locked out temporarily; throttled requests get 429 with Retry-After.
+// @Tags auth
+// @Accept x-www-form-urlencoded
+// @Accept json
+// @Param grant_type formData string true "client_credentials, password, refresh_token or authorization_code"
+// @Param client_id formData string false "Client ID, unless sent with HTTP Basic"
+// @Param client_secret formData string false "Client secret, unless sent with HTTP Basic"
+// @Param username formData string false "Username, email or telephone for the password grant"
+// @Param password formData string false "Password for the password grant"
+// @Param refresh_token formData string false "Refresh token for the refresh_token grant"
+// @Param scope formData string false "Space-delimited scope, within the client's scope; defaults to the client's scope, or to the refresh token's"
+// @Param code formData string false "Authorization code for the authorization_code grant"
+// @Param redirect_uri formData string false "Redirect URI the authorization code was issued for"
+// @Param code_verifier formData string false "PKCE code verifier for the authorization_code grant"
+// @Produce json
+// @Success 200 {object} oauth.TokenResponse
+// @Failure 400 {object} oauth.ErrorResponse
+// @Failure 401 {object} oauth.ErrorResponse
+// @Failure 429 {object} oauth.ErrorResponse
+// @Failure 500 {object} oauth.ErrorResponse
+// @Router /v1/auth/token [post]
+func (h *AuthHandler) CreateToken(w http.ResponseWriter, r *http.Request) {
+	requestFormat, err := parseTokenRequest(r)
+	if err != nil {
+		respondOAuth(w, http.StatusBadRequest, oauth.ErrorResponse{Error: oauth.ErrorCodeInvalidRequest, ErrorDescription: err.Error()})
+		return
+	}
+
+	credential := requestFormat.credential()
+	if credential.ClientID == "" {
+		respondOAuthError(w, errors.New(oauth.ErrorEmptyCredential))
+		return
+	}
+
+	if !h.Token.ClientScopeAllowed(credential.ClientID) {
+		respondOAuth(w, http.StatusBadRequest, oauth.ErrorResponse{Error: oauth.ErrorCodeUnauthorizedClient, ErrorDescription: oauth.ErrorInvalidClient})
+		return
+	}
+
+	var token *oauth.TokenResponse
+	if credential.GrantType == oauth.Password {
+		token, err = h.createGuardedToken(credential, clientIP(r, h.LoginGuard.Config.TrustForwardedFor))
+	} else {
+		token, err = h.Token.Create(credential)
+	}
+
+	if throttled, ok := err.(*oauth.LoginThrottledError); ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+	}
+
+	if err != nil {
+		respondOAuthError(w, err)
+		return
+	}
+
+	respondOAuth(w, http.StatusOK, token)
+}
+
+// createGuardedToken issues a token for the password grant, unless the
+// username or the IP address is throttled after failed logins.
+func (h *AuthHandler) createGuardedToken(credential oauth.Credential, ip string) (*oauth.TokenResponse, error) {
+	err := h.LoginGuard.Check(credential.Username, ip)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := h.Token.Create(credential)
+	h.LoginGuard.Record(credential.Username, ip, err)
+
+	return token, err
+}
+
+// UnlockLogin lifts a login lockout.
+// @Summary Lift a login lockout.
+// @Description This endpoint lifts the lockout of a user or an IP address after too many failed logins, and clears its failed logins. Users are unlocked by any of their username, email or telephone. Unlocks are audited.
+// @Tags auth
+// @Security EVMOauthToken
+// @Param unlock body LoginUnlockRequestFormat true "The username or IP address to be unlocked."
+// @Produce json
+// @Success 204
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/auth/unlock [post]
+func (h *AuthHandler) UnlockLogin(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+	var requestFormat LoginUnlockRequestFormat
+	err := decoder.Decode(&requestFormat)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	err = shared.GetValidator().Struct(requestFormat)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	claims, ok := r.Context().Value("claims").(shared.Claims)
+	if !ok {
+		response.WithError(w, failure.Unauthorized("User not authorized"))
+		return
+	}
+
+	err = h.LoginGuard.Unlock(requestFormat.Username, requestFormat.IP, claims.UserID.String())
+	if err != nil {
+		response.WithError(w, failure.InternalError(err))
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// RevokeToken revokes an access or refresh token.
+// @Summary Revoke a token.
+// @Description This endpoint revokes an access or refresh token following RFC 7009.
+// @Description Revoking a refresh token also revokes all tokens descending from the same login.
+// @Description Unknown tokens are ignored, so the response is the same whether the token existed or not.
+// @Tags auth
+// @Accept x-www-form-urlencoded
+// @Accept json
+// @Param token formData string true "The token to be revoked"
+// @Param token_type_hint formData string false "access_token or refresh_token"
+// @Param client_id formData string false "Client ID, unless sent with HTTP Basic"
+// @Param client_secret formData string false "Client secret, unless sent with HTTP Basic"
+// @Success 200
+// @Failure 400 {object} oauth.ErrorResponse
+// @Failure 401 {object} oauth.ErrorResponse
+// @Failure 500 {object} oauth.ErrorResponse
+// @Router /v1/auth/revoke [post]
+func (h *AuthHandler) RevokeToken(w http.ResponseWriter, r *http.Request) {
+	requestFormat, err := parseTokenRequest(r)
+	if err != nil {
+		respondOAuth(w, http.StatusBadRequest, oauth.ErrorResponse{Error: oauth.ErrorCodeInvalidRequest, ErrorDescription: err.Error()})
+		return
+	}
+
+	if requestFormat.Token == "" {
+		respondOAuth(w, http.StatusBadRequest, oauth.ErrorResponse{Error: oauth.ErrorCodeInvalidRequest, ErrorDescription: "token is required"})
+		return
+	}
+
+	credential := requestFormat.credential()
+	if credential.ClientID == "" {
+		respondOAuthError(w, errors.New(oauth.ErrorEmptyCredential))
+		return
+	}
+
+	err = h.Token.Revoke(credential, requestFormat.Token, requestFormat.TokenTypeHint)
+	if err != nil {
+		respondOAuthError(w, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+}
+
+// RequestPasswordReset sends a password reset link.
+// @Summary Request a password reset link.
+// @Description This endpoint mails a single use, expiring password reset link to the user with the email.
+// @Description The response is the same whether the email is registered or not.
+// @Tags auth
+// @Param request body user.PasswordResetRequestFormat true "The email of the user."
+// @Produce json
+// @Success 202 {object} response.Base
+// @Failure 400 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/auth/password/forgot [post]
+func (h *AuthHandler) RequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+	var requestFormat user.PasswordResetRequestFormat
+	err := decoder.Decode(&requestFormat)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	err = shared.GetValidator().Struct(requestFormat)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	err = h.UserService.RequestPasswordReset(requestFormat)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+
+	response.WithMessage(w, http.StatusAccepted, "If the email is registered, a password reset link was sent to it.")
+}
+
+// ResetPassword sets a new password with a password reset token.
+// @Summary Reset a password.
+// @Description This endpoint sets a new password with a password reset token. The token can only be used once.
+// @Description All access and refresh tokens of the user are revoked, so that every session has to log in again.
+// @Tags auth
+// @Param request body user.PasswordResetConfirmFormat true "The password reset token and the new password."
+// @Produce json
+// @Success 204
+// @Failure 400 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/auth/password/reset [post]
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+	var requestFormat user.PasswordResetConfirmFormat
+	err := decoder.Decode(&requestFormat)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	err = shared.GetValidator().Struct(requestFormat)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	err = h.UserService.ResetPassword(requestFormat)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// RequestEmailVerification sends an email verification link.
+// @Summary Request an email verification link.
+// @Description This endpoint mails a single use, expiring email verification link to the logged in user.
+// @Tags auth
+// @Security EVMOauthToken
+// @Produce json
+// @Success 202 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 409 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/auth/email/verification [post]
+func (h *AuthHandler) RequestEmailVerification(w http.ResponseWriter, r *http.Request) {
+	accessToken := r.Context().Value("accessToken").(oauth.OauthAccessToken)
+
+	userID, err := uuid.FromString(accessToken.UserID.String)
+	if err != nil {
+		response.WithError(w, failure.Unauthorized("User not authorized"))
+		return
+	}
+
+	err = h.UserService.RequestEmailVerification(userID)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+
+	response.WithMessage(w, http.StatusAccepted, "An email verification link was sent.")
+}
+
+// VerifyEmail verifies an email with an email verification token.
+// @Summary Verify an email.
+// @Description This endpoint verifies the email of a user with an email verification token. The token can only be used once.
+// @Tags auth
+// @Param request body user.EmailVerificationFormat true "The email verification token."
+// @Produce json
+// @Success 200 {object} response.Base{data=user.UserResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/auth/email/verify [post]
+func (h *AuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+	var requestFormat user.EmailVerificationFormat
+	err := decoder.Decode(&requestFormat)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	err = shared.GetValidator().Struct(requestFormat)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	user, err := h.UserService.VerifyEmail(requestFormat)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+
+	response.WithJSON(w, http.StatusOK, user)
+}
+
+// parseTokenRequest reads a token or revocation request from a form-encoded
+// or JSON body. Client credentials sent with HTTP Basic take precedence over
+// the body.
+func parseTokenRequest(r *http.Request) (requestFormat tokenRequestFormat, err error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/json" {
+		err = json.NewDecoder(r.Body).Decode(&requestFormat)
+		if err != nil {
+			return
+		}
+	} else {
+		err = r.ParseForm()
+		if err != nil {
+			return
+		}
+
+		requestFormat = tokenRequestFormat{
+			GrantType:     r.PostForm.Get("grant_type"),
+			ClientID:      r.PostForm.Get("client_id"),
+			ClientSecret:  r.PostForm.Get("client_secret"),
+			Username:      r.PostForm.Get("username"),
+			Password:      r.PostForm.Get("password"),
+			RefreshToken:  r.PostForm.Get("refresh_token"),
+			Scope:         r.PostForm.Get("scope"),
+			Code:          r.PostForm.Get("code"),
+			RedirectURI:   r.PostForm.Get("redirect_uri"),
+			CodeVerifier:  r.PostForm.Get("code_verifier"),
+			Token:         r.PostForm.Get("token"),
+			TokenTypeHint: r.PostForm.Get("token_type_hint"),
+		}
+	}
+
+	if clientID, clientSecret, ok := r.BasicAuth(); ok {
+		requestFormat.ClientID = clientID
+		requestFormat.ClientSecret = clientSecret
+	}
+
+	return
+}
+
+// clientIP returns the IP address of the client. X-Forwarded-For is only
+// trusted when set by a proxy, whose entry is the last one.
+func clientIP(r *http.Request, trustForwardedFor bool) string {
+	if trustForwardedFor {
+		forwarded := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+		if ip := strings.TrimSpace(forwarded[len(forwarded)-1]); ip != "" {
+			return ip
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+func (f tokenRequestFormat) credential() oauth.Credential {
+	return oauth.Credential{
+		GrantType:    oauth.GrantType(f.GrantType),
+		ClientID:     f.ClientID,
+		ClientSecret: f.ClientSecret,
+		Username:     f.Username,
+		Password:     f.Password,
+		RefreshToken: f.RefreshToken,
+		Scope:        f.Scope,
+		Code:         f.Code,
+		RedirectURI:  f.RedirectURI,
+		CodeVerifier: f.CodeVerifier,
+	}
+}
+
+func respondOAuthError(w http.ResponseWriter, err error) {
+	status, errorResponse := oauth.NewErrorResponse(err)
+	if status == http.StatusInternalServerError {
+		logger.ErrorWithStack(err)
+	}
+
+	respondOAuth(w, status, errorResponse)
+}
+
+// respondOAuth writes an unwrapped OAuth2 response, which must not be cached.
+func respondOAuth(w http.ResponseWriter, code int, payload interface{}) {
+	body, _ := json.Marshal(payload)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	w.WriteHeader(code)
+	_, err := w.Write(body)
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/evermos/boilerplate-go/internal/domain/cart"
 	"github.com/evermos/boilerplate-go/shared"
 	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/rbac"
 	"github.com/evermos/boilerplate-go/transport/http/middleware"
 	"github.com/evermos/boilerplate-go/transport/http/response"
 	"github.com/go-chi/chi"
@@ -28,8 +29,8 @@ func (h *CartHandler) Router(r chi.Router) {
 	r.Route("/carts", func(r chi.Router) {
 		r.Group(func(r chi.Router) {
 			r.Use(h.AuthMiddleware.ValidateAuth)
-			r.Post("/", h.AddToCart)
-			r.Get("/", h.GetCartByUserID)
+			r.With(h.AuthMiddleware.RequirePermission(rbac.PermissionCartWrite)).Post("/", h.AddToCart)
+			r.With(h.AuthMiddleware.RequirePermission(rbac.PermissionCartRead)).Get("/", h.GetCartByUserID)
 		})
 	})
 }
@@ -42,6 +43,7 @@ func (h *CartHandler) Router(r chi.Router) {
 // @Produce json
 // @Success 200 {object} response.Base{data=cart.CartResponseFormat}
 // @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
 // @Failure 500 {object} response.Base
 // @Router /v1/carts [get]
 func (h *CartHandler) GetCartByUserID(w http.ResponseWriter, r *http.Request) {
@@ -62,7 +64,7 @@ func (h *CartHandler) GetCartByUserID(w http.ResponseWriter, r *http.Request) {
 
 // AddToCart adds an item to the user's cart.
 // @Summary Add an item to the cart.
-// @Description This endpoint adds an item to the cart of the current authenticated user.
+// @Description This endpoint adds a product variant to the cart of the current authenticated user. The variantID may be left out for products sold without options.
 // @Tags cart
 // @Security EVMOauthToken
 // @Param item body cart.CartItemRequestFormat true "The item to be added to the cart."
@@ -70,6 +72,7 @@ func (h *CartHandler) GetCartByUserID(w http.ResponseWriter, r *http.Request) {
 // @Success 201 {object} response.Base{data=cart.CartItemResponseFormat}
 // @Failure 400 {object} response.Base
 // @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
 // @Failure 500 {object} response.Base
 // @Router /v1/carts [post]
 func (h *CartHandler) AddToCart(w http.ResponseWriter, r *http.Request) {
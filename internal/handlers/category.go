@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/evermos/boilerplate-go/internal/domain/category"
+	"github.com/evermos/boilerplate-go/shared"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/rbac"
+	"github.com/evermos/boilerplate-go/transport/http/middleware"
+	"github.com/evermos/boilerplate-go/transport/http/response"
+	"github.com/go-chi/chi"
+	"github.com/gofrs/uuid"
+)
+
+type CategoryHandler struct {
+	CategoryService category.CategoryService
+	AuthMiddleware  *middleware.Authentication
+}
+
+func ProvideCategoryHandler(categoryService category.CategoryService, authMiddleware *middleware.Authentication) CategoryHandler {
+	return CategoryHandler{
+		CategoryService: categoryService,
+		AuthMiddleware:  authMiddleware,
+	}
+}
+
+func (h *CategoryHandler) Router(r chi.Router) {
+	r.Route("/categories", func(r chi.Router) {
+		r.Group(func(r chi.Router) {
+			r.Use(h.AuthMiddleware.ValidateAuth)
+			r.With(h.AuthMiddleware.RequirePermission(rbac.PermissionProductRead)).Get("/", h.ResolveCategoryTree)
+			r.With(h.AuthMiddleware.RequirePermission(rbac.PermissionCategoryManage)).Post("/", h.CreateCategory)
+			r.With(h.AuthMiddleware.RequirePermission(rbac.PermissionCategoryManage)).Put("/{id}", h.UpdateCategory)
+		})
+	})
+}
+
+// ResolveCategoryTree retrieves the category tree.
+// @Summary Retrieve the category tree.
+// @Description This endpoint retrieves every category as a tree: the top-level categories, each with its children.
+// @Description Siblings are ordered by position, then by name. Products are filtered by category slug.
+// @Tags categories
+// @Security EVMOauthToken
+// @Produce json
+// @Success 200 {object} response.Base{data=[]category.CategoryResponseFormat}
+// @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/categories [get]
+func (h *CategoryHandler) ResolveCategoryTree(w http.ResponseWriter, r *http.Request) {
+	categories, err := h.CategoryService.ResolveCategories()
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+
+	response.WithJSON(w, http.StatusOK, categories.Tree())
+}
+
+// CreateCategory creates a new category.
+// @Summary Create a new category.
+// @Description This endpoint creates a new category, below its parent if any. Without a slug, one is derived from the name.
+// @Tags categories
+// @Security EVMOauthToken
+// @Param category body category.CategoryRequestFormat true "The category to be created."
+// @Produce json
+// @Success 201 {object} response.Base{data=category.CategoryResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
+// @Failure 409 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/categories [post]
+func (h *CategoryHandler) CreateCategory(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+	var requestFormat category.CategoryRequestFormat
+	err := decoder.Decode(&requestFormat)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	err = shared.GetValidator().Struct(requestFormat)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	claims, ok := r.Context().Value("claims").(shared.Claims)
+	if !ok {
+		response.WithError(w, failure.Unauthorized("User not authorized"))
+		return
+	}
+
+	created, err := h.CategoryService.CreateCategory(requestFormat, claims.UserID)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+
+	response.WithJSON(w, http.StatusCreated, created)
+}
+
+// UpdateCategory updates a category.
+// @Summary Update a category.
+// @Description This endpoint replaces the name, slug, parent and position of a category. Without a slug, the category keeps its own.
+// @Description A category cannot be moved below itself. Renaming a category renames it on its products too.
+// @Tags categories
+// @Security EVMOauthToken
+// @Param id path string true "The category's identifier."
+// @Param category body category.CategoryRequestFormat true "The category to be updated."
+// @Produce json
+// @Success 200 {object} response.Base{data=category.CategoryResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 409 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/categories/{id} [put]
+func (h *CategoryHandler) UpdateCategory(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.FromString(chi.URLParam(r, "id"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	var requestFormat category.CategoryRequestFormat
+	err = decoder.Decode(&requestFormat)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	err = shared.GetValidator().Struct(requestFormat)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	claims, ok := r.Context().Value("claims").(shared.Claims)
+	if !ok {
+		response.WithError(w, failure.Unauthorized("User not authorized"))
+		return
+	}
+
+	updated, err := h.CategoryService.UpdateCategory(id, requestFormat, claims.UserID)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+
+	response.WithJSON(w, http.StatusOK, updated)
+}
@@ -0,0 +1,311 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/evermos/boilerplate-go/internal/domain/oauthclient"
+	"github.com/evermos/boilerplate-go/shared"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/oauth"
+	"github.com/evermos/boilerplate-go/shared/rbac"
+	"github.com/evermos/boilerplate-go/transport/http/middleware"
+	"github.com/evermos/boilerplate-go/transport/http/response"
+	"github.com/go-chi/chi"
+)
+
+type OAuthHandler struct {
+	ClientService  oauthclient.ClientService
+	Token          *oauth.Token
+	Introspector   *oauth.Introspector
+	AuthMiddleware *middleware.Authentication
+}
+
+func ProvideOAuthHandler(clientService oauthclient.ClientService, token *oauth.Token, introspector *oauth.Introspector, authMiddleware *middleware.Authentication) OAuthHandler {
+	return OAuthHandler{
+		ClientService:  clientService,
+		Token:          token,
+		Introspector:   introspector,
+		AuthMiddleware: authMiddleware,
+	}
+}
+
+func (h *OAuthHandler) Router(r chi.Router) {
+	r.Route("/oauth", func(r chi.Router) {
+		r.Group(func(r chi.Router) {
+			r.Use(h.AuthMiddleware.ClientCredentialGrant)
+			r.Use(h.AuthMiddleware.RequireScopes(oauth.ScopeOAuthIntrospect))
+			r.Post("/introspect", h.Introspect)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(h.AuthMiddleware.Password)
+			r.Get("/authorize", h.ResolveAuthorization)
+			r.Post("/authorize", h.Authorize)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(h.AuthMiddleware.ValidateAuth)
+			r.Use(h.AuthMiddleware.RequirePermission(rbac.PermissionClientManage))
+			r.Post("/clients", h.CreateClient)
+			r.Post("/clients/{clientId}/secret", h.RotateClientSecret)
+			r.Post("/clients/{clientId}/disable", h.DisableClient)
+		})
+	})
+}
+
+// Introspect introspects an access token.
+// @Summary Introspect an access token.
+// @Description This endpoint tells whether an access token is active, and returns its scope, client, subject and expiry, following RFC 7662.
+// @Description Callers authenticate with a client credentials access token with the oauth:introspect scope, such as one of client_service. Tokens issued to users are refused. Responses are cached briefly.
+// @Tags oauth
+// @Security EVMOauthToken
+// @Accept x-www-form-urlencoded
+// @Accept json
+// @Param token formData string true "The access token to be introspected"
+// @Param token_type_hint formData string false "access_token"
+// @Produce json
+// @Success 200 {object} oauth.IntrospectionResponse
+// @Failure 400 {object} oauth.ErrorResponse
+// @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
+// @Failure 500 {object} oauth.ErrorResponse
+// @Router /v1/oauth/introspect [post]
+func (h *OAuthHandler) Introspect(w http.ResponseWriter, r *http.Request) {
+	requestFormat, err := parseTokenRequest(r)
+	if err != nil || requestFormat.Token == "" {
+		respondOAuth(w, http.StatusBadRequest, oauth.ErrorResponse{Error: oauth.ErrorCodeInvalidRequest, ErrorDescription: "token is required"})
+		return
+	}
+
+	introspection, err := h.Introspector.Introspect(requestFormat.Token)
+	if err != nil {
+		respondOAuthError(w, err)
+		return
+	}
+
+	respondOAuth(w, http.StatusOK, introspection)
+}
+
+// AuthorizationResponse tells the consent screen where to send the user once
+// the user approved or denied an authorization request.
+type AuthorizationResponse struct {
+	RedirectTo string `json:"redirectTo"`
+}
+
+// ResolveAuthorization validates an authorization request for consent.
+// @Summary Validate an authorization request for consent.
+// @Description This endpoint validates an authorization code request of a third-party client, and returns what the user is asked to consent to.
+// @Description The redirect_uri must match the client's exactly, and a S256 PKCE code_challenge is required.
+// @Description The user authenticates with an access token of the password grant.
+// @Tags oauth
+// @Security EVMOauthToken
+// @Param response_type query string true "code"
+// @Param client_id query string true "Client ID"
+// @Param redirect_uri query string false "Redirect URI, defaults to the client's"
+// @Param scope query string false "Requested scope, defaults to the client's scope"
+// @Param state query string false "Opaque value returned to the client"
+// @Param code_challenge query string true "PKCE code challenge"
+// @Param code_challenge_method query string true "S256"
+// @Produce json
+// @Success 200 {object} oauth.ValidAuthorization
+// @Failure 400 {object} oauth.ErrorResponse
+// @Failure 401 {object} response.Base
+// @Failure 500 {object} oauth.ErrorResponse
+// @Router /v1/oauth/authorize [get]
+func (h *OAuthHandler) ResolveAuthorization(w http.ResponseWriter, r *http.Request) {
+	authorization, err := h.Token.ValidateAuthorizationRequest(parseAuthorizationRequest(r))
+	if err != nil {
+		respondOAuthError(w, err)
+		return
+	}
+
+	respondOAuth(w, http.StatusOK, authorization)
+}
+
+// Authorize records the user's consent to an authorization request.
+// @Summary Approve or deny an authorization request.
+// @Description This endpoint issues a short-lived, single use authorization code when the user approves an authorization request, and returns the redirect URI carrying it.
+// @Description When the user denies the request, or the request is invalid, the redirect URI carries the error instead.
+// @Description Errors about the client or the redirect_uri are never redirected, as the redirect URI is not trusted.
+// @Tags oauth
+// @Security EVMOauthToken
+// @Accept x-www-form-urlencoded
+// @Param response_type formData string true "code"
+// @Param client_id formData string true "Client ID"
+// @Param redirect_uri formData string false "Redirect URI, defaults to the client's"
+// @Param scope formData string false "Requested scope, defaults to the client's scope"
+// @Param state formData string false "Opaque value returned to the client"
+// @Param code_challenge formData string true "PKCE code challenge"
+// @Param code_challenge_method formData string true "S256"
+// @Param approved formData boolean true "Whether the user approved the request"
+// @Produce json
+// @Success 200 {object} AuthorizationResponse
+// @Failure 400 {object} oauth.ErrorResponse
+// @Failure 401 {object} response.Base
+// @Failure 500 {object} oauth.ErrorResponse
+// @Router /v1/oauth/authorize [post]
+func (h *OAuthHandler) Authorize(w http.ResponseWriter, r *http.Request) {
+	authorization, err := h.Token.ValidateAuthorizationRequest(parseAuthorizationRequest(r))
+	if err != nil && authorization.RedirectURI == "" {
+		respondOAuthError(w, err)
+		return
+	}
+
+	approved, _ := strconv.ParseBool(r.Form.Get("approved"))
+	if err == nil && !approved {
+		err = errors.New(oauth.ErrorAccessDenied)
+	}
+
+	if err != nil {
+		status, errorResponse := oauth.NewErrorResponse(err)
+		if status == http.StatusInternalServerError {
+			respondOAuthError(w, err)
+			return
+		}
+
+		respondOAuth(w, http.StatusOK, AuthorizationResponse{
+			RedirectTo: authorization.RedirectWith(url.Values{
+				"error":             {errorResponse.Error},
+				"error_description": {errorResponse.ErrorDescription},
+			}),
+		})
+		return
+	}
+
+	accessToken := r.Context().Value("accessToken").(oauth.OauthAccessToken)
+
+	code, err := h.Token.Authorize(authorization, accessToken.UserID.String)
+	if err != nil {
+		respondOAuthError(w, err)
+		return
+	}
+
+	respondOAuth(w, http.StatusOK, AuthorizationResponse{
+		RedirectTo: authorization.RedirectWith(url.Values{"code": {code}}),
+	})
+}
+
+// parseAuthorizationRequest reads an authorization request from the query, or
+// from a form-encoded body.
+func parseAuthorizationRequest(r *http.Request) oauth.AuthorizationRequest {
+	_ = r.ParseForm()
+
+	return oauth.AuthorizationRequest{
+		ResponseType:        r.Form.Get("response_type"),
+		ClientID:            r.Form.Get("client_id"),
+		RedirectURI:         r.Form.Get("redirect_uri"),
+		Scope:               r.Form.Get("scope"),
+		State:               r.Form.Get("state"),
+		CodeChallenge:       r.Form.Get("code_challenge"),
+		CodeChallengeMethod: r.Form.Get("code_challenge_method"),
+	}
+}
+
+// CreateClient creates a new OAuth2 client.
+// @Summary Create a new OAuth2 client.
+// @Description This endpoint creates a new OAuth2 client with a generated secret. The secret is only stored hashed, and is shown in this response only.
+// @Description Clients allowed the authorization_code grant require a redirectUri.
+// @Tags oauth
+// @Security EVMOauthToken
+// @Param client body oauthclient.ClientRequestFormat true "The client to be created."
+// @Produce json
+// @Success 201 {object} response.Base{data=oauthclient.ClientResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
+// @Failure 409 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/oauth/clients [post]
+func (h *OAuthHandler) CreateClient(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+	var requestFormat oauthclient.ClientRequestFormat
+	err := decoder.Decode(&requestFormat)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	err = shared.GetValidator().Struct(requestFormat)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	claims, ok := r.Context().Value("claims").(shared.Claims)
+	if !ok {
+		response.WithError(w, failure.Unauthorized("User not authorized"))
+		return
+	}
+
+	client, err := h.ClientService.CreateClient(requestFormat, claims.UserID)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	response.WithJSON(w, http.StatusCreated, client)
+}
+
+// RotateClientSecret replaces the secret of an OAuth2 client.
+// @Summary Rotate the secret of an OAuth2 client.
+// @Description This endpoint replaces the secret of an OAuth2 client with a new generated one. The new secret is shown in this response only, and the old secret stops working immediately.
+// @Tags oauth
+// @Security EVMOauthToken
+// @Param clientId path string true "The client's identifier."
+// @Produce json
+// @Success 200 {object} response.Base{data=oauthclient.ClientResponseFormat}
+// @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/oauth/clients/{clientId}/secret [post]
+func (h *OAuthHandler) RotateClientSecret(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value("claims").(shared.Claims)
+	if !ok {
+		response.WithError(w, failure.Unauthorized("User not authorized"))
+		return
+	}
+
+	client, err := h.ClientService.RotateSecret(chi.URLParam(r, "clientId"), claims.UserID)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	response.WithJSON(w, http.StatusOK, client)
+}
+
+// DisableClient disables an OAuth2 client.
+// @Summary Disable an OAuth2 client.
+// @Description This endpoint disables an OAuth2 client, and revokes every token and authorization code issued to it.
+// @Tags oauth
+// @Security EVMOauthToken
+// @Param clientId path string true "The client's identifier."
+// @Produce json
+// @Success 200 {object} response.Base{data=oauthclient.ClientResponseFormat}
+// @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/oauth/clients/{clientId}/disable [post]
+func (h *OAuthHandler) DisableClient(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value("claims").(shared.Claims)
+	if !ok {
+		response.WithError(w, failure.Unauthorized("User not authorized"))
+		return
+	}
+
+	client, err := h.ClientService.DisableClient(chi.URLParam(r, "clientId"), claims.UserID)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+
+	response.WithJSON(w, http.StatusOK, client)
+}
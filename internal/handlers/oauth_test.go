@@ -0,0 +1,83 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/evermos/boilerplate-go/internal/handlers"
+	"github.com/evermos/boilerplate-go/shared/oauth"
+	"github.com/evermos/boilerplate-go/transport/http/middleware"
+	"github.com/go-chi/chi"
+	"github.com/go-redis/redis"
+	"github.com/guregu/null"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOAuthHandlerIntrospect(t *testing.T) {
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+	defer server.Close()
+
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	store := oauth.NewTokenStoreRedis(client)
+	token := oauth.New(nil, store, oauth.Config{})
+	handler := handlers.ProvideOAuthHandler(nil, token, &oauth.Introspector{Token: token, Redis: client, TTL: time.Second}, middleware.ProvideAuthentication(nil, nil, nil, token, nil))
+
+	router := chi.NewRouter()
+	handler.Router(router)
+
+	for _, accessToken := range []oauth.OauthAccessToken{
+		{AccessToken: "service", ClientID: "client_service", Scope: null.StringFrom(oauth.ScopeOAuthIntrospect)},
+		{AccessToken: "unscoped", ClientID: "client_service", Scope: null.StringFrom(oauth.ScopeInventoryRead)},
+		{AccessToken: "user", ClientID: "client_web", UserID: null.StringFrom("user-1"), Scope: null.StringFrom(oauth.ScopeOAuthIntrospect)},
+	} {
+		accessToken.Expires = time.Now().Add(time.Hour)
+		require.NoError(t, store.CreateAccessToken(accessToken))
+	}
+
+	introspect := func(accessToken string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/oauth/introspect", strings.NewReader(url.Values{"token": {"user"}}.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.Header.Set(middleware.HeaderAuthorization, "Bearer "+accessToken)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("Client Credentials", func(t *testing.T) {
+		w := introspect("service")
+
+		require.Equal(t, http.StatusOK, w.Code)
+		var response oauth.IntrospectionResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.True(t, response.Active)
+		assert.Equal(t, "user-1", response.Sub)
+	})
+
+	t.Run("Missing Scope", func(t *testing.T) {
+		w := introspect("unscoped")
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("User Token", func(t *testing.T) {
+		w := introspect("user")
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.NotContains(t, w.Body.String(), "user-1")
+	})
+
+	t.Run("Unknown Token", func(t *testing.T) {
+		w := introspect("unknown")
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/evermos/boilerplate-go/internal/domain/order"
 	"github.com/evermos/boilerplate-go/shared"
 	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/rbac"
 	"github.com/evermos/boilerplate-go/transport/http/middleware"
 	"github.com/evermos/boilerplate-go/transport/http/response"
 	"github.com/go-chi/chi"
@@ -28,7 +29,7 @@ func (h *OrderHandler) Router(r chi.Router) {
 	r.Route("/orders", func(r chi.Router) {
 		r.Group(func(r chi.Router) {
 			r.Use(h.AuthMiddleware.ValidateAuth)
-			r.Post("/checkout", h.CheckoutOrder)
+			r.With(h.AuthMiddleware.RequirePermission(rbac.PermissionOrderWrite)).Post("/checkout", h.CheckoutOrder)
 		})
 	})
 }
@@ -36,6 +37,8 @@ func (h *OrderHandler) Router(r chi.Router) {
 // CheckoutOrder checks out the user's cart and creates an order.
 // @Summary Checkout the user's cart and create an order.
 // @Description This endpoint checks out the user's cart, creates an order, and returns the order details.
+// @Description Each item ships from warehouses holding its stock, picked by the configured allocation: the nearest to the coordinates of the shipping address, when given, or the one holding the most.
+// @Description Items no single warehouse holds enough of are split across warehouses. The order records the warehouse of each item.
 // @Tags order
 // @Security EVMOauthToken
 // @Param order body order.OrderRequestFormat true "The order details and items."
@@ -43,6 +46,8 @@ func (h *OrderHandler) Router(r chi.Router) {
 // @Success 201 {object} response.Base{data=order.OrderResponseFormat}
 // @Failure 400 {object} response.Base
 // @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
+// @Failure 409 {object} response.Base
 // @Failure 500 {object} response.Base
 // @Router /v1/orders/checkout [post]
 func (h *OrderHandler) CheckoutOrder(w http.ResponseWriter, r *http.Request) {
@@ -2,16 +2,29 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
+	"io/ioutil"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 
 	"github.com/evermos/boilerplate-go/internal/domain/product"
 	"github.com/evermos/boilerplate-go/shared"
 	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/oauth"
+	"github.com/evermos/boilerplate-go/shared/rbac"
 	"github.com/evermos/boilerplate-go/transport/http/middleware"
 	"github.com/evermos/boilerplate-go/transport/http/response"
 	"github.com/go-chi/chi"
+	"github.com/gofrs/uuid"
+	"github.com/guregu/null"
 )
 
+// multipartOverheadBytes is the room left for the multipart framing around an
+// uploaded image.
+const multipartOverheadBytes = 1 << 20
+
 type ProductHandler struct {
 	ProductService product.ProductService
 	AuthMiddleware *middleware.Authentication
@@ -28,65 +41,149 @@ func (h *ProductHandler) Router(r chi.Router) {
 	r.Route("/products", func(r chi.Router) {
 		r.Group(func(r chi.Router) {
 			r.Use(h.AuthMiddleware.ValidateAuth)
-			r.Get("/", h.ResolveProducts)
-			r.Post("/", h.CreateProduct)
+			r.With(h.AuthMiddleware.RequirePermission(rbac.PermissionProductRead)).Get("/", h.ResolveProducts)
+			r.With(h.AuthMiddleware.RequirePermission(rbac.PermissionProductWrite)).Post("/", h.CreateProduct)
+			r.With(h.AuthMiddleware.RequirePermission(rbac.PermissionProductRead)).Get("/{id}", h.ResolveProductByID)
+			r.With(h.AuthMiddleware.RequirePermission(rbac.PermissionProductWrite)).Put("/{id}", h.UpdateProduct)
+			r.With(h.AuthMiddleware.RequirePermission(rbac.PermissionProductWrite)).Delete("/{id}", h.SoftDeleteProduct)
+			r.With(h.AuthMiddleware.RequirePermission(rbac.PermissionProductWrite)).Post("/{id}/images", h.AddProductImage)
+			r.With(h.AuthMiddleware.RequirePermission(rbac.PermissionProductWrite)).Delete("/{id}/images/{imageID}", h.DeleteProductImage)
+			r.With(h.AuthMiddleware.RequirePermission(rbac.PermissionProductWrite)).Post("/{id}/stock-movements", h.AdjustProductStock)
+			r.With(h.AuthMiddleware.RequirePermission(rbac.PermissionProductWrite)).Get("/{id}/stock-history", h.ResolveProductStockHistory)
+		})
+	})
+
+	// The jobs syncing inventory authenticate as services, with an API key
+	// or a client credentials access token, acting for the user owning the
+	// key or the admin who created the client. Tokens issued to users, such
+	// as browser sessions, are refused.
+	r.Route("/inventory/products", func(r chi.Router) {
+		r.Group(func(r chi.Router) {
+			r.Use(h.AuthMiddleware.ServiceCredential)
+			r.With(h.AuthMiddleware.RequireScopes(oauth.ScopeInventoryWrite)).Post("/{id}/stock-movements", h.SyncProductStock)
+			r.With(h.AuthMiddleware.RequireScopes(oauth.ScopeInventoryRead)).Get("/{id}/stock-history", h.ResolveSyncedProductStockHistory)
 		})
 	})
 }
 
+// maxProductsLimit is the largest page of products.
+const maxProductsLimit = 100
+
 // ResolveProducts retrieves a list of products with optional filtering and pagination.
 // @Summary Retrieve a list of products.
 // @Description This endpoint retrieves a list of products with optional filtering and pagination.
+// @Description Lists are paginated by cursor: pass the nextCursor of a page, along with the same filters, to resolve the next one. The cursor keeps the sort of its list.
+// @Description Products searched by keywords, which may contain typos, are ordered by relevance rather than by sort, and paginated by page.
+// @Description Facets count the products matching the filters across all pages per brand and per category, the most common first.
 // @Tags products
 // @Security EVMOauthToken
-// @Param page query integer false "Page number for pagination (default 1)"
-// @Param limit query integer false "Number of items per page (default 10)"
-// @Param sort query string false "Sort field for ordering"
+// @Param limit query integer false "Number of items per page (default 10, at most 100)"
+// @Param cursor query string false "The nextCursor of the previous page"
+// @Param page query integer false "Page number of keyword searches (default 1)"
+// @Param sort query string false "Sort field for ordering: name, price, brand, stock or created_at (default, newest first)"
 // @Param order query string false "Sort order ('asc' or 'desc')"
-// @Param brand query string false "Filter by brand"
-// @Param category query string false "Filter by category"
+// @Param brand query []string false "Filter by brands, repeated or comma separated" collectionFormat(multi)
+// @Param category query []string false "Filter by category slugs, repeated or comma separated. Categories match the categories below them too" collectionFormat(multi)
+// @Param minPrice query number false "Filter by a price of at least minPrice"
+// @Param maxPrice query number false "Filter by a price of at most maxPrice"
+// @Param inStock query boolean false "Filter by products in stock"
+// @Param q query string false "Keywords searched in the name, brand and category"
 // @Produce json
-// @Success 200 {object} response.Base{data=product.ProductResponseFormat}
+// @Success 200 {object} response.Base{data=shared.Page}
 // @Failure 400 {object} response.Base
 // @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
 // @Failure 500 {object} response.Base
 // @Router /v1/products [get]
 func (h *ProductHandler) ResolveProducts(w http.ResponseWriter, r *http.Request) {
-	pageString := r.URL.Query().Get("page")
-	page, err := shared.ConvertQueryParamsToInt(pageString)
-	if err != nil || page < 0 {
+	query := r.URL.Query()
+
+	page, err := shared.ConvertQueryParamsToInt(query.Get("page"))
+	if err != nil || page <= 0 {
 		page = 1
 	}
 
-	limitString := r.URL.Query().Get("limit")
-	limit, err := shared.ConvertQueryParamsToInt(limitString)
+	limit, err := shared.ConvertQueryParamsToInt(query.Get("limit"))
 	if err != nil || limit <= 0 {
 		limit = 10
 	}
+	if limit > maxProductsLimit {
+		limit = maxProductsLimit
+	}
 
 	params := product.ProductQueryParams{
-		Page:     page,
-		Limit:    limit,
-		Sort:     r.URL.Query().Get("sort"),
-		Order:    r.URL.Query().Get("order"),
-		Brand:    r.URL.Query().Get("brand"),
-		Category: r.URL.Query().Get("category"),
+		Page:       page,
+		Limit:      limit,
+		Sort:       query.Get("sort"),
+		Order:      query.Get("order"),
+		Cursor:     query.Get("cursor"),
+		Brands:     queryList(query, "brand"),
+		Categories: queryList(query, "category"),
+		Query:      strings.TrimSpace(query.Get("q")),
 	}
 
-	products, total, err := h.ProductService.GetProducts(params)
+	params.MinPrice, err = queryFloat(query, "minPrice")
 	if err != nil {
-		response.WithError(w, err)
+		response.WithError(w, failure.BadRequest(err))
 		return
 	}
 
-	resp, err := h.ProductService.CreatePaginationResponse(products, total, limit, page)
+	params.MaxPrice, err = queryFloat(query, "maxPrice")
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	if inStock := query.Get("inStock"); inStock != "" {
+		params.InStock, err = strconv.ParseBool(inStock)
+		if err != nil {
+			response.WithError(w, failure.BadRequestFromString("inStock must be true or false"))
+			return
+		}
+	}
+
+	resp, err := h.ProductService.GetProducts(params)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
 
 	response.WithJSON(w, http.StatusOK, resp)
 }
 
+// queryList returns the values of a repeated or comma separated query
+// parameter.
+func queryList(query url.Values, key string) (values []string) {
+	for _, value := range query[key] {
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+	}
+
+	return
+}
+
+// queryFloat returns the number of a query parameter, null when it is absent.
+func queryFloat(query url.Values, key string) (value null.Float, err error) {
+	s := query.Get(key)
+	if s == "" {
+		return
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return value, fmt.Errorf("%s must be a number", key)
+	}
+
+	return null.FloatFrom(f), nil
+}
+
 // CreateProduct creates a new product.
 // @Summary Create a new product.
-// @Description This endpoint creates a new product.
+// @Description This endpoint creates a new product. Products sold in several options, such as sizes and colors, list a variant with its own SKU and stock per combination;
+// @Description products without variants are sold as a single default variant holding stock. The stock is kept in the default warehouse.
 // @Tags products
 // @Security EVMOauthToken
 // @Param product body product.ProductRequestFormat true "The product to be created."
@@ -94,6 +191,7 @@ func (h *ProductHandler) ResolveProducts(w http.ResponseWriter, r *http.Request)
 // @Success 201 {object} response.Base{data=product.ProductResponseFormat}
 // @Failure 400 {object} response.Base
 // @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
 // @Failure 500 {object} response.Base
 // @Router /v1/products [post]
 func (h *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
@@ -123,3 +221,391 @@ func (h *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
 
 	response.WithJSON(w, http.StatusCreated, product)
 }
+
+// ResolveProductByID retrieves a product by its ID.
+// @Summary Retrieve a product by its ID.
+// @Description This endpoint retrieves a product by its ID. Deleted products are not found.
+// @Tags products
+// @Security EVMOauthToken
+// @Param id path string true "The product's identifier."
+// @Produce json
+// @Success 200 {object} response.Base{data=product.ProductResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/products/{id} [get]
+func (h *ProductHandler) ResolveProductByID(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.FromString(chi.URLParam(r, "id"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	product, err := h.ProductService.ResolveByID(id)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+
+	response.WithJSON(w, http.StatusOK, product)
+}
+
+// UpdateProduct updates a product.
+// @Summary Update a product.
+// @Description This endpoint updates a product. Shop admins may only update the products they own; admins may update every product.
+// @Description Variants are matched by SKU: listed variants are created or updated, and unlisted ones are removed. Placed orders keep the name and price the product had at checkout.
+// @Description Stock is the stock of variants across warehouses. Changes to it move the stock of the default warehouse, and are recorded in the stock history as manual corrections;
+// @Description stock the default warehouse does not hold cannot be removed this way, but through stock movements of the warehouses holding it.
+// @Tags products
+// @Security EVMOauthToken
+// @Param id path string true "The product's identifier."
+// @Param product body product.ProductRequestFormat true "The product's new details."
+// @Produce json
+// @Success 200 {object} response.Base{data=product.ProductResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 409 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/products/{id} [put]
+func (h *ProductHandler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.FromString(chi.URLParam(r, "id"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	var requestFormat product.ProductRequestFormat
+	err = decoder.Decode(&requestFormat)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	err = shared.GetValidator().Struct(requestFormat)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	claims, ok := r.Context().Value("claims").(shared.Claims)
+	if !ok {
+		response.WithError(w, failure.Unauthorized("User not authorized"))
+		return
+	}
+
+	product, err := h.ProductService.UpdateProduct(id, requestFormat, claims.UserID, claims.Role)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+
+	response.WithJSON(w, http.StatusOK, product)
+}
+
+// SoftDeleteProduct marks a product as deleted.
+// @Summary Mark a product as deleted.
+// @Description This endpoint marks a product as deleted, so that it is no longer listed or sold. Shop admins may only delete the products they own; admins may delete every product.
+// @Tags products
+// @Security EVMOauthToken
+// @Param id path string true "The product's identifier."
+// @Produce json
+// @Success 200 {object} response.Base{data=product.ProductResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/products/{id} [delete]
+func (h *ProductHandler) SoftDeleteProduct(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.FromString(chi.URLParam(r, "id"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	claims, ok := r.Context().Value("claims").(shared.Claims)
+	if !ok {
+		response.WithError(w, failure.Unauthorized("User not authorized"))
+		return
+	}
+
+	product, err := h.ProductService.SoftDeleteProduct(id, claims.UserID, claims.Role)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+
+	response.WithJSON(w, http.StatusOK, product)
+}
+
+// AddProductImage uploads an image of a product.
+// @Summary Upload an image of a product.
+// @Description This endpoint uploads a JPEG, PNG or GIF image of a product, told apart by its content rather than its declared type. The image is shown after the product's other images, along with a generated thumbnail.
+// @Description Shop admins may only add images to the products they own; admins may add images to every product.
+// @Tags products
+// @Security EVMOauthToken
+// @Accept multipart/form-data
+// @Param id path string true "The product's identifier."
+// @Param image formData file true "The image to be uploaded."
+// @Produce json
+// @Success 201 {object} response.Base{data=product.ProductResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 413 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/products/{id}/images [post]
+func (h *ProductHandler) AddProductImage(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.FromString(chi.URLParam(r, "id"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	maxSize := h.ProductService.MaxImageSize()
+	tooLarge := fmt.Sprintf("Image must be at most %d bytes", maxSize)
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize+multipartOverheadBytes)
+	file, header, err := r.FormFile("image")
+	if err != nil && strings.Contains(err.Error(), "request body too large") {
+		response.WithMessage(w, http.StatusRequestEntityTooLarge, tooLarge)
+		return
+	}
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	defer file.Close()
+
+	if header.Size > maxSize {
+		response.WithMessage(w, http.StatusRequestEntityTooLarge, tooLarge)
+		return
+	}
+
+	content, err := ioutil.ReadAll(file)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	claims, ok := r.Context().Value("claims").(shared.Claims)
+	if !ok {
+		response.WithError(w, failure.Unauthorized("User not authorized"))
+		return
+	}
+
+	product, err := h.ProductService.AddImage(id, content, claims.UserID, claims.Role)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+
+	response.WithJSON(w, http.StatusCreated, product)
+}
+
+// DeleteProductImage removes an image of a product.
+// @Summary Remove an image of a product.
+// @Description This endpoint removes an image of a product along with its thumbnail. Shop admins may only remove images of the products they own; admins may remove images of every product.
+// @Tags products
+// @Security EVMOauthToken
+// @Param id path string true "The product's identifier."
+// @Param imageID path string true "The image's identifier."
+// @Produce json
+// @Success 200 {object} response.Base{data=product.ProductResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/products/{id}/images/{imageID} [delete]
+func (h *ProductHandler) DeleteProductImage(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.FromString(chi.URLParam(r, "id"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	imageID, err := uuid.FromString(chi.URLParam(r, "imageID"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	claims, ok := r.Context().Value("claims").(shared.Claims)
+	if !ok {
+		response.WithError(w, failure.Unauthorized("User not authorized"))
+		return
+	}
+
+	product, err := h.ProductService.DeleteImage(id, imageID, claims.UserID, claims.Role)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+
+	response.WithJSON(w, http.StatusOK, product)
+}
+
+// AdjustProductStock moves the stock of a variant of a product.
+// @Summary Move the stock of a variant of a product.
+// @Description This endpoint adds stock to a variant in a warehouse, or takes it with a negative quantity, recording the movement in the stock history with its reason and reference, such as the order of returned items.
+// @Description Without warehouseID, the stock of the default warehouse moves. The stock of the variant is the sum of its stock in every warehouse.
+// @Description Stock taken at checkout is recorded automatically. Shop admins may only move the stock of the products they own; admins may move the stock of every product.
+// @Tags products
+// @Security EVMOauthToken
+// @Param id path string true "The product's identifier."
+// @Param movement body product.StockMovementRequestFormat true "The stock movement."
+// @Produce json
+// @Success 201 {object} response.Base{data=product.ProductResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 409 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/products/{id}/stock-movements [post]
+func (h *ProductHandler) AdjustProductStock(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.FromString(chi.URLParam(r, "id"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	var requestFormat product.StockMovementRequestFormat
+	err = decoder.Decode(&requestFormat)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	err = shared.GetValidator().Struct(requestFormat)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	claims, ok := r.Context().Value("claims").(shared.Claims)
+	if !ok {
+		response.WithError(w, failure.Unauthorized("User not authorized"))
+		return
+	}
+
+	product, err := h.ProductService.AdjustStock(id, requestFormat, claims.UserID, claims.Role)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+
+	response.WithJSON(w, http.StatusCreated, product)
+}
+
+// maxStockMovementsLimit is the largest page of stock movements.
+const maxStockMovementsLimit = 100
+
+// ResolveProductStockHistory retrieves the stock movements of a product.
+// @Summary Retrieve the stock movements of a product.
+// @Description This endpoint retrieves the stock movements of a product, newest first, with why, by whom and for what the stock of its variants moved.
+// @Description Levels reconcile the stock of every variant against the sum of its movements and the sum of its stock in every warehouse; a variant is reconciled when all are equal.
+// @Description Shop admins may only retrieve the stock history of the products they own; admins may retrieve every product's.
+// @Tags products
+// @Security EVMOauthToken
+// @Param id path string true "The product's identifier."
+// @Param limit query integer false "Number of movements per page (default 20, at most 100)"
+// @Param page query integer false "Page number (default 1)"
+// @Produce json
+// @Success 200 {object} response.Base{data=product.StockHistory{movements=shared.Page{data=[]product.StockMovementResponseFormat}}}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/products/{id}/stock-history [get]
+func (h *ProductHandler) ResolveProductStockHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.FromString(chi.URLParam(r, "id"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	query := r.URL.Query()
+
+	page, err := shared.ConvertQueryParamsToInt(query.Get("page"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+
+	limit, err := shared.ConvertQueryParamsToInt(query.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+	if limit > maxStockMovementsLimit {
+		limit = maxStockMovementsLimit
+	}
+
+	claims, ok := r.Context().Value("claims").(shared.Claims)
+	if !ok {
+		response.WithError(w, failure.Unauthorized("User not authorized"))
+		return
+	}
+
+	history, err := h.ProductService.ResolveStockHistory(id, page, limit, claims.UserID, claims.Role)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+
+	response.WithJSON(w, http.StatusOK, history)
+}
+
+// SyncProductStock moves the stock of a variant of a product for a service.
+// @Summary Move the stock of a variant of a product for a service.
+// @Description This endpoint moves stock as /v1/products/{id}/stock-movements does, for the services syncing inventory.
+// @Description Services authenticate with an API key in the X-API-Key header, or with a client credentials access token, and need the inventory:write scope. Tokens issued to users are refused. Services act for the user owning the key, or the admin who created the client.
+// @Tags inventory
+// @Security APIKey
+// @Security EVMOauthToken
+// @Param id path string true "The product's identifier."
+// @Param movement body product.StockMovementRequestFormat true "The stock movement."
+// @Produce json
+// @Success 201 {object} response.Base{data=product.ProductResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 409 {object} response.Base
+// @Failure 429 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/inventory/products/{id}/stock-movements [post]
+func (h *ProductHandler) SyncProductStock(w http.ResponseWriter, r *http.Request) {
+	h.AdjustProductStock(w, r)
+}
+
+// ResolveSyncedProductStockHistory retrieves the stock movements of a product for a service.
+// @Summary Retrieve the stock movements of a product for a service.
+// @Description This endpoint retrieves the stock history as /v1/products/{id}/stock-history does, for the services syncing inventory.
+// @Description Services authenticate with an API key in the X-API-Key header, or with a client credentials access token, and need the inventory:read scope. Tokens issued to users are refused. Services act for the user owning the key, or the admin who created the client.
+// @Tags inventory
+// @Security APIKey
+// @Security EVMOauthToken
+// @Param id path string true "The product's identifier."
+// @Param limit query integer false "Number of movements per page (default 20, at most 100)"
+// @Param page query integer false "Page number (default 1)"
+// @Produce json
+// @Success 200 {object} response.Base{data=product.StockHistory{movements=shared.Page{data=[]product.StockMovementResponseFormat}}}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 429 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/inventory/products/{id}/stock-history [get]
+func (h *ProductHandler) ResolveSyncedProductStockHistory(w http.ResponseWriter, r *http.Request) {
+	h.ResolveProductStockHistory(w, r)
+}
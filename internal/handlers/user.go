@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/evermos/boilerplate-go/internal/domain/user"
+	"github.com/evermos/boilerplate-go/shared"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/rbac"
+	"github.com/evermos/boilerplate-go/transport/http/middleware"
+	"github.com/evermos/boilerplate-go/transport/http/response"
+	"github.com/go-chi/chi"
+	"github.com/gofrs/uuid"
+)
+
+type UserHandler struct {
+	UserService    user.UserService
+	AuthMiddleware *middleware.Authentication
+}
+
+func ProvideUserHandler(userService user.UserService, authMiddleware *middleware.Authentication) UserHandler {
+	return UserHandler{
+		UserService:    userService,
+		AuthMiddleware: authMiddleware,
+	}
+}
+
+func (h *UserHandler) Router(r chi.Router) {
+	r.Route("/users", func(r chi.Router) {
+		r.Group(func(r chi.Router) {
+			r.Use(h.AuthMiddleware.ValidateAuth)
+			r.Use(h.AuthMiddleware.RequirePermission(rbac.PermissionUserManage))
+			r.Put("/{id}/role", h.UpdateRole)
+		})
+	})
+}
+
+// UpdateRole changes the role of a user.
+// @Summary Change the role of a user.
+// @Description This endpoint changes the role of a user to admin, shop_admin or user. Only admins may change roles.
+// @Tags users
+// @Security EVMOauthToken
+// @Param id path string true "The user's identifier."
+// @Param role body user.RoleRequestFormat true "The user's new role."
+// @Produce json
+// @Success 200 {object} response.Base{data=user.UserResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/users/{id}/role [put]
+func (h *UserHandler) UpdateRole(w http.ResponseWriter, r *http.Request) {
+	idString := chi.URLParam(r, "id")
+	id, err := uuid.FromString(idString)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	var requestFormat user.RoleRequestFormat
+	err = decoder.Decode(&requestFormat)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	err = shared.GetValidator().Struct(requestFormat)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	claims, ok := r.Context().Value("claims").(shared.Claims)
+	if !ok {
+		response.WithError(w, failure.Unauthorized("User not authorized"))
+		return
+	}
+
+	user, err := h.UserService.UpdateRole(id, requestFormat, claims.UserID)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+
+	response.WithJSON(w, http.StatusOK, user)
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/evermos/boilerplate-go/internal/domain/warehouse"
+	"github.com/evermos/boilerplate-go/shared"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/rbac"
+	"github.com/evermos/boilerplate-go/transport/http/middleware"
+	"github.com/evermos/boilerplate-go/transport/http/response"
+	"github.com/go-chi/chi"
+	"github.com/gofrs/uuid"
+)
+
+type WarehouseHandler struct {
+	WarehouseService warehouse.WarehouseService
+	AuthMiddleware   *middleware.Authentication
+}
+
+func ProvideWarehouseHandler(warehouseService warehouse.WarehouseService, authMiddleware *middleware.Authentication) WarehouseHandler {
+	return WarehouseHandler{
+		WarehouseService: warehouseService,
+		AuthMiddleware:   authMiddleware,
+	}
+}
+
+func (h *WarehouseHandler) Router(r chi.Router) {
+	r.Route("/warehouses", func(r chi.Router) {
+		r.Group(func(r chi.Router) {
+			r.Use(h.AuthMiddleware.ValidateAuth)
+			r.With(h.AuthMiddleware.RequirePermission(rbac.PermissionProductWrite)).Get("/", h.ResolveWarehouses)
+			r.With(h.AuthMiddleware.RequirePermission(rbac.PermissionWarehouseManage)).Post("/", h.CreateWarehouse)
+			r.With(h.AuthMiddleware.RequirePermission(rbac.PermissionWarehouseManage)).Put("/{id}", h.UpdateWarehouse)
+		})
+	})
+}
+
+// ResolveWarehouses retrieves the warehouses.
+// @Summary Retrieve the warehouses.
+// @Description This endpoint retrieves every warehouse, ordered by code. Stock is moved in a warehouse by its ID.
+// @Tags warehouses
+// @Security EVMOauthToken
+// @Produce json
+// @Success 200 {object} response.Base{data=[]warehouse.WarehouseResponseFormat}
+// @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/warehouses [get]
+func (h *WarehouseHandler) ResolveWarehouses(w http.ResponseWriter, r *http.Request) {
+	warehouses, err := h.WarehouseService.ResolveWarehouses()
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+
+	if warehouses == nil {
+		warehouses = make(warehouse.Warehouses, 0)
+	}
+
+	response.WithJSON(w, http.StatusOK, warehouses)
+}
+
+// CreateWarehouse creates a new warehouse.
+// @Summary Create a new warehouse.
+// @Description This endpoint creates a new warehouse. Warehouses with coordinates may ship the orders of shipping addresses nearby.
+// @Description Making the warehouse the default makes the previous default an ordinary warehouse; the default warehouse holds the stock set through products.
+// @Tags warehouses
+// @Security EVMOauthToken
+// @Param warehouse body warehouse.WarehouseRequestFormat true "The warehouse to be created."
+// @Produce json
+// @Success 201 {object} response.Base{data=warehouse.WarehouseResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
+// @Failure 409 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/warehouses [post]
+func (h *WarehouseHandler) CreateWarehouse(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+	var requestFormat warehouse.WarehouseRequestFormat
+	err := decoder.Decode(&requestFormat)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	err = shared.GetValidator().Struct(requestFormat)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	claims, ok := r.Context().Value("claims").(shared.Claims)
+	if !ok {
+		response.WithError(w, failure.Unauthorized("User not authorized"))
+		return
+	}
+
+	created, err := h.WarehouseService.CreateWarehouse(requestFormat, claims.UserID)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+
+	response.WithJSON(w, http.StatusCreated, created)
+}
+
+// UpdateWarehouse updates a warehouse.
+// @Summary Update a warehouse.
+// @Description This endpoint replaces the code, name, address and coordinates of a warehouse, and whether it is the default.
+// @Description The default warehouse stays the default until another warehouse is made the default.
+// @Tags warehouses
+// @Security EVMOauthToken
+// @Param id path string true "The warehouse's identifier."
+// @Param warehouse body warehouse.WarehouseRequestFormat true "The warehouse to be updated."
+// @Produce json
+// @Success 200 {object} response.Base{data=warehouse.WarehouseResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 403 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 409 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/warehouses/{id} [put]
+func (h *WarehouseHandler) UpdateWarehouse(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.FromString(chi.URLParam(r, "id"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	var requestFormat warehouse.WarehouseRequestFormat
+	err = decoder.Decode(&requestFormat)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	err = shared.GetValidator().Struct(requestFormat)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	claims, ok := r.Context().Value("claims").(shared.Claims)
+	if !ok {
+		response.WithError(w, failure.Unauthorized("User not authorized"))
+		return
+	}
+
+	updated, err := h.WarehouseService.UpdateWarehouse(id, requestFormat, claims.UserID)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+
+	response.WithJSON(w, http.StatusOK, updated)
+}
@@ -13,6 +13,9 @@ var config *configs.Config
 //@securityDefinitions.apikey EVMOauthToken
 //@in header
 //@name Authorization
+//@securityDefinitions.apikey APIKey
+//@in header
+//@name X-API-Key
 func main() {
 	// Initialize logger
 	logger.InitLogger()
@@ -26,10 +29,11 @@ func main() {
 	// Wire everything up
 	http := InitializeService()
 
-	// consumers := InitializeEvent()
+	consumers := InitializeEvent()
 
-	// Start consumers
-	// consumers.Start()
+	// Start consumers, and drain them when the server receives SIGTERM
+	consumers.Start()
+	http.RegisterShutdownHook(consumers.Stop)
 
 	// Run server
 	http.SetupAndServe()
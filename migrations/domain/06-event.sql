@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS `processed_messages` (
+  `consumer` VARCHAR(100) NOT NULL,
+  `message_id` VARCHAR(100) NOT NULL,
+  `processed_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`consumer`, `message_id`),
+  INDEX `idx_processed_messages_1` (`processed_at`)
+) ENGINE=InnoDB
+DEFAULT CHARSET=utf8;
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS `user` (
+  `id` VARCHAR(55) PRIMARY KEY NOT NULL,
+  `username` VARCHAR(100) NOT NULL,
+  `email` VARCHAR(255) NOT NULL,
+  `telephone` VARCHAR(20) NOT NULL,
+  `password` VARCHAR(255) NOT NULL,
+  `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `created_by` VARCHAR(55) NOT NULL,
+  `updated_at` TIMESTAMP NULL DEFAULT NULL,
+  `updated_by` VARCHAR(55) NULL DEFAULT NULL,
+  `deleted_at` TIMESTAMP NULL DEFAULT NULL,
+  `deleted_by` VARCHAR(55) NULL DEFAULT NULL,
+  UNIQUE INDEX `uq_user_username` (`username`),
+  UNIQUE INDEX `uq_user_email` (`email`),
+  UNIQUE INDEX `uq_user_telephone` (`telephone`)
+) ENGINE=InnoDB
+DEFAULT CHARSET=utf8;
+
+-- Access tokens reference users by their UUID.
+ALTER TABLE `oauth_access_tokens` MODIFY `user_id` VARCHAR(55) NULL;
@@ -0,0 +1 @@
+ALTER TABLE `user` ADD `role` VARCHAR(20) NOT NULL DEFAULT 'user' AFTER `password`;
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS `oauth_refresh_tokens` (
+    `token_hash` VARCHAR(64) NOT NULL,
+    `family_id` VARCHAR(55) NOT NULL,
+    `client_id` VARCHAR(32) NOT NULL,
+    `user_id` VARCHAR(55) NULL,
+    `scope` VARCHAR(2000) NULL,
+    `expires` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `used_at` TIMESTAMP NULL DEFAULT NULL,
+    `revoked_at` TIMESTAMP NULL DEFAULT NULL,
+    PRIMARY KEY (`token_hash`),
+    INDEX `idx_oauth_refresh_tokens_1` (`family_id`)
+) ENGINE=InnoDB
+DEFAULT CHARSET=utf8;
+
+-- Access tokens issued along with a refresh token share its family, so that
+-- revoking the family also revokes them.
+ALTER TABLE `oauth_access_tokens`
+    ADD `family_id` VARCHAR(55) NULL,
+    ADD INDEX `idx_oauth_access_tokens_1` (`family_id`);
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS `oauth_authorization_codes` (
+    `code_hash` VARCHAR(64) NOT NULL,
+    `family_id` VARCHAR(55) NOT NULL,
+    `client_id` VARCHAR(32) NOT NULL,
+    `user_id` VARCHAR(55) NOT NULL,
+    `redirect_uri` VARCHAR(1000) NOT NULL,
+    `scope` VARCHAR(2000) NULL,
+    `code_challenge` VARCHAR(128) NOT NULL,
+    `expires` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `used_at` TIMESTAMP NULL DEFAULT NULL,
+    PRIMARY KEY (`code_hash`),
+    INDEX `idx_oauth_authorization_codes_1` (`expires`)
+) ENGINE=InnoDB
+DEFAULT CHARSET=utf8;
+
+INSERT INTO `oauth_clients`
+(`client_id`, `client_secret`, `redirect_uri`, `grant_types`, `scope`, `user_id`)
+VALUES
+('client_partner', 'p4rtn3r', 'https://partner.example.com/callback', 'authorization_code refresh_token', 'user', NULL);
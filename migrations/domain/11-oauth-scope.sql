@@ -0,0 +1,3 @@
+-- Scopes are space-delimited, and clients are issued at most their scope.
+UPDATE `oauth_clients` SET `scope` = 'foo:read foo:write' WHERE `client_id` = 'client_web';
+UPDATE `oauth_clients` SET `scope` = 'foo:read' WHERE `client_id` = 'client_partner';
@@ -0,0 +1,13 @@
+-- Client secrets are stored as bcrypt hashes. Disabled clients can no longer
+-- authenticate.
+ALTER TABLE `oauth_clients`
+    MODIFY `client_secret` VARCHAR(100) NOT NULL,
+    ADD `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ADD `created_by` VARCHAR(55) NULL,
+    ADD `updated_at` TIMESTAMP NULL DEFAULT NULL,
+    ADD `updated_by` VARCHAR(55) NULL,
+    ADD `disabled_at` TIMESTAMP NULL DEFAULT NULL,
+    ADD `disabled_by` VARCHAR(55) NULL;
+
+UPDATE `oauth_clients` SET `client_secret` = '$2a$10$rv.c2DZl6Sn.vE2FTLqXjecqy9jYLgZs/RpHDwDduKFCs3dkQOt6i' WHERE `client_id` = 'client_web';
+UPDATE `oauth_clients` SET `client_secret` = '$2a$10$fR4D8I7qikd2Hc6HHI5.lOCaEuSVWztN.UJaj5CetGPMn6JmaAUxW' WHERE `client_id` = 'client_partner';
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS `audit_event` (
+    `id` VARCHAR(55) NOT NULL,
+    `type` VARCHAR(50) NOT NULL,
+    `subject` VARCHAR(255) NOT NULL,
+    `ip` VARCHAR(45) NULL,
+    `actor` VARCHAR(55) NULL,
+    `details` VARCHAR(1000) NULL,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    INDEX `idx_audit_event_1` (`type`, `created_at`),
+    INDEX `idx_audit_event_2` (`subject`)
+) ENGINE=InnoDB
+DEFAULT CHARSET=utf8;
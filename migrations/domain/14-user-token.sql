@@ -0,0 +1,15 @@
+ALTER TABLE `user` ADD `email_verified_at` TIMESTAMP NULL DEFAULT NULL AFTER `email`;
+
+-- Password reset and email verification tokens. Only the hash of a token is
+-- stored, and a token can be used once.
+CREATE TABLE IF NOT EXISTS `user_token` (
+    `token_hash` VARCHAR(64) NOT NULL,
+    `user_id` VARCHAR(55) NOT NULL,
+    `purpose` VARCHAR(30) NOT NULL,
+    `expires` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `used_at` TIMESTAMP NULL DEFAULT NULL,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`token_hash`),
+    INDEX `idx_user_token_1` (`user_id`, `purpose`)
+) ENGINE=InnoDB
+DEFAULT CHARSET=utf8;
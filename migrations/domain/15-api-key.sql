@@ -0,0 +1,21 @@
+-- Long-lived API keys of services. Only the hash of a key is stored, along
+-- with its first characters to tell keys apart.
+CREATE TABLE IF NOT EXISTS `api_key` (
+    `id` VARCHAR(55) NOT NULL,
+    `name` VARCHAR(100) NOT NULL,
+    `prefix` VARCHAR(20) NOT NULL,
+    `key_hash` VARCHAR(64) NOT NULL,
+    `owner_id` VARCHAR(55) NOT NULL,
+    `scope` VARCHAR(2000) NULL,
+    `quota_per_minute` INT NOT NULL,
+    `expires_at` TIMESTAMP NULL DEFAULT NULL,
+    `last_used_at` TIMESTAMP NULL DEFAULT NULL,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `created_by` VARCHAR(55) NOT NULL,
+    `revoked_at` TIMESTAMP NULL DEFAULT NULL,
+    `revoked_by` VARCHAR(55) NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uq_api_key_key_hash` (`key_hash`),
+    INDEX `idx_api_key_1` (`owner_id`)
+) ENGINE=InnoDB
+DEFAULT CHARSET=utf8;
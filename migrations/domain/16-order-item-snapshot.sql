@@ -0,0 +1,7 @@
+-- Order items keep the name the product had at checkout, next to its price,
+-- so that updating or deleting the product does not alter placed orders.
+ALTER TABLE `order_item` ADD `product_name` VARCHAR(255) NULL AFTER `product_id`;
+
+UPDATE `order_item`
+    JOIN `product` ON `product`.`id` = `order_item`.`product_id`
+    SET `order_item`.`product_name` = `product`.`name`;
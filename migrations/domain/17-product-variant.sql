@@ -0,0 +1,87 @@
+-- Products are sold as variants, such as sizes and colors, each with its own
+-- SKU, option values, stock and an optional price overriding the product's.
+-- The stock of a product is the sum of its variants' stock.
+CREATE TABLE IF NOT EXISTS `product_variant` (
+    `id` VARCHAR(55) NOT NULL,
+    `product_id` VARCHAR(55) NOT NULL,
+    `sku` VARCHAR(64) NOT NULL,
+    `options` JSON NOT NULL,
+    `price` DECIMAL(10,2) NULL DEFAULT NULL,
+    `stock` INT NOT NULL,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `created_by` VARCHAR(55) NOT NULL,
+    `updated_at` TIMESTAMP NULL DEFAULT NULL,
+    `updated_by` VARCHAR(55) NULL DEFAULT NULL,
+    `deleted_at` TIMESTAMP NULL DEFAULT NULL,
+    `deleted_by` VARCHAR(55) NULL DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uq_product_variant_sku` (`sku`),
+    INDEX `idx_product_variant_1` (`product_id`),
+    CONSTRAINT `fk_product_variant_product` FOREIGN KEY (`product_id`) REFERENCES `product`(`id`)
+) ENGINE=InnoDB
+DEFAULT CHARSET=utf8;
+
+-- Existing products are sold as a single default variant without options.
+INSERT INTO `product_variant` (`id`, `product_id`, `sku`, `options`, `stock`, `created_at`, `created_by`)
+SELECT UUID(), `id`, CONCAT('SKU-', UPPER(LEFT(`id`, 8))), '{}', `stock`, `created_at`, `created_by`
+FROM `product`;
+
+-- Cart and order items reference the variant; order items also keep its SKU.
+ALTER TABLE `cart_item` ADD `variant_id` VARCHAR(55) NULL AFTER `product_id`;
+
+UPDATE `cart_item`
+    JOIN `product_variant` ON `product_variant`.`product_id` = `cart_item`.`product_id`
+    SET `cart_item`.`variant_id` = `product_variant`.`id`;
+
+ALTER TABLE `cart_item`
+    MODIFY `variant_id` VARCHAR(55) NOT NULL,
+    ADD CONSTRAINT `fk_cart_item_variant` FOREIGN KEY (`variant_id`) REFERENCES `product_variant`(`id`),
+    ADD INDEX `idx_cart_item_cart_variant` (`cart_id`, `variant_id`);
+
+ALTER TABLE `order_item`
+    ADD `variant_id` VARCHAR(55) NULL AFTER `product_id`,
+    ADD `sku` VARCHAR(64) NULL AFTER `product_name`;
+
+UPDATE `order_item`
+    JOIN `product_variant` ON `product_variant`.`product_id` = `order_item`.`product_id`
+    SET `order_item`.`variant_id` = `product_variant`.`id`,
+        `order_item`.`sku` = `product_variant`.`sku`;
+
+-- Cart prices follow the variant's price, or the product's when the variant
+-- does not override it.
+DROP TRIGGER IF EXISTS `update_cart_item_price`;
+
+DELIMITER //
+
+CREATE TRIGGER update_cart_item_price
+AFTER UPDATE ON product
+FOR EACH ROW
+BEGIN
+    IF OLD.price != NEW.price THEN
+        UPDATE cart_item
+        JOIN product_variant ON product_variant.id = cart_item.variant_id
+        SET cart_item.unit_price = NEW.price,
+            cart_item.cost = cart_item.quantity * NEW.price
+        WHERE cart_item.product_id = NEW.id
+            AND product_variant.price IS NULL;
+    END IF;
+END;
+
+//
+
+CREATE TRIGGER update_cart_item_variant_price
+AFTER UPDATE ON product_variant
+FOR EACH ROW
+BEGIN
+    IF NOT (OLD.price <=> NEW.price) THEN
+        UPDATE cart_item
+        JOIN product ON product.id = cart_item.product_id
+        SET cart_item.unit_price = COALESCE(NEW.price, product.price),
+            cart_item.cost = cart_item.quantity * COALESCE(NEW.price, product.price)
+        WHERE cart_item.variant_id = NEW.id;
+    END IF;
+END;
+
+//
+
+DELIMITER ;
@@ -0,0 +1,23 @@
+-- Images of products, stored in the blob store along with their thumbnails.
+-- Images are shown in the order of their position.
+CREATE TABLE IF NOT EXISTS `product_image` (
+    `id` VARCHAR(55) NOT NULL,
+    `product_id` VARCHAR(55) NOT NULL,
+    `position` INT NOT NULL,
+    `content_type` VARCHAR(50) NOT NULL,
+    `width` INT NOT NULL,
+    `height` INT NOT NULL,
+    `size` INT NOT NULL,
+    `blob_key` VARCHAR(255) NOT NULL,
+    `url` VARCHAR(1000) NOT NULL,
+    `thumbnail_key` VARCHAR(255) NOT NULL,
+    `thumbnail_url` VARCHAR(1000) NOT NULL,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `created_by` VARCHAR(55) NOT NULL,
+    `deleted_at` TIMESTAMP NULL DEFAULT NULL,
+    `deleted_by` VARCHAR(55) NULL DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_product_image_1` (`product_id`, `position`),
+    CONSTRAINT `fk_product_image_product` FOREIGN KEY (`product_id`) REFERENCES `product`(`id`)
+) ENGINE=InnoDB
+DEFAULT CHARSET=utf8;
@@ -0,0 +1,3 @@
+-- Keyword search over products. The ngram parser indexes bigrams, so that
+-- misspelled keywords still match most of the right word.
+ALTER TABLE `product` ADD FULLTEXT INDEX `idx_product_1` (`name`, `brand`, `category`) WITH PARSER ngram;
@@ -0,0 +1,8 @@
+-- Product lists are paginated by keyset on their sort column and ID, and
+-- filtered by brand and category.
+ALTER TABLE `product`
+    ADD INDEX `idx_product_2` (`created_at`, `id`),
+    ADD INDEX `idx_product_3` (`price`, `id`),
+    ADD INDEX `idx_product_4` (`name`, `id`),
+    ADD INDEX `idx_product_5` (`brand`),
+    ADD INDEX `idx_product_6` (`category`);
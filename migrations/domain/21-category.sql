@@ -0,0 +1,62 @@
+-- Categories form a tree: top-level categories have no parent, and siblings
+-- are shown in the order of their position. Slugs identify categories in URLs
+-- and filters.
+CREATE TABLE IF NOT EXISTS `category` (
+    `id` VARCHAR(55) NOT NULL,
+    `parent_id` VARCHAR(55) NULL DEFAULT NULL,
+    `name` VARCHAR(100) NOT NULL,
+    `slug` VARCHAR(100) NOT NULL,
+    `position` INT NOT NULL DEFAULT 0,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `created_by` VARCHAR(55) NOT NULL,
+    `updated_at` TIMESTAMP NULL DEFAULT NULL,
+    `updated_by` VARCHAR(55) NULL DEFAULT NULL,
+    `deleted_at` TIMESTAMP NULL DEFAULT NULL,
+    `deleted_by` VARCHAR(55) NULL DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uq_category_slug` (`slug`),
+    INDEX `idx_category_1` (`parent_id`, `position`),
+    CONSTRAINT `fk_category_category` FOREIGN KEY (`parent_id`) REFERENCES `category`(`id`)
+) ENGINE=InnoDB
+DEFAULT CHARSET=utf8;
+
+-- The free text categories of existing products become top-level categories.
+-- The collation is case insensitive, so categories differing only in case
+-- become one. Slugs are derived as category.Slugify does: lowercase letters
+-- and digits, with every other run of characters replaced by a single dash.
+-- Categories whose slugs collide are numbered after the first, and categories
+-- without letters or digits are slugged "category".
+INSERT INTO `category` (`id`, `name`, `slug`, `created_by`)
+SELECT
+    UUID(),
+    `name`,
+    IF(`number` = 1, `slug`, CONCAT(TRIM(TRAILING '-' FROM LEFT(`slug`, 90)), '-', `number`)),
+    `created_by`
+FROM (
+    SELECT `name`, `slug`, `created_by`, ROW_NUMBER() OVER (PARTITION BY `slug` ORDER BY `name`) AS `number`
+    FROM (
+        SELECT
+            `name`,
+            IFNULL(NULLIF(TRIM(BOTH '-' FROM REGEXP_REPLACE(LOWER(`name`), '[^a-z0-9]+', '-', 1, 0, 'c')), ''), 'category') AS `slug`,
+            `created_by`
+        FROM (
+            SELECT TRIM(`category`) AS `name`, MIN(`created_by`) AS `created_by`
+            FROM `product`
+            GROUP BY TRIM(`category`)
+        ) AS `categories`
+    ) AS `slugged`
+) AS `numbered`;
+
+-- Products link to their category. The category column keeps the category's
+-- name for searching, and is kept in sync when the category is renamed.
+ALTER TABLE `product` ADD `category_id` VARCHAR(55) NULL AFTER `brand`;
+
+UPDATE `product`
+JOIN `category` ON `category`.`name` = TRIM(`product`.`category`)
+SET `product`.`category_id` = `category`.`id`, `product`.`category` = `category`.`name`;
+
+ALTER TABLE `product`
+    MODIFY `category_id` VARCHAR(55) NOT NULL,
+    DROP INDEX `idx_product_6`,
+    ADD INDEX `idx_product_6` (`category_id`),
+    ADD CONSTRAINT `fk_product_category` FOREIGN KEY (`category_id`) REFERENCES `category`(`id`);
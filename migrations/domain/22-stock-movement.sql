@@ -0,0 +1,27 @@
+-- Every change to the stock of a variant is recorded as a movement: how much
+-- the stock changed by and what it became, why, who changed it and what it
+-- references, such as an order. The stock of a variant is the sum of its
+-- movements.
+CREATE TABLE IF NOT EXISTS `stock_movement` (
+    `id` VARCHAR(55) NOT NULL,
+    `product_id` VARCHAR(55) NOT NULL,
+    `variant_id` VARCHAR(55) NOT NULL,
+    `quantity` INT NOT NULL,
+    `balance` INT NOT NULL,
+    `reason` ENUM('initial', 'checkout', 'cancel', 'return', 'manual', 'import') NOT NULL,
+    `reference_id` VARCHAR(100) NULL DEFAULT NULL,
+    `actor_id` VARCHAR(55) NOT NULL,
+    `note` VARCHAR(255) NULL DEFAULT NULL,
+    `created_at` TIMESTAMP(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    PRIMARY KEY (`id`),
+    INDEX `idx_stock_movement_1` (`product_id`, `created_at`),
+    INDEX `idx_stock_movement_2` (`variant_id`),
+    CONSTRAINT `fk_stock_movement_product` FOREIGN KEY (`product_id`) REFERENCES `product`(`id`),
+    CONSTRAINT `fk_stock_movement_product_variant` FOREIGN KEY (`variant_id`) REFERENCES `product_variant`(`id`)
+) ENGINE=InnoDB
+DEFAULT CHARSET=utf8;
+
+-- The current stock of existing variants opens their ledger.
+INSERT INTO `stock_movement` (`id`, `product_id`, `variant_id`, `quantity`, `balance`, `reason`, `actor_id`, `note`, `created_at`)
+SELECT UUID(), `product_id`, `id`, `stock`, `stock`, 'initial', `created_by`, 'Opening balance', CURRENT_TIMESTAMP(6)
+FROM `product_variant`;
@@ -0,0 +1,73 @@
+-- Stock is kept in warehouses. Orders ship from warehouses picked by their
+-- distance to the shipping address, which needs their coordinates, or by
+-- their stock. The default warehouse holds the stock set through products.
+CREATE TABLE IF NOT EXISTS `warehouse` (
+    `id` VARCHAR(55) NOT NULL,
+    `code` VARCHAR(50) NOT NULL,
+    `name` VARCHAR(100) NOT NULL,
+    `address` VARCHAR(500) NULL DEFAULT NULL,
+    `latitude` DECIMAL(9,6) NULL DEFAULT NULL,
+    `longitude` DECIMAL(9,6) NULL DEFAULT NULL,
+    `is_default` TINYINT(1) NOT NULL DEFAULT 0,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `created_by` VARCHAR(55) NOT NULL,
+    `updated_at` TIMESTAMP NULL DEFAULT NULL,
+    `updated_by` VARCHAR(55) NULL DEFAULT NULL,
+    `deleted_at` TIMESTAMP NULL DEFAULT NULL,
+    `deleted_by` VARCHAR(55) NULL DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uq_warehouse_code` (`code`)
+) ENGINE=InnoDB
+DEFAULT CHARSET=utf8;
+
+INSERT INTO `warehouse` (`id`, `code`, `name`, `is_default`, `created_by`)
+VALUES (UUID(), 'main', 'Main Warehouse', 1, 'admin');
+
+-- The stock of a variant in each warehouse. The stock of a variant is the sum
+-- of its stock in every warehouse.
+CREATE TABLE IF NOT EXISTS `warehouse_stock` (
+    `warehouse_id` VARCHAR(55) NOT NULL,
+    `variant_id` VARCHAR(55) NOT NULL,
+    `product_id` VARCHAR(55) NOT NULL,
+    `stock` INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (`warehouse_id`, `variant_id`),
+    INDEX `idx_warehouse_stock_1` (`variant_id`),
+    INDEX `idx_warehouse_stock_2` (`product_id`),
+    CONSTRAINT `fk_warehouse_stock_warehouse` FOREIGN KEY (`warehouse_id`) REFERENCES `warehouse`(`id`),
+    CONSTRAINT `fk_warehouse_stock_product_variant` FOREIGN KEY (`variant_id`) REFERENCES `product_variant`(`id`),
+    CONSTRAINT `fk_warehouse_stock_product` FOREIGN KEY (`product_id`) REFERENCES `product`(`id`)
+) ENGINE=InnoDB
+DEFAULT CHARSET=utf8;
+
+-- Existing stock, and the stock moved so far, is in the default warehouse.
+INSERT INTO `warehouse_stock` (`warehouse_id`, `variant_id`, `product_id`, `stock`)
+SELECT `warehouse`.`id`, `product_variant`.`id`, `product_variant`.`product_id`, `product_variant`.`stock`
+FROM `product_variant`
+JOIN `warehouse` ON `warehouse`.`is_default` = 1;
+
+ALTER TABLE `stock_movement` ADD `warehouse_id` VARCHAR(55) NULL AFTER `variant_id`;
+
+UPDATE `stock_movement`
+JOIN `warehouse` ON `warehouse`.`is_default` = 1
+SET `stock_movement`.`warehouse_id` = `warehouse`.`id`;
+
+ALTER TABLE `stock_movement`
+    MODIFY `warehouse_id` VARCHAR(55) NOT NULL,
+    ADD CONSTRAINT `fk_stock_movement_warehouse` FOREIGN KEY (`warehouse_id`) REFERENCES `warehouse`(`id`);
+
+-- Order items record the warehouse they ship from, and orders the address
+-- they ship to.
+ALTER TABLE `order_item` ADD `warehouse_id` VARCHAR(55) NULL AFTER `variant_id`;
+
+UPDATE `order_item`
+JOIN `warehouse` ON `warehouse`.`is_default` = 1
+SET `order_item`.`warehouse_id` = `warehouse`.`id`;
+
+ALTER TABLE `order_item`
+    MODIFY `warehouse_id` VARCHAR(55) NOT NULL,
+    ADD CONSTRAINT `fk_order_item_warehouse` FOREIGN KEY (`warehouse_id`) REFERENCES `warehouse`(`id`);
+
+ALTER TABLE `orders`
+    ADD `shipping_address` VARCHAR(500) NULL DEFAULT NULL AFTER `status`,
+    ADD `shipping_latitude` DECIMAL(9,6) NULL DEFAULT NULL AFTER `shipping_address`,
+    ADD `shipping_longitude` DECIMAL(9,6) NULL DEFAULT NULL AFTER `shipping_latitude`;
@@ -0,0 +1,9 @@
+-- Services introspecting tokens authenticate with the client credentials of a
+-- dedicated client, so that the tokens users log in with are never issued the
+-- service scopes. The secret is 's3rv1c3'. Services syncing inventory act for
+-- the admin who created their client, so admins create those clients with the
+-- inventory:read and inventory:write scopes.
+INSERT INTO `oauth_clients`
+(`client_id`, `client_secret`, `redirect_uri`, `grant_types`, `scope`, `user_id`)
+VALUES
+('client_service', '$2a$10$y1yPgki7XoySh8bCNCGREumhyXmQSlVojs9A6cOpKNOR/.47AAtzK', NULL, 'client_credentials', 'oauth:introspect', NULL);
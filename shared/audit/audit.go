@@ -0,0 +1,91 @@
+package audit
+
+import (
+	"time"
+
+	"github.com/evermos/boilerplate-go/infras"
+	"github.com/gofrs/uuid"
+	"github.com/guregu/null"
+	"github.com/rs/zerolog/log"
+)
+
+// Type is the type of an audit event.
+type Type string
+
+const (
+	// TypeLoginLockout is recorded when logins are locked out after too many failures.
+	TypeLoginLockout Type = "login_lockout"
+	// TypeLoginUnlock is recorded when an admin lifts a login lockout.
+	TypeLoginUnlock Type = "login_unlock"
+)
+
+// Event is a security relevant event. Subject is what the event is about,
+// such as a username or an IP address, and Actor is who caused it, if known.
+type Event struct {
+	ID        uuid.UUID   `db:"id"`
+	Type      Type        `db:"type"`
+	Subject   string      `db:"subject"`
+	IP        null.String `db:"ip"`
+	Actor     null.String `db:"actor"`
+	Details   null.String `db:"details"`
+	CreatedAt time.Time   `db:"created_at"`
+}
+
+// Recorder records audit events.
+type Recorder interface {
+	Record(event Event) error
+}
+
+const queryInsertEvent = `INSERT INTO audit_event (
+		id,
+		type,
+		subject,
+		ip,
+		actor,
+		details,
+		created_at
+	) VALUES (
+		:id,
+		:type,
+		:subject,
+		:ip,
+		:actor,
+		:details,
+		:created_at
+	)`
+
+// RecorderMySQL records audit events in the audit_event table.
+type RecorderMySQL struct {
+	DB *infras.MySQLConn
+}
+
+// ProvideRecorderMySQL is the provider for RecorderMySQL.
+func ProvideRecorderMySQL(db *infras.MySQLConn) *RecorderMySQL {
+	return &RecorderMySQL{DB: db}
+}
+
+// Record stores the event, filling in its ID and creation time when unset.
+// Events are also logged, so that they are not lost when storing fails.
+func (r *RecorderMySQL) Record(event Event) (err error) {
+	if event.ID == uuid.Nil {
+		event.ID, err = uuid.NewV4()
+		if err != nil {
+			return
+		}
+	}
+
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+
+	log.Info().
+		Str("type", string(event.Type)).
+		Str("subject", event.Subject).
+		Str("ip", event.IP.String).
+		Str("actor", event.Actor.String).
+		Str("details", event.Details.String).
+		Msg("Audit event.")
+
+	_, err = r.DB.Write.NamedExec(queryInsertEvent, event)
+	return
+}
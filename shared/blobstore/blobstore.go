@@ -0,0 +1,187 @@
+package blobstore
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/evermos/boilerplate-go/configs"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// DriverLocal stores blobs on the local filesystem, for local runs and
+	// single instances.
+	DriverLocal = "local"
+	// DriverS3 stores blobs in an S3-compatible bucket.
+	DriverS3 = "s3"
+
+	// LocalPath is the path local blobs are served under.
+	LocalPath = "/media"
+	// defaultLocalDir is the directory of local blobs, when none is
+	// configured.
+	defaultLocalDir = "media"
+)
+
+// BlobStore stores blobs, such as uploaded images, under keys of
+// slash-separated names.
+type BlobStore interface {
+	Put(key string, content []byte, contentType string) error
+	Delete(key string) error
+	// URL is the public URL of a stored blob.
+	URL(key string) string
+}
+
+// ProvideBlobStore is the provider for the BlobStore selected by Blob.Driver.
+func ProvideBlobStore(config *configs.Config) BlobStore {
+	blob := config.Blob
+
+	switch blob.Driver {
+	case DriverLocal, "":
+		baseURL := blob.BaseURL
+		if baseURL == "" {
+			baseURL = strings.TrimSuffix(config.App.URL, "/") + LocalPath
+		}
+
+		return &LocalBlobStore{Dir: LocalDir(config), BaseURL: baseURL}
+	case DriverS3:
+		store, err := NewS3BlobStore(config)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed creating the S3 blob store.")
+		}
+
+		return store
+	default:
+		log.Fatal().Str("driver", blob.Driver).Msg("Unknown blob driver.")
+		return nil
+	}
+}
+
+// LocalDir is the directory of the local blob store.
+func LocalDir(config *configs.Config) string {
+	if config.Blob.Dir != "" {
+		return config.Blob.Dir
+	}
+
+	return defaultLocalDir
+}
+
+// LocalBlobStore stores every blob as a file of Dir. The files are served
+// under LocalPath by the HTTP server.
+type LocalBlobStore struct {
+	Dir     string
+	BaseURL string
+}
+
+// Put writes the blob to its file, replacing any previous one.
+func (s *LocalBlobStore) Put(key string, content []byte, contentType string) error {
+	name := s.path(key)
+
+	err := os.MkdirAll(filepath.Dir(name), 0755)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(name, content, 0644)
+}
+
+// Delete removes the blob's file. Missing blobs are not an error.
+func (s *LocalBlobStore) Delete(key string) error {
+	err := os.Remove(s.path(key))
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+}
+
+func (s *LocalBlobStore) URL(key string) string {
+	return strings.TrimSuffix(s.BaseURL, "/") + "/" + cleanKey(key)
+}
+
+func (s *LocalBlobStore) path(key string) string {
+	return filepath.Join(s.Dir, filepath.FromSlash(cleanKey(key)))
+}
+
+// S3BlobStore stores blobs in a bucket of S3, or of an S3-compatible store
+// such as MinIO when an endpoint is configured.
+type S3BlobStore struct {
+	Bucket  string
+	BaseURL string
+	client  *s3.S3
+}
+
+// NewS3BlobStore creates an S3BlobStore of the Blob.S3 configuration. Blob URLs
+// default to the bucket's path-style URL at the endpoint.
+func NewS3BlobStore(config *configs.Config) (*S3BlobStore, error) {
+	s3Config := config.Blob.S3
+
+	awsConfig := &aws.Config{
+		Region:           aws.String(s3Config.Region),
+		S3ForcePathStyle: aws.Bool(s3Config.ForcePathStyle),
+	}
+	if s3Config.AccessKeyID != "" {
+		awsConfig.Credentials = credentials.NewStaticCredentials(s3Config.AccessKeyID, s3Config.SecretAccessKey, "")
+	}
+	if s3Config.Endpoint != "" {
+		awsConfig.Endpoint = aws.String(s3Config.Endpoint)
+	}
+
+	sess, err := session.NewSession(awsConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL := config.Blob.BaseURL
+	if baseURL == "" {
+		endpoint := s3Config.Endpoint
+		if endpoint == "" {
+			endpoint = "https://s3." + s3Config.Region + ".amazonaws.com"
+		}
+		baseURL = strings.TrimSuffix(endpoint, "/") + "/" + s3Config.Bucket
+	}
+
+	return &S3BlobStore{
+		Bucket:  s3Config.Bucket,
+		BaseURL: baseURL,
+		client:  s3.New(sess),
+	}, nil
+}
+
+func (s *S3BlobStore) Put(key string, content []byte, contentType string) error {
+	_, err := s.client.PutObject(&s3.PutObjectInput{
+		Bucket:      aws.String(s.Bucket),
+		Key:         aws.String(cleanKey(key)),
+		Body:        bytes.NewReader(content),
+		ContentType: aws.String(contentType),
+	})
+
+	return err
+}
+
+// Delete deletes the blob. S3 does not fail deleting missing objects.
+func (s *S3BlobStore) Delete(key string) error {
+	_, err := s.client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(cleanKey(key)),
+	})
+
+	return err
+}
+
+func (s *S3BlobStore) URL(key string) string {
+	return strings.TrimSuffix(s.BaseURL, "/") + "/" + cleanKey(key)
+}
+
+// cleanKey keeps keys relative and free of "..", so that they cannot escape
+// the local directory.
+func cleanKey(key string) string {
+	return strings.TrimPrefix(path.Clean("/"+key), "/")
+}
@@ -0,0 +1,42 @@
+package blobstore_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/evermos/boilerplate-go/shared/blobstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalBlobStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "blobstore")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+
+	store := &blobstore.LocalBlobStore{Dir: dir, BaseURL: "http://localhost:8080/media/"}
+
+	t.Run("Put And Delete", func(t *testing.T) {
+		require.NoError(t, store.Put("products/1/image.png", []byte("content"), "image/png"))
+
+		content, err := ioutil.ReadFile(filepath.Join(dir, "products", "1", "image.png"))
+		require.NoError(t, err)
+		assert.Equal(t, "content", string(content))
+		assert.Equal(t, "http://localhost:8080/media/products/1/image.png", store.URL("products/1/image.png"))
+
+		require.NoError(t, store.Delete("products/1/image.png"))
+		_, err = os.Stat(filepath.Join(dir, "products", "1", "image.png"))
+		assert.True(t, os.IsNotExist(err))
+
+		assert.NoError(t, store.Delete("products/1/image.png"))
+	})
+
+	t.Run("Keys Stay In Dir", func(t *testing.T) {
+		require.NoError(t, store.Put("../../escaped.txt", []byte("content"), "text/plain"))
+
+		_, err := os.Stat(filepath.Join(dir, "escaped.txt"))
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,78 @@
+package breaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrOpen is returned while the breaker rejects calls.
+var ErrOpen = errors.New("circuit breaker is open")
+
+// Breaker is a circuit breaker. After MaxFailures consecutive failures it
+// opens and rejects calls for Cooldown, then lets a single trial call through;
+// the breaker closes again once a trial succeeds.
+type Breaker struct {
+	MaxFailures int
+	Cooldown    time.Duration
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	trial    bool
+}
+
+// New creates a new, closed Breaker.
+func New(maxFailures int, cooldown time.Duration) *Breaker {
+	return &Breaker{
+		MaxFailures: maxFailures,
+		Cooldown:    cooldown,
+	}
+}
+
+// Execute runs call unless the breaker is open. Failures are errors for which
+// isFailure returns true; other errors are returned without tripping the
+// breaker.
+func (b *Breaker) Execute(call func() error, isFailure func(error) bool) error {
+	if !b.allow() {
+		return ErrOpen
+	}
+
+	err := call()
+	b.record(err != nil && isFailure(err))
+
+	return err
+}
+
+func (b *Breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.MaxFailures {
+		return true
+	}
+
+	if b.trial || time.Since(b.openedAt) < b.Cooldown {
+		return false
+	}
+
+	b.trial = true
+	return true
+}
+
+func (b *Breaker) record(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+
+	if !failed {
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.failures >= b.MaxFailures {
+		b.openedAt = time.Now()
+	}
+}
@@ -0,0 +1,44 @@
+package breaker_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/evermos/boilerplate-go/shared/breaker"
+	"github.com/stretchr/testify/assert"
+)
+
+var errFailed = errors.New("failed")
+
+func always(err error) bool { return true }
+
+func TestBreaker(t *testing.T) {
+	t.Run("Opens After Consecutive Failures", func(t *testing.T) {
+		b := breaker.New(2, time.Hour)
+		fail := func() error { return errFailed }
+
+		assert.Equal(t, errFailed, b.Execute(fail, always))
+		assert.Equal(t, errFailed, b.Execute(fail, always))
+		assert.Equal(t, breaker.ErrOpen, b.Execute(func() error { return nil }, always))
+	})
+
+	t.Run("Ignores Non Failures", func(t *testing.T) {
+		b := breaker.New(1, time.Hour)
+		never := func(err error) bool { return false }
+
+		assert.Equal(t, errFailed, b.Execute(func() error { return errFailed }, never))
+		assert.NoError(t, b.Execute(func() error { return nil }, always))
+	})
+
+	t.Run("Closes After Successful Trial", func(t *testing.T) {
+		b := breaker.New(1, 10*time.Millisecond)
+
+		assert.Equal(t, errFailed, b.Execute(func() error { return errFailed }, always))
+		assert.Equal(t, breaker.ErrOpen, b.Execute(func() error { return nil }, always))
+
+		time.Sleep(20 * time.Millisecond)
+		assert.NoError(t, b.Execute(func() error { return nil }, always))
+		assert.NoError(t, b.Execute(func() error { return nil }, always))
+	})
+}
@@ -0,0 +1,134 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	// Registers the GIF decoder.
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+// jpegQuality is the quality of encoded JPEG images.
+const jpegQuality = 85
+
+// extensions are the file extensions of the supported content types.
+var extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+var (
+	// ErrUnsupportedType is returned decoding content that is not a JPEG,
+	// PNG or GIF image.
+	ErrUnsupportedType = errors.New("image must be a JPEG, PNG or GIF")
+	// ErrTooManyPixels is returned decoding images larger than allowed.
+	ErrTooManyPixels = errors.New("image has too many pixels")
+)
+
+// Decode decodes an image, telling its type from the content rather than any
+// declared content type. The dimensions are checked before the pixels are
+// decoded, so that small files of huge images are rejected cheaply.
+func Decode(content []byte, maxPixels int) (img image.Image, contentType string, err error) {
+	contentType = http.DetectContentType(content)
+	if _, ok := extensions[contentType]; !ok {
+		return nil, contentType, ErrUnsupportedType
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return nil, contentType, fmt.Errorf("invalid image: %v", err)
+	}
+
+	if maxPixels > 0 && config.Width*config.Height > maxPixels {
+		return nil, contentType, ErrTooManyPixels
+	}
+
+	img, _, err = image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, contentType, fmt.Errorf("invalid image: %v", err)
+	}
+
+	return img, contentType, nil
+}
+
+// Extension returns the file extension of a supported content type.
+func Extension(contentType string) string {
+	return extensions[contentType]
+}
+
+// Thumbnail scales an image down to fit within size by size pixels, keeping
+// its aspect ratio. Every pixel of the thumbnail averages the pixels it
+// covers. Images that already fit are returned as they are.
+func Thumbnail(src image.Image, size int) image.Image {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if size <= 0 || (width <= size && height <= size) {
+		return src
+	}
+
+	dstWidth, dstHeight := size, size
+	if width > height {
+		dstHeight = max(1, height*size/width)
+	} else {
+		dstWidth = max(1, width*size/height)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	for dy := 0; dy < dstHeight; dy++ {
+		y0 := bounds.Min.Y + dy*height/dstHeight
+		y1 := max(y0+1, bounds.Min.Y+(dy+1)*height/dstHeight)
+
+		for dx := 0; dx < dstWidth; dx++ {
+			x0 := bounds.Min.X + dx*width/dstWidth
+			x1 := max(x0+1, bounds.Min.X+(dx+1)*width/dstWidth)
+
+			var r, g, b, a, n uint64
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					pr, pg, pb, pa := src.At(x, y).RGBA()
+					r, g, b, a = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa)
+					n++
+				}
+			}
+
+			i := dst.PixOffset(dx, dy)
+			dst.Pix[i+0] = uint8(r / n >> 8)
+			dst.Pix[i+1] = uint8(g / n >> 8)
+			dst.Pix[i+2] = uint8(b / n >> 8)
+			dst.Pix[i+3] = uint8(a / n >> 8)
+		}
+	}
+
+	return dst
+}
+
+// Encode encodes an image as a JPEG when asked for one, and as a PNG
+// otherwise, which keeps transparency. The content type used is returned.
+func Encode(img image.Image, contentType string) (content []byte, encodedType string, err error) {
+	var b bytes.Buffer
+	switch contentType {
+	case "image/jpeg":
+		err = jpeg.Encode(&b, img, &jpeg.Options{Quality: jpegQuality})
+	default:
+		contentType = "image/png"
+		err = png.Encode(&b, img)
+	}
+	if err != nil {
+		return nil, contentType, err
+	}
+
+	return b.Bytes(), contentType, nil
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,57 @@
+package imaging_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/evermos/boilerplate-go/shared/imaging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImaging(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 400, 200))
+	for y := 0; y < 200; y++ {
+		for x := 0; x < 400; x++ {
+			src.Set(x, y, color.RGBA{R: 255, A: 255})
+		}
+	}
+
+	var b bytes.Buffer
+	require.NoError(t, png.Encode(&b, src))
+
+	t.Run("Decode Sniffs The Content", func(t *testing.T) {
+		img, contentType, err := imaging.Decode(b.Bytes(), 0)
+		require.NoError(t, err)
+
+		assert.Equal(t, "image/png", contentType)
+		assert.Equal(t, 400, img.Bounds().Dx())
+	})
+
+	t.Run("Decode Rejects Other Content", func(t *testing.T) {
+		_, _, err := imaging.Decode([]byte("<html><body>not an image</body></html>"), 0)
+		assert.Equal(t, imaging.ErrUnsupportedType, err)
+	})
+
+	t.Run("Decode Rejects Too Many Pixels", func(t *testing.T) {
+		_, _, err := imaging.Decode(b.Bytes(), 400*200-1)
+		assert.Equal(t, imaging.ErrTooManyPixels, err)
+	})
+
+	t.Run("Thumbnail Keeps The Aspect Ratio", func(t *testing.T) {
+		thumbnail := imaging.Thumbnail(src, 100)
+
+		assert.Equal(t, image.Rect(0, 0, 100, 50), thumbnail.Bounds())
+		r, g, _, a := thumbnail.At(50, 25).RGBA()
+		assert.Equal(t, uint32(0xffff), r)
+		assert.Equal(t, uint32(0), g)
+		assert.Equal(t, uint32(0xffff), a)
+	})
+
+	t.Run("Thumbnail Does Not Upscale", func(t *testing.T) {
+		assert.Equal(t, src.Bounds(), imaging.Thumbnail(src, 1000).Bounds())
+	})
+}
@@ -0,0 +1,304 @@
+package jwks
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// fetchTimeout bounds how long fetching the key set may take.
+	fetchTimeout = 10 * time.Second
+	// maxKeySetSize bounds the size of a fetched key set.
+	maxKeySetSize = 1024 * 1024
+	// minRefreshInterval rate limits refreshes triggered by unknown key IDs.
+	minRefreshInterval = time.Minute
+)
+
+// Errors returned when resolving keys.
+var (
+	ErrKeyNotFound    = errors.New("signing key not found in key set")
+	ErrNoKeyID        = errors.New("token has no key ID")
+	ErrUnsupportedKey = errors.New("unsupported JSON web key")
+)
+
+// Source loads a JSON Web Key Set document.
+type Source interface {
+	Load() ([]byte, error)
+}
+
+// URLSource loads the key set from an HTTP endpoint.
+type URLSource struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewURLSource creates a new URLSource.
+func NewURLSource(url string) *URLSource {
+	return &URLSource{
+		URL:    url,
+		Client: &http.Client{Timeout: fetchTimeout},
+	}
+}
+
+// Load fetches the key set.
+func (s *URLSource) Load() ([]byte, error) {
+	resp, err := s.Client.Get(s.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed fetching JWKS: %s", resp.Status)
+	}
+
+	return ioutil.ReadAll(io.LimitReader(resp.Body, maxKeySetSize))
+}
+
+// FileSource loads the key set from a local file.
+type FileSource struct {
+	Path string
+}
+
+// Load reads the key set.
+func (s *FileSource) Load() ([]byte, error) {
+	return ioutil.ReadFile(s.Path)
+}
+
+// jsonWebKey is a key of RFC 7517, limited to the RSA and EC public key members.
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// Parse parses a key set document into public keys by key ID. Keys that are
+// not signing keys or are not supported are skipped.
+func Parse(data []byte) (map[string]interface{}, error) {
+	var document struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+
+	err := json.Unmarshal(data, &document)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]interface{})
+	for _, jwk := range document.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := jwk.publicKey()
+		if err != nil {
+			log.Warn().Err(err).Str("kid", jwk.Kid).Msg("Skipping JSON web key.")
+			continue
+		}
+
+		keys[jwk.Kid] = key
+	}
+
+	return keys, nil
+}
+
+func (k jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, ErrUnsupportedKey
+		}
+
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		if !curve.IsOnCurve(x, y) {
+			return nil, ErrUnsupportedKey
+		}
+
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, ErrUnsupportedKey
+	}
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(data), nil
+}
+
+// KeySet caches the keys of a Source and refreshes them in the background, so
+// that rotated keys are picked up. A token signed with an unknown key also
+// triggers a refresh, at most once per minute.
+type KeySet struct {
+	Source Source
+
+	mu          sync.RWMutex
+	keys        map[string]interface{}
+	refreshedAt time.Time
+	refreshMu   sync.Mutex
+	stop        chan struct{}
+	stopOnce    sync.Once
+}
+
+// NewKeySet creates a new KeySet, loads its keys and refreshes them every
+// interval until Close is called. A failing initial load is logged, and
+// retried on the next refresh.
+func NewKeySet(source Source, interval time.Duration) *KeySet {
+	k := &KeySet{
+		Source: source,
+		keys:   make(map[string]interface{}),
+		stop:   make(chan struct{}),
+	}
+
+	err := k.Refresh()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed loading JWKS.")
+	}
+
+	if interval > 0 {
+		go k.refreshEvery(interval)
+	}
+
+	return k
+}
+
+// Refresh reloads the keys from the source. The current keys are kept when
+// loading fails.
+func (k *KeySet) Refresh() error {
+	k.refreshMu.Lock()
+	defer k.refreshMu.Unlock()
+
+	data, err := k.Source.Load()
+	if err != nil {
+		return err
+	}
+
+	keys, err := Parse(data)
+	if err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	k.keys = keys
+	k.refreshedAt = time.Now()
+	k.mu.Unlock()
+
+	return nil
+}
+
+// Key returns the key with the given ID.
+func (k *KeySet) Key(kid string) (interface{}, error) {
+	k.mu.RLock()
+	key, ok := k.keys[kid]
+	refreshedAt := k.refreshedAt
+	k.mu.RUnlock()
+
+	if ok {
+		return key, nil
+	}
+
+	if time.Since(refreshedAt) < minRefreshInterval {
+		return nil, ErrKeyNotFound
+	}
+
+	err := k.Refresh()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed refreshing JWKS.")
+		return nil, ErrKeyNotFound
+	}
+
+	k.mu.RLock()
+	key, ok = k.keys[kid]
+	k.mu.RUnlock()
+
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+
+	return key, nil
+}
+
+// Keyfunc resolves the key a token was signed with, for use with jwt.Parse.
+func (k *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, ErrNoKeyID
+	}
+
+	return k.Key(kid)
+}
+
+// Close stops refreshing the keys in the background.
+func (k *KeySet) Close() {
+	k.stopOnce.Do(func() {
+		close(k.stop)
+	})
+}
+
+func (k *KeySet) refreshEvery(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			err := k.Refresh()
+			if err != nil {
+				log.Error().Err(err).Msg("Failed refreshing JWKS.")
+			}
+		case <-k.stop:
+			return
+		}
+	}
+}
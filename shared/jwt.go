@@ -1,6 +1,7 @@
 package shared
 
 import (
+	"github.com/evermos/boilerplate-go/shared/rbac"
 	"github.com/gofrs/uuid"
 	"github.com/golang-jwt/jwt"
 )
@@ -9,5 +10,6 @@ type Claims struct {
 	UserID   uuid.UUID `json:"user_id"`
 	Username string    `json:"username"`
 	Email    string    `json:"email"`
+	Role     rbac.Role `json:"role"`
 	jwt.StandardClaims
 }
@@ -0,0 +1,126 @@
+package mailer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"io/ioutil"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/evermos/boilerplate-go/configs"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// DriverSMTP sends mail through an SMTP server.
+	DriverSMTP = "smtp"
+	// DriverFile writes mail to files, for local runs.
+	DriverFile = "file"
+	// DriverLog logs mail, for local runs.
+	DriverLog = "log"
+)
+
+// Message is a plain text mail message.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends mail.
+type Mailer interface {
+	Send(message Message) error
+}
+
+// ProvideMailer is the provider for the Mailer selected by Mail.Driver.
+func ProvideMailer(config *configs.Config) Mailer {
+	mail := config.Mail
+
+	switch mail.Driver {
+	case DriverSMTP:
+		return &SMTPMailer{
+			Addr: mail.SMTP.Host + ":" + mail.SMTP.Port,
+			Auth: smtp.PlainAuth("", mail.SMTP.Username, mail.SMTP.Password, mail.SMTP.Host),
+			From: mail.From,
+		}
+	case DriverFile:
+		return &FileMailer{Dir: mail.Dir, From: mail.From}
+	case DriverLog, "":
+		return &LogMailer{}
+	default:
+		log.Fatal().Str("driver", mail.Driver).Msg("Unknown mail driver.")
+		return nil
+	}
+}
+
+// SMTPMailer sends mail through an SMTP server.
+type SMTPMailer struct {
+	Addr string
+	Auth smtp.Auth
+	From string
+}
+
+// Send sends the message.
+func (m *SMTPMailer) Send(message Message) error {
+	return smtp.SendMail(m.Addr, m.Auth, m.From, []string{message.To}, format(m.From, message))
+}
+
+// FileMailer writes every message to a file of Dir.
+type FileMailer struct {
+	Dir  string
+	From string
+}
+
+// Send writes the message to a new file.
+func (m *FileMailer) Send(message Message) error {
+	err := os.MkdirAll(m.Dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	suffix := make([]byte, 4)
+	_, err = rand.Read(suffix)
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s-%x.eml", time.Now().Format("20060102T150405"), suffix)
+	return ioutil.WriteFile(filepath.Join(m.Dir, name), format(m.From, message), 0644)
+}
+
+// LogMailer logs every message. Messages may carry secrets such as password
+// reset links, so it must only be used for local runs.
+type LogMailer struct{}
+
+// Send logs the message.
+func (m *LogMailer) Send(message Message) error {
+	log.Info().
+		Str("to", message.To).
+		Str("subject", message.Subject).
+		Str("body", message.Body).
+		Msg("Mail message.")
+
+	return nil
+}
+
+// format formats a message with its headers. Line breaks are removed from
+// header values, so that they cannot inject headers.
+func format(from string, message Message) []byte {
+	header := strings.NewReplacer("\r", "", "\n", "")
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", header.Replace(from))
+	fmt.Fprintf(&b, "To: %s\r\n", header.Replace(message.To))
+	fmt.Fprintf(&b, "Subject: %s\r\n", header.Replace(message.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(message.Body, "\n", "\r\n"))
+
+	return b.Bytes()
+}
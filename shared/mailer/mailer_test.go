@@ -0,0 +1,39 @@
+package mailer_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/evermos/boilerplate-go/shared/mailer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileMailer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mailer")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+
+	m := &mailer.FileMailer{Dir: filepath.Join(dir, "mail"), From: "no-reply@example.com"}
+
+	err = m.Send(mailer.Message{
+		To:      "user@example.com\r\nBcc: attacker@example.com",
+		Subject: "Reset your password",
+		Body:    "Hello\nWorld",
+	})
+	require.NoError(t, err)
+
+	files, err := filepath.Glob(filepath.Join(dir, "mail", "*.eml"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	content, err := ioutil.ReadFile(files[0])
+	require.NoError(t, err)
+
+	assert.Contains(t, string(content), "Subject: Reset your password\r\n")
+	assert.Contains(t, string(content), "To: user@example.comBcc: attacker@example.com\r\n")
+	assert.NotContains(t, string(content), "\r\nBcc:")
+	assert.Contains(t, string(content), "\r\n\r\nHello\r\nWorld")
+}
@@ -1,6 +1,8 @@
 package oauth
 
 import (
+	"errors"
+
 	"github.com/jmoiron/sqlx"
 )
 
@@ -9,23 +11,27 @@ type GrantType string
 const (
 	ClientCredentials GrantType = "client_credentials"
 	Password          GrantType = "password"
+	RefreshToken      GrantType = "refresh_token"
+	AuthorizationCode GrantType = "authorization_code"
 )
 
 type Token struct {
 	config          Config
-	tokenRepository TokenStore
+	tokenRepository TokenRepository
 }
 
-func New(db *sqlx.DB, config Config) *Token {
+func New(db *sqlx.DB, tokens TokenStore, config Config) *Token {
 	return &Token{
 		config:          config,
-		tokenRepository: NewTokenStore(db),
+		tokenRepository: NewTokenRepository(db, tokens),
 	}
 }
 
 type Config struct {
-	Expiration  int64
-	ClientScope []string
+	Expiration                  int64
+	RefreshExpiration           int64
+	AuthorizationCodeExpiration int64
+	ClientScope                 []string
 }
 
 // Create is function to store NewToken into database
@@ -38,9 +44,36 @@ func (t *Token) Create(credential Credential) (*TokenResponse, error) {
 	return grant.toCreateTokenResponse(), nil
 }
 
+// Revoke revokes an access or refresh token of an authenticated client,
+// following RFC 7009. Tokens that are unknown or were issued to another
+// client are ignored, so that the response does not reveal them.
+func (t *Token) Revoke(credential Credential, token string, tokenTypeHint string) error {
+	client, err := t.tokenRepository.resolveClientByClientID(credential.ClientID)
+	if err != nil {
+		return err
+	}
+
+	if !client.VerifyClient(credential) {
+		return errors.New(ErrorInvalidClient)
+	}
+
+	return t.tokenRepository.revoke(client.ClientID, token, tokenTypeHint)
+}
+
+// IdentifyUser resolves the ID of the user with a username, email or
+// telephone. Unknown users fail with ErrorUserNotFound.
+func (t *Token) IdentifyUser(login string) (string, error) {
+	user, err := t.tokenRepository.resolveByTelephoneOrEmail(login)
+	if err != nil {
+		return "", err
+	}
+
+	return user.ID, nil
+}
+
 // ParseWithAccessToken is function to exchange valid token into token info
 func (t *Token) ParseWithAccessToken(accessToken string) (OauthAccessToken, error) {
-	return NewParser(t.tokenRepository).Parse(accessToken)
+	return NewParser(t.tokenRepository.tokens).Parse(accessToken)
 }
 
 // ClientScopeAllowed is function that is used to limit the client
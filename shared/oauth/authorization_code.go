@@ -0,0 +1,274 @@
+package oauth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/guregu/null"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// ResponseTypeCode is the response type of the authorization code grant.
+	ResponseTypeCode = "code"
+	// CodeChallengeMethodS256 is the only PKCE code challenge method accepted.
+	CodeChallengeMethodS256 = "S256"
+)
+
+// codeVerifierPattern matches PKCE code verifiers, and S256 code challenges,
+// as RFC 7636 section 4.1 defines them.
+var codeVerifierPattern = regexp.MustCompile(`^[A-Za-z0-9\-._~]{43,128}$`)
+
+// AuthorizationRequest is the authorization request of RFC 6749 section
+// 4.1.1, extended with the PKCE parameters of RFC 7636 section 4.3.
+type AuthorizationRequest struct {
+	ResponseType        string
+	ClientID            string
+	RedirectURI         string
+	Scope               string
+	State               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+}
+
+// OauthAuthorizationCode is an issued authorization code. Only the hash of
+// the code is stored. Tokens issued for the code start a new token family, so
+// that they can be revoked when the code is replayed.
+type OauthAuthorizationCode struct {
+	CodeHash      string      `db:"code_hash"`
+	FamilyID      string      `db:"family_id"`
+	ClientID      string      `db:"client_id"`
+	UserID        string      `db:"user_id"`
+	RedirectURI   string      `db:"redirect_uri"`
+	Scope         null.String `db:"scope"`
+	CodeChallenge string      `db:"code_challenge"`
+	Expires       time.Time   `db:"expires"`
+	UsedAt        null.Time   `db:"used_at"`
+}
+
+// verifyCodeVerifier checks a PKCE code verifier against the S256 challenge
+// the code was issued for.
+func (o *OauthAuthorizationCode) verifyCodeVerifier(codeVerifier string) bool {
+	if !codeVerifierPattern.MatchString(codeVerifier) {
+		return false
+	}
+
+	sum := sha256.Sum256([]byte(codeVerifier))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	return subtle.ConstantTimeCompare([]byte(challenge), []byte(o.CodeChallenge)) == 1
+}
+
+// ValidAuthorization is a validated authorization request, ready to be
+// presented to the user for consent.
+type ValidAuthorization struct {
+	ClientID      string `json:"clientId"`
+	RedirectURI   string `json:"redirectUri"`
+	Scope         string `json:"scope,omitempty"`
+	State         string `json:"state,omitempty"`
+	codeChallenge string
+}
+
+// RedirectWith returns the redirect URI with the given parameters and the
+// state of the request added to its query.
+func (v ValidAuthorization) RedirectWith(params url.Values) string {
+	redirectURI, _ := url.Parse(v.RedirectURI)
+
+	query := redirectURI.Query()
+	for key := range params {
+		query.Set(key, params.Get(key))
+	}
+
+	if v.State != "" {
+		query.Set("state", v.State)
+	}
+
+	redirectURI.RawQuery = query.Encode()
+	return redirectURI.String()
+}
+
+// ValidateAuthorizationRequest validates an authorization request. The client
+// must allow the authorization code grant, the redirect URI must match the
+// client's exactly, and a S256 code challenge is required. The requested scope
+// must be within the client's scope, and defaults to it.
+//
+// Errors about the client or the redirect URI must not be redirected to the
+// redirect URI, as it is not trusted.
+func (t *Token) ValidateAuthorizationRequest(request AuthorizationRequest) (authorization ValidAuthorization, err error) {
+	client, err := t.tokenRepository.resolveClientByClientID(request.ClientID)
+	if err != nil {
+		return
+	}
+
+	if client.DisabledAt.Valid {
+		err = errors.New(ErrorClientNotFound)
+		return
+	}
+
+	if !client.RedirectURI.Valid || (request.RedirectURI != "" && request.RedirectURI != client.RedirectURI.String) {
+		err = errors.New(ErrorInvalidRedirectURI)
+		return
+	}
+
+	if _, parseErr := url.Parse(client.RedirectURI.String); parseErr != nil {
+		err = errors.New(ErrorInvalidRedirectURI)
+		return
+	}
+
+	authorization = ValidAuthorization{
+		ClientID:      client.ClientID,
+		RedirectURI:   client.RedirectURI.String,
+		State:         request.State,
+		codeChallenge: request.CodeChallenge,
+	}
+
+	switch {
+	case request.ResponseType != ResponseTypeCode:
+		err = errors.New(ErrorUnsupportedResponseType)
+	case !client.AllowsGrant(AuthorizationCode):
+		err = errors.New(ErrorUnauthorizedGrant)
+	case request.CodeChallengeMethod != CodeChallengeMethodS256 || !codeVerifierPattern.MatchString(request.CodeChallenge):
+		err = errors.New(ErrorInvalidCodeChallenge)
+	}
+	if err != nil {
+		return
+	}
+
+	scope, err := client.grantedScope(request.Scope, AuthorizationCode)
+	if err != nil {
+		return
+	}
+
+	authorization.Scope = scope.String()
+	return
+}
+
+// Authorize issues an authorization code for a request the user consented to.
+func (t *Token) Authorize(authorization ValidAuthorization, userID string) (code string, err error) {
+	code, err = generateAccessToken()
+	if err != nil {
+		return "", errors.New(ErrorGenerateAccessToken)
+	}
+
+	familyID, err := newFamilyID()
+	if err != nil {
+		return "", errors.New(ErrorGenerateAccessToken)
+	}
+
+	authorizationCode := OauthAuthorizationCode{
+		CodeHash:      hashToken(code),
+		FamilyID:      familyID,
+		ClientID:      authorization.ClientID,
+		UserID:        userID,
+		RedirectURI:   authorization.RedirectURI,
+		CodeChallenge: authorization.codeChallenge,
+		Expires:       time.Now().Add(time.Second * time.Duration(t.config.AuthorizationCodeExpiration)),
+	}
+
+	if authorization.Scope != "" {
+		authorizationCode.Scope = null.StringFrom(authorization.Scope)
+	}
+
+	err = t.tokenRepository.createAuthorizationCode(authorizationCode)
+	if err != nil {
+		return "", err
+	}
+
+	return code, nil
+}
+
+// AuthorizationCodeAuth exchanges an authorization code for tokens. Codes are
+// single use; redeeming a code twice revokes the tokens issued for it, as RFC
+// 6749 section 4.1.2 recommends.
+type AuthorizationCodeAuth struct {
+	tokenRepository TokenRepository
+	config          Config
+}
+
+func (c *AuthorizationCodeAuth) Create(credential Credential) (oauthAccessToken OauthAccessToken, err error) {
+	client, err := c.tokenRepository.resolveClientByClientID(credential.ClientID)
+	if err != nil {
+		return
+	}
+
+	if !client.VerifyClient(credential) {
+		err = errors.New(ErrorInvalidClient)
+		return
+	}
+
+	if !client.AllowsGrant(AuthorizationCode) {
+		err = errors.New(ErrorUnauthorizedGrant)
+		return
+	}
+
+	code, err := c.tokenRepository.resolveAuthorizationCodeByHash(hashToken(credential.Code))
+	if err != nil {
+		return
+	}
+
+	if code.ClientID != credential.ClientID || code.RedirectURI != credential.RedirectURI || time.Now().After(code.Expires) {
+		err = errors.New(ErrorInvalidAuthorizationCode)
+		return
+	}
+
+	if code.UsedAt.Valid {
+		err = c.revokeReplayedCode(code)
+		return
+	}
+
+	if !code.verifyCodeVerifier(credential.CodeVerifier) {
+		err = errors.New(ErrorInvalidAuthorizationCode)
+		return
+	}
+
+	accessToken, err := generateAccessToken()
+	if err != nil {
+		err = errors.New(ErrorGenerateAccessToken)
+		return
+	}
+
+	oauthAccessToken = new(OauthAccessToken).Generate(accessToken, code.ClientID, &code.UserID, ParseScope(code.Scope.String), c.config)
+	oauthAccessToken.FamilyID = null.StringFrom(code.FamilyID)
+
+	var refreshToken *OauthRefreshToken
+	if client.AllowsGrant(RefreshToken) {
+		var next OauthRefreshToken
+		oauthAccessToken, next, err = withRefreshToken(oauthAccessToken, code.FamilyID, c.config)
+		if err != nil {
+			return
+		}
+		refreshToken = &next
+	}
+
+	redeemed, err := c.tokenRepository.redeemAuthorizationCode(code, oauthAccessToken, refreshToken)
+	if err != nil {
+		return
+	}
+
+	if !redeemed {
+		// A concurrent request redeemed the same code first.
+		err = c.revokeReplayedCode(code)
+		return
+	}
+
+	return
+}
+
+func (c *AuthorizationCodeAuth) revokeReplayedCode(code OauthAuthorizationCode) error {
+	log.Warn().
+		Str("clientId", code.ClientID).
+		Str("familyId", code.FamilyID).
+		Msg("Authorization code replayed, revoking the tokens issued for it.")
+
+	err := c.tokenRepository.revokeFamily(code.FamilyID)
+	if err != nil {
+		return err
+	}
+
+	return errors.New(ErrorInvalidAuthorizationCode)
+}
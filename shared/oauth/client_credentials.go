@@ -5,12 +5,12 @@ import (
 )
 
 type ClientCredentialsAuth struct {
-	tokenStore TokenStore
-	config     Config
+	tokenRepository TokenRepository
+	config          Config
 }
 
 func (c *ClientCredentialsAuth) Create(credential Credential) (oauthAccessToken OauthAccessToken, err error) {
-	client, err := c.tokenStore.resolveClientByClientID(credential.ClientID)
+	client, err := c.tokenRepository.resolveClientByClientID(credential.ClientID)
 	if err != nil {
 		return
 	}
@@ -20,14 +20,24 @@ func (c *ClientCredentialsAuth) Create(credential Credential) (oauthAccessToken
 		return
 	}
 
+	if !client.AllowsGrant(ClientCredentials) {
+		err = errors.New(ErrorUnauthorizedGrant)
+		return
+	}
+
+	scope, err := client.grantedScope(credential.Scope, ClientCredentials)
+	if err != nil {
+		return
+	}
+
 	accessToken, err := generateAccessToken()
 	if err != nil {
 		err = errors.New(ErrorGenerateAccessToken)
 		return
 	}
 
-	oauthAccessToken = new(OauthAccessToken).Generate(accessToken, credential.ClientID, nil, true, c.config)
-	err = c.tokenStore.createAccessToken(oauthAccessToken)
+	oauthAccessToken = new(OauthAccessToken).Generate(accessToken, credential.ClientID, nil, scope, c.config)
+	err = c.tokenRepository.createAccessToken(oauthAccessToken)
 	if err != nil {
 		return
 	}
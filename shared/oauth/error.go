@@ -3,9 +3,21 @@ package oauth
 const (
 	ErrorEmptyCredential     string = "Credential can't be empty"
 	ErrorClientNotFound      string = "Client does not exist"
+	ErrorUserNotFound        string = "User does not exist"
 	ErrorInvalidPassword     string = "Invalid password credential"
 	ErrorInvalidClient       string = "Invalid client credentials"
 	ErrorInvalidToken        string = "Invalid Token"
 	ErrorTokenTypeMismatch   string = "Token type mismatch"
 	ErrorGenerateAccessToken string = "Error generating access token"
+	ErrorUnsupportedGrant    string = "Unsupported grant type"
+	ErrorUnauthorizedGrant   string = "Grant type not allowed for client"
+	ErrorInvalidRefreshToken string = "Invalid refresh token"
+
+	ErrorInvalidAuthorizationCode string = "Invalid authorization code"
+	ErrorInvalidCodeChallenge     string = "A S256 code challenge is required"
+	ErrorInvalidRedirectURI       string = "Invalid redirect URI"
+	ErrorInvalidScope             string = "Invalid scope"
+	ErrorUnsupportedResponseType  string = "Unsupported response type"
+	ErrorAccessDenied             string = "The user denied the request"
+	ErrorTooManyLoginAttempts     string = "Too many failed login attempts, try again later"
 )
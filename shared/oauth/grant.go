@@ -1,25 +1,36 @@
 package oauth
 
+import (
+	"errors"
+)
+
 type AuthorizationMethod interface {
 	Create(credential Credential) (OauthAccessToken, error)
 }
 
 type Grant struct {
-	TokenStore TokenStore
-	Config     Config
+	TokenRepository TokenRepository
+	Config          Config
 }
 
-func NewGrant(tokenStore TokenStore, config Config) *Grant {
+func NewGrant(tokenRepository TokenRepository, config Config) *Grant {
 	return &Grant{
-		TokenStore: tokenStore,
-		Config:     config,
+		TokenRepository: tokenRepository,
+		Config:          config,
 	}
 }
 
 func (g *Grant) Create(credential Credential) (OauthAccessToken, error) {
 	authMap := make(map[GrantType]AuthorizationMethod)
-	authMap[ClientCredentials] = &ClientCredentialsAuth{tokenStore: g.TokenStore, config: g.Config}
-	authMap[Password] = &PasswordAuth{tokenStore: g.TokenStore, config: g.Config}
+	authMap[ClientCredentials] = &ClientCredentialsAuth{tokenRepository: g.TokenRepository, config: g.Config}
+	authMap[Password] = &PasswordAuth{tokenRepository: g.TokenRepository, config: g.Config}
+	authMap[RefreshToken] = &RefreshTokenAuth{tokenRepository: g.TokenRepository, config: g.Config}
+	authMap[AuthorizationCode] = &AuthorizationCodeAuth{tokenRepository: g.TokenRepository, config: g.Config}
+
+	auth, ok := authMap[credential.GrantType]
+	if !ok {
+		return OauthAccessToken{}, errors.New(ErrorUnsupportedGrant)
+	}
 
-	return authMap[credential.GrantType].Create(credential)
+	return auth.Create(credential)
 }
@@ -0,0 +1,121 @@
+package oauth_test
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/evermos/boilerplate-go/shared/oauth"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestGrant(t *testing.T) {
+	t.Run("Unsupported Grant Type", func(t *testing.T) {
+		grant := oauth.NewGrant(oauth.NewTokenRepository(nil, nil), oauth.Config{})
+
+		assert.NotPanics(t, func() {
+			_, err := grant.Create(oauth.Credential{GrantType: "implicit"})
+			assert.EqualError(t, err, oauth.ErrorUnsupportedGrant)
+		})
+	})
+
+	t.Run("Client Grant Types", func(t *testing.T) {
+		client := oauth.OauthClient{GrantTypes: "client_credentials password refresh_token"}
+
+		assert.True(t, client.AllowsGrant(oauth.RefreshToken))
+		assert.False(t, client.AllowsGrant(oauth.GrantType("refresh")))
+	})
+
+	t.Run("Client Not Allowed The Grant", func(t *testing.T) {
+		secret, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+		require.NoError(t, err)
+
+		for _, grantType := range []oauth.GrantType{oauth.Password, oauth.ClientCredentials} {
+			t.Run(string(grantType), func(t *testing.T) {
+				db, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				defer db.Close()
+
+				mock.ExpectQuery("FROM\\s+oauth_clients").
+					WithArgs("client_partner").
+					WillReturnRows(sqlmock.NewRows([]string{"client_id", "client_secret", "redirect_uri", "grant_types", "scope", "disabled_at"}).
+						AddRow("client_partner", string(secret), "https://partner.example.com/callback", "authorization_code refresh_token", "product:read", nil))
+
+				grant := oauth.NewGrant(oauth.NewTokenRepository(sqlx.NewDb(db, "mysql"), nil), oauth.Config{})
+				_, err = grant.Create(oauth.Credential{
+					GrantType:    grantType,
+					ClientID:     "client_partner",
+					ClientSecret: "secret",
+					Username:     "john",
+					Password:     "password",
+				})
+
+				assert.EqualError(t, err, oauth.ErrorUnauthorizedGrant)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			})
+		}
+	})
+
+	t.Run("Service Scopes Are Only Granted With Client Credentials", func(t *testing.T) {
+		secret, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+		require.NoError(t, err)
+
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		expectClient := func() {
+			mock.ExpectQuery("FROM\\s+oauth_clients").
+				WithArgs("client_web").
+				WillReturnRows(sqlmock.NewRows([]string{"client_id", "client_secret", "redirect_uri", "grant_types", "scope", "disabled_at"}).
+					AddRow("client_web", string(secret), nil, "client_credentials password", "foo:read oauth:introspect inventory:write", nil))
+		}
+		store := &memoryTokenStore{tokens: make(map[string]oauth.OauthAccessToken)}
+		grant := oauth.NewGrant(oauth.NewTokenRepository(sqlx.NewDb(db, "mysql"), store), oauth.Config{})
+		credential := oauth.Credential{
+			ClientID:     "client_web",
+			ClientSecret: "secret",
+			Username:     "john",
+			Password:     "secret",
+		}
+
+		expectClient()
+		credential.GrantType = oauth.ClientCredentials
+		token, err := grant.Create(credential)
+		require.NoError(t, err)
+		assert.Equal(t, "foo:read inventory:write oauth:introspect", token.Scope.String)
+
+		expectClient()
+		mock.ExpectQuery("FROM\\s+user").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password"}).AddRow("user-1", "john", string(secret)))
+		credential.GrantType = oauth.Password
+		token, err = grant.Create(credential)
+		require.NoError(t, err)
+		assert.Equal(t, "foo:read", token.Scope.String)
+
+		expectClient()
+		credential.Scope = oauth.ScopeOAuthIntrospect
+		_, err = grant.Create(credential)
+		assert.EqualError(t, err, oauth.ErrorInvalidScope)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Authorization Redirect", func(t *testing.T) {
+		authorization := oauth.ValidAuthorization{
+			RedirectURI: "https://partner.example.com/callback?tenant=a",
+			State:       "xyz",
+		}
+
+		redirectTo, err := url.Parse(authorization.RedirectWith(url.Values{"code": {"abc"}}))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "partner.example.com", redirectTo.Host)
+		assert.Equal(t, "a", redirectTo.Query().Get("tenant"))
+		assert.Equal(t, "abc", redirectTo.Query().Get("code"))
+		assert.Equal(t, "xyz", redirectTo.Query().Get("state"))
+	})
+}
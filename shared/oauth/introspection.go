@@ -0,0 +1,111 @@
+package oauth
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/evermos/boilerplate-go/configs"
+	"github.com/go-redis/redis"
+	"github.com/rs/zerolog/log"
+)
+
+// defaultIntrospectionCacheTTL is how long introspection responses are cached when no TTL is configured.
+const defaultIntrospectionCacheTTL = 10 * time.Second
+
+// IntrospectionResponse is the introspection response of RFC 7662 section
+// 2.2. Inactive tokens only have Active set.
+type IntrospectionResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Sub       string `json:"sub,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+}
+
+// Introspect resolves whether an access token is active, and its details.
+// Unknown and expired tokens are inactive.
+func (t *Token) Introspect(accessToken string) (IntrospectionResponse, error) {
+	token, err := t.ParseWithAccessToken(string(Bearer) + " " + accessToken)
+	if err != nil {
+		switch err.Error() {
+		case ErrorClientNotFound, ErrorEmptyCredential, ErrorTokenTypeMismatch:
+			return IntrospectionResponse{}, nil
+		default:
+			return IntrospectionResponse{}, err
+		}
+	}
+
+	if !token.VerifyExpireIn() {
+		return IntrospectionResponse{}, nil
+	}
+
+	return IntrospectionResponse{
+		Active:    true,
+		Scope:     token.Scope.String,
+		ClientID:  token.ClientID,
+		Sub:       token.UserID.String,
+		Exp:       token.Expires.Unix(),
+		TokenType: string(Bearer),
+	}, nil
+}
+
+// Introspector introspects access tokens, caching the responses in Redis for
+// a short TTL to absorb traffic. A token revoked in the meantime may thus be
+// reported active until its cached response expires.
+type Introspector struct {
+	Token *Token
+	Redis *redis.Client
+	TTL   time.Duration
+}
+
+// ProvideIntrospector is the provider for Introspector.
+func ProvideIntrospector(token *Token, redis *redis.Client, config *configs.Config) *Introspector {
+	ttl := time.Duration(config.OAuth.IntrospectionCacheSeconds) * time.Second
+	if ttl <= 0 {
+		ttl = defaultIntrospectionCacheTTL
+	}
+
+	return &Introspector{
+		Token: token,
+		Redis: redis,
+		TTL:   ttl,
+	}
+}
+
+// Introspect introspects an access token, from cache when possible. Cache
+// failures are logged, and fall back to the token store.
+func (i *Introspector) Introspect(accessToken string) (response IntrospectionResponse, err error) {
+	key := fmt.Sprintf("oauth_introspection:%s", hashToken(accessToken))
+
+	cached, err := i.Redis.Get(key).Bytes()
+	if err == nil && json.Unmarshal(cached, &response) == nil {
+		return response, nil
+	}
+
+	if err != nil && err != redis.Nil {
+		log.Warn().Err(err).Msg("Failed reading cached introspection response.")
+	}
+
+	response, err = i.Token.Introspect(accessToken)
+	if err != nil {
+		return
+	}
+
+	ttl := i.TTL
+	if response.Active {
+		if untilExpiry := time.Until(time.Unix(response.Exp, 0)); untilExpiry < ttl {
+			ttl = untilExpiry
+		}
+	}
+
+	if ttl > 0 {
+		value, _ := json.Marshal(response)
+		if cacheErr := i.Redis.Set(key, value, ttl).Err(); cacheErr != nil {
+			log.Warn().Err(cacheErr).Msg("Failed caching introspection response.")
+		}
+	}
+
+	return response, nil
+}
@@ -0,0 +1,197 @@
+package oauth_test
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/evermos/boilerplate-go/shared/oauth"
+	"github.com/go-redis/redis"
+	"github.com/guregu/null"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryTokenStore is an in-memory TokenStore counting the tokens resolved.
+type memoryTokenStore struct {
+	tokens   map[string]oauth.OauthAccessToken
+	err      error
+	resolved int
+}
+
+func (s *memoryTokenStore) CreateAccessToken(accessToken oauth.OauthAccessToken) error {
+	s.tokens[accessToken.AccessToken] = accessToken
+	return nil
+}
+
+func (s *memoryTokenStore) ResolveAccessToken(accessToken string) (oauth.OauthAccessToken, error) {
+	s.resolved++
+	if s.err != nil {
+		return oauth.OauthAccessToken{}, s.err
+	}
+
+	token, ok := s.tokens[accessToken]
+	if !ok {
+		return oauth.OauthAccessToken{}, errors.New(oauth.ErrorClientNotFound)
+	}
+
+	return token, nil
+}
+
+func (s *memoryTokenStore) RevokeAccessToken(accessToken string, clientID string) (bool, error) {
+	_, ok := s.tokens[accessToken]
+	delete(s.tokens, accessToken)
+	return ok, nil
+}
+
+func (s *memoryTokenStore) RevokeFamily(familyID string) error { return nil }
+
+func (s *memoryTokenStore) RevokeUser(userID string) error { return nil }
+
+func (s *memoryTokenStore) RevokeClient(clientID string) error { return nil }
+
+func introspectionKey(accessToken string) string {
+	return fmt.Sprintf("oauth_introspection:%x", sha256.Sum256([]byte(accessToken)))
+}
+
+func TestIntrospector(t *testing.T) {
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+	defer server.Close()
+
+	expires := time.Now().Add(time.Hour)
+	store := &memoryTokenStore{}
+	introspector := &oauth.Introspector{
+		Token: oauth.New(nil, store, oauth.Config{}),
+		Redis: redis.NewClient(&redis.Options{Addr: server.Addr()}),
+		TTL:   10 * time.Second,
+	}
+
+	reset := func() {
+		server.FlushAll()
+		store.tokens = map[string]oauth.OauthAccessToken{
+			"active": {
+				AccessToken: "active",
+				ClientID:    "client_web",
+				UserID:      null.StringFrom("user-1"),
+				Expires:     expires,
+				Scope:       null.StringFrom("foo:read"),
+			},
+		}
+		store.err = nil
+		store.resolved = 0
+	}
+
+	t.Run("Active Token", func(t *testing.T) {
+		reset()
+
+		response, err := introspector.Introspect("active")
+
+		require.NoError(t, err)
+		assert.Equal(t, oauth.IntrospectionResponse{
+			Active:    true,
+			Scope:     "foo:read",
+			ClientID:  "client_web",
+			Sub:       "user-1",
+			Exp:       expires.Unix(),
+			TokenType: "Bearer",
+		}, response)
+	})
+
+	t.Run("Cache Hit", func(t *testing.T) {
+		reset()
+
+		first, err := introspector.Introspect("active")
+		require.NoError(t, err)
+
+		// Revoked tokens stay active until their cached response expires.
+		delete(store.tokens, "active")
+		second, err := introspector.Introspect("active")
+		require.NoError(t, err)
+
+		assert.Equal(t, first, second)
+		assert.Equal(t, 1, store.resolved)
+		assert.Equal(t, 10*time.Second, server.TTL(introspectionKey("active")))
+
+		server.FastForward(11 * time.Second)
+		third, err := introspector.Introspect("active")
+		require.NoError(t, err)
+		assert.False(t, third.Active)
+		assert.Equal(t, 2, store.resolved)
+	})
+
+	t.Run("Cache Failure", func(t *testing.T) {
+		reset()
+		server.SetError("LOADING")
+		defer server.SetError("")
+
+		for i := 0; i < 2; i++ {
+			response, err := introspector.Introspect("active")
+			require.NoError(t, err)
+			assert.True(t, response.Active)
+		}
+
+		assert.Equal(t, 2, store.resolved)
+	})
+
+	t.Run("Malformed Cached Response", func(t *testing.T) {
+		reset()
+		require.NoError(t, server.Set(introspectionKey("active"), "{"))
+
+		response, err := introspector.Introspect("active")
+
+		require.NoError(t, err)
+		assert.True(t, response.Active)
+		assert.Equal(t, 1, store.resolved)
+	})
+
+	t.Run("TTL Capped At Expiry", func(t *testing.T) {
+		reset()
+		soon := store.tokens["active"]
+		soon.AccessToken = "soon"
+		soon.Expires = time.Now().Add(3 * time.Second)
+		store.tokens["soon"] = soon
+
+		response, err := introspector.Introspect("soon")
+
+		require.NoError(t, err)
+		assert.True(t, response.Active)
+		ttl := server.TTL(introspectionKey("soon"))
+		assert.True(t, ttl > 0 && ttl <= 3*time.Second, "ttl %s", ttl)
+	})
+
+	t.Run("Inactive Tokens", func(t *testing.T) {
+		reset()
+		expired := store.tokens["active"]
+		expired.AccessToken = "expired"
+		expired.Expires = time.Now().Add(-time.Second)
+		store.tokens["expired"] = expired
+
+		for _, accessToken := range []string{"expired", "unknown", ""} {
+			response, err := introspector.Introspect(accessToken)
+			require.NoError(t, err)
+			assert.Equal(t, oauth.IntrospectionResponse{}, response, accessToken)
+
+			response, err = introspector.Introspect(accessToken)
+			require.NoError(t, err)
+			assert.False(t, response.Active, accessToken)
+			assert.Equal(t, 10*time.Second, server.TTL(introspectionKey(accessToken)), accessToken)
+		}
+
+		// Inactive responses are cached too.
+		assert.Equal(t, 3, store.resolved)
+	})
+
+	t.Run("Store Failure", func(t *testing.T) {
+		reset()
+		store.err = errors.New("connection refused")
+
+		_, err := introspector.Introspect("active")
+
+		assert.EqualError(t, err, "connection refused")
+		assert.False(t, server.Exists(introspectionKey("active")))
+	})
+}
@@ -0,0 +1,289 @@
+package oauth
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/evermos/boilerplate-go/shared/audit"
+	"github.com/go-redis/redis"
+	"github.com/gofrs/uuid"
+	"github.com/guregu/null"
+	"github.com/rs/zerolog/log"
+)
+
+// maxDelayShift bounds the exponent of the progressive delay.
+const maxDelayShift = 20
+
+// LoginGuardConfig configures the LoginGuard. Failures are counted over a
+// sliding Window. After FreeAttempts failures of a username, every further
+// attempt must wait a delay starting at BaseDelay and doubling with each
+// failure, up to MaxDelay. Reaching UsernameLockout failures of a username,
+// or IPLockout failures from an IP address, locks it out for Lockout.
+type LoginGuardConfig struct {
+	Window          time.Duration
+	FreeAttempts    int64
+	BaseDelay       time.Duration
+	MaxDelay        time.Duration
+	UsernameLockout int64
+	IPLockout       int64
+	Lockout         time.Duration
+	// TrustForwardedFor takes the client IP address from the last entry of
+	// X-Forwarded-For, which must then be set by a trusted proxy.
+	TrustForwardedFor bool
+}
+
+// LoginThrottledError is returned for login attempts that must wait, either
+// for a progressive delay or for a lockout to end.
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottledError) Error() string {
+	return ErrorTooManyLoginAttempts
+}
+
+// UserIdentifier resolves the ID of the user logging in with a username,
+// email or telephone.
+type UserIdentifier interface {
+	IdentifyUser(login string) (userID string, err error)
+}
+
+// LoginGuard protects the password grant against brute-force attacks by
+// counting failed logins per user and per IP address in Redis. Failures are
+// counted per user ID, whichever of the user's username, email or telephone
+// is logged in with; logins of unknown users are counted per login. Redis
+// failures are logged and let logins through, so that an outage of Redis does
+// not lock everyone out.
+type LoginGuard struct {
+	Redis  *redis.Client
+	Audit  audit.Recorder
+	Users  UserIdentifier
+	Config LoginGuardConfig
+}
+
+type loginSubject struct {
+	kind    string
+	value   string
+	lockout int64
+}
+
+// Check checks whether a login attempt may proceed.
+func (g *LoginGuard) Check(username string, ip string) error {
+	subjects := g.subjects(username, ip)
+
+	for _, subject := range subjects {
+		ttl, err := g.Redis.PTTL(lockoutKey(subject)).Result()
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed checking login lockout.")
+			return nil
+		}
+
+		if ttl > 0 {
+			return &LoginThrottledError{RetryAfter: ttl}
+		}
+	}
+
+	failures, last, err := g.failures(subjects[0])
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed checking failed logins.")
+		return nil
+	}
+
+	if wait := time.Until(last.Add(g.delay(failures))); wait > 0 {
+		return &LoginThrottledError{RetryAfter: wait}
+	}
+
+	return nil
+}
+
+// Record records the outcome of a login attempt. Invalid credentials count as
+// failures, and may lock the username or the IP address out. A successful
+// login clears the failures of the username, but not those of the IP
+// address. Other errors are not counted.
+func (g *LoginGuard) Record(username string, ip string, loginErr error) {
+	subjects := g.subjects(username, ip)
+
+	if loginErr == nil {
+		err := g.Redis.Del(failuresKey(subjects[0])).Err()
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed clearing failed logins.")
+		}
+		return
+	}
+
+	if loginErr.Error() != ErrorInvalidPassword && loginErr.Error() != ErrorUserNotFound {
+		return
+	}
+
+	for _, subject := range subjects {
+		failures, err := g.recordFailure(subject)
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed recording failed login.")
+			continue
+		}
+
+		if subject.lockout > 0 && failures >= subject.lockout {
+			g.lock(subject, ip, failures)
+		}
+	}
+}
+
+// Unlock lifts the lockout and clears the failures of a user, by any of their
+// username, email or telephone, or of an IP address, on behalf of an admin.
+func (g *LoginGuard) Unlock(username string, ip string, actorID string) error {
+	subjects := make([]loginSubject, 0)
+	for _, subject := range g.subjects(username, ip) {
+		if subject.value != "" {
+			subjects = append(subjects, subject)
+		}
+	}
+
+	for _, subject := range subjects {
+		err := g.Redis.Del(lockoutKey(subject), failuresKey(subject)).Err()
+		if err != nil {
+			return err
+		}
+
+		g.audit(audit.Event{
+			Type:    audit.TypeLoginUnlock,
+			Subject: subject.kind + ":" + subject.value,
+			Actor:   null.StringFrom(actorID),
+		})
+	}
+
+	return nil
+}
+
+// subjects returns the user and the IP address subjects of a login, in that
+// order. Logins of known users are keyed on the user ID, and others on the
+// login, compared case insensitively.
+func (g *LoginGuard) subjects(username string, ip string) []loginSubject {
+	user := loginSubject{kind: "username", value: strings.ToLower(strings.TrimSpace(username)), lockout: g.Config.UsernameLockout}
+	if user.value != "" && g.Users != nil {
+		userID, err := g.Users.IdentifyUser(strings.TrimSpace(username))
+		switch {
+		case err == nil:
+			user.kind, user.value = "user", userID
+		case err.Error() != ErrorUserNotFound:
+			log.Warn().Err(err).Msg("Failed identifying the user logging in.")
+		}
+	}
+
+	return []loginSubject{
+		user,
+		{kind: "ip", value: ip, lockout: g.Config.IPLockout},
+	}
+}
+
+// failures counts the failures of a subject within the window, and returns
+// when the last one happened.
+func (g *LoginGuard) failures(subject loginSubject) (count int64, last time.Time, err error) {
+	key := failuresKey(subject)
+
+	pipe := g.Redis.TxPipeline()
+	pipe.ZRemRangeByScore(key, "-inf", windowStart(g.Config.Window))
+	card := pipe.ZCard(key)
+	latest := pipe.ZRevRangeWithScores(key, 0, 0)
+
+	_, err = pipe.Exec()
+	if err != nil {
+		return
+	}
+
+	if len(latest.Val()) > 0 {
+		last = time.Unix(0, int64(latest.Val()[0].Score)*int64(time.Millisecond))
+	}
+
+	return card.Val(), last, nil
+}
+
+func (g *LoginGuard) recordFailure(subject loginSubject) (count int64, err error) {
+	member, err := uuid.NewV4()
+	if err != nil {
+		return
+	}
+
+	key := failuresKey(subject)
+
+	pipe := g.Redis.TxPipeline()
+	pipe.ZRemRangeByScore(key, "-inf", windowStart(g.Config.Window))
+	pipe.ZAdd(key, redis.Z{Score: float64(time.Now().UnixNano() / int64(time.Millisecond)), Member: member.String()})
+	card := pipe.ZCard(key)
+	pipe.Expire(key, g.Config.Window)
+
+	_, err = pipe.Exec()
+	if err != nil {
+		return
+	}
+
+	return card.Val(), nil
+}
+
+// lock locks a subject out. Its failures are cleared, so that it starts over
+// once the lockout ends. Only the first lockout is audited when concurrent
+// failures reach the threshold together.
+func (g *LoginGuard) lock(subject loginSubject, ip string, failures int64) {
+	locked, err := g.Redis.SetNX(lockoutKey(subject), failures, g.Config.Lockout).Result()
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed locking out login.")
+		return
+	}
+
+	if !locked {
+		return
+	}
+
+	err = g.Redis.Del(failuresKey(subject)).Err()
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed clearing failed logins.")
+	}
+
+	g.audit(audit.Event{
+		Type:    audit.TypeLoginLockout,
+		Subject: subject.kind + ":" + subject.value,
+		IP:      null.NewString(ip, ip != ""),
+		Details: null.StringFrom(fmt.Sprintf("%d failed logins within %s, locked out for %s", failures, g.Config.Window, g.Config.Lockout)),
+	})
+}
+
+// delay returns how long to wait after the last failure, given the number of
+// failures within the window.
+func (g *LoginGuard) delay(failures int64) time.Duration {
+	if failures < g.Config.FreeAttempts || failures == 0 {
+		return 0
+	}
+
+	shift := failures - g.Config.FreeAttempts
+	if shift > maxDelayShift {
+		shift = maxDelayShift
+	}
+
+	delay := g.Config.BaseDelay << uint(shift)
+	if delay > g.Config.MaxDelay {
+		delay = g.Config.MaxDelay
+	}
+
+	return delay
+}
+
+func (g *LoginGuard) audit(event audit.Event) {
+	err := g.Audit.Record(event)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed recording audit event.")
+	}
+}
+
+func failuresKey(subject loginSubject) string {
+	return "login_failures:" + subject.kind + ":" + subject.value
+}
+
+func lockoutKey(subject loginSubject) string {
+	return "login_lockout:" + subject.kind + ":" + subject.value
+}
+
+// windowStart returns the score of the oldest failure within the window.
+func windowStart(window time.Duration) string {
+	return "(" + strconv.FormatInt(time.Now().Add(-window).UnixNano()/int64(time.Millisecond), 10)
+}
@@ -0,0 +1,202 @@
+package oauth
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/evermos/boilerplate-go/shared/audit"
+	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// userIdentifier identifies the users of a map from each of their logins to
+// their ID.
+type userIdentifier map[string]string
+
+func (u userIdentifier) IdentifyUser(login string) (string, error) {
+	userID, ok := u[login]
+	if !ok {
+		return "", errors.New(ErrorUserNotFound)
+	}
+
+	return userID, nil
+}
+
+type recorder struct {
+	events []audit.Event
+}
+
+func (r *recorder) Record(event audit.Event) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+func TestLoginGuardDelay(t *testing.T) {
+	guard := LoginGuard{Config: LoginGuardConfig{
+		FreeAttempts: 3,
+		BaseDelay:    time.Second,
+		MaxDelay:     30 * time.Second,
+	}}
+
+	for failures, delay := range map[int64]time.Duration{
+		0:  0,
+		1:  0,
+		2:  0,
+		3:  time.Second,
+		4:  2 * time.Second,
+		5:  4 * time.Second,
+		7:  16 * time.Second,
+		8:  30 * time.Second,
+		64: 30 * time.Second,
+	} {
+		assert.Equal(t, delay, guard.delay(failures), "%d failures", failures)
+	}
+}
+
+func TestLoginGuard(t *testing.T) {
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+	defer server.Close()
+
+	audits := &recorder{}
+	guard := &LoginGuard{
+		Redis: redis.NewClient(&redis.Options{Addr: server.Addr()}),
+		Audit: audits,
+		Users: userIdentifier{
+			"john":          "user-1",
+			"john@mail.com": "user-1",
+			"08123456789":   "user-1",
+		},
+		Config: LoginGuardConfig{
+			Window:          15 * time.Minute,
+			FreeAttempts:    2,
+			BaseDelay:       time.Second,
+			MaxDelay:        30 * time.Second,
+			UsernameLockout: 4,
+			IPLockout:       100,
+			Lockout:         15 * time.Minute,
+		},
+	}
+	invalidPassword := errors.New(ErrorInvalidPassword)
+
+	throttled := func(t *testing.T, err error) time.Duration {
+		require.Error(t, err)
+		throttledErr, ok := err.(*LoginThrottledError)
+		require.True(t, ok)
+		return throttledErr.RetryAfter
+	}
+
+	t.Run("Free Attempts", func(t *testing.T) {
+		server.FlushAll()
+
+		guard.Record("john", "10.0.0.1", invalidPassword)
+		assert.NoError(t, guard.Check("john", "10.0.0.1"))
+
+		guard.Record("john", "10.0.0.1", invalidPassword)
+		assert.InDelta(t, time.Second.Seconds(), throttled(t, guard.Check("john", "10.0.0.1")).Seconds(), 0.1)
+	})
+
+	t.Run("Counts Failures Per User Across Logins", func(t *testing.T) {
+		server.FlushAll()
+
+		guard.Record("john", "10.0.0.1", invalidPassword)
+		guard.Record("john@mail.com", "10.0.0.2", invalidPassword)
+		guard.Record("08123456789", "10.0.0.3", invalidPassword)
+
+		members, err := server.ZMembers("login_failures:user:user-1")
+		require.NoError(t, err)
+		assert.Len(t, members, 3)
+		assert.False(t, server.Exists("login_failures:username:john"))
+
+		throttled(t, guard.Check("john@mail.com", "10.0.0.4"))
+	})
+
+	t.Run("Locks The User Out", func(t *testing.T) {
+		server.FlushAll()
+		audits.events = nil
+
+		for _, login := range []string{"john", "john@mail.com", "08123456789", "john"} {
+			guard.Record(login, "10.0.0.1", invalidPassword)
+		}
+
+		for _, login := range []string{"john", "john@mail.com", "08123456789"} {
+			retryAfter := throttled(t, guard.Check(login, "10.0.0.9"))
+			assert.InDelta(t, (15 * time.Minute).Seconds(), retryAfter.Seconds(), 1)
+		}
+
+		require.Len(t, audits.events, 1)
+		assert.Equal(t, audit.TypeLoginLockout, audits.events[0].Type)
+		assert.Equal(t, "user:user-1", audits.events[0].Subject)
+	})
+
+	t.Run("Unknown Users Are Counted Per Login", func(t *testing.T) {
+		server.FlushAll()
+
+		guard.Record(" Jane ", "10.0.0.1", errors.New(ErrorUserNotFound))
+
+		assert.True(t, server.Exists("login_failures:username:jane"))
+	})
+
+	t.Run("Other Errors Are Not Counted", func(t *testing.T) {
+		server.FlushAll()
+
+		guard.Record("john", "10.0.0.1", errors.New(ErrorInvalidClient))
+
+		assert.Empty(t, server.Keys())
+	})
+
+	t.Run("Success Clears The User Failures", func(t *testing.T) {
+		server.FlushAll()
+
+		guard.Record("john", "10.0.0.1", invalidPassword)
+		guard.Record("john", "10.0.0.1", invalidPassword)
+		guard.Record("08123456789", "10.0.0.1", nil)
+
+		assert.False(t, server.Exists("login_failures:user:user-1"))
+		assert.True(t, server.Exists("login_failures:ip:10.0.0.1"))
+		assert.NoError(t, guard.Check("john", "10.0.0.2"))
+	})
+
+	t.Run("Locks The IP Address Out", func(t *testing.T) {
+		server.FlushAll()
+		guard.Config.IPLockout = 3
+		defer func() { guard.Config.IPLockout = 100 }()
+
+		for _, login := range []string{"alice", "bob", "carol"} {
+			guard.Record(login, "10.0.0.1", errors.New(ErrorUserNotFound))
+		}
+
+		throttled(t, guard.Check("dave", "10.0.0.1"))
+		assert.NoError(t, guard.Check("dave", "10.0.0.2"))
+	})
+
+	t.Run("Unlock", func(t *testing.T) {
+		server.FlushAll()
+		audits.events = nil
+
+		for i := 0; i < 4; i++ {
+			guard.Record("john", "10.0.0.1", invalidPassword)
+		}
+		throttled(t, guard.Check("john", "10.0.0.1"))
+
+		require.NoError(t, guard.Unlock("08123456789", "", "admin-1"))
+
+		assert.NoError(t, guard.Check("john", "10.0.0.1"))
+		require.Len(t, audits.events, 2)
+		assert.Equal(t, audit.TypeLoginUnlock, audits.events[1].Type)
+		assert.Equal(t, "user:user-1", audits.events[1].Subject)
+		assert.Equal(t, "admin-1", audits.events[1].Actor.String)
+	})
+
+	t.Run("Lets Logins Through When Redis Fails", func(t *testing.T) {
+		server.FlushAll()
+		server.SetError("LOADING")
+		defer server.SetError("")
+
+		guard.Record("john", "10.0.0.1", invalidPassword)
+		assert.NoError(t, guard.Check("john", "10.0.0.1"))
+	})
+}
@@ -1,11 +1,11 @@
 package oauth
 
 import (
-	"golang.org/x/crypto/bcrypt"
-	"strconv"
+	"strings"
 	"time"
 
 	"github.com/guregu/null"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type TokenType string
@@ -14,12 +14,6 @@ const (
 	Bearer TokenType = "Bearer"
 )
 
-var scope = struct {
-	User string
-}{
-	"user",
-}
-
 // Credential is
 type Credential struct {
 	GrantType    GrantType
@@ -27,6 +21,15 @@ type Credential struct {
 	ClientSecret string
 	Username     string
 	Password     string
+	RefreshToken string
+	// Scope is the requested scope, the client's whole scope when empty. Only
+	// client credentials are granted service scopes.
+	Scope string
+	// Code, RedirectURI and CodeVerifier are the parameters of the
+	// authorization code grant.
+	Code         string
+	RedirectURI  string
+	CodeVerifier string
 }
 
 type OauthAccessToken struct {
@@ -35,15 +38,19 @@ type OauthAccessToken struct {
 	UserID      null.String `json:"userId" db:"user_id"`
 	Expires     time.Time   `json:"expires" db:"expires"`
 	Scope       null.String `json:"scope" db:"scope"`
+	FamilyID    null.String `json:"-" db:"family_id"`
+	// RefreshToken is the plain refresh token issued along with the access
+	// token. It is never stored.
+	RefreshToken string `json:"-" db:"-"`
 }
 
-func (o *OauthAccessToken) Generate(accessToken string, clientID string, userID *int, withScope bool, config Config) OauthAccessToken {
+func (o *OauthAccessToken) Generate(accessToken string, clientID string, userID *string, scope Scope, config Config) OauthAccessToken {
 	if userID != nil {
-		o.UserID = null.StringFrom(strconv.Itoa(*userID))
+		o.UserID = null.StringFrom(*userID)
 	}
 
-	if withScope {
-		o.Scope = null.StringFrom(scope.User)
+	if len(scope) > 0 {
+		o.Scope = null.StringFrom(scope.String())
 	}
 
 	o.ClientID = clientID
@@ -63,49 +70,73 @@ func (o *OauthAccessToken) VerifyExpireIn() bool {
 }
 
 func (o *OauthAccessToken) VerifyUserLoggedIn() bool {
-	if o.UserID.Valid && !o.Scope.Valid {
-		return true
-	}
-	return false
+	return o.UserID.Valid
+}
+
+// Scopes returns the scope the access token was issued with.
+func (o *OauthAccessToken) Scopes() Scope {
+	return ParseScope(o.Scope.String)
 }
 
 func (o *OauthAccessToken) toCreateTokenResponse() *TokenResponse {
+	expiresIn := int64(time.Until(o.Expires).Round(time.Second).Seconds())
+	if expiresIn < 0 {
+		expiresIn = 0
+	}
+
 	return &TokenResponse{
-		AccessToken: o.AccessToken,
-		ExpiresIn:   o.Expires,
-		TokenType:   string(Bearer),
-		Scope:       scope.User,
+		AccessToken:  o.AccessToken,
+		ExpiresIn:    expiresIn,
+		TokenType:    string(Bearer),
+		Scope:        o.Scope.String,
+		RefreshToken: o.RefreshToken,
 	}
 }
 
 type OauthClient struct {
-	ClientID     string `json:"clientId" db:"client_id"`
-	ClientSecret string `json:"clientSecret" db:"client_secret"`
-	RedirectURI  string `json:"redirectUri" db:"redirect_uri"`
-	GrantTypes   string `json:"grantTypes" db:"grant_types"`
+	ClientID     string      `json:"clientId" db:"client_id"`
+	ClientSecret string      `json:"clientSecret" db:"client_secret"`
+	RedirectURI  null.String `json:"redirectUri" db:"redirect_uri"`
+	GrantTypes   string      `json:"grantTypes" db:"grant_types"`
+	Scope        null.String `json:"scope" db:"scope"`
+	DisabledAt   null.Time   `json:"disabledAt" db:"disabled_at"`
 }
 
-func (o *OauthClient) VerifyClient(credential Credential) bool {
-	if o.ClientID != credential.ClientID {
-		return false
+// AllowsGrant checks whether the client may use the grant type.
+func (o *OauthClient) AllowsGrant(grantType GrantType) bool {
+	for _, g := range strings.Fields(o.GrantTypes) {
+		if GrantType(g) == grantType {
+			return true
+		}
 	}
 
-	if o.ClientSecret != credential.ClientSecret {
+	return false
+}
+
+// VerifyClient checks the client credential against the bcrypt hash of the
+// client secret. bcrypt compares hashes in constant time. Disabled clients
+// never verify.
+func (o *OauthClient) VerifyClient(credential Credential) bool {
+	if o.ClientID != credential.ClientID || o.DisabledAt.Valid {
 		return false
 	}
 
-	return true
+	err := bcrypt.CompareHashAndPassword([]byte(o.ClientSecret), []byte(credential.ClientSecret))
+	return err == nil
 }
 
+// TokenResponse is the access token response of RFC 6749 section 5.1.
+// ExpiresIn is the lifetime of the access token in seconds.
 type TokenResponse struct {
-	AccessToken string
-	ExpiresIn   time.Time
-	TokenType   string
-	Scope       string
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	Scope        string `json:"scope,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
 }
 
 type User struct {
-	ID       int    `json:"id" db:"id"`
+	ID       string `json:"id" db:"id"`
 	Username string `json:"username" db:"username"`
 	Password string `json:"password" db:"password"`
 }
@@ -5,12 +5,12 @@ import (
 )
 
 type PasswordAuth struct {
-	tokenStore TokenStore
-	config     Config
+	tokenRepository TokenRepository
+	config          Config
 }
 
 func (c *PasswordAuth) Create(credential Credential) (oauthAccessToken OauthAccessToken, err error) {
-	client, err := c.tokenStore.resolveClientByClientID(credential.ClientID)
+	client, err := c.tokenRepository.resolveClientByClientID(credential.ClientID)
 	if err != nil {
 		return
 	}
@@ -20,7 +20,17 @@ func (c *PasswordAuth) Create(credential Credential) (oauthAccessToken OauthAcce
 		return
 	}
 
-	user, err := c.tokenStore.resolveByTelephoneOrEmail(credential.Username)
+	if !client.AllowsGrant(Password) {
+		err = errors.New(ErrorUnauthorizedGrant)
+		return
+	}
+
+	scope, err := client.grantedScope(credential.Scope, Password)
+	if err != nil {
+		return
+	}
+
+	user, err := c.tokenRepository.resolveByTelephoneOrEmail(credential.Username)
 	if err != nil {
 		return
 	}
@@ -36,9 +46,25 @@ func (c *PasswordAuth) Create(credential Credential) (oauthAccessToken OauthAcce
 		return
 	}
 
-	oauthAccessToken = new(OauthAccessToken).Generate(accessToken, credential.ClientID, &user.ID, false, c.config)
+	oauthAccessToken = new(OauthAccessToken).Generate(accessToken, credential.ClientID, &user.ID, scope, c.config)
+
+	if !client.AllowsGrant(RefreshToken) {
+		err = c.tokenRepository.createAccessToken(oauthAccessToken)
+		return
+	}
+
+	familyID, err := newFamilyID()
+	if err != nil {
+		err = errors.New(ErrorGenerateAccessToken)
+		return
+	}
+
+	oauthAccessToken, refreshToken, err := withRefreshToken(oauthAccessToken, familyID, c.config)
+	if err != nil {
+		return
+	}
 
-	err = c.tokenStore.createAccessToken(oauthAccessToken)
+	err = c.tokenRepository.createTokenPair(oauthAccessToken, refreshToken)
 	if err != nil {
 		return
 	}
@@ -0,0 +1,123 @@
+package oauth
+
+import (
+	"time"
+
+	"github.com/evermos/boilerplate-go/configs"
+	"github.com/evermos/boilerplate-go/infras"
+	"github.com/evermos/boilerplate-go/shared/audit"
+	"github.com/go-redis/redis"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// defaultExpiration is the access token lifetime in seconds when none is configured.
+	defaultExpiration = 3600
+	// defaultRefreshExpiration is the refresh token lifetime in seconds when none is configured.
+	defaultRefreshExpiration = 30 * 24 * 3600
+	// defaultAuthorizationCodeExpiration is the authorization code lifetime in seconds when none is configured.
+	defaultAuthorizationCodeExpiration = 60
+	// defaultTokenPurgeInterval is how often expired access tokens are purged from MySQL.
+	defaultTokenPurgeInterval = 10 * time.Minute
+
+	defaultLoginWindow          = 15 * time.Minute
+	defaultLoginFreeAttempts    = 3
+	defaultLoginBaseDelay       = time.Second
+	defaultLoginMaxDelay        = 30 * time.Second
+	defaultLoginUsernameLockout = 10
+	defaultLoginIPLockout       = 100
+	defaultLoginLockout         = 15 * time.Minute
+)
+
+// ProvideTokenStore is the provider for the TokenStore selected by the
+// OAuth.TokenStore configuration, MySQL by default. The MySQL store purges
+// expired access tokens in the background.
+func ProvideTokenStore(db *infras.MySQLConn, redis *redis.Client, config *configs.Config) TokenStore {
+	switch config.OAuth.TokenStore {
+	case TokenStoreRedisDriver:
+		return NewTokenStoreRedis(redis)
+	case "", TokenStoreMySQLDriver:
+	default:
+		log.Warn().Str("driver", config.OAuth.TokenStore).Msg("Unknown token store, falling back to MySQL")
+	}
+
+	interval := time.Duration(config.OAuth.TokenPurgeIntervalSeconds) * time.Second
+	if interval <= 0 {
+		interval = defaultTokenPurgeInterval
+	}
+
+	store := NewTokenStoreMySQL(db.Read, db.Write)
+	store.StartPurge(interval)
+	return store
+}
+
+// ProvideToken is the provider for Token, configured from the OAuth configuration.
+func ProvideToken(db *infras.MySQLConn, tokens TokenStore, config *configs.Config) *Token {
+	expiration := config.OAuth.ExpirationSeconds
+	if expiration <= 0 {
+		expiration = defaultExpiration
+	}
+
+	refreshExpiration := config.OAuth.RefreshExpirationSeconds
+	if refreshExpiration <= 0 {
+		refreshExpiration = defaultRefreshExpiration
+	}
+
+	authorizationCodeExpiration := config.OAuth.AuthorizationCodeExpirationSeconds
+	if authorizationCodeExpiration <= 0 {
+		authorizationCodeExpiration = defaultAuthorizationCodeExpiration
+	}
+
+	return New(db.Write, tokens, Config{
+		Expiration:                  expiration,
+		RefreshExpiration:           refreshExpiration,
+		AuthorizationCodeExpiration: authorizationCodeExpiration,
+		ClientScope:                 config.OAuth.ClientScope,
+	})
+}
+
+// ProvideLoginGuard is the provider for LoginGuard, configured from the
+// OAuth.Login configuration.
+func ProvideLoginGuard(redis *redis.Client, recorder audit.Recorder, token *Token, config *configs.Config) *LoginGuard {
+	login := config.OAuth.Login
+
+	guardConfig := LoginGuardConfig{
+		Window:            time.Duration(login.WindowSeconds) * time.Second,
+		FreeAttempts:      login.FreeAttempts,
+		BaseDelay:         time.Duration(login.BaseDelayMilliseconds) * time.Millisecond,
+		MaxDelay:          time.Duration(login.MaxDelaySeconds) * time.Second,
+		UsernameLockout:   login.UsernameLockout,
+		IPLockout:         login.IPLockout,
+		Lockout:           time.Duration(login.LockoutSeconds) * time.Second,
+		TrustForwardedFor: login.TrustForwardedFor,
+	}
+
+	if guardConfig.Window <= 0 {
+		guardConfig.Window = defaultLoginWindow
+	}
+	if guardConfig.FreeAttempts <= 0 {
+		guardConfig.FreeAttempts = defaultLoginFreeAttempts
+	}
+	if guardConfig.BaseDelay <= 0 {
+		guardConfig.BaseDelay = defaultLoginBaseDelay
+	}
+	if guardConfig.MaxDelay <= 0 {
+		guardConfig.MaxDelay = defaultLoginMaxDelay
+	}
+	if guardConfig.UsernameLockout <= 0 {
+		guardConfig.UsernameLockout = defaultLoginUsernameLockout
+	}
+	if guardConfig.IPLockout <= 0 {
+		guardConfig.IPLockout = defaultLoginIPLockout
+	}
+	if guardConfig.Lockout <= 0 {
+		guardConfig.Lockout = defaultLoginLockout
+	}
+
+	return &LoginGuard{
+		Redis:  redis,
+		Audit:  recorder,
+		Users:  token,
+		Config: guardConfig,
+	}
+}
@@ -0,0 +1,202 @@
+package oauth
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/guregu/null"
+	"github.com/rs/zerolog/log"
+)
+
+// OauthRefreshToken is a stored refresh token. Only the hash of the token is
+// stored. Every refresh token belongs to a family, which starts at a password
+// grant and continues through each rotation.
+type OauthRefreshToken struct {
+	TokenHash string      `db:"token_hash"`
+	FamilyID  string      `db:"family_id"`
+	ClientID  string      `db:"client_id"`
+	UserID    null.String `db:"user_id"`
+	Scope     null.String `db:"scope"`
+	Expires   time.Time   `db:"expires"`
+	UsedAt    null.Time   `db:"used_at"`
+	RevokedAt null.Time   `db:"revoked_at"`
+}
+
+// hashToken hashes a token for storage and lookup.
+func hashToken(token string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(token)))
+}
+
+// withRefreshToken issues a refresh token of the family along with an access
+// token. The plain refresh token is set on the returned access token.
+func withRefreshToken(accessToken OauthAccessToken, familyID string, config Config) (OauthAccessToken, OauthRefreshToken, error) {
+	plain, err := generateAccessToken()
+	if err != nil {
+		return accessToken, OauthRefreshToken{}, errors.New(ErrorGenerateAccessToken)
+	}
+
+	accessToken.FamilyID = null.StringFrom(familyID)
+	accessToken.RefreshToken = plain
+
+	refreshToken := OauthRefreshToken{
+		TokenHash: hashToken(plain),
+		FamilyID:  familyID,
+		ClientID:  accessToken.ClientID,
+		UserID:    accessToken.UserID,
+		Scope:     accessToken.Scope,
+		Expires:   time.Now().Add(time.Second * time.Duration(config.RefreshExpiration)),
+	}
+
+	return accessToken, refreshToken, nil
+}
+
+// newFamilyID creates the ID of a new refresh token family.
+func newFamilyID() (string, error) {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return "", err
+	}
+
+	return id.String(), nil
+}
+
+// RefreshTokenAuth exchanges a refresh token for a new access token. Refresh
+// tokens are rotated on every use; presenting an already used refresh token
+// revokes its whole family, as it means the token was leaked.
+type RefreshTokenAuth struct {
+	tokenRepository TokenRepository
+	config          Config
+}
+
+func (c *RefreshTokenAuth) Create(credential Credential) (oauthAccessToken OauthAccessToken, err error) {
+	client, err := c.tokenRepository.resolveClientByClientID(credential.ClientID)
+	if err != nil {
+		return
+	}
+
+	if !client.VerifyClient(credential) {
+		err = errors.New(ErrorInvalidClient)
+		return
+	}
+
+	if !client.AllowsGrant(RefreshToken) {
+		err = errors.New(ErrorUnauthorizedGrant)
+		return
+	}
+
+	refreshToken, err := c.tokenRepository.resolveRefreshTokenByHash(hashToken(credential.RefreshToken))
+	if err != nil {
+		return
+	}
+
+	if refreshToken.ClientID != credential.ClientID || refreshToken.RevokedAt.Valid || time.Now().After(refreshToken.Expires) {
+		err = errors.New(ErrorInvalidRefreshToken)
+		return
+	}
+
+	if refreshToken.UsedAt.Valid {
+		err = c.revokeReusedFamily(refreshToken)
+		return
+	}
+
+	// The access token may be issued with a narrower scope than the refresh
+	// token, which keeps its original scope.
+	scope := ParseScope(refreshToken.Scope.String)
+	if requested := ParseScope(credential.Scope); len(requested) > 0 {
+		if !scope.Contains(requested) {
+			err = errors.New(ErrorInvalidScope)
+			return
+		}
+		scope = requested
+	}
+
+	accessToken, err := generateAccessToken()
+	if err != nil {
+		err = errors.New(ErrorGenerateAccessToken)
+		return
+	}
+
+	oauthAccessToken = new(OauthAccessToken).Generate(accessToken, refreshToken.ClientID, nil, scope, c.config)
+	oauthAccessToken.UserID = refreshToken.UserID
+
+	oauthAccessToken, next, err := withRefreshToken(oauthAccessToken, refreshToken.FamilyID, c.config)
+	if err != nil {
+		return
+	}
+	next.Scope = refreshToken.Scope
+
+	rotated, err := c.tokenRepository.rotateRefreshToken(refreshToken, oauthAccessToken, next)
+	if err != nil {
+		return
+	}
+
+	if !rotated {
+		// A concurrent request used the same refresh token first.
+		err = c.revokeReusedFamily(refreshToken)
+		return
+	}
+
+	return
+}
+
+func (c *RefreshTokenAuth) revokeReusedFamily(refreshToken OauthRefreshToken) error {
+	log.Warn().
+		Str("clientId", refreshToken.ClientID).
+		Str("familyId", refreshToken.FamilyID).
+		Msg("Refresh token reused, revoking its token family.")
+
+	err := c.tokenRepository.revokeFamily(refreshToken.FamilyID)
+	if err != nil {
+		return err
+	}
+
+	return errors.New(ErrorInvalidRefreshToken)
+}
+
+// TokenTypeHint values of RFC 7009.
+const (
+	TokenTypeHintAccessToken  = "access_token"
+	TokenTypeHintRefreshToken = "refresh_token"
+)
+
+// revoke revokes an access or refresh token issued to the client, looking it
+// up as the hinted type first. Revoking a refresh token revokes its whole
+// family. Unknown tokens and tokens of other clients are ignored.
+func (a *TokenRepository) revoke(clientID string, token string, tokenTypeHint string) error {
+	revokeAccess := func() (bool, error) {
+		return a.revokeAccessToken(token, clientID)
+	}
+
+	revokeRefresh := func() (bool, error) {
+		refreshToken, err := a.resolveRefreshTokenByHash(hashToken(token))
+		if err != nil {
+			if err.Error() == ErrorInvalidRefreshToken {
+				return false, nil
+			}
+			return false, err
+		}
+
+		if refreshToken.ClientID != clientID {
+			return false, nil
+		}
+
+		return true, a.revokeFamily(refreshToken.FamilyID)
+	}
+
+	attempts := []func() (bool, error){revokeAccess, revokeRefresh}
+	if tokenTypeHint == TokenTypeHintRefreshToken {
+		attempts = []func() (bool, error){revokeRefresh, revokeAccess}
+	}
+
+	for _, attempt := range attempts {
+		revoked, err := attempt()
+		if err != nil || revoked {
+			return err
+		}
+	}
+
+	return nil
+}
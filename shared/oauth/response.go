@@ -0,0 +1,57 @@
+package oauth
+
+import (
+	"net/http"
+)
+
+// Error codes of RFC 6749 section 5.2.
+const (
+	ErrorCodeInvalidRequest       = "invalid_request"
+	ErrorCodeInvalidClient        = "invalid_client"
+	ErrorCodeInvalidGrant         = "invalid_grant"
+	ErrorCodeUnauthorizedClient   = "unauthorized_client"
+	ErrorCodeUnsupportedGrantType = "unsupported_grant_type"
+	ErrorCodeServerError          = "server_error"
+	// Error codes of the authorization endpoint, RFC 6749 section 4.1.2.1.
+	ErrorCodeAccessDenied            = "access_denied"
+	ErrorCodeInvalidScope            = "invalid_scope"
+	ErrorCodeUnsupportedResponseType = "unsupported_response_type"
+)
+
+// ErrorResponse is the error response of RFC 6749 section 5.2.
+type ErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// NewErrorResponse maps an error returned while creating a token to its
+// OAuth2 error response and HTTP status. Unknown users are reported like
+// invalid passwords, so that the response does not reveal which users exist.
+func NewErrorResponse(err error) (status int, response ErrorResponse) {
+	switch err.Error() {
+	case ErrorEmptyCredential:
+		return http.StatusBadRequest, ErrorResponse{ErrorCodeInvalidRequest, err.Error()}
+	case ErrorClientNotFound, ErrorInvalidClient:
+		return http.StatusUnauthorized, ErrorResponse{ErrorCodeInvalidClient, ErrorInvalidClient}
+	case ErrorUserNotFound, ErrorInvalidPassword:
+		return http.StatusBadRequest, ErrorResponse{ErrorCodeInvalidGrant, ErrorInvalidPassword}
+	case ErrorInvalidRefreshToken, ErrorInvalidAuthorizationCode:
+		return http.StatusBadRequest, ErrorResponse{ErrorCodeInvalidGrant, err.Error()}
+	case ErrorInvalidCodeChallenge, ErrorInvalidRedirectURI:
+		return http.StatusBadRequest, ErrorResponse{ErrorCodeInvalidRequest, err.Error()}
+	case ErrorInvalidScope:
+		return http.StatusBadRequest, ErrorResponse{ErrorCodeInvalidScope, err.Error()}
+	case ErrorUnsupportedResponseType:
+		return http.StatusBadRequest, ErrorResponse{ErrorCodeUnsupportedResponseType, err.Error()}
+	case ErrorTooManyLoginAttempts:
+		return http.StatusTooManyRequests, ErrorResponse{ErrorCodeInvalidGrant, err.Error()}
+	case ErrorAccessDenied:
+		return http.StatusForbidden, ErrorResponse{ErrorCodeAccessDenied, err.Error()}
+	case ErrorUnauthorizedGrant:
+		return http.StatusBadRequest, ErrorResponse{ErrorCodeUnauthorizedClient, err.Error()}
+	case ErrorUnsupportedGrant:
+		return http.StatusBadRequest, ErrorResponse{ErrorCodeUnsupportedGrantType, err.Error()}
+	default:
+		return http.StatusInternalServerError, ErrorResponse{ErrorCodeServerError, ErrorGenerateAccessToken}
+	}
+}
@@ -0,0 +1,43 @@
+package oauth_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/evermos/boilerplate-go/shared/oauth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewErrorResponse(t *testing.T) {
+	t.Run("Invalid Client", func(t *testing.T) {
+		status, response := oauth.NewErrorResponse(errors.New(oauth.ErrorClientNotFound))
+
+		assert.Equal(t, http.StatusUnauthorized, status)
+		assert.Equal(t, oauth.ErrorCodeInvalidClient, response.Error)
+	})
+
+	t.Run("Unknown User Looks Like Invalid Password", func(t *testing.T) {
+		_, unknownUser := oauth.NewErrorResponse(errors.New(oauth.ErrorUserNotFound))
+		_, invalidPassword := oauth.NewErrorResponse(errors.New(oauth.ErrorInvalidPassword))
+
+		assert.Equal(t, oauth.ErrorCodeInvalidGrant, unknownUser.Error)
+		assert.Equal(t, invalidPassword, unknownUser)
+	})
+
+	t.Run("Throttled Login", func(t *testing.T) {
+		status, response := oauth.NewErrorResponse(&oauth.LoginThrottledError{RetryAfter: time.Minute})
+
+		assert.Equal(t, http.StatusTooManyRequests, status)
+		assert.Equal(t, oauth.ErrorCodeInvalidGrant, response.Error)
+	})
+
+	t.Run("Unexpected Error", func(t *testing.T) {
+		status, response := oauth.NewErrorResponse(errors.New("connection refused"))
+
+		assert.Equal(t, http.StatusInternalServerError, status)
+		assert.Equal(t, oauth.ErrorCodeServerError, response.Error)
+		assert.NotContains(t, response.ErrorDescription, "connection refused")
+	})
+}
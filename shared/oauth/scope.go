@@ -0,0 +1,98 @@
+package oauth
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// Scopes of the access tokens issued by this service. Clients are issued at
+// most the scopes listed in oauth_clients.scope.
+const (
+	ScopeFooRead         = "foo:read"
+	ScopeFooWrite        = "foo:write"
+	ScopeOAuthIntrospect = "oauth:introspect"
+	ScopeInventoryRead   = "inventory:read"
+	ScopeInventoryWrite  = "inventory:write"
+)
+
+// serviceScopes are the scopes of services acting as themselves, which only
+// client credentials access tokens are granted.
+var serviceScopes = []string{ScopeOAuthIntrospect, ScopeInventoryRead, ScopeInventoryWrite}
+
+// Scope is a set of scope tokens, parsed from the space-delimited form of RFC
+// 6749 section 3.3.
+type Scope map[string]struct{}
+
+// ParseScope parses a space-delimited scope string.
+func ParseScope(scope string) Scope {
+	s := make(Scope)
+	for _, token := range strings.Fields(scope) {
+		s[token] = struct{}{}
+	}
+
+	return s
+}
+
+// Has checks whether the scope includes the token.
+func (s Scope) Has(token string) bool {
+	_, ok := s[token]
+	return ok
+}
+
+// Contains checks whether the scope includes every token of another scope.
+func (s Scope) Contains(other Scope) bool {
+	return len(s.Missing(other.Tokens()...)) == 0
+}
+
+// Missing returns the required tokens the scope does not include.
+func (s Scope) Missing(required ...string) []string {
+	missing := make([]string, 0)
+	for _, token := range required {
+		if !s.Has(token) {
+			missing = append(missing, token)
+		}
+	}
+
+	return missing
+}
+
+// Tokens returns the tokens of the scope, sorted.
+func (s Scope) Tokens() []string {
+	tokens := make([]string, 0, len(s))
+	for token := range s {
+		tokens = append(tokens, token)
+	}
+	sort.Strings(tokens)
+
+	return tokens
+}
+
+// String returns the space-delimited form of the scope.
+func (s Scope) String() string {
+	return strings.Join(s.Tokens(), " ")
+}
+
+// grantedScope resolves the scope granted to the client with a grant type for
+// a requested scope, which must be within the client's scope. Without a
+// requested scope, the client's whole scope is granted. Service scopes are
+// only granted with client credentials, never to tokens issued to users.
+func (o *OauthClient) grantedScope(requested string, grantType GrantType) (Scope, error) {
+	allowed := ParseScope(o.Scope.String)
+	if grantType != ClientCredentials {
+		for _, token := range serviceScopes {
+			delete(allowed, token)
+		}
+	}
+
+	scope := ParseScope(requested)
+	if len(scope) == 0 {
+		return allowed, nil
+	}
+
+	if !allowed.Contains(scope) {
+		return nil, errors.New(ErrorInvalidScope)
+	}
+
+	return scope, nil
+}
@@ -0,0 +1,36 @@
+package oauth_test
+
+import (
+	"testing"
+
+	"github.com/evermos/boilerplate-go/shared/oauth"
+	"github.com/guregu/null"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScope(t *testing.T) {
+	t.Run("Parse", func(t *testing.T) {
+		scope := oauth.ParseScope("  foo:write foo:read\tfoo:write ")
+
+		assert.Len(t, scope, 2)
+		assert.Equal(t, "foo:read foo:write", scope.String())
+		assert.Empty(t, oauth.ParseScope("").String())
+	})
+
+	t.Run("Contains", func(t *testing.T) {
+		scope := oauth.ParseScope("foo:read foo:write")
+
+		assert.True(t, scope.Contains(oauth.ParseScope("foo:read")))
+		assert.True(t, scope.Contains(oauth.ParseScope("")))
+		assert.False(t, scope.Contains(oauth.ParseScope("foo:read oauth:introspect")))
+		assert.Equal(t, []string{"oauth:introspect"}, scope.Missing("foo:read", "oauth:introspect"))
+	})
+
+	t.Run("Access Token Scopes", func(t *testing.T) {
+		accessToken := oauth.OauthAccessToken{Scope: null.StringFrom("foo:read")}
+
+		assert.True(t, accessToken.Scopes().Has(oauth.ScopeFooRead))
+		assert.False(t, accessToken.Scopes().Has(oauth.ScopeFooWrite))
+		assert.Empty(t, new(oauth.OauthAccessToken).Scopes())
+	})
+}
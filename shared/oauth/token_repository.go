@@ -0,0 +1,308 @@
+package oauth
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// TokenRepository stores clients, users, refresh tokens and authorization
+// codes in MySQL, and access tokens in its TokenStore. Writes spanning both
+// store the access token first, and remove it again when the MySQL write
+// fails, so that no refresh token or code is spent without a usable access
+// token.
+type TokenRepository struct {
+	db     *sqlx.DB
+	tokens TokenStore
+}
+
+const (
+	querySelectClients = `SELECT
+			client_id,
+			client_secret,
+			redirect_uri,
+			grant_types,
+			scope,
+			disabled_at
+		FROM 
+			oauth_clients`
+
+	queryInsertRefreshToken = `INSERT INTO oauth_refresh_tokens (
+			token_hash,
+			family_id,
+			client_id,
+			user_id,
+			scope,
+			expires
+		) VALUES (
+			:token_hash,
+			:family_id,
+			:client_id,
+			:user_id,
+			:scope,
+			:expires
+		)`
+
+	querySelectRefreshToken = `SELECT
+			token_hash,
+			family_id,
+			client_id,
+			user_id,
+			scope,
+			expires,
+			used_at,
+			revoked_at
+		FROM
+			oauth_refresh_tokens`
+
+	queryUseRefreshToken = `UPDATE oauth_refresh_tokens
+		SET used_at = NOW()
+		WHERE token_hash = ? AND used_at IS NULL AND revoked_at IS NULL`
+
+	queryRevokeRefreshTokenFamily = `UPDATE oauth_refresh_tokens
+		SET revoked_at = NOW()
+		WHERE family_id = ? AND revoked_at IS NULL`
+
+	queryInsertAuthorizationCode = `INSERT INTO oauth_authorization_codes (
+			code_hash,
+			family_id,
+			client_id,
+			user_id,
+			redirect_uri,
+			scope,
+			code_challenge,
+			expires
+		) VALUES (
+			:code_hash,
+			:family_id,
+			:client_id,
+			:user_id,
+			:redirect_uri,
+			:scope,
+			:code_challenge,
+			:expires
+		)`
+
+	querySelectAuthorizationCode = `SELECT
+			code_hash,
+			family_id,
+			client_id,
+			user_id,
+			redirect_uri,
+			scope,
+			code_challenge,
+			expires,
+			used_at
+		FROM
+			oauth_authorization_codes`
+
+	queryUseAuthorizationCode = `UPDATE oauth_authorization_codes
+		SET used_at = NOW()
+		WHERE code_hash = ? AND used_at IS NULL`
+
+	querySelectUser = `
+			SELECT
+				id,
+				username,
+				password
+			FROM
+				user`
+)
+
+func NewTokenRepository(db *sqlx.DB, tokens TokenStore) TokenRepository {
+	return TokenRepository{
+		db:     db,
+		tokens: tokens,
+	}
+}
+
+func (a *TokenRepository) createAccessToken(accessToken OauthAccessToken) error {
+	return a.tokens.CreateAccessToken(accessToken)
+}
+
+func (a *TokenRepository) resolveAccessTokenByAccessToken(accessToken string) (OauthAccessToken, error) {
+	return a.tokens.ResolveAccessToken(accessToken)
+}
+
+func (a *TokenRepository) resolveClientByClientID(clientID string) (client OauthClient, err error) {
+	err = a.db.Get(&client, querySelectClients+" WHERE client_id = ?", clientID)
+	switch {
+	case err == sql.ErrNoRows:
+		err = errors.New(ErrorClientNotFound)
+		return
+	case err != nil:
+		return
+	}
+
+	return
+}
+
+func (a *TokenRepository) resolveByTelephoneOrEmail(username string) (User, error) {
+	var user User
+
+	err := a.db.Get(&user, querySelectUser+" WHERE (telephone = ? OR email = ? OR username = ?) AND deleted_at IS NULL", username, username, username)
+	switch {
+	case err == sql.ErrNoRows:
+		return User{}, errors.New(ErrorUserNotFound)
+	case err != nil:
+		return User{}, err
+	}
+
+	return user, nil
+}
+
+// createTokenPair stores an access token and the refresh token issued along
+// with it.
+func (a *TokenRepository) createTokenPair(accessToken OauthAccessToken, refreshToken OauthRefreshToken) error {
+	err := a.tokens.CreateAccessToken(accessToken)
+	if err != nil {
+		return err
+	}
+
+	_, err = a.db.NamedExec(queryInsertRefreshToken, refreshToken)
+	if err != nil {
+		a.discardAccessToken(accessToken)
+		return err
+	}
+
+	return nil
+}
+
+func (a *TokenRepository) resolveRefreshTokenByHash(tokenHash string) (refreshToken OauthRefreshToken, err error) {
+	err = a.db.Get(&refreshToken, querySelectRefreshToken+" WHERE token_hash = ?", tokenHash)
+	if err == sql.ErrNoRows {
+		err = errors.New(ErrorInvalidRefreshToken)
+	}
+
+	return
+}
+
+// rotateRefreshToken marks a refresh token as used and stores its successor
+// and a new access token. It returns false, storing nothing, when the refresh
+// token was already used or revoked in the meantime.
+func (a *TokenRepository) rotateRefreshToken(used OauthRefreshToken, accessToken OauthAccessToken, next OauthRefreshToken) (rotated bool, err error) {
+	err = a.tokens.CreateAccessToken(accessToken)
+	if err != nil {
+		return
+	}
+
+	defer func() {
+		if !rotated {
+			a.discardAccessToken(accessToken)
+		}
+	}()
+
+	tx, err := a.db.Beginx()
+	if err != nil {
+		return
+	}
+
+	result, err := tx.Exec(queryUseRefreshToken, used.TokenHash)
+	if err != nil {
+		_ = tx.Rollback()
+		return
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 0 {
+		_ = tx.Rollback()
+		return
+	}
+
+	_, err = tx.NamedExec(queryInsertRefreshToken, next)
+	if err != nil {
+		_ = tx.Rollback()
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return
+	}
+
+	return true, nil
+}
+
+// revokeFamily revokes every refresh token of a family, and the access tokens
+// issued with them.
+func (a *TokenRepository) revokeFamily(familyID string) error {
+	_, err := a.db.Exec(queryRevokeRefreshTokenFamily, familyID)
+	if err != nil {
+		return err
+	}
+
+	return a.tokens.RevokeFamily(familyID)
+}
+
+func (a *TokenRepository) revokeAccessToken(accessToken string, clientID string) (revoked bool, err error) {
+	return a.tokens.RevokeAccessToken(accessToken, clientID)
+}
+
+func (a *TokenRepository) createAuthorizationCode(code OauthAuthorizationCode) error {
+	_, err := a.db.NamedExec(queryInsertAuthorizationCode, code)
+	return err
+}
+
+func (a *TokenRepository) resolveAuthorizationCodeByHash(codeHash string) (code OauthAuthorizationCode, err error) {
+	err = a.db.Get(&code, querySelectAuthorizationCode+" WHERE code_hash = ?", codeHash)
+	if err == sql.ErrNoRows {
+		err = errors.New(ErrorInvalidAuthorizationCode)
+	}
+
+	return
+}
+
+// redeemAuthorizationCode marks an authorization code as used and stores the
+// tokens issued for it. It returns false, storing nothing, when the code was
+// already used in the meantime.
+func (a *TokenRepository) redeemAuthorizationCode(code OauthAuthorizationCode, accessToken OauthAccessToken, refreshToken *OauthRefreshToken) (redeemed bool, err error) {
+	err = a.tokens.CreateAccessToken(accessToken)
+	if err != nil {
+		return
+	}
+
+	defer func() {
+		if !redeemed {
+			a.discardAccessToken(accessToken)
+		}
+	}()
+
+	tx, err := a.db.Beginx()
+	if err != nil {
+		return
+	}
+
+	result, err := tx.Exec(queryUseAuthorizationCode, code.CodeHash)
+	if err != nil {
+		_ = tx.Rollback()
+		return
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 0 {
+		_ = tx.Rollback()
+		return
+	}
+
+	if refreshToken != nil {
+		_, err = tx.NamedExec(queryInsertRefreshToken, *refreshToken)
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return
+	}
+
+	return true, nil
+}
+
+// discardAccessToken removes an access token whose refresh token or code
+// could not be stored.
+func (a *TokenRepository) discardAccessToken(accessToken OauthAccessToken) {
+	_, _ = a.tokens.RevokeAccessToken(accessToken.AccessToken, accessToken.ClientID)
+}
@@ -3,12 +3,35 @@ package oauth
 import (
 	"database/sql"
 	"errors"
+	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
 )
 
-type TokenStore struct {
-	db *sqlx.DB
+const (
+	TokenStoreMySQLDriver = "mysql"
+	TokenStoreRedisDriver = "redis"
+)
+
+// TokenStore stores the access tokens issued by the server.
+type TokenStore interface {
+	CreateAccessToken(accessToken OauthAccessToken) error
+	// ResolveAccessToken returns ErrorClientNotFound for unknown tokens.
+	ResolveAccessToken(accessToken string) (OauthAccessToken, error)
+	// RevokeAccessToken reports whether the client had the token.
+	RevokeAccessToken(accessToken string, clientID string) (bool, error)
+	RevokeFamily(familyID string) error
+	RevokeUser(userID string) error
+	RevokeClient(clientID string) error
+}
+
+// TxTokenStore is implemented by the token stores kept in the MySQL database,
+// which revoke tokens in the transaction revoking the rest of a session, so
+// that they are revoked together or not at all.
+type TxTokenStore interface {
+	RevokeUserTx(tx *sqlx.Tx, userID string) error
+	RevokeClientTx(tx *sqlx.Tx, clientID string) error
 }
 
 const (
@@ -17,13 +40,15 @@ const (
 			client_id,
 			user_id,
 			expires,
-			scope
+			scope,
+			family_id
 		) VALUES (
 			:access_token,
 			:client_id,
 			:user_id,
 			:expires,
-			:scope
+			:scope,
+			:family_id
 		)`
 
 	querySelectAccessToken = `SELECT 
@@ -31,95 +56,126 @@ const (
 			client_id,
 			user_id,
 			expires,
-			scope
+			scope,
+			family_id
 		FROM
 			oauth_access_tokens`
 
-	querySelectClients = `SELECT
-			client_id,
-			client_secret,
-			redirect_uri,
-			grant_types
-		FROM 
-			oauth_clients`
-
-	querySelectUser = `
-			SELECT
-				id,
-				username,
-				password
-			FROM
-				user`
+	queryDeleteAccessToken = `DELETE FROM oauth_access_tokens WHERE access_token = ? AND client_id = ?`
+
+	queryDeleteAccessTokensByFamily = `DELETE FROM oauth_access_tokens WHERE family_id = ?`
+
+	queryDeleteAccessTokensByUser = `DELETE FROM oauth_access_tokens WHERE user_id = ?`
+
+	queryDeleteAccessTokensByClient = `DELETE FROM oauth_access_tokens WHERE client_id = ?`
+
+	queryPurgeAccessTokens = `DELETE FROM oauth_access_tokens WHERE expires < ? LIMIT ?`
 )
 
-func NewTokenStore(db *sqlx.DB) TokenStore {
-	return TokenStore{
-		db: db,
-	}
-}
+const tokenPurgeBatchSize = 1000
 
-func (a *TokenStore) createAccessToken(accessToken OauthAccessToken) error {
-	stmt, err := a.db.PrepareNamed(queryInsertAccessToken)
-	if err != nil {
-		return err
-	}
-	defer stmt.Close()
+// TokenStoreMySQL stores access tokens in the oauth_access_tokens table.
+// Tokens are read from the read replica.
+type TokenStoreMySQL struct {
+	read  *sqlx.DB
+	write *sqlx.DB
+}
 
-	_, err = stmt.Exec(accessToken)
-	if err != nil {
-		return err
+func NewTokenStoreMySQL(read *sqlx.DB, write *sqlx.DB) *TokenStoreMySQL {
+	return &TokenStoreMySQL{
+		read:  read,
+		write: write,
 	}
+}
 
-	return nil
+func (s *TokenStoreMySQL) CreateAccessToken(accessToken OauthAccessToken) error {
+	_, err := s.write.NamedExec(queryInsertAccessToken, accessToken)
+	return err
 }
 
-func (a *TokenStore) resolveAccessTokenByAccessToken(accessToken string) (oauthAccessToken OauthAccessToken, err error) {
-	err = a.db.Get(&oauthAccessToken, querySelectAccessToken+" WHERE access_token = ?", accessToken)
-	switch {
-	case err == sql.ErrNoRows:
+func (s *TokenStoreMySQL) ResolveAccessToken(accessToken string) (oauthAccessToken OauthAccessToken, err error) {
+	err = s.read.Get(&oauthAccessToken, querySelectAccessToken+" WHERE access_token = ?", accessToken)
+	if err == sql.ErrNoRows {
 		err = errors.New(ErrorClientNotFound)
-		return
-	case err != nil:
-		return
 	}
 
 	return
 }
 
-func (a *TokenStore) resolveAllClients(db *sqlx.DB) ([]OauthClient, error) {
-	var clients []OauthClient
+func (s *TokenStoreMySQL) RevokeAccessToken(accessToken string, clientID string) (revoked bool, err error) {
+	result, err := s.write.Exec(queryDeleteAccessToken, accessToken, clientID)
+	if err != nil {
+		return
+	}
 
-	err := a.db.Get(&clients, querySelectClients)
+	affected, err := result.RowsAffected()
 	if err != nil {
-		return []OauthClient{}, err
+		return
 	}
 
-	return clients, nil
+	return affected > 0, nil
 }
 
-func (a *TokenStore) resolveClientByClientID(clientID string) (client OauthClient, err error) {
-	err = a.db.Get(&client, querySelectClients+" WHERE client_id = ?", clientID)
-	switch {
-	case err == sql.ErrNoRows:
-		err = errors.New(ErrorClientNotFound)
-		return
-	case err != nil:
-		return
-	}
+func (s *TokenStoreMySQL) RevokeFamily(familyID string) error {
+	_, err := s.write.Exec(queryDeleteAccessTokensByFamily, familyID)
+	return err
+}
 
-	return
+func (s *TokenStoreMySQL) RevokeUser(userID string) error {
+	_, err := s.write.Exec(queryDeleteAccessTokensByUser, userID)
+	return err
+}
+
+func (s *TokenStoreMySQL) RevokeClient(clientID string) error {
+	_, err := s.write.Exec(queryDeleteAccessTokensByClient, clientID)
+	return err
 }
 
-func (a *TokenStore) resolveByTelephoneOrEmail(username string) (User, error) {
-	var user User
+func (s *TokenStoreMySQL) RevokeUserTx(tx *sqlx.Tx, userID string) error {
+	_, err := tx.Exec(queryDeleteAccessTokensByUser, userID)
+	return err
+}
+
+func (s *TokenStoreMySQL) RevokeClientTx(tx *sqlx.Tx, clientID string) error {
+	_, err := tx.Exec(queryDeleteAccessTokensByClient, clientID)
+	return err
+}
 
-	err := a.db.Get(&user, querySelectUser+" WHERE telephone = ? OR  email = ?", username, username)
-	switch {
-	case err == sql.ErrNoRows:
-		return User{}, errors.New(ErrorClientNotFound)
-	case err != nil:
-		return User{}, err
+// Purge deletes expired access tokens in batches, so that a large backlog
+// does not hold locks on the table for long. It returns the number of tokens
+// deleted.
+func (s *TokenStoreMySQL) Purge() (purged int64, err error) {
+	now := time.Now()
+	for {
+		result, err := s.write.Exec(queryPurgeAccessTokens, now, tokenPurgeBatchSize)
+		if err != nil {
+			return purged, err
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return purged, err
+		}
+
+		purged += affected
+		if affected < tokenPurgeBatchSize {
+			return purged, nil
+		}
 	}
+}
 
-	return user, nil
+// StartPurge purges expired access tokens every interval in the background.
+func (s *TokenStoreMySQL) StartPurge(interval time.Duration) {
+	go func() {
+		for range time.Tick(interval) {
+			purged, err := s.Purge()
+			if err != nil {
+				log.Error().Err(err).Msg("Failed purging expired access tokens")
+				continue
+			}
+			if purged > 0 {
+				log.Info().Int64("purged", purged).Msg("Purged expired access tokens")
+			}
+		}
+	}()
 }
@@ -0,0 +1,172 @@
+package oauth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/guregu/null"
+)
+
+// TokenStoreRedis stores access tokens in Redis under the hash of the token,
+// expiring with the token. Sorted sets of token keys per family, user and
+// client, scored by the expiry of the token, allow revoking them together.
+// Expired tokens are removed from a set whenever a token is added to it, and
+// a set expires along with the token expiring last.
+type TokenStoreRedis struct {
+	redis *redis.Client
+}
+
+// storedAccessToken is the Redis representation of an OauthAccessToken.
+type storedAccessToken struct {
+	AccessToken string      `json:"accessToken"`
+	ClientID    string      `json:"clientId"`
+	UserID      null.String `json:"userId"`
+	Expires     time.Time   `json:"expires"`
+	Scope       null.String `json:"scope"`
+	FamilyID    null.String `json:"familyId"`
+}
+
+// createAccessTokenScript stores the token ARGV[1] under KEYS[1] for ARGV[2]
+// milliseconds, and adds it to the indexes KEYS[2:] scored by its expiry
+// ARGV[3], in Unix milliseconds. Tokens expired at ARGV[4] are pruned from
+// the indexes.
+var createAccessTokenScript = redis.NewScript(`
+	redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+	for i = 2, #KEYS do
+		redis.call("ZADD", KEYS[i], ARGV[3], KEYS[1])
+		redis.call("ZREMRANGEBYSCORE", KEYS[i], "-inf", ARGV[4])
+		local last = redis.call("ZRANGE", KEYS[i], -1, -1, "WITHSCORES")
+		redis.call("PEXPIREAT", KEYS[i], last[2])
+	end
+	return 1
+`)
+
+func NewTokenStoreRedis(redis *redis.Client) *TokenStoreRedis {
+	return &TokenStoreRedis{
+		redis: redis,
+	}
+}
+
+func accessTokenKey(accessToken string) string {
+	return fmt.Sprintf("oauth_access_token:%s", hashToken(accessToken))
+}
+
+func accessTokenIndexKey(kind string, value string) string {
+	return fmt.Sprintf("oauth_access_tokens:%s:%s", kind, value)
+}
+
+func (s *TokenStoreRedis) CreateAccessToken(accessToken OauthAccessToken) error {
+	ttl := time.Until(accessToken.Expires)
+	if ttl <= 0 {
+		return nil
+	}
+
+	value, err := json.Marshal(storedAccessToken{
+		AccessToken: accessToken.AccessToken,
+		ClientID:    accessToken.ClientID,
+		UserID:      accessToken.UserID,
+		Expires:     accessToken.Expires,
+		Scope:       accessToken.Scope,
+		FamilyID:    accessToken.FamilyID,
+	})
+	if err != nil {
+		return err
+	}
+
+	key := accessTokenKey(accessToken.AccessToken)
+	indexes := []string{accessTokenIndexKey("client", accessToken.ClientID)}
+	if accessToken.UserID.Valid {
+		indexes = append(indexes, accessTokenIndexKey("user", accessToken.UserID.String))
+	}
+	if accessToken.FamilyID.Valid {
+		indexes = append(indexes, accessTokenIndexKey("family", accessToken.FamilyID.String))
+	}
+
+	keys := append([]string{key}, indexes...)
+	ttlMillis := int64(ttl / time.Millisecond)
+	if ttlMillis <= 0 {
+		ttlMillis = 1
+	}
+
+	return createAccessTokenScript.Run(s.redis, keys, value, ttlMillis, unixMilli(accessToken.Expires), unixMilli(time.Now())).Err()
+}
+
+func unixMilli(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+func (s *TokenStoreRedis) ResolveAccessToken(accessToken string) (OauthAccessToken, error) {
+	value, err := s.redis.Get(accessTokenKey(accessToken)).Bytes()
+	if err == redis.Nil {
+		return OauthAccessToken{}, errors.New(ErrorClientNotFound)
+	}
+	if err != nil {
+		return OauthAccessToken{}, err
+	}
+
+	var stored storedAccessToken
+	err = json.Unmarshal(value, &stored)
+	if err != nil {
+		return OauthAccessToken{}, err
+	}
+
+	return OauthAccessToken{
+		AccessToken: stored.AccessToken,
+		ClientID:    stored.ClientID,
+		UserID:      stored.UserID,
+		Expires:     stored.Expires,
+		Scope:       stored.Scope,
+		FamilyID:    stored.FamilyID,
+	}, nil
+}
+
+func (s *TokenStoreRedis) RevokeAccessToken(accessToken string, clientID string) (bool, error) {
+	oauthAccessToken, err := s.ResolveAccessToken(accessToken)
+	if err != nil {
+		if err.Error() == ErrorClientNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if oauthAccessToken.ClientID != clientID {
+		return false, nil
+	}
+
+	deleted, err := s.redis.Del(accessTokenKey(accessToken)).Result()
+	if err != nil {
+		return false, err
+	}
+
+	return deleted > 0, nil
+}
+
+func (s *TokenStoreRedis) RevokeFamily(familyID string) error {
+	return s.revokeIndex(accessTokenIndexKey("family", familyID))
+}
+
+func (s *TokenStoreRedis) RevokeUser(userID string) error {
+	return s.revokeIndex(accessTokenIndexKey("user", userID))
+}
+
+func (s *TokenStoreRedis) RevokeClient(clientID string) error {
+	return s.revokeIndex(accessTokenIndexKey("client", clientID))
+}
+
+// revokeIndex deletes every token of an index that has not expired, and the
+// index itself.
+func (s *TokenStoreRedis) revokeIndex(index string) error {
+	keys, err := s.redis.ZRangeByScore(index, redis.ZRangeBy{
+		Min: "(" + strconv.FormatInt(unixMilli(time.Now()), 10),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return err
+	}
+
+	return s.redis.Del(append(keys, index)...).Err()
+}
@@ -0,0 +1,238 @@
+package oauth_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/evermos/boilerplate-go/shared/oauth"
+	"github.com/go-redis/redis"
+	"github.com/guregu/null"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newAccessToken(accessToken string, clientID string, userID string, familyID string, ttl time.Duration) oauth.OauthAccessToken {
+	token := oauth.OauthAccessToken{
+		AccessToken: accessToken,
+		ClientID:    clientID,
+		Expires:     time.Now().Add(ttl),
+		Scope:       null.StringFrom("foo:read"),
+	}
+	if userID != "" {
+		token.UserID = null.StringFrom(userID)
+	}
+	if familyID != "" {
+		token.FamilyID = null.StringFrom(familyID)
+	}
+
+	return token
+}
+
+func TestTokenStoreRedis(t *testing.T) {
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+	defer server.Close()
+
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	store := oauth.NewTokenStoreRedis(client)
+
+	resolvable := func(accessToken string) bool {
+		_, err := store.ResolveAccessToken(accessToken)
+		if err != nil {
+			require.EqualError(t, err, oauth.ErrorClientNotFound)
+			return false
+		}
+		return true
+	}
+
+	t.Run("Create And Resolve", func(t *testing.T) {
+		server.FlushAll()
+		token := newAccessToken("create", "client_web", "user-1", "family-1", time.Hour)
+		require.NoError(t, store.CreateAccessToken(token))
+
+		resolved, err := store.ResolveAccessToken("create")
+		require.NoError(t, err)
+		assert.Equal(t, token.ClientID, resolved.ClientID)
+		assert.Equal(t, token.UserID, resolved.UserID)
+		assert.Equal(t, token.FamilyID, resolved.FamilyID)
+		assert.Equal(t, token.Scope, resolved.Scope)
+		assert.WithinDuration(t, token.Expires, resolved.Expires, time.Millisecond)
+
+		assert.False(t, resolvable("unknown"))
+	})
+
+	t.Run("Expired Tokens Are Not Stored", func(t *testing.T) {
+		server.FlushAll()
+		require.NoError(t, store.CreateAccessToken(newAccessToken("expired", "client_web", "", "", -time.Second)))
+
+		assert.False(t, resolvable("expired"))
+		assert.Empty(t, server.Keys())
+	})
+
+	t.Run("Revoke Access Token", func(t *testing.T) {
+		server.FlushAll()
+		require.NoError(t, store.CreateAccessToken(newAccessToken("revoke", "client_web", "", "", time.Hour)))
+
+		revoked, err := store.RevokeAccessToken("revoke", "client_other")
+		require.NoError(t, err)
+		assert.False(t, revoked)
+		assert.True(t, resolvable("revoke"))
+
+		revoked, err = store.RevokeAccessToken("revoke", "client_web")
+		require.NoError(t, err)
+		assert.True(t, revoked)
+		assert.False(t, resolvable("revoke"))
+	})
+
+	t.Run("Revoke By Family, User And Client", func(t *testing.T) {
+		server.FlushAll()
+		for _, token := range []oauth.OauthAccessToken{
+			newAccessToken("family-a", "client_web", "user-1", "family-1", time.Hour),
+			newAccessToken("family-b", "client_web", "user-1", "family-2", time.Hour),
+			newAccessToken("user-b", "client_web", "user-2", "", time.Hour),
+			newAccessToken("client-b", "client_partner", "", "", time.Hour),
+		} {
+			require.NoError(t, store.CreateAccessToken(token))
+		}
+
+		require.NoError(t, store.RevokeFamily("family-1"))
+		assert.False(t, resolvable("family-a"))
+		assert.True(t, resolvable("family-b"))
+
+		require.NoError(t, store.RevokeUser("user-1"))
+		assert.False(t, resolvable("family-b"))
+		assert.True(t, resolvable("user-b"))
+
+		require.NoError(t, store.RevokeClient("client_web"))
+		assert.False(t, resolvable("user-b"))
+		assert.True(t, resolvable("client-b"))
+	})
+
+	t.Run("Purges Expired Tokens From Indexes", func(t *testing.T) {
+		server.FlushAll()
+		index := "oauth_access_tokens:client:client_web"
+
+		// A token that expired by the wall clock, left in the index.
+		server.ZAdd(index, float64(time.Now().Add(-time.Minute).UnixNano()/int64(time.Millisecond)), "oauth_access_token:stale")
+
+		require.NoError(t, store.CreateAccessToken(newAccessToken("long", "client_web", "", "", time.Hour)))
+		require.NoError(t, store.CreateAccessToken(newAccessToken("short", "client_web", "", "", time.Minute)))
+
+		members, err := server.ZMembers(index)
+		require.NoError(t, err)
+		assert.Len(t, members, 2)
+		assert.NotContains(t, members, "oauth_access_token:stale")
+
+		// The index lives as long as the token expiring last, even when a
+		// token expiring earlier is added after it.
+		assert.InDelta(t, time.Hour.Seconds(), server.TTL(index).Seconds(), 2)
+
+		server.FastForward(2 * time.Minute)
+		assert.False(t, resolvable("short"))
+		assert.True(t, resolvable("long"))
+
+		server.FastForward(time.Hour)
+		assert.False(t, resolvable("long"))
+		assert.False(t, server.Exists(index))
+	})
+}
+
+func TestTokenStoreMySQL(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "mysql")
+	store := oauth.NewTokenStoreMySQL(sqlxDB, sqlxDB)
+	columns := []string{"access_token", "client_id", "user_id", "expires", "scope", "family_id"}
+
+	t.Run("Create And Resolve", func(t *testing.T) {
+		token := newAccessToken("create", "client_web", "user-1", "family-1", time.Hour)
+		mock.ExpectExec("INSERT INTO oauth_access_tokens").
+			WithArgs(token.AccessToken, token.ClientID, token.UserID, token.Expires, token.Scope, token.FamilyID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		require.NoError(t, store.CreateAccessToken(token))
+
+		mock.ExpectQuery("FROM\\s+oauth_access_tokens WHERE access_token = \\?").
+			WithArgs("create").
+			WillReturnRows(sqlmock.NewRows(columns).AddRow("create", "client_web", "user-1", token.Expires, "foo:read", "family-1"))
+		resolved, err := store.ResolveAccessToken("create")
+		require.NoError(t, err)
+		assert.Equal(t, token, resolved)
+
+		mock.ExpectQuery("FROM\\s+oauth_access_tokens WHERE access_token = \\?").
+			WithArgs("unknown").
+			WillReturnRows(sqlmock.NewRows(columns))
+		_, err = store.ResolveAccessToken("unknown")
+		assert.EqualError(t, err, oauth.ErrorClientNotFound)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Revoke Access Token", func(t *testing.T) {
+		mock.ExpectExec("DELETE FROM oauth_access_tokens WHERE access_token = \\? AND client_id = \\?").
+			WithArgs("revoke", "client_other").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		revoked, err := store.RevokeAccessToken("revoke", "client_other")
+		require.NoError(t, err)
+		assert.False(t, revoked)
+
+		mock.ExpectExec("DELETE FROM oauth_access_tokens WHERE access_token = \\? AND client_id = \\?").
+			WithArgs("revoke", "client_web").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		revoked, err = store.RevokeAccessToken("revoke", "client_web")
+		require.NoError(t, err)
+		assert.True(t, revoked)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Revoke By Family, User And Client", func(t *testing.T) {
+		mock.ExpectExec("DELETE FROM oauth_access_tokens WHERE family_id = \\?").WithArgs("family-1").WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec("DELETE FROM oauth_access_tokens WHERE user_id = \\?").WithArgs("user-1").WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec("DELETE FROM oauth_access_tokens WHERE client_id = \\?").WithArgs("client_web").WillReturnResult(sqlmock.NewResult(0, 2))
+
+		require.NoError(t, store.RevokeFamily("family-1"))
+		require.NoError(t, store.RevokeUser("user-1"))
+		require.NoError(t, store.RevokeClient("client_web"))
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Revoke In Transaction", func(t *testing.T) {
+		var tokens oauth.TokenStore = store
+		txStore, ok := tokens.(oauth.TxTokenStore)
+		require.True(t, ok)
+
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE FROM oauth_access_tokens WHERE user_id = \\?").WithArgs("user-1").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("DELETE FROM oauth_access_tokens WHERE client_id = \\?").WithArgs("client_web").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectRollback()
+
+		tx, err := sqlxDB.Beginx()
+		require.NoError(t, err)
+		require.NoError(t, txStore.RevokeUserTx(tx, "user-1"))
+		require.NoError(t, txStore.RevokeClientTx(tx, "client_web"))
+		require.NoError(t, tx.Rollback())
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Purge", func(t *testing.T) {
+		mock.ExpectExec("DELETE FROM oauth_access_tokens WHERE expires < \\? LIMIT \\?").
+			WithArgs(sqlmock.AnyArg(), 1000).
+			WillReturnResult(sqlmock.NewResult(0, 1000))
+		mock.ExpectExec("DELETE FROM oauth_access_tokens WHERE expires < \\? LIMIT \\?").
+			WithArgs(sqlmock.AnyArg(), 1000).
+			WillReturnResult(sqlmock.NewResult(0, 3))
+
+		purged, err := store.Purge()
+		require.NoError(t, err)
+		assert.Equal(t, int64(1003), purged)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,80 @@
+package shared
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math"
+)
+
+// ErrInvalidCursor is returned decoding a cursor that was not encoded by
+// Cursor.Encode.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Page is the pagination envelope of list endpoints. Keyset paginated lists
+// set NextCursor while more items follow; page numbered lists set
+// CurrentPage. Filterable lists may set Facets, counting the items of every
+// page per value of their filters.
+type Page struct {
+	Data        interface{}        `json:"data"`
+	Total       int                `json:"total"`
+	PerPage     int                `json:"perPage"`
+	TotalPages  int                `json:"totalPages"`
+	CurrentPage int                `json:"currentPage,omitempty"`
+	NextCursor  string             `json:"nextCursor,omitempty"`
+	Facets      map[string][]Facet `json:"facets,omitempty"`
+}
+
+// Facet is the number of items having a value of a filter, which is passed
+// back to the filter as is. Label is how the value is shown.
+type Facet struct {
+	Value string `json:"value"`
+	Label string `json:"label"`
+	Count int    `json:"count"`
+}
+
+// NewPage creates the envelope of a page of data, out of total items.
+func NewPage(data interface{}, total int, perPage int) Page {
+	page := Page{
+		Data:    data,
+		Total:   total,
+		PerPage: perPage,
+	}
+
+	if perPage > 0 {
+		page.TotalPages = int(math.Ceil(float64(total) / float64(perPage)))
+	}
+
+	return page
+}
+
+// Cursor is the position after the last item of a keyset paginated page: the
+// sort of the list, and the sort value and ID of the last item. Clients only
+// see it encoded, and pass it back as is to resolve the next page.
+type Cursor struct {
+	Sort  string `json:"s"`
+	Order string `json:"o"`
+	Value string `json:"v"`
+	ID    string `json:"i"`
+}
+
+// Encode encodes the cursor opaquely, safe for use in URLs.
+func (c Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor decodes a cursor encoded by Cursor.Encode.
+func DecodeCursor(encoded string) (cursor Cursor, err error) {
+	b, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return cursor, ErrInvalidCursor
+	}
+
+	err = json.Unmarshal(b, &cursor)
+	if err != nil || cursor.Sort == "" || cursor.ID == "" {
+		return cursor, ErrInvalidCursor
+	}
+
+	return cursor, nil
+}
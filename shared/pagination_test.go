@@ -0,0 +1,31 @@
+package shared_test
+
+import (
+	"testing"
+
+	"github.com/evermos/boilerplate-go/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPagination(t *testing.T) {
+	t.Run("Cursor Round Trip", func(t *testing.T) {
+		cursor := shared.Cursor{Sort: "price", Order: "desc", Value: "799.5", ID: "660e8400-e29b-41d4-a716-446655440000"}
+
+		decoded, err := shared.DecodeCursor(cursor.Encode())
+		require.NoError(t, err)
+		assert.Equal(t, cursor, decoded)
+	})
+
+	t.Run("Invalid Cursor", func(t *testing.T) {
+		for _, encoded := range []string{"not a cursor", "e30"} {
+			_, err := shared.DecodeCursor(encoded)
+			assert.Equal(t, shared.ErrInvalidCursor, err)
+		}
+	})
+
+	t.Run("Total Pages", func(t *testing.T) {
+		page := shared.NewPage([]int{1, 2}, 21, 10)
+		assert.Equal(t, 3, page.TotalPages)
+	})
+}
@@ -0,0 +1,105 @@
+package rbac
+
+import (
+	"github.com/gofrs/uuid"
+)
+
+// Role is the role of a user.
+type Role string
+
+const (
+	// RoleAdmin manages everything.
+	RoleAdmin Role = "admin"
+	// RoleShopAdmin manages the products and orders they own.
+	RoleShopAdmin Role = "shop_admin"
+	// RoleUser manages their own cart and orders.
+	RoleUser Role = "user"
+)
+
+// Permission is an action on a kind of resource.
+type Permission string
+
+const (
+	PermissionProductRead     Permission = "product:read"
+	PermissionProductWrite    Permission = "product:write"
+	PermissionCartRead        Permission = "cart:read"
+	PermissionCartWrite       Permission = "cart:write"
+	PermissionOrderRead       Permission = "order:read"
+	PermissionOrderWrite      Permission = "order:write"
+	PermissionUserManage      Permission = "user:manage"
+	PermissionClientManage    Permission = "client:manage"
+	PermissionAPIKeyManage    Permission = "api_key:manage"
+	PermissionCategoryManage  Permission = "category:manage"
+	PermissionWarehouseManage Permission = "warehouse:manage"
+)
+
+// Reach is which resources a permission is granted on.
+type Reach int
+
+const (
+	// ReachNone grants the permission on no resource.
+	ReachNone Reach = iota
+	// ReachOwn grants the permission on resources the user owns.
+	ReachOwn
+	// ReachAll grants the permission on every resource.
+	ReachAll
+)
+
+// matrix is the permission matrix of every role.
+var matrix = map[Role]map[Permission]Reach{
+	RoleAdmin: {
+		PermissionProductRead:     ReachAll,
+		PermissionProductWrite:    ReachAll,
+		PermissionCartRead:        ReachAll,
+		PermissionCartWrite:       ReachAll,
+		PermissionOrderRead:       ReachAll,
+		PermissionOrderWrite:      ReachAll,
+		PermissionUserManage:      ReachAll,
+		PermissionClientManage:    ReachAll,
+		PermissionAPIKeyManage:    ReachAll,
+		PermissionCategoryManage:  ReachAll,
+		PermissionWarehouseManage: ReachAll,
+	},
+	RoleShopAdmin: {
+		PermissionProductRead:  ReachAll,
+		PermissionProductWrite: ReachOwn,
+		PermissionOrderRead:    ReachOwn,
+		PermissionOrderWrite:   ReachOwn,
+	},
+	RoleUser: {
+		PermissionProductRead: ReachAll,
+		PermissionCartRead:    ReachOwn,
+		PermissionCartWrite:   ReachOwn,
+		PermissionOrderRead:   ReachOwn,
+		PermissionOrderWrite:  ReachOwn,
+	},
+}
+
+// Valid checks whether the role is known.
+func (r Role) Valid() bool {
+	_, ok := matrix[r]
+	return ok
+}
+
+// ReachOf returns which resources the role is granted the permission on.
+func ReachOf(role Role, permission Permission) Reach {
+	return matrix[role][permission]
+}
+
+// Can checks whether the role is granted the permission on at least its own resources.
+func Can(role Role, permission Permission) bool {
+	return ReachOf(role, permission) != ReachNone
+}
+
+// CanAccess checks whether the role is granted the permission on a resource
+// owned by ownerID, for a user with the given ID.
+func CanAccess(role Role, permission Permission, userID uuid.UUID, ownerID uuid.UUID) bool {
+	switch ReachOf(role, permission) {
+	case ReachAll:
+		return true
+	case ReachOwn:
+		return userID != uuid.Nil && userID == ownerID
+	default:
+		return false
+	}
+}
@@ -0,0 +1,36 @@
+package rbac_test
+
+import (
+	"testing"
+
+	"github.com/evermos/boilerplate-go/shared/rbac"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanAccess(t *testing.T) {
+	owner := uuid.Must(uuid.NewV4())
+	other := uuid.Must(uuid.NewV4())
+
+	t.Run("Admin Manages Everything", func(t *testing.T) {
+		assert.True(t, rbac.CanAccess(rbac.RoleAdmin, rbac.PermissionProductWrite, other, owner))
+		assert.True(t, rbac.CanAccess(rbac.RoleAdmin, rbac.PermissionUserManage, other, owner))
+	})
+
+	t.Run("Shop Admin Manages Own Products", func(t *testing.T) {
+		assert.True(t, rbac.CanAccess(rbac.RoleShopAdmin, rbac.PermissionProductWrite, owner, owner))
+		assert.False(t, rbac.CanAccess(rbac.RoleShopAdmin, rbac.PermissionProductWrite, other, owner))
+		assert.False(t, rbac.Can(rbac.RoleShopAdmin, rbac.PermissionCartWrite))
+	})
+
+	t.Run("User Manages Own Cart And Orders", func(t *testing.T) {
+		assert.True(t, rbac.CanAccess(rbac.RoleUser, rbac.PermissionCartWrite, owner, owner))
+		assert.False(t, rbac.CanAccess(rbac.RoleUser, rbac.PermissionOrderRead, other, owner))
+		assert.False(t, rbac.Can(rbac.RoleUser, rbac.PermissionProductWrite))
+	})
+
+	t.Run("Unknown Role", func(t *testing.T) {
+		assert.False(t, rbac.Role("root").Valid())
+		assert.False(t, rbac.CanAccess(rbac.Role("root"), rbac.PermissionProductRead, owner, owner))
+	})
+}
@@ -6,12 +6,14 @@ import (
 	"os"
 	"os/signal"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/evermos/boilerplate-go/configs"
 	"github.com/evermos/boilerplate-go/docs"
 	"github.com/evermos/boilerplate-go/infras"
+	"github.com/evermos/boilerplate-go/shared/blobstore"
 	"github.com/evermos/boilerplate-go/shared/logger"
 	"github.com/evermos/boilerplate-go/transport/http/response"
 	"github.com/evermos/boilerplate-go/transport/http/router"
@@ -39,11 +41,12 @@ const (
 
 // HTTP is the HTTP server.
 type HTTP struct {
-	Config *configs.Config
-	DB     *infras.MySQLConn
-	Router router.Router
-	State  ServerState
-	mux    *chi.Mux
+	Config        *configs.Config
+	DB            *infras.MySQLConn
+	Router        router.Router
+	State         ServerState
+	mux           *chi.Mux
+	shutdownHooks []func()
 }
 
 // ProvideHTTP is the provider for HTTP.
@@ -74,6 +77,13 @@ func (h *HTTP) SetupAndServe() {
 	}
 }
 
+// RegisterShutdownHook registers a function that is run as soon as the server
+// receives SIGTERM, e.g. to drain event consumers. The server waits for all
+// hooks until the end of its cleanup period before shutting down.
+func (h *HTTP) RegisterShutdownHook(hook func()) {
+	h.shutdownHooks = append(h.shutdownHooks, hook)
+}
+
 func (h *HTTP) setupSwaggerDocs() {
 	if h.Config.Server.Env == "development" {
 		docs.SwaggerInfo.Title = h.Config.App.Name
@@ -86,9 +96,44 @@ func (h *HTTP) setupSwaggerDocs() {
 
 func (h *HTTP) setupRoutes() {
 	h.mux.Get("/health", h.HealthCheck)
+	h.setupLocalBlobs()
 	h.Router.SetupRoutes(h.mux)
 }
 
+// setupLocalBlobs serves the blobs of the local blob store, such as product
+// images. Other stores serve their blobs themselves.
+func (h *HTTP) setupLocalBlobs() {
+	blob := h.Config.Blob
+	if blob.Driver != blobstore.DriverLocal && blob.Driver != "" {
+		return
+	}
+
+	dir := blobstore.LocalDir(h.Config)
+	files := http.StripPrefix(blobstore.LocalPath, http.FileServer(blobFileSystem{http.Dir(dir)}))
+	h.mux.Handle(blobstore.LocalPath+"/*", files)
+	log.Info().Str("dir", dir).Str("path", blobstore.LocalPath).Msg("Serving local blobs.")
+}
+
+// blobFileSystem opens only files, so that directories are not listed.
+type blobFileSystem struct {
+	fs http.FileSystem
+}
+
+func (b blobFileSystem) Open(name string) (http.File, error) {
+	f, err := b.fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	stat, err := f.Stat()
+	if err == nil && stat.IsDir() {
+		f.Close()
+		return nil, os.ErrNotExist
+	}
+
+	return f, err
+}
+
 func (h *HTTP) setupGracefulShutdown() {
 	done := make(chan os.Signal, 1)
 	signal.Notify(done, os.Interrupt, syscall.SIGTERM)
@@ -102,6 +147,8 @@ func (h *HTTP) respondToSigterm(done chan os.Signal) {
 	shutdownConfig := h.Config.Server.Shutdown
 
 	log.Info().Msg("Received SIGTERM.")
+	hooksDone := h.runShutdownHooks()
+
 	log.Info().Int64("seconds", shutdownConfig.GracePeriodSeconds).Msg("Entering grace period.")
 	h.State = ServerStateInGracePeriod
 	time.Sleep(time.Duration(shutdownConfig.GracePeriodSeconds) * time.Second)
@@ -110,7 +157,32 @@ func (h *HTTP) respondToSigterm(done chan os.Signal) {
 	h.State = ServerStateInCleanupPeriod
 	time.Sleep(time.Duration(shutdownConfig.CleanupPeriodSeconds) * time.Second)
 
-	log.Info().Msg("Cleaning up completed. Shutting down now.")
+	select {
+	case <-hooksDone:
+		log.Info().Msg("Cleaning up completed. Shutting down now.")
+	default:
+		log.Warn().Msg("Shutdown hooks did not complete within the cleanup period. Shutting down now.")
+	}
+}
+
+func (h *HTTP) runShutdownHooks() <-chan struct{} {
+	done := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for _, hook := range h.shutdownHooks {
+		wg.Add(1)
+		go func(hook func()) {
+			defer wg.Done()
+			hook()
+		}(hook)
+	}
+
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	return done
 }
 
 func (h *HTTP) setupMiddleware() {
@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/evermos/boilerplate-go/internal/domain/apikey"
+	"github.com/evermos/boilerplate-go/shared"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/logger"
+	"github.com/evermos/boilerplate-go/shared/nuuid"
+	"github.com/evermos/boilerplate-go/shared/oauth"
+	"github.com/evermos/boilerplate-go/transport/http/response"
+)
+
+const (
+	// HeaderAPIKey is the header services send their API key in.
+	HeaderAPIKey = "X-API-Key"
+
+	HeaderRateLimitLimit     = "X-RateLimit-Limit"
+	HeaderRateLimitRemaining = "X-RateLimit-Remaining"
+	HeaderRateLimitReset     = "X-RateLimit-Reset"
+	HeaderRetryAfter         = "Retry-After"
+)
+
+const querySelectClientCreator = `SELECT created_by FROM oauth_clients WHERE client_id = ?`
+
+// APIKey authenticates services with the API key of the X-API-Key header and
+// counts the request against the key's quota, rejecting it with 429 once the
+// quota is exceeded. It puts the API key in the request context.
+func (a *Authentication) APIKey(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, err := a.apiKeys.Authenticate(r.Header.Get(HeaderAPIKey))
+		if err != nil {
+			response.WithError(w, err)
+			return
+		}
+
+		quota, err := a.apiKeys.ConsumeQuota(key)
+		if err != nil {
+			response.WithError(w, err)
+			return
+		}
+
+		remaining := quota.Remaining
+		if remaining < 0 {
+			remaining = 0
+		}
+		reset := strconv.Itoa(int(math.Ceil(quota.Reset.Seconds())))
+
+		w.Header().Set(HeaderRateLimitLimit, strconv.Itoa(quota.Limit))
+		w.Header().Set(HeaderRateLimitRemaining, strconv.Itoa(remaining))
+		w.Header().Set(HeaderRateLimitReset, reset)
+
+		if quota.Exceeded() {
+			w.Header().Set(HeaderRetryAfter, reset)
+			response.WithMessage(w, http.StatusTooManyRequests, "API key quota exceeded")
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), "apiKey", key)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// ServiceCredential authenticates services with the API key of the X-API-Key
+// header, or otherwise with a client credentials access token as
+// ClientCredentialGrant does, so that the tokens users log in with are never
+// accepted. Services act for a user: the owner of the API key or the admin who
+// created the client, whose claims it puts in the request context. Clients
+// created by no user are rejected with 403.
+func (a *Authentication) ServiceCredential(next http.Handler) http.Handler {
+	withClaims := a.serviceClaims(next)
+	apiKey := a.APIKey(withClaims)
+	clientCredential := a.ClientCredentialGrant(withClaims)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(HeaderAPIKey) != "" {
+			apiKey.ServeHTTP(w, r)
+			return
+		}
+
+		clientCredential.ServeHTTP(w, r)
+	})
+}
+
+func (a *Authentication) serviceClaims(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var claims shared.Claims
+		if key, ok := r.Context().Value("apiKey").(apikey.APIKey); ok {
+			claims.UserID = key.OwnerID
+		} else if accessToken, ok := r.Context().Value("accessToken").(oauth.OauthAccessToken); ok {
+			creator, err := a.resolveClientCreator(accessToken.ClientID)
+			if err != nil {
+				response.WithError(w, failure.InternalError(err))
+				return
+			}
+
+			if !creator.Valid {
+				response.WithError(w, failure.Forbidden("Client is not created by a user"))
+				return
+			}
+			claims.UserID = creator.UUID
+		} else {
+			response.WithError(w, failure.Unauthorized(oauth.ErrorInvalidToken))
+			return
+		}
+
+		role, err := a.resolveRole(claims.UserID)
+		if err != nil {
+			response.WithError(w, failure.InternalError(err))
+			return
+		}
+		claims.Role = role
+
+		ctx := context.WithValue(r.Context(), "claims", claims)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// resolveClientCreator resolves the user who created a client, if any.
+func (a *Authentication) resolveClientCreator(clientID string) (creator nuuid.NUUID, err error) {
+	err = a.db.Read.Get(&creator, querySelectClientCreator, clientID)
+	if err == sql.ErrNoRows {
+		return creator, nil
+	}
+
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+
+	return
+}
@@ -0,0 +1,261 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/evermos/boilerplate-go/configs"
+	"github.com/evermos/boilerplate-go/infras"
+	"github.com/evermos/boilerplate-go/internal/domain/apikey"
+	"github.com/evermos/boilerplate-go/shared"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/oauth"
+	"github.com/evermos/boilerplate-go/shared/rbac"
+	"github.com/evermos/boilerplate-go/transport/http/middleware"
+	"github.com/go-redis/redis"
+	"github.com/gofrs/uuid"
+	"github.com/guregu/null"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// apiKeyRepository is an in-memory APIKeyRepository.
+type apiKeyRepository struct {
+	keys map[string]apikey.APIKey
+}
+
+func (r *apiKeyRepository) CreateAPIKey(key apikey.APIKey) (err error) {
+	r.keys[key.KeyHash] = key
+	return
+}
+
+func (r *apiKeyRepository) ResolveAPIKeys() (keys []apikey.APIKey, err error) {
+	for _, key := range r.keys {
+		keys = append(keys, key)
+	}
+	return
+}
+
+func (r *apiKeyRepository) ResolveAPIKeyByID(id uuid.UUID) (key apikey.APIKey, err error) {
+	for _, key := range r.keys {
+		if key.ID == id {
+			return key, nil
+		}
+	}
+	return key, failure.NotFound("api key")
+}
+
+func (r *apiKeyRepository) ResolveAPIKeyByHash(keyHash string) (key apikey.APIKey, err error) {
+	key, ok := r.keys[keyHash]
+	if !ok {
+		return key, failure.NotFound("api key")
+	}
+	return
+}
+
+func (r *apiKeyRepository) RevokeAPIKey(key apikey.APIKey) (err error) {
+	r.keys[key.KeyHash] = key
+	return
+}
+
+func (r *apiKeyRepository) UpdateLastUsed(id uuid.UUID, lastUsedAt time.Time) (err error) {
+	return
+}
+
+func TestAPIKey(t *testing.T) {
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+	defer server.Close()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	conn := &infras.MySQLConn{Read: sqlx.NewDb(db, "mysql"), Write: sqlx.NewDb(db, "mysql")}
+
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	tokens := oauth.NewTokenStoreRedis(client)
+	repository := &apiKeyRepository{keys: make(map[string]apikey.APIKey)}
+	service := apikey.ProvideAPIKeyServiceImpl(repository, client, &configs.Config{})
+	auth := middleware.ProvideAuthentication(conn, nil, nil, oauth.New(nil, tokens, oauth.Config{}), service)
+
+	ownerID := uuid.Must(uuid.NewV4())
+	adminID := uuid.Must(uuid.NewV4())
+	create := func(requestFormat apikey.APIKeyRequestFormat) (apikey.APIKey, string) {
+		requestFormat.Name = "inventory-sync"
+		key, err := service.CreateAPIKey(requestFormat, ownerID)
+		require.NoError(t, err)
+
+		body, err := json.Marshal(key)
+		require.NoError(t, err)
+		var responseFormat apikey.APIKeyResponseFormat
+		require.NoError(t, json.Unmarshal(body, &responseFormat))
+
+		return key, responseFormat.Key
+	}
+
+	var served *http.Request
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served = r
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	serveRequest := func(handler http.Handler, r *http.Request) *httptest.ResponseRecorder {
+		served = nil
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	serve := func(handler http.Handler, plain string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if plain != "" {
+			r.Header.Set(middleware.HeaderAPIKey, plain)
+		}
+
+		return serveRequest(handler, r)
+	}
+
+	serveToken := func(handler http.Handler, accessToken oauth.OauthAccessToken) *httptest.ResponseRecorder {
+		accessToken.Expires = time.Now().Add(time.Hour)
+		require.NoError(t, tokens.CreateAccessToken(accessToken))
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set(middleware.HeaderAuthorization, "Bearer "+accessToken.AccessToken)
+
+		return serveRequest(handler, r)
+	}
+
+	t.Run("Active Key", func(t *testing.T) {
+		key, plain := create(apikey.APIKeyRequestFormat{QuotaPerMinute: 10})
+
+		w := serve(auth.APIKey(next), plain)
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, "10", w.Header().Get(middleware.HeaderRateLimitLimit))
+		assert.Equal(t, "9", w.Header().Get(middleware.HeaderRateLimitRemaining))
+		require.NotNil(t, served)
+		assert.Equal(t, key.ID, served.Context().Value("apiKey").(apikey.APIKey).ID)
+	})
+
+	t.Run("Missing Key", func(t *testing.T) {
+		w := serve(auth.APIKey(next), "")
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Nil(t, served)
+	})
+
+	t.Run("Unknown Key", func(t *testing.T) {
+		w := serve(auth.APIKey(next), apikey.KeyPrefix+"unknown")
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Nil(t, served)
+	})
+
+	t.Run("Revoked Key", func(t *testing.T) {
+		key, plain := create(apikey.APIKeyRequestFormat{})
+		_, err := service.RevokeAPIKey(key.ID, adminID)
+		require.NoError(t, err)
+
+		w := serve(auth.APIKey(next), plain)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Nil(t, served)
+	})
+
+	t.Run("Expired Key", func(t *testing.T) {
+		key, plain := create(apikey.APIKeyRequestFormat{ExpiresAt: null.TimeFrom(time.Now().Add(time.Hour))})
+		key.ExpiresAt = null.TimeFrom(time.Now().Add(-time.Minute))
+		repository.keys[key.KeyHash] = key
+
+		w := serve(auth.APIKey(next), plain)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Nil(t, served)
+	})
+
+	t.Run("Quota Exceeded", func(t *testing.T) {
+		_, plain := create(apikey.APIKeyRequestFormat{QuotaPerMinute: 2})
+
+		for i := 0; i < 2; i++ {
+			w := serve(auth.APIKey(next), plain)
+			require.Equal(t, http.StatusNoContent, w.Code)
+		}
+
+		w := serve(auth.APIKey(next), plain)
+
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "0", w.Header().Get(middleware.HeaderRateLimitRemaining))
+		assert.NotEmpty(t, w.Header().Get(middleware.HeaderRetryAfter))
+		assert.Equal(t, w.Header().Get(middleware.HeaderRateLimitReset), w.Header().Get(middleware.HeaderRetryAfter))
+		assert.Nil(t, served)
+	})
+
+	t.Run("Service Scopes", func(t *testing.T) {
+		handler := auth.ServiceCredential(auth.RequireScopes(oauth.ScopeInventoryWrite)(next))
+
+		_, readOnly := create(apikey.APIKeyRequestFormat{Scope: oauth.ScopeInventoryRead})
+		mock.ExpectQuery("SELECT role FROM user").
+			WithArgs(ownerID.String()).
+			WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow(rbac.RoleShopAdmin))
+
+		w := serve(handler, readOnly)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Header().Get(middleware.HeaderWWWAuthenticate), `scope="inventory:write"`)
+		assert.Nil(t, served)
+
+		_, readWrite := create(apikey.APIKeyRequestFormat{Scope: oauth.ScopeInventoryRead + " " + oauth.ScopeInventoryWrite})
+		mock.ExpectQuery("SELECT role FROM user").
+			WithArgs(ownerID.String()).
+			WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow(rbac.RoleShopAdmin))
+
+		w = serve(handler, readWrite)
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		require.NotNil(t, served)
+		assert.Equal(t, shared.Claims{UserID: ownerID, Role: rbac.RoleShopAdmin}, served.Context().Value("claims"))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Service Access Tokens", func(t *testing.T) {
+		handler := auth.ServiceCredential(auth.RequireScopes(oauth.ScopeInventoryWrite)(next))
+		scope := null.StringFrom(oauth.ScopeInventoryWrite)
+
+		// Tokens issued to users are refused, even with the scope.
+		w := serveToken(handler, oauth.OauthAccessToken{AccessToken: "user", ClientID: "client_web", UserID: null.StringFrom(ownerID.String()), Scope: scope})
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Nil(t, served)
+
+		// Client credentials tokens act for the admin who created the client.
+		mock.ExpectQuery("SELECT created_by FROM oauth_clients").
+			WithArgs("client_inventory").
+			WillReturnRows(sqlmock.NewRows([]string{"created_by"}).AddRow(adminID.String()))
+		mock.ExpectQuery("SELECT role FROM user").
+			WithArgs(adminID.String()).
+			WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow(rbac.RoleAdmin))
+
+		w = serveToken(handler, oauth.OauthAccessToken{AccessToken: "client", ClientID: "client_inventory", Scope: scope})
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		require.NotNil(t, served)
+		assert.Equal(t, shared.Claims{UserID: adminID, Role: rbac.RoleAdmin}, served.Context().Value("claims"))
+
+		// Clients created by no user have no one to act for.
+		mock.ExpectQuery("SELECT created_by FROM oauth_clients").
+			WithArgs("client_service").
+			WillReturnRows(sqlmock.NewRows([]string{"created_by"}).AddRow(nil))
+
+		w = serveToken(handler, oauth.OauthAccessToken{AccessToken: "seeded", ClientID: "client_service", Scope: scope})
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Nil(t, served)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
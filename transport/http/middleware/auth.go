@@ -2,11 +2,11 @@ package middleware
 
 import (
 	"context"
-	"encoding/json"
 	"net/http"
 
 	"github.com/evermos/boilerplate-go/configs"
 	"github.com/evermos/boilerplate-go/infras"
+	"github.com/evermos/boilerplate-go/internal/domain/apikey"
 	"github.com/evermos/boilerplate-go/shared"
 	"github.com/evermos/boilerplate-go/shared/failure"
 	"github.com/evermos/boilerplate-go/shared/oauth"
@@ -14,8 +14,11 @@ import (
 )
 
 type Authentication struct {
-	db     *infras.MySQLConn
-	config *configs.Config
+	db       *infras.MySQLConn
+	config   *configs.Config
+	verifier ClaimsVerifier
+	token    *oauth.Token
+	apiKeys  apikey.APIKeyService
 }
 
 type ValidateAuthResponse struct {
@@ -26,40 +29,40 @@ const (
 	HeaderAuthorization = "Authorization"
 )
 
-func ProvideAuthentication(db *infras.MySQLConn, config *configs.Config) *Authentication {
+func ProvideAuthentication(db *infras.MySQLConn, config *configs.Config, verifier ClaimsVerifier, token *oauth.Token, apiKeys apikey.APIKeyService) *Authentication {
 	return &Authentication{
-		db:     db,
-		config: config,
+		db:       db,
+		config:   config,
+		verifier: verifier,
+		token:    token,
+		apiKeys:  apiKeys,
 	}
 }
 
+// ValidateAuth verifies the access token with the configured ClaimsVerifier
+// and puts its claims in the request context.
 func (a *Authentication) ValidateAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		tokenStr := r.Header.Get("Authorization")
-
-		client := &http.Client{}
-		req, err := http.NewRequest("GET", a.config.App.AuthUrl, nil)
-		if err != nil {
-			response.WithMessage(w, http.StatusInternalServerError, err.Error())
+		claims, err := a.verifier.Verify(r.Header.Get(HeaderAuthorization))
+		if err == ErrVerifierUnavailable {
+			response.WithMessage(w, http.StatusServiceUnavailable, err.Error())
 			return
 		}
 
-		req.Header.Add("Authorization", tokenStr)
-
-		resp, err := client.Do(req)
 		if err != nil {
-			response.WithMessage(w, http.StatusInternalServerError, err.Error())
+			response.WithError(w, failure.Unauthorized(err.Error()))
 			return
 		}
 
-		decoder := json.NewDecoder(resp.Body)
-		var responseBody ValidateAuthResponse
-		err = decoder.Decode(&responseBody)
-		if err != nil {
-			response.WithError(w, failure.BadRequest(err))
+		if claims.Role == "" {
+			claims.Role, err = a.resolveRole(claims.UserID)
+			if err != nil {
+				response.WithError(w, failure.InternalError(err))
+				return
+			}
 		}
 
-		ctx := context.WithValue(r.Context(), "claims", responseBody.Data)
+		ctx := context.WithValue(r.Context(), "claims", claims)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -67,20 +70,19 @@ func (a *Authentication) ValidateAuth(next http.Handler) http.Handler {
 func (a *Authentication) ClientCredential(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		accessToken := r.Header.Get(HeaderAuthorization)
-		token := oauth.New(a.db.Read, oauth.Config{})
-
-		parseToken, err := token.ParseWithAccessToken(accessToken)
+		parseToken, err := a.token.ParseWithAccessToken(accessToken)
 		if err != nil {
 			response.WithMessage(w, http.StatusUnauthorized, err.Error())
 			return
 		}
 
 		if !parseToken.VerifyExpireIn() {
-			response.WithMessage(w, http.StatusUnauthorized, err.Error())
+			response.WithMessage(w, http.StatusUnauthorized, oauth.ErrorInvalidToken)
 			return
 		}
 
-		next.ServeHTTP(w, r)
+		ctx := context.WithValue(r.Context(), "accessToken", parseToken)
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
@@ -91,35 +93,33 @@ func (a *Authentication) ClientCredentialWithQueryParameter(next http.Handler) h
 		tokenType := params.Get("token_type")
 		accessToken := tokenType + " " + token
 
-		auth := oauth.New(a.db.Read, oauth.Config{})
-		parseToken, err := auth.ParseWithAccessToken(accessToken)
+		parseToken, err := a.token.ParseWithAccessToken(accessToken)
 		if err != nil {
 			response.WithMessage(w, http.StatusUnauthorized, err.Error())
 			return
 		}
 
 		if !parseToken.VerifyExpireIn() {
-			response.WithMessage(w, http.StatusUnauthorized, err.Error())
+			response.WithMessage(w, http.StatusUnauthorized, oauth.ErrorInvalidToken)
 			return
 		}
 
-		next.ServeHTTP(w, r)
+		ctx := context.WithValue(r.Context(), "accessToken", parseToken)
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
 func (a *Authentication) Password(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		accessToken := r.Header.Get(HeaderAuthorization)
-		token := oauth.New(a.db.Read, oauth.Config{})
-
-		parseToken, err := token.ParseWithAccessToken(accessToken)
+		parseToken, err := a.token.ParseWithAccessToken(accessToken)
 		if err != nil {
 			response.WithMessage(w, http.StatusUnauthorized, err.Error())
 			return
 		}
 
 		if !parseToken.VerifyExpireIn() {
-			response.WithMessage(w, http.StatusUnauthorized, err.Error())
+			response.WithMessage(w, http.StatusUnauthorized, oauth.ErrorInvalidToken)
 			return
 		}
 
@@ -128,6 +128,34 @@ func (a *Authentication) Password(next http.Handler) http.Handler {
 			return
 		}
 
-		next.ServeHTTP(w, r)
+		ctx := context.WithValue(r.Context(), "accessToken", parseToken)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// ClientCredentialGrant authenticates as ClientCredential does, but only with
+// access tokens issued with client credentials. Tokens issued to a user are
+// rejected with 403.
+func (a *Authentication) ClientCredentialGrant(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accessToken := r.Header.Get(HeaderAuthorization)
+		parseToken, err := a.token.ParseWithAccessToken(accessToken)
+		if err != nil {
+			response.WithMessage(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+
+		if !parseToken.VerifyExpireIn() {
+			response.WithMessage(w, http.StatusUnauthorized, oauth.ErrorInvalidToken)
+			return
+		}
+
+		if parseToken.VerifyUserLoggedIn() {
+			response.WithMessage(w, http.StatusForbidden, "Access token is issued to a user")
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), "accessToken", parseToken)
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/evermos/boilerplate-go/internal/domain/apikey"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/oauth"
+	"github.com/evermos/boilerplate-go/transport/http/response"
+)
+
+// HeaderWWWAuthenticate is the header describing why a bearer token was rejected.
+const HeaderWWWAuthenticate = "WWW-Authenticate"
+
+// RequireScopes only lets through access tokens and API keys issued with
+// every one of the given scopes. Others are rejected with 403 and an
+// insufficient_scope challenge, as RFC 6750 section 3.1 specifies. It must be
+// used after ClientCredential, Password or APIKey.
+func (a *Authentication) RequireScopes(scopes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			granted, ok := grantedScopes(r)
+			if !ok {
+				w.Header().Set(HeaderWWWAuthenticate, `Bearer error="invalid_token"`)
+				response.WithError(w, failure.Unauthorized(oauth.ErrorInvalidToken))
+				return
+			}
+
+			missing := granted.Missing(scopes...)
+			if len(missing) > 0 {
+				w.Header().Set(HeaderWWWAuthenticate, fmt.Sprintf(
+					`Bearer error="insufficient_scope", error_description="The access token lacks the required scope", scope="%s"`,
+					strings.Join(scopes, " "),
+				))
+				response.WithError(w, failure.Forbidden("Access token lacks scope "+strings.Join(missing, " ")))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// grantedScopes returns the scopes of the access token or API key the request
+// was authenticated with.
+func grantedScopes(r *http.Request) (oauth.Scope, bool) {
+	if accessToken, ok := r.Context().Value("accessToken").(oauth.OauthAccessToken); ok {
+		return accessToken.Scopes(), true
+	}
+
+	if key, ok := r.Context().Value("apiKey").(apikey.APIKey); ok {
+		return key.Scopes(), true
+	}
+
+	return nil, false
+}
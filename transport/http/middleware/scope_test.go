@@ -0,0 +1,61 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/evermos/boilerplate-go/internal/domain/apikey"
+	"github.com/evermos/boilerplate-go/shared/oauth"
+	"github.com/evermos/boilerplate-go/transport/http/middleware"
+	"github.com/guregu/null"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireScopes(t *testing.T) {
+	auth := middleware.ProvideAuthentication(nil, nil, nil, nil, nil)
+	handler := auth.RequireScopes(oauth.ScopeFooRead, oauth.ScopeFooWrite)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	serve := func(accessToken interface{}) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if accessToken != nil {
+			r = r.WithContext(context.WithValue(r.Context(), "accessToken", accessToken))
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("All Scopes", func(t *testing.T) {
+		w := serve(oauth.OauthAccessToken{Scope: null.StringFrom("foo:write foo:read oauth:introspect")})
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+	})
+
+	t.Run("Insufficient Scope", func(t *testing.T) {
+		w := serve(oauth.OauthAccessToken{Scope: null.StringFrom("foo:read")})
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Header().Get(middleware.HeaderWWWAuthenticate), `error="insufficient_scope"`)
+		assert.Contains(t, w.Header().Get(middleware.HeaderWWWAuthenticate), `scope="foo:read foo:write"`)
+	})
+
+	t.Run("API Key", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r = r.WithContext(context.WithValue(r.Context(), "apiKey", apikey.APIKey{Scope: null.StringFrom("foo:read foo:write")}))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+	})
+
+	t.Run("Unauthenticated", func(t *testing.T) {
+		w := serve(nil)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
@@ -0,0 +1,273 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/evermos/boilerplate-go/configs"
+	"github.com/evermos/boilerplate-go/shared"
+	"github.com/evermos/boilerplate-go/shared/breaker"
+	"github.com/evermos/boilerplate-go/shared/jwks"
+	"github.com/golang-jwt/jwt"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// AuthModeLocal verifies JWTs locally against a JWKS.
+	AuthModeLocal = "local"
+	// AuthModeRemote validates tokens by calling the auth service.
+	AuthModeRemote = "remote"
+
+	defaultJWKSRefresh           = time.Hour
+	defaultRemoteCacheTTL        = 30 * time.Second
+	defaultRemoteTimeout         = 5 * time.Second
+	defaultRemoteBreakerFailures = 5
+	defaultRemoteBreakerCooldown = 30 * time.Second
+	maxRemoteCacheEntries        = 10000
+	bearerPrefix                 = "Bearer "
+)
+
+// Errors returned when verifying access tokens.
+var (
+	ErrInvalidToken        = errors.New("invalid access token")
+	ErrVerifierUnavailable = errors.New("access token verification is unavailable")
+)
+
+// signingMethods are the JWT algorithms accepted by the LocalVerifier. HMAC
+// algorithms are excluded, as JWKS public keys must never be used as secrets.
+var signingMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// ClaimsVerifier verifies the access token of an Authorization header and
+// returns its claims.
+type ClaimsVerifier interface {
+	Verify(authorization string) (claims shared.Claims, err error)
+}
+
+// ProvideClaimsVerifier is the provider for the ClaimsVerifier selected by
+// Auth.Mode. Without a mode, tokens are verified locally when a JWKS is
+// configured, and remotely otherwise.
+func ProvideClaimsVerifier(config *configs.Config) ClaimsVerifier {
+	auth := config.Auth
+
+	mode := auth.Mode
+	if mode == "" {
+		mode = AuthModeRemote
+		if auth.JWKS.URL != "" || auth.JWKS.File != "" {
+			mode = AuthModeLocal
+		}
+	}
+
+	switch mode {
+	case AuthModeLocal:
+		var source jwks.Source = &jwks.FileSource{Path: auth.JWKS.File}
+		if auth.JWKS.URL != "" {
+			source = jwks.NewURLSource(auth.JWKS.URL)
+		}
+
+		refresh := time.Duration(auth.JWKS.RefreshSeconds) * time.Second
+		if refresh <= 0 {
+			refresh = defaultJWKSRefresh
+		}
+
+		return NewLocalVerifier(jwks.NewKeySet(source, refresh), auth.JWT.Issuer, auth.JWT.Audience)
+	case AuthModeRemote:
+		return NewRemoteVerifier(config)
+	default:
+		log.Fatal().Str("mode", mode).Msg("Unknown auth mode.")
+		return nil
+	}
+}
+
+// LocalVerifier verifies JWTs against the keys of a JWKS, checking their
+// signature, expiry, issuer and audience.
+type LocalVerifier struct {
+	KeySet   *jwks.KeySet
+	Issuer   string
+	Audience string
+
+	parser *jwt.Parser
+}
+
+// NewLocalVerifier creates a new LocalVerifier. An empty issuer or audience
+// is not checked.
+func NewLocalVerifier(keySet *jwks.KeySet, issuer string, audience string) *LocalVerifier {
+	return &LocalVerifier{
+		KeySet:   keySet,
+		Issuer:   issuer,
+		Audience: audience,
+		parser:   &jwt.Parser{ValidMethods: signingMethods},
+	}
+}
+
+// Verify verifies a bearer JWT.
+func (v *LocalVerifier) Verify(authorization string) (claims shared.Claims, err error) {
+	if !strings.HasPrefix(authorization, bearerPrefix) {
+		return claims, ErrInvalidToken
+	}
+
+	_, err = v.parser.ParseWithClaims(strings.TrimPrefix(authorization, bearerPrefix), &claims, v.KeySet.Keyfunc)
+	if err != nil {
+		log.Debug().Err(err).Msg("Rejected access token.")
+		return shared.Claims{}, ErrInvalidToken
+	}
+
+	switch {
+	case !claims.VerifyExpiresAt(time.Now().Unix(), true):
+		return shared.Claims{}, errors.New("access token is expired")
+	case v.Issuer != "" && !claims.VerifyIssuer(v.Issuer, true):
+		return shared.Claims{}, errors.New("access token has an unexpected issuer")
+	case v.Audience != "" && !claims.VerifyAudience(v.Audience, true):
+		return shared.Claims{}, errors.New("access token has an unexpected audience")
+	}
+
+	return
+}
+
+type cachedClaims struct {
+	claims  shared.Claims
+	expires time.Time
+}
+
+// RemoteVerifier validates tokens by calling the auth service. Valid tokens
+// are cached briefly, and a circuit breaker stops calling the auth service
+// while it is failing.
+type RemoteVerifier struct {
+	URL      string
+	Client   *http.Client
+	Breaker  *breaker.Breaker
+	CacheTTL time.Duration
+
+	mu    sync.Mutex
+	cache map[[sha256.Size]byte]cachedClaims
+}
+
+// NewRemoteVerifier creates a new RemoteVerifier calling App.AuthUrl.
+func NewRemoteVerifier(config *configs.Config) *RemoteVerifier {
+	remote := config.Auth.Remote
+
+	timeout := time.Duration(remote.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = defaultRemoteTimeout
+	}
+
+	cacheTTL := time.Duration(remote.CacheSeconds) * time.Second
+	if cacheTTL <= 0 {
+		cacheTTL = defaultRemoteCacheTTL
+	}
+
+	maxFailures := remote.BreakerMaxFailures
+	if maxFailures <= 0 {
+		maxFailures = defaultRemoteBreakerFailures
+	}
+
+	cooldown := time.Duration(remote.BreakerCooldownSeconds) * time.Second
+	if cooldown <= 0 {
+		cooldown = defaultRemoteBreakerCooldown
+	}
+
+	return &RemoteVerifier{
+		URL:      config.App.AuthUrl,
+		Client:   &http.Client{Timeout: timeout},
+		Breaker:  breaker.New(maxFailures, cooldown),
+		CacheTTL: cacheTTL,
+		cache:    make(map[[sha256.Size]byte]cachedClaims),
+	}
+}
+
+// Verify validates a token with the auth service, or from cache.
+func (v *RemoteVerifier) Verify(authorization string) (claims shared.Claims, err error) {
+	if authorization == "" {
+		return claims, ErrInvalidToken
+	}
+
+	key := sha256.Sum256([]byte(authorization))
+
+	v.mu.Lock()
+	cached, ok := v.cache[key]
+	v.mu.Unlock()
+
+	if ok && time.Now().Before(cached.expires) {
+		return cached.claims, nil
+	}
+
+	err = v.Breaker.Execute(func() (err error) {
+		claims, err = v.fetch(authorization)
+		return
+	}, func(err error) bool {
+		return err != ErrInvalidToken
+	})
+
+	switch {
+	case err == ErrInvalidToken:
+		return shared.Claims{}, err
+	case err != nil:
+		log.Error().Err(err).Msg("Failed validating access token with the auth service.")
+		return shared.Claims{}, ErrVerifierUnavailable
+	}
+
+	v.store(key, claims)
+
+	return
+}
+
+func (v *RemoteVerifier) fetch(authorization string) (claims shared.Claims, err error) {
+	req, err := http.NewRequest(http.MethodGet, v.URL, nil)
+	if err != nil {
+		return
+	}
+
+	req.Header.Add(HeaderAuthorization, authorization)
+
+	resp, err := v.Client.Do(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return claims, ErrInvalidToken
+	case resp.StatusCode != http.StatusOK:
+		return claims, fmt.Errorf("auth service responded %s", resp.Status)
+	}
+
+	var responseBody ValidateAuthResponse
+	err = json.NewDecoder(resp.Body).Decode(&responseBody)
+	if err != nil {
+		return
+	}
+
+	return responseBody.Data, nil
+}
+
+// store caches claims until the cache TTL or the token's expiry, whichever
+// comes first.
+func (v *RemoteVerifier) store(key [sha256.Size]byte, claims shared.Claims) {
+	now := time.Now()
+
+	expires := now.Add(v.CacheTTL)
+	if claims.ExpiresAt > 0 && time.Unix(claims.ExpiresAt, 0).Before(expires) {
+		expires = time.Unix(claims.ExpiresAt, 0)
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if len(v.cache) >= maxRemoteCacheEntries {
+		for k, cached := range v.cache {
+			if now.After(cached.expires) {
+				delete(v.cache, k)
+			}
+		}
+	}
+
+	if len(v.cache) < maxRemoteCacheEntries {
+		v.cache[key] = cachedClaims{claims: claims, expires: expires}
+	}
+}
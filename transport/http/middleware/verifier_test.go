@@ -0,0 +1,163 @@
+package middleware_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/evermos/boilerplate-go/configs"
+	"github.com/evermos/boilerplate-go/shared"
+	"github.com/evermos/boilerplate-go/shared/jwks"
+	"github.com/evermos/boilerplate-go/transport/http/middleware"
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeJWKS(t *testing.T, kid string, key *rsa.PublicKey) string {
+	document := map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": kid,
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}},
+	}
+
+	dir, err := ioutil.TempDir("", "jwks")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	data, _ := json.Marshal(document)
+	path := filepath.Join(dir, "jwks.json")
+	require.NoError(t, ioutil.WriteFile(path, data, 0600))
+
+	return path
+}
+
+func sign(t *testing.T, key *rsa.PrivateKey, kid string, claims shared.Claims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+
+	return "Bearer " + signed
+}
+
+func TestLocalVerifier(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	keySet := jwks.NewKeySet(&jwks.FileSource{Path: writeJWKS(t, "key-1", &key.PublicKey)}, 0)
+	verifier := middleware.NewLocalVerifier(keySet, "https://auth.evermos.com", "shipping-cart")
+
+	valid := shared.Claims{
+		Username: "jane",
+		StandardClaims: jwt.StandardClaims{
+			Audience:  "shipping-cart",
+			ExpiresAt: time.Now().Add(time.Hour).Unix(),
+			Issuer:    "https://auth.evermos.com",
+		},
+	}
+
+	t.Run("Valid Token", func(t *testing.T) {
+		claims, err := verifier.Verify(sign(t, key, "key-1", valid))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "jane", claims.Username)
+	})
+
+	t.Run("Rejected Claims", func(t *testing.T) {
+		expired, wrongIssuer, wrongAudience, noExpiry := valid, valid, valid, valid
+		expired.ExpiresAt = time.Now().Add(-time.Minute).Unix()
+		wrongIssuer.Issuer = "https://evil.com"
+		wrongAudience.Audience = "other-service"
+		noExpiry.ExpiresAt = 0
+
+		for _, claims := range []shared.Claims{expired, wrongIssuer, wrongAudience, noExpiry} {
+			_, err := verifier.Verify(sign(t, key, "key-1", claims))
+			assert.Error(t, err)
+		}
+	})
+
+	t.Run("Unknown Key", func(t *testing.T) {
+		otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+
+		_, err = verifier.Verify(sign(t, otherKey, "key-1", valid))
+		assert.Equal(t, middleware.ErrInvalidToken, err)
+
+		_, err = verifier.Verify(sign(t, key, "key-2", valid))
+		assert.Equal(t, middleware.ErrInvalidToken, err)
+	})
+
+	t.Run("Symmetric Algorithm", func(t *testing.T) {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, valid)
+		token.Header["kid"] = "key-1"
+		signed, _ := token.SignedString(key.PublicKey.N.Bytes())
+
+		_, err := verifier.Verify("Bearer " + signed)
+		assert.Equal(t, middleware.ErrInvalidToken, err)
+	})
+}
+
+func TestRemoteVerifier(t *testing.T) {
+	calls := 0
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(middleware.ValidateAuthResponse{Data: shared.Claims{Username: "jane"}})
+	}))
+	defer server.Close()
+
+	config := &configs.Config{}
+	config.App.AuthUrl = server.URL
+	config.Auth.Remote.BreakerMaxFailures = 2
+	config.Auth.Remote.BreakerCooldownSeconds = 60
+
+	t.Run("Cached Response", func(t *testing.T) {
+		verifier := middleware.NewRemoteVerifier(config)
+		calls = 0
+
+		for i := 0; i < 3; i++ {
+			claims, err := verifier.Verify("Bearer token")
+			assert.NoError(t, err)
+			assert.Equal(t, "jane", claims.Username)
+		}
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("Unauthorized Token", func(t *testing.T) {
+		verifier := middleware.NewRemoteVerifier(config)
+		status = http.StatusUnauthorized
+		defer func() { status = http.StatusOK }()
+
+		_, err := verifier.Verify("Bearer token")
+		assert.Equal(t, middleware.ErrInvalidToken, err)
+	})
+
+	t.Run("Open Breaker", func(t *testing.T) {
+		verifier := middleware.NewRemoteVerifier(config)
+		status = http.StatusBadGateway
+		defer func() { status = http.StatusOK }()
+		calls = 0
+
+		for i := 0; i < 4; i++ {
+			_, err := verifier.Verify("Bearer token")
+			assert.Equal(t, middleware.ErrVerifierUnavailable, err)
+		}
+		assert.Equal(t, 2, calls)
+	})
+}
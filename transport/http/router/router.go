@@ -11,6 +11,12 @@ type DomainHandlers struct {
 	ProductHandler   handlers.ProductHandler
 	CartHandler      handlers.CartHandler
 	OrderHandler     handlers.OrderHandler
+	AuthHandler      handlers.AuthHandler
+	UserHandler      handlers.UserHandler
+	OAuthHandler     handlers.OAuthHandler
+	APIKeyHandler    handlers.APIKeyHandler
+	CategoryHandler  handlers.CategoryHandler
+	WarehouseHandler handlers.WarehouseHandler
 }
 
 // Router is the router struct containing handlers.
@@ -32,5 +38,11 @@ func (r *Router) SetupRoutes(mux *chi.Mux) {
 		r.DomainHandlers.ProductHandler.Router(rc)
 		r.DomainHandlers.CartHandler.Router(rc)
 		r.DomainHandlers.OrderHandler.Router(rc)
+		r.DomainHandlers.AuthHandler.Router(rc)
+		r.DomainHandlers.UserHandler.Router(rc)
+		r.DomainHandlers.OAuthHandler.Router(rc)
+		r.DomainHandlers.APIKeyHandler.Router(rc)
+		r.DomainHandlers.CategoryHandler.Router(rc)
+		r.DomainHandlers.WarehouseHandler.Router(rc)
 	})
 }
@@ -5,13 +5,25 @@ package main
 
 import (
 	"github.com/evermos/boilerplate-go/configs"
+	"github.com/evermos/boilerplate-go/event"
+	"github.com/evermos/boilerplate-go/event/consumer"
+	fooBarBazEvent "github.com/evermos/boilerplate-go/event/domain/foobarbaz"
 	"github.com/evermos/boilerplate-go/event/producer"
 	"github.com/evermos/boilerplate-go/infras"
+	"github.com/evermos/boilerplate-go/internal/domain/apikey"
 	"github.com/evermos/boilerplate-go/internal/domain/cart"
+	"github.com/evermos/boilerplate-go/internal/domain/category"
 	"github.com/evermos/boilerplate-go/internal/domain/foobarbaz"
+	"github.com/evermos/boilerplate-go/internal/domain/oauthclient"
 	"github.com/evermos/boilerplate-go/internal/domain/order"
 	"github.com/evermos/boilerplate-go/internal/domain/product"
+	"github.com/evermos/boilerplate-go/internal/domain/user"
+	"github.com/evermos/boilerplate-go/internal/domain/warehouse"
 	"github.com/evermos/boilerplate-go/internal/handlers"
+	"github.com/evermos/boilerplate-go/shared/audit"
+	"github.com/evermos/boilerplate-go/shared/blobstore"
+	"github.com/evermos/boilerplate-go/shared/mailer"
+	"github.com/evermos/boilerplate-go/shared/oauth"
 	"github.com/evermos/boilerplate-go/transport/http"
 	"github.com/evermos/boilerplate-go/transport/http/middleware"
 	"github.com/evermos/boilerplate-go/transport/http/router"
@@ -26,6 +38,7 @@ var configurations = wire.NewSet(
 // Wiring for persistences.
 var persistences = wire.NewSet(
 	infras.ProvideMySQLConn,
+	infras.ProvideRedis,
 )
 
 // Wiring for domain FooBarBaz.
@@ -47,6 +60,24 @@ var domainProduct = wire.NewSet(
 	wire.Bind(new(product.ProductService), new(*product.ProductServiceImpl)),
 	product.ProvideProductRepositoryMySQL,
 	wire.Bind(new(product.ProductRepository), new(*product.ProductRepositoryMySQL)),
+	product.ProvideSearcher,
+)
+
+// Wiring for domain Category.
+var domainCategory = wire.NewSet(
+	category.ProvideCategoryServiceImpl,
+	wire.Bind(new(category.CategoryService), new(*category.CategoryServiceImpl)),
+	category.ProvideCategoryRepositoryMySQL,
+	wire.Bind(new(category.CategoryRepository), new(*category.CategoryRepositoryMySQL)),
+)
+
+// Wiring for domain Warehouse.
+var domainWarehouse = wire.NewSet(
+	warehouse.ProvideWarehouseServiceImpl,
+	wire.Bind(new(warehouse.WarehouseService), new(*warehouse.WarehouseServiceImpl)),
+	warehouse.ProvideWarehouseRepositoryMySQL,
+	wire.Bind(new(warehouse.WarehouseRepository), new(*warehouse.WarehouseRepositoryMySQL)),
+	warehouse.ProvideAllocator,
 )
 
 // Wiring for domain Cart.
@@ -65,33 +96,86 @@ var domainOrder = wire.NewSet(
 	wire.Bind(new(order.OrderRepository), new(*order.OrderRepositoryMySQL)),
 )
 
+// Wiring for domain User.
+var domainUser = wire.NewSet(
+	user.ProvideUserServiceImpl,
+	wire.Bind(new(user.UserService), new(*user.UserServiceImpl)),
+	user.ProvideUserRepositoryMySQL,
+	wire.Bind(new(user.UserRepository), new(*user.UserRepositoryMySQL)),
+)
+
+var domainOAuthClient = wire.NewSet(
+	oauthclient.ProvideClientServiceImpl,
+	wire.Bind(new(oauthclient.ClientService), new(*oauthclient.ClientServiceImpl)),
+	oauthclient.ProvideClientRepositoryMySQL,
+	wire.Bind(new(oauthclient.ClientRepository), new(*oauthclient.ClientRepositoryMySQL)),
+)
+
+var domainAPIKey = wire.NewSet(
+	apikey.ProvideAPIKeyServiceImpl,
+	wire.Bind(new(apikey.APIKeyService), new(*apikey.APIKeyServiceImpl)),
+	apikey.ProvideAPIKeyRepositoryMySQL,
+	wire.Bind(new(apikey.APIKeyRepository), new(*apikey.APIKeyRepositoryMySQL)),
+)
+
 // Wiring for all domains.
 var domains = wire.NewSet(
 	domainFooBarBaz,
 	domainProduct,
+	domainCategory,
+	domainWarehouse,
 	domainCart,
 	domainOrder,
+	domainUser,
+	domainOAuthClient,
+	domainAPIKey,
 )
 
 var authMiddleware = wire.NewSet(
 	middleware.ProvideAuthentication,
+	middleware.ProvideClaimsVerifier,
+	oauth.ProvideToken,
+	oauth.ProvideTokenStore,
+	oauth.ProvideIntrospector,
+	oauth.ProvideLoginGuard,
+	audit.ProvideRecorderMySQL,
+	wire.Bind(new(audit.Recorder), new(*audit.RecorderMySQL)),
+)
+
+// Wiring for outgoing mail.
+var mail = wire.NewSet(
+	mailer.ProvideMailer,
+)
+
+// Wiring for blob storage.
+var blobs = wire.NewSet(
+	blobstore.ProvideBlobStore,
 )
 
 // Wiring for HTTP routing.
 var routing = wire.NewSet(
-	wire.Struct(new(router.DomainHandlers), "FooBarBazHandler", "ProductHandler", "CartHandler", "OrderHandler"),
+	wire.Struct(new(router.DomainHandlers), "FooBarBazHandler", "ProductHandler", "CartHandler", "OrderHandler", "AuthHandler", "UserHandler", "OAuthHandler", "APIKeyHandler", "CategoryHandler", "WarehouseHandler"),
 	handlers.ProvideFooBarBazHandler,
 	handlers.ProvideProductHandler,
 	handlers.ProvideCartHandler,
 	handlers.ProvideOrderHandler,
+	handlers.ProvideAuthHandler,
+	handlers.ProvideUserHandler,
+	handlers.ProvideOAuthHandler,
+	handlers.ProvideAPIKeyHandler,
+	handlers.ProvideCategoryHandler,
+	handlers.ProvideWarehouseHandler,
 	router.ProvideRouter,
 )
 
 // Wiring for all domains event consumer.
-// var evco = wire.NewSet(
-// 	wire.Struct(new(event.Consumers), "FooBarBaz"),
-// 	fooBarBazEvent.ProvideConsumerImpl,
-// )
+var evco = wire.NewSet(
+	event.ProvideConsumers,
+	event.ProvideRegistry,
+	consumer.ProvideLedger,
+	consumer.ProvideVerifier,
+	fooBarBazEvent.ProvideConsumerImpl,
+)
 
 // Wiring for everything.
 func InitializeService() *http.HTTP {
@@ -102,6 +186,10 @@ func InitializeService() *http.HTTP {
 		persistences,
 		// middleware
 		authMiddleware,
+		// mail
+		mail,
+		// blob storage
+		blobs,
 		// domains
 		domains,
 		// routing
@@ -112,16 +200,16 @@ func InitializeService() *http.HTTP {
 }
 
 // Wiring the event needs.
-// func InitializeEvent() event.Consumers {
-// 	wire.Build(
-// 		// configurations
-// 		configurations,
-// 		// persistences
-// 		persistences,
-// 		// domains
-// 		domains,
-// 		// event consumer
-// 		evco)
-
-// 	return event.Consumers{}
-// }
+func InitializeEvent() *event.Consumers {
+	wire.Build(
+		// configurations
+		configurations,
+		// persistences
+		persistences,
+		// domains
+		domains,
+		// event consumer
+		evco)
+
+	return &event.Consumers{}
+}